@@ -0,0 +1,303 @@
+package handlers
+
+import (
+	"log"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/projectflow/apperr"
+	"github.com/projectflow/config"
+	"github.com/projectflow/mailer"
+	"github.com/projectflow/models"
+	"github.com/projectflow/utils"
+	"github.com/projectflow/utils/accounttoken"
+	"github.com/projectflow/utils/password"
+	"github.com/projectflow/utils/refreshtoken"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	// How long an email verification link works
+	verifyEmailTTL = 48 * time.Hour
+	// How long a password reset link works
+	resetPasswordTTL = time.Hour
+)
+
+var (
+	// Single-use tokens sent by email
+	accountTokens = accounttoken.NewMemoryStore()
+
+	// Rules for new passwords
+	passwordPolicy = password.NewPolicy(10)
+
+	// Whether unverified users are refused at login
+	requireEmailVerification = false
+
+	// Where profile pictures are stored and the URL they are served from
+	avatarDir      = "uploads/avatars"
+	avatarBaseURL  = "http://localhost:8080/avatars/"
+	avatarMaxBytes = 1024 * 1024
+)
+
+// ConfigureAccounts applies the password policy, email verification and
+// profile picture settings
+func ConfigureAccounts(cfg *config.Config) error {
+	passwordPolicy.MinLength = cfg.PasswordMinLength
+	passwordPolicy.RequireUpper = cfg.PasswordRequireMixedCase
+	passwordPolicy.RequireLower = cfg.PasswordRequireMixedCase
+	passwordPolicy.RequireDigit = cfg.PasswordRequireDigit
+	passwordPolicy.RequireSymbol = cfg.PasswordRequireSymbol
+	requireEmailVerification = cfg.RequireEmailVerification
+	avatarDir = cfg.AvatarDir
+	avatarBaseURL = strings.TrimSuffix(cfg.APIURL, "/") + "/avatars/"
+	avatarMaxBytes = cfg.AvatarMaxKB * 1024
+
+	if cfg.PasswordBreachedListFile != "" {
+		return passwordPolicy.LoadBreachedFile(cfg.PasswordBreachedListFile)
+	}
+	return nil
+}
+
+// checkPassword returns the policy violations of a new password as an error,
+// or nil if the password is acceptable
+func checkPassword(newPassword string, user *models.User) error {
+	var personal []string
+	if user != nil {
+		personal = []string{user.Username, user.Email}
+	}
+
+	problems := passwordPolicy.Validate(newPassword, personal...)
+	if len(problems) == 0 {
+		return nil
+	}
+	return apperr.Invalid("weak_password", "Password "+strings.Join(problems, ", ")).
+		With("problems", problems)
+}
+
+// setPassword hashes and stores a new password and ends every existing session
+func setPassword(user *models.User, newPassword string) error {
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	user.PasswordHash = string(hashedPassword)
+	user.PasswordChangedAt = &now
+	user.UpdatedAt = now
+
+	utils.RevokeAllTokens(user.ID)
+	refreshtoken.DefaultStore.RevokeUser(user.ID)
+	accountTokens.Revoke(user.ID, accounttoken.PurposeResetPassword)
+
+	return nil
+}
+
+// sendAccountEmail renders and queues an account email. Failures are only
+// logged so they never reveal anything to the caller.
+func sendAccountEmail(user *models.User, template, content, actionURL string) {
+	if mailQueue == nil {
+		log.Printf("Email delivery is not configured, dropping %s email for %s", template, user.Email)
+		return
+	}
+
+	msg, err := mailer.Render(template, user.Email, mailer.EventData{
+		RecipientName: user.FullName,
+		Content:       content,
+		ActionURL:     actionURL,
+		AppURL:        appURL,
+	})
+	if err != nil {
+		log.Printf("Failed to render %s email: %v", template, err)
+		return
+	}
+	if err := mailQueue.Send(msg); err != nil {
+		log.Printf("Failed to send %s email: %v", template, err)
+	}
+}
+
+// sendVerificationEmail emails a fresh verification link to a user
+func sendVerificationEmail(user *models.User) {
+	token, err := accountTokens.Issue(user.ID, accounttoken.PurposeVerifyEmail, verifyEmailTTL)
+	if err != nil {
+		log.Printf("Failed to issue verification token: %v", err)
+		return
+	}
+
+	sendAccountEmail(user, "verify_email",
+		"Please confirm that "+user.Email+" is your email address. The link expires in 48 hours.",
+		appURL+"/verify-email?token="+url.QueryEscape(token))
+}
+
+// GetPasswordPolicy returns the rules new passwords must follow
+func GetPasswordPolicy(c *fiber.Ctx) error {
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"policy": passwordPolicy,
+	})
+}
+
+// VerifyEmail confirms a user's email address with an emailed token
+func VerifyEmail(c *fiber.Ctx) error {
+	// Parse request body
+	var req models.VerifyEmailRequest
+	if err := parseBody(c, &req); err != nil {
+		return err
+	}
+
+	userID, err := accountTokens.Consume(req.Token, accounttoken.PurposeVerifyEmail)
+	if err != nil {
+		// Links confirming a new address use the same page
+		return confirmEmailChange(c, req.Token)
+	}
+
+	user, ok := users[userID]
+	if !ok {
+		return apperr.Invalid("invalid_verification_token", "Invalid or expired verification link")
+	}
+	user.EmailVerified = true
+	user.UpdatedAt = time.Now()
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Email address verified",
+	})
+}
+
+// ResendVerificationEmail sends a new verification link to the current user
+func ResendVerificationEmail(c *fiber.Ctx) error {
+	user, err := currentUser(c)
+	if err != nil {
+		return err
+	}
+
+	if user.EmailVerified {
+		return apperr.Invalid("email_already_verified", "Email address is already verified")
+	}
+	if retryAfter := rateLimited(c, "verify-email:user:"+user.ID.String(), accountEmailRule); retryAfter > 0 {
+		return tooManyAttempts(c, retryAfter)
+	}
+
+	sendVerificationEmail(user)
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Verification email sent",
+	})
+}
+
+// ForgotPassword emails a reset link. The response is the same whether or
+// not the account exists.
+func ForgotPassword(c *fiber.Ctx) error {
+	// Parse request body
+	var req models.ForgotPasswordRequest
+	if err := parseBody(c, &req); err != nil {
+		return err
+	}
+
+	// Limit reset emails per client address and per email address
+	if retryAfter := rateLimited(c, "forgot-password:ip:"+c.IP(), emailIPRule); retryAfter > 0 {
+		return tooManyAttempts(c, retryAfter)
+	}
+	if retryAfter := rateLimited(c, "forgot-password:account:"+normalizeEmail(req.Email), accountEmailRule); retryAfter > 0 {
+		// Looks like success, so the limit doesn't reveal whether the account exists
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{
+			"message": "If an account exists for that email, a reset link has been sent",
+		})
+	}
+
+	for _, user := range users {
+		if !strings.EqualFold(user.Email, req.Email) {
+			continue
+		}
+
+		token, err := accountTokens.Issue(user.ID, accounttoken.PurposeResetPassword, resetPasswordTTL)
+		if err != nil {
+			log.Printf("Failed to issue password reset token: %v", err)
+			break
+		}
+		sendAccountEmail(user, "password_reset",
+			"Someone asked to reset the password for your account. The link expires in one hour and can be used once.",
+			appURL+"/reset-password?token="+url.QueryEscape(token))
+		break
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "If an account exists for that email, a reset link has been sent",
+	})
+}
+
+// ResetPassword sets a new password with an emailed token and ends all sessions
+func ResetPassword(c *fiber.Ctx) error {
+	// Parse request body
+	var req models.ResetPasswordRequest
+	if err := parseBody(c, &req); err != nil {
+		return err
+	}
+
+	userID, err := accountTokens.Lookup(req.Token, accounttoken.PurposeResetPassword)
+	user, ok := users[userID]
+	if err != nil || !ok {
+		return apperr.Invalid("invalid_reset_token", "Invalid or expired reset link")
+	}
+
+	// The link stays usable until a valid password is chosen
+	if err := checkPassword(req.Password, user); err != nil {
+		return err
+	}
+	if _, err := accountTokens.Consume(req.Token, accounttoken.PurposeResetPassword); err != nil {
+		return apperr.Invalid("invalid_reset_token", "Invalid or expired reset link")
+	}
+
+	if err := setPassword(user, req.Password); err != nil {
+		return apperr.Internal("internal_error", "Failed to hash password").Wrap(err)
+	}
+
+	// Receiving the link proves the user owns the address
+	user.EmailVerified = true
+
+	sendAccountEmail(user, "password_changed",
+		"Your password was reset and you were signed out everywhere. If this wasn't you, reset your password again and contact your administrator.",
+		appURL+"/login")
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Password has been reset, please sign in",
+	})
+}
+
+// ChangePassword changes the current user's password, ends all other
+// sessions and returns a new session for this client
+func ChangePassword(c *fiber.Ctx) error {
+	user, err := currentUser(c)
+	if err != nil {
+		return err
+	}
+
+	// Parse request body
+	var req models.ChangePasswordRequest
+	if err := parseBody(c, &req); err != nil {
+		return err
+	}
+
+	// Accounts created through single sign-on may not have a password yet
+	if user.PasswordHash != "" && bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.CurrentPassword)) != nil {
+		return apperr.Unauthenticated("invalid_password", "Current password is incorrect")
+	}
+
+	if err := checkPassword(req.NewPassword, user); err != nil {
+		return err
+	}
+
+	if err := setPassword(user, req.NewPassword); err != nil {
+		return apperr.Internal("internal_error", "Failed to hash password").Wrap(err)
+	}
+
+	sendAccountEmail(user, "password_changed",
+		"Your password was changed and your other sessions were signed out. If this wasn't you, reset your password and contact your administrator.",
+		appURL+"/login")
+
+	session, err := issueSession(user)
+	if err != nil {
+		return apperr.Internal("internal_error", "Failed to generate token").Wrap(err)
+	}
+	return c.Status(fiber.StatusOK).JSON(session)
+}
@@ -0,0 +1,490 @@
+package handlers
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/projectflow/apperr"
+	"github.com/projectflow/models"
+	"github.com/projectflow/utils"
+	"github.com/projectflow/utils/apikey"
+	"github.com/projectflow/utils/refreshtoken"
+)
+
+// Number of requests kept in the audit record of one impersonation
+const maxImpersonatedRequests = 500
+
+// In-memory storage for development
+var impersonations = make(map[uuid.UUID]*models.Impersonation)
+
+// adminTarget loads the user in the :id parameter. It returns an error if
+// the user isn't in the admin's organization.
+func adminTarget(c *fiber.Ctx) (*models.User, error) {
+	userID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return nil, apperr.Invalid("invalid_user_id", "Invalid user ID")
+	}
+	user, ok := tenantOf(c).user(userID)
+	if !ok {
+		return nil, apperr.ErrUserNotFound
+	}
+	return user, nil
+}
+
+// notSelf rejects admin actions on the admin's own account
+func notSelf(c *fiber.Ctx, user *models.User, action string) error {
+	if user.ID == c.Locals("userID").(uuid.UUID) {
+		return apperr.Forbidden("self_action_not_allowed", "You cannot "+action+" your own account")
+	}
+	return nil
+}
+
+// endSessions signs a user out everywhere
+func endSessions(userID uuid.UUID) {
+	utils.RevokeAllTokens(userID)
+	refreshtoken.DefaultStore.RevokeUser(userID)
+}
+
+// UpdateUserProfile changes a user's username, email and name
+func UpdateUserProfile(c *fiber.Ctx) error {
+	user, err := adminTarget(c)
+	if err != nil {
+		return err
+	}
+
+	// Parse request body
+	var req models.AdminUpdateUserRequest
+	if err := parseBody(c, &req); err != nil {
+		return err
+	}
+
+	for _, other := range users {
+		if other.ID == user.ID {
+			continue
+		}
+		if strings.EqualFold(other.Email, req.Email) {
+			return apperr.Conflict("email_taken", "Email already registered")
+		}
+		if other.Username == req.Username {
+			return apperr.Conflict("username_taken", "Username already taken")
+		}
+	}
+
+	user.Username = strings.TrimSpace(req.Username)
+	user.Email = strings.TrimSpace(req.Email)
+	user.FullName = req.FullName
+	user.UpdatedAt = time.Now()
+	invalidateUserProjects(user.ID)
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"user": user.ToResponse(),
+	})
+}
+
+// UpdateUserRole changes a user's global role. The user's access tokens are
+// revoked so the new role applies from their next refresh.
+func UpdateUserRole(c *fiber.Ctx) error {
+	user, err := adminTarget(c)
+	if err != nil {
+		return err
+	}
+	if err := notSelf(c, user, "change the role of"); err != nil {
+		return err
+	}
+
+	// Parse request body
+	var req models.UpdateUserRoleRequest
+	if err := parseBody(c, &req); err != nil {
+		return err
+	}
+
+	if user.Role != req.Role {
+		user.Role = req.Role
+		user.UpdatedAt = time.Now()
+		utils.RevokeAllTokens(user.ID)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"user": user.ToResponse(),
+	})
+}
+
+// DeactivateUser blocks a user from signing in and ends their sessions,
+// API keys and any impersonation of them
+func DeactivateUser(c *fiber.Ctx) error {
+	user, err := adminTarget(c)
+	if err != nil {
+		return err
+	}
+	if err := notSelf(c, user, "deactivate"); err != nil {
+		return err
+	}
+	if !user.Active() {
+		return apperr.Conflict("user_deactivated", "User is already deactivated")
+	}
+
+	now := time.Now()
+	user.DeactivatedAt = &now
+	user.UpdatedAt = now
+	endSessions(user.ID)
+	apikey.DefaultStore.RevokeUser(user.ID)
+	for _, impersonation := range impersonations {
+		if impersonation.UserID == user.ID {
+			endImpersonation(impersonation)
+		}
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"user": user.ToResponse(),
+	})
+}
+
+// ReactivateUser lets a deactivated user sign in again. Their revoked API
+// keys stay revoked.
+func ReactivateUser(c *fiber.Ctx) error {
+	user, err := adminTarget(c)
+	if err != nil {
+		return err
+	}
+	if user.Active() {
+		return apperr.Conflict("user_not_deactivated", "User is not deactivated")
+	}
+
+	user.DeactivatedAt = nil
+	user.UpdatedAt = time.Now()
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"user": user.ToResponse(),
+	})
+}
+
+// ReassignUserWork hands a user's open tasks and resource allocations in the
+// organization's projects to another user. Work in projects the new user
+// isn't a member of is skipped unless they are added to those projects.
+// Allocations are moved first, in one transaction, so nothing changes if
+// that fails.
+func (h *ResourceHandler) ReassignUserWork(c *fiber.Ctx) error {
+	user, err := adminTarget(c)
+	if err != nil {
+		return err
+	}
+
+	// Parse request body
+	var req models.ReassignWorkRequest
+	if err := parseBody(c, &req); err != nil {
+		return err
+	}
+
+	t := tenantOf(c)
+	target, ok := t.user(req.ToUserID)
+	if !ok || !target.Active() {
+		return apperr.Invalid("invalid_reassignment_target", "Work can only be reassigned to an active member of the organization")
+	}
+	if target.ID == user.ID {
+		return apperr.Invalid("invalid_reassignment_target", "Work cannot be reassigned to the same user")
+	}
+
+	// canReceive reports whether the new user can work in a project,
+	// possibly after being added to it
+	canReceive := func(projectID uuid.UUID) bool {
+		if _, ok := t.project(projectID); !ok {
+			return false
+		}
+		return req.AddToProjects || projectRole(projectID, target.ID) != nil
+	}
+	// admit adds the new user to a project they aren't a member of yet
+	admit := func(projectID uuid.UUID) {
+		if projectRole(projectID, target.ID) != nil {
+			return
+		}
+		if projectMembers[projectID] == nil {
+			projectMembers[projectID] = make(map[uuid.UUID]*models.ProjectMember)
+		}
+		member := &models.ProjectMember{
+			ProjectID: projectID,
+			UserID:    target.ID,
+			Role:      models.RoleMember,
+			JoinedAt:  time.Now(),
+		}
+		projectMembers[projectID][target.ID] = member
+		publishProjectEvent(projectID, models.WebhookMemberAdded, member)
+	}
+
+	reassignedAllocations, skippedAllocations, err := h.reassignAllocations(t, user.ID, target.ID, canReceive)
+	if err != nil {
+		return err
+	}
+	for _, projectID := range reassignedAllocations {
+		admit(projectID)
+	}
+
+	reassignedTasks, skippedTasks := []uuid.UUID{}, []uuid.UUID{}
+	tasksMu.Lock()
+	for taskID, task := range tasks {
+		if task.AssigneeID == nil || *task.AssigneeID != user.ID {
+			continue
+		}
+		if _, ok := t.project(task.ProjectID); !ok || isDoneStatus(task.ProjectID, task.StatusID) {
+			continue
+		}
+		if !canReceive(task.ProjectID) {
+			skippedTasks = append(skippedTasks, taskID)
+			continue
+		}
+
+		admit(task.ProjectID)
+		assignee := target.ID
+		task.AssigneeID = &assignee
+		reassignedTasks = append(reassignedTasks, taskID)
+		publishProjectEvent(task.ProjectID, models.WebhookTaskUpdated, task)
+	}
+	tasksMu.Unlock()
+	if len(reassignedTasks) > 0 {
+		notifyUser(target.ID, models.NotificationTaskAssigned,
+			"You have been assigned the open tasks of "+user.FullName, nil)
+	}
+
+	reassignedIDs := make([]int, 0, len(reassignedAllocations))
+	for id := range reassignedAllocations {
+		reassignedIDs = append(reassignedIDs, id)
+	}
+	sort.Ints(reassignedIDs)
+
+	return c.Status(http.StatusOK).JSON(fiber.Map{
+		"reassigned_tasks":       reassignedTasks,
+		"skipped_tasks":          skippedTasks,
+		"reassigned_allocations": reassignedIDs,
+		"skipped_allocations":    skippedAllocations,
+	})
+}
+
+// reassignAllocations moves the allocations of one user in the tenant's
+// projects that canReceive allows to another user, in one transaction. Allocations that
+// would take the new user over 100% on any day, or duplicate one they
+// already have in the same project starting the same day, are skipped. It
+// returns the project of each moved allocation by allocation ID, and the
+// IDs of the skipped allocations.
+func (h *ResourceHandler) reassignAllocations(t tenant, fromID, toID uuid.UUID, canReceive func(uuid.UUID) bool) (map[int]uuid.UUID, []int, error) {
+	tx, err := h.DB.Begin()
+	if err != nil {
+		return nil, nil, apperr.Internal("internal_error", "Failed to reassign resource allocations").Wrap(err)
+	}
+	defer tx.Rollback()
+
+	// Lock both users' allocations so the cap can't change under us
+	if _, err := tx.Exec("SELECT id FROM resource_allocations WHERE user_id IN ($1, $2) FOR UPDATE", fromID, toID); err != nil {
+		return nil, nil, apperr.Internal("internal_error", "Failed to lock resource allocations").Wrap(err)
+	}
+
+	rows, err := tx.Query(`
+		SELECT id, project_id, allocation_percentage, start_date, end_date
+		FROM resource_allocations
+		WHERE user_id = $1
+		ORDER BY start_date, id`, fromID)
+	if err != nil {
+		return nil, nil, apperr.Internal("internal_error", "Failed to fetch resource allocations").Wrap(err)
+	}
+	type allocationRef struct {
+		id         int
+		projectID  uuid.UUID
+		percentage int
+		startDate  time.Time
+		endDate    *time.Time
+	}
+	allocationRefs := []allocationRef{}
+	for rows.Next() {
+		var ref allocationRef
+		if err := rows.Scan(&ref.id, &ref.projectID, &ref.percentage, &ref.startDate, &ref.endDate); err != nil {
+			rows.Close()
+			return nil, nil, apperr.Internal("internal_error", "Failed to scan resource allocation").Wrap(err)
+		}
+		allocationRefs = append(allocationRefs, ref)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, nil, apperr.Internal("internal_error", "Failed to fetch resource allocations").Wrap(err)
+	}
+
+	reassigned, skipped := make(map[int]uuid.UUID), []int{}
+	for _, ref := range allocationRefs {
+		if _, ok := t.project(ref.projectID); !ok {
+			continue
+		}
+		if !canReceive(ref.projectID) {
+			skipped = append(skipped, ref.id)
+			continue
+		}
+
+		// Open-ended allocations run forever
+		var totalAllocation int
+		var duplicates int
+		err := tx.QueryRow(`
+			SELECT COALESCE(SUM(allocation_percentage), 0),
+				COUNT(*) FILTER (WHERE project_id = $2 AND start_date = $3)
+			FROM resource_allocations
+			WHERE user_id = $1
+			AND start_date <= COALESCE($4::date, 'infinity'::date)
+			AND COALESCE(end_date, 'infinity'::date) >= $3`,
+			toID, ref.projectID, ref.startDate, ref.endDate).Scan(&totalAllocation, &duplicates)
+		if err != nil {
+			return nil, nil, apperr.Internal("internal_error", "Failed to check allocation overlap").Wrap(err)
+		}
+		if duplicates > 0 || totalAllocation+ref.percentage > 100 {
+			skipped = append(skipped, ref.id)
+			continue
+		}
+
+		if _, err := tx.Exec("UPDATE resource_allocations SET user_id = $1, updated_at = NOW() WHERE id = $2", toID, ref.id); err != nil {
+			return nil, nil, apperr.Internal("internal_error", "Failed to reassign resource allocation").Wrap(err)
+		}
+		reassigned[ref.id] = ref.projectID
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, nil, apperr.Internal("internal_error", "Failed to reassign resource allocations").Wrap(err)
+	}
+	return reassigned, skipped, nil
+}
+
+// endImpersonation revokes the token of an impersonation that is still running
+func endImpersonation(impersonation *models.Impersonation) {
+	if impersonation.EndedAt != nil || time.Now().After(impersonation.ExpiresAt) {
+		return
+	}
+	now := time.Now()
+	impersonation.EndedAt = &now
+	utils.RevokeToken(impersonation.TokenID, impersonation.ExpiresAt)
+}
+
+// StartImpersonation issues a short-lived token that lets the admin act as
+// a user for support. There is no refresh token; when it expires, it's over.
+func StartImpersonation(c *fiber.Ctx) error {
+	user, err := adminTarget(c)
+	if err != nil {
+		return err
+	}
+	if err := notSelf(c, user, "impersonate"); err != nil {
+		return err
+	}
+	if user.Role == "admin" || !user.Active() {
+		return apperr.Forbidden("impersonation_not_allowed", "Admins and deactivated users cannot be impersonated")
+	}
+
+	// Parse request body
+	var req models.StartImpersonationRequest
+	if err := parseBody(c, &req); err != nil {
+		return err
+	}
+	if req.Minutes == 0 {
+		req.Minutes = 15
+	}
+
+	adminID := c.Locals("userID").(uuid.UUID)
+	orgID := tenantOf(c).orgID
+	ttl := time.Duration(req.Minutes) * time.Minute
+	token, tokenID, err := utils.GenerateImpersonationToken(user.ID, orgID, user.Role, adminID, ttl)
+	if err != nil {
+		return apperr.Internal("internal_error", "Failed to generate token").Wrap(err)
+	}
+
+	now := time.Now()
+	impersonation := &models.Impersonation{
+		ID:             uuid.New(),
+		AdminID:        adminID,
+		UserID:         user.ID,
+		OrganizationID: orgID,
+		Reason:         strings.TrimSpace(req.Reason),
+		TokenID:        tokenID,
+		StartedAt:      now,
+		ExpiresAt:      now.Add(ttl),
+		Requests:       []models.ImpersonatedRequest{},
+	}
+	impersonations[impersonation.ID] = impersonation
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"impersonation": impersonation,
+		"token":         token,
+		"expires_in":    int(ttl.Seconds()),
+	})
+}
+
+// GetImpersonations lists the impersonations in the admin's organization,
+// newest first
+func GetImpersonations(c *fiber.Ctx) error {
+	orgID := tenantOf(c).orgID
+	impersonationList := []*models.Impersonation{}
+	for _, impersonation := range impersonations {
+		if impersonation.OrganizationID == orgID {
+			impersonationList = append(impersonationList, impersonation)
+		}
+	}
+	sort.Slice(impersonationList, func(i, j int) bool {
+		return impersonationList[i].StartedAt.After(impersonationList[j].StartedAt)
+	})
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"impersonations": impersonationList,
+	})
+}
+
+// EndImpersonation revokes an impersonation token before it expires
+func EndImpersonation(c *fiber.Ctx) error {
+	impersonationID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return apperr.Invalid("invalid_impersonation_id", "Invalid impersonation ID")
+	}
+
+	impersonation, ok := impersonations[impersonationID]
+	if !ok || impersonation.OrganizationID != tenantOf(c).orgID {
+		return apperr.NotFound("impersonation_not_found", "Impersonation not found")
+	}
+	endImpersonation(impersonation)
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"impersonation": impersonation,
+	})
+}
+
+// AuditImpersonation is a middleware that records every request made with an
+// impersonation token in the impersonation's audit record
+func AuditImpersonation() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		err := c.Next()
+
+		if c.Locals("impersonatorID") == nil {
+			return err
+		}
+		claims, ok := c.Locals("claims").(*utils.JWTClaims)
+		if !ok {
+			return err
+		}
+
+		// Render errors now so the recorded status is the one sent
+		if err != nil {
+			if handlerErr := c.App().ErrorHandler(c, err); handlerErr != nil {
+				return handlerErr
+			}
+			err = nil
+		}
+
+		for _, impersonation := range impersonations {
+			if impersonation.TokenID != claims.ID {
+				continue
+			}
+			if len(impersonation.Requests) < maxImpersonatedRequests {
+				impersonation.Requests = append(impersonation.Requests, models.ImpersonatedRequest{
+					Method: c.Method(),
+					Path:   c.Path(),
+					Status: c.Response().StatusCode(),
+					At:     time.Now(),
+				})
+			}
+			break
+		}
+		return err
+	}
+}
@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/projectflow/apperr"
+	"github.com/projectflow/models"
+	"github.com/projectflow/utils/apikey"
+)
+
+// GetAPIKeys returns the current user's API keys
+func GetAPIKeys(c *fiber.Ctx) error {
+	// Get user ID from context (set by auth middleware)
+	userID, ok := c.Locals("userID").(uuid.UUID)
+	if !ok {
+		return apperr.ErrUnauthorized
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"api_keys": apikey.DefaultStore.ListByUser(userID),
+		"scopes":   models.APIKeyScopes,
+	})
+}
+
+// CreateAPIKey issues a new API key for the current user
+func CreateAPIKey(c *fiber.Ctx) error {
+	// Get user ID from context (set by auth middleware)
+	userID, ok := c.Locals("userID").(uuid.UUID)
+	if !ok {
+		return apperr.ErrUnauthorized
+	}
+
+	// Parse request body
+	var req models.CreateAPIKeyRequest
+	if err := parseBody(c, &req); err != nil {
+		return err
+	}
+
+	// Validate scopes
+	known := make(map[string]bool, len(models.APIKeyScopes))
+	for _, scope := range models.APIKeyScopes {
+		known[scope] = true
+	}
+	for _, scope := range req.Scopes {
+		if !known[scope] {
+			return apperr.Invalid("unknown_scope", "Unknown scope: "+scope)
+		}
+	}
+
+	// Project-scoped keys require access to the project
+	if req.ProjectID != nil {
+		if _, ok := tenantOf(c).project(*req.ProjectID); !ok {
+			return apperr.NotFound("project_not_found", "Project not found")
+		}
+		if c.Locals("role") != "admin" && projectRole(*req.ProjectID, userID) == nil {
+			return apperr.Forbidden("project_access_denied", "You don't have access to this project")
+		}
+	}
+
+	plaintext, prefix, hash, err := apikey.Generate()
+	if err != nil {
+		return apperr.Internal("internal_error", "Failed to generate API key").Wrap(err)
+	}
+
+	now := time.Now()
+	key := &models.APIKey{
+		ID:             uuid.New(),
+		UserID:         userID,
+		ProjectID:      req.ProjectID,
+		OrganizationID: tenantOf(c).orgID,
+		Name:           req.Name,
+		Prefix:         prefix,
+		KeyHash:        hash,
+		Scopes:         req.Scopes,
+		CreatedAt:      now,
+	}
+	if req.ExpiresInDays > 0 {
+		expiresAt := now.AddDate(0, 0, req.ExpiresInDays)
+		key.ExpiresAt = &expiresAt
+	}
+
+	apikey.DefaultStore.Save(key)
+
+	// The plaintext key is only ever returned once
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"api_key": key,
+		"key":     plaintext,
+	})
+}
+
+// RevokeAPIKey revokes one of the current user's API keys
+func RevokeAPIKey(c *fiber.Ctx) error {
+	// Get user ID from context (set by auth middleware)
+	userID, ok := c.Locals("userID").(uuid.UUID)
+	if !ok {
+		return apperr.ErrUnauthorized
+	}
+
+	// Get key ID from URL parameter
+	keyID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return apperr.Invalid("invalid_api_key_id", "Invalid API key ID")
+	}
+
+	if !apikey.DefaultStore.Revoke(userID, keyID) {
+		return apperr.NotFound("api_key_not_found", "API key not found")
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "API key revoked successfully",
+	})
+}
@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/projectflow/apperr"
+	"github.com/projectflow/models"
+	"github.com/projectflow/utils"
+	"github.com/projectflow/utils/refreshtoken"
+)
+
+// issueSession creates an access token and a new refresh token family for a user
+func issueSession(user *models.User) (fiber.Map, error) {
+	token, err := generateAccessToken(user)
+	if err != nil {
+		return nil, err
+	}
+
+	refreshToken, _, err := refreshtoken.DefaultStore.Issue(user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	session := fiber.Map{
+		"user":          user.ToResponse(),
+		"token":         token,
+		"refresh_token": refreshToken,
+		"expires_in":    int(utils.AccessTokenTTL.Seconds()),
+	}
+	if enrollmentRequired(user) {
+		session["two_factor_enrollment_required"] = true
+	}
+	return session, nil
+}
+
+// RefreshSession exchanges a refresh token for a new access and refresh token
+func RefreshSession(c *fiber.Ctx) error {
+	// Parse request body
+	var req models.RefreshTokenRequest
+	if err := parseBody(c, &req); err != nil {
+		return err
+	}
+
+	refreshToken, stored, err := refreshtoken.DefaultStore.Rotate(req.RefreshToken)
+	if err != nil {
+		return apperr.Unauthenticated("invalid_refresh_token", "Invalid or expired refresh token")
+	}
+
+	// Find user; deactivated users lose their sessions
+	user, ok := users[stored.UserID]
+	if !ok || !user.Active() {
+		refreshtoken.DefaultStore.RevokeUser(stored.UserID)
+		return apperr.Unauthenticated("invalid_refresh_token", "Invalid or expired refresh token")
+	}
+
+	token, err := generateAccessToken(user)
+	if err != nil {
+		return apperr.Internal("internal_error", "Failed to generate token").Wrap(err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"token":         token,
+		"refresh_token": refreshToken,
+		"expires_in":    int(utils.AccessTokenTTL.Seconds()),
+	})
+}
+
+// Logout revokes the current access token and, if given, its refresh token
+func Logout(c *fiber.Ctx) error {
+	// Get user ID from context (set by auth middleware)
+	userID, ok := c.Locals("userID").(uuid.UUID)
+	if !ok {
+		return apperr.ErrUnauthorized
+	}
+
+	if claims, ok := c.Locals("claims").(*utils.JWTClaims); ok && claims.ExpiresAt != nil {
+		utils.RevokeToken(claims.ID, claims.ExpiresAt.Time)
+	}
+
+	// The refresh token is optional, without it only the access token ends
+	var req models.RefreshTokenRequest
+	if err := c.BodyParser(&req); err == nil && req.RefreshToken != "" {
+		refreshtoken.DefaultStore.Revoke(userID, req.RefreshToken)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Logged out successfully",
+	})
+}
+
+// LogoutAll revokes every access and refresh token issued to the current user
+func LogoutAll(c *fiber.Ctx) error {
+	// Get user ID from context (set by auth middleware)
+	userID, ok := c.Locals("userID").(uuid.UUID)
+	if !ok {
+		return apperr.ErrUnauthorized
+	}
+
+	utils.RevokeAllTokens(userID)
+	refreshtoken.DefaultStore.RevokeUser(userID)
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Logged out of all sessions",
+	})
+}
+
+// GetJWKS publishes the public keys access tokens can be verified with
+func GetJWKS(c *fiber.Ctx) error {
+	// Let verifiers cache the set, but pick up rotated keys quickly
+	c.Set(fiber.HeaderCacheControl, "public, max-age=300")
+	return c.Status(fiber.StatusOK).JSON(utils.JWKS())
+}
@@ -0,0 +1,218 @@
+package handlers
+
+import (
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/projectflow/apperr"
+	"github.com/projectflow/config"
+	"github.com/projectflow/models"
+	"github.com/projectflow/utils/ratelimit"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Number of login attempts kept for auditing
+const maxLoginAttempts = 10000
+
+var (
+	// Counts attempts for rate limits and lockouts
+	loginLimiter ratelimit.Limiter = ratelimit.NewMemoryLimiter()
+
+	// Attempts per client address and per account
+	loginIPRule      = ratelimit.Rule{Limit: 20, Window: 15 * time.Minute}
+	loginAccountRule = ratelimit.Rule{Limit: 10, Window: 15 * time.Minute}
+	registerIPRule   = ratelimit.Rule{Limit: 10, Window: time.Hour}
+
+	// Account emails requested per client address and per account
+	emailIPRule      = ratelimit.Rule{Limit: 10, Window: time.Hour}
+	accountEmailRule = ratelimit.Rule{Limit: 3, Window: time.Hour}
+
+	// Locks an account after repeated failed passwords or second factors
+	loginLockout = &ratelimit.Lockout{
+		Limiter:   loginLimiter,
+		Threshold: 5,
+		BaseDelay: time.Minute,
+		MaxDelay:  time.Hour,
+		Window:    24 * time.Hour,
+	}
+
+	// Recent login attempts, oldest first
+	loginAttempts   []models.LoginAttempt
+	loginAttemptsMu sync.Mutex
+
+	// Compared against when the email is unknown, so both cases take as long
+	dummyPasswordHash     []byte
+	dummyPasswordHashOnce sync.Once
+)
+
+// ConfigureLoginProtection applies the login throttling settings and sets the
+// limiter that stores the counts
+func ConfigureLoginProtection(cfg *config.Config, limiter ratelimit.Limiter) {
+	window := time.Duration(cfg.LoginRateWindowMinutes) * time.Minute
+
+	loginLimiter = limiter
+	loginIPRule = ratelimit.Rule{Limit: cfg.LoginIPLimit, Window: window}
+	loginAccountRule = ratelimit.Rule{Limit: cfg.LoginAccountLimit, Window: window}
+	registerIPRule = ratelimit.Rule{Limit: cfg.RegisterIPLimit, Window: time.Hour}
+
+	loginLockout.Limiter = limiter
+	loginLockout.Threshold = cfg.LockoutThreshold
+	loginLockout.BaseDelay = time.Duration(cfg.LockoutBaseDelaySeconds) * time.Second
+	loginLockout.MaxDelay = time.Duration(cfg.LockoutMaxDelayMinutes) * time.Minute
+}
+
+// normalizeEmail is the form emails take in limiter keys and audit records
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// rateLimited checks a rule and reports how long the client has to wait,
+// or zero if the request may go ahead. Limiter failures let requests through
+// so an unavailable database doesn't block every login.
+func rateLimited(c *fiber.Ctx, key string, rule ratelimit.Rule) time.Duration {
+	res, err := loginLimiter.Allow(c.UserContext(), key, rule)
+	if err != nil {
+		log.Printf("Rate limiter failed: %v", err)
+		return 0
+	}
+	if res.Allowed {
+		return 0
+	}
+	return res.RetryAfter
+}
+
+// tooManyAttempts sets the Retry-After header and returns a rate limit error
+func tooManyAttempts(c *fiber.Ctx, retryAfter time.Duration) error {
+	seconds := int((retryAfter + time.Second - 1) / time.Second)
+	c.Set(fiber.HeaderRetryAfter, strconv.Itoa(seconds))
+	return apperr.RateLimited("too_many_attempts", "Too many attempts, please try again later")
+}
+
+// lockedFor returns how long an account is locked out, or zero
+func lockedFor(c *fiber.Ctx, email string) time.Duration {
+	locked, err := loginLockout.LockedFor(c.UserContext(), email)
+	if err != nil {
+		log.Printf("Rate limiter failed: %v", err)
+	}
+	return locked
+}
+
+// loginFailed counts a wrong password or second factor towards the lockout
+func loginFailed(c *fiber.Ctx, email string) {
+	if _, err := loginLockout.Fail(c.UserContext(), email); err != nil {
+		log.Printf("Rate limiter failed: %v", err)
+	}
+}
+
+// loginSucceeded clears the failures of an account
+func loginSucceeded(c *fiber.Ctx, email string) {
+	if err := loginLockout.Reset(c.UserContext(), email); err != nil {
+		log.Printf("Rate limiter failed: %v", err)
+	}
+}
+
+// comparePassword checks a password against a user's hash. Unknown users
+// are compared against a dummy hash so timing doesn't reveal which emails exist.
+func comparePassword(user *models.User, password string) bool {
+	if user == nil || user.PasswordHash == "" {
+		dummyPasswordHashOnce.Do(func() {
+			dummyPasswordHash, _ = bcrypt.GenerateFromPassword([]byte("not a real password"), bcrypt.DefaultCost)
+		})
+		bcrypt.CompareHashAndPassword(dummyPasswordHash, []byte(password))
+		return false
+	}
+	return bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)) == nil
+}
+
+// recordLoginAttempt adds an attempt to the audit log
+func recordLoginAttempt(c *fiber.Ctx, email string, user *models.User, outcome string) {
+	attempt := models.LoginAttempt{
+		ID:        uuid.New(),
+		Email:     normalizeEmail(email),
+		IPAddress: c.IP(),
+		UserAgent: c.Get(fiber.HeaderUserAgent),
+		Outcome:   outcome,
+		CreatedAt: time.Now(),
+	}
+	if user != nil {
+		attempt.UserID = &user.ID
+	}
+
+	loginAttemptsMu.Lock()
+	defer loginAttemptsMu.Unlock()
+
+	loginAttempts = append(loginAttempts, attempt)
+	if len(loginAttempts) > maxLoginAttempts {
+		loginAttempts = append([]models.LoginAttempt(nil), loginAttempts[len(loginAttempts)-maxLoginAttempts:]...)
+	}
+}
+
+// GetLoginAttempts lists recent login attempts, newest first. Results can be
+// filtered by email, ip and outcome.
+func GetLoginAttempts(c *fiber.Ctx) error {
+	email := normalizeEmail(c.Query("email"))
+	ip := c.Query("ip")
+	outcome := c.Query("outcome")
+	limit := c.QueryInt("limit", 100)
+	if limit < 1 || limit > 1000 {
+		limit = 100
+	}
+
+	loginAttemptsMu.Lock()
+	defer loginAttemptsMu.Unlock()
+
+	result := []models.LoginAttempt{}
+	for i := len(loginAttempts) - 1; i >= 0 && len(result) < limit; i-- {
+		attempt := loginAttempts[i]
+		if (email != "" && attempt.Email != email) ||
+			(ip != "" && attempt.IPAddress != ip) ||
+			(outcome != "" && attempt.Outcome != outcome) {
+			continue
+		}
+		result = append(result, attempt)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"attempts": result,
+	})
+}
+
+// GetLockout shows whether an account is locked out
+func GetLockout(c *fiber.Ctx) error {
+	email := normalizeEmail(c.Query("email"))
+	if email == "" {
+		return apperr.Invalid("email_required", "Email is required")
+	}
+
+	locked, err := loginLockout.LockedFor(c.UserContext(), email)
+	if err != nil {
+		return apperr.Internal("internal_error", "Failed to read lockout").Wrap(err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"email":             email,
+		"locked":            locked > 0,
+		"remaining_seconds": int(locked.Seconds()),
+	})
+}
+
+// ClearLockout unlocks an account and forgets its failed attempts
+func ClearLockout(c *fiber.Ctx) error {
+	email := normalizeEmail(c.Query("email"))
+	if email == "" {
+		return apperr.Invalid("email_required", "Email is required")
+	}
+
+	if err := loginLockout.Reset(c.UserContext(), email); err != nil {
+		return apperr.Internal("internal_error", "Failed to clear lockout").Wrap(err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Account unlocked",
+	})
+}
@@ -1,34 +1,345 @@
 package handlers
 
 import (
+	"encoding/base64"
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
+	"github.com/projectflow/apperr"
+	"github.com/projectflow/config"
+	"github.com/projectflow/mailer"
 	"github.com/projectflow/models"
+	"github.com/projectflow/notifier"
 )
 
 // In-memory storage for development
 var notifications = make(map[uuid.UUID]*models.Notification)
 
-// GetUserNotifications returns all notifications for the current user
+// Guards notifications, which background jobs also read and write
+var notificationsMu sync.RWMutex
+var notificationPreferences = make(map[uuid.UUID]*models.NotificationPreferences)
+
+// Guards notificationPreferences, which the digest job reads
+var notificationPreferencesMu sync.RWMutex
+
+// Delivery pipeline routing notifications to in-app storage and external channels
+var notificationDispatcher = notifier.New(saveNotification, notifier.NewWebhookChannel())
+
+// Tasks that already triggered a due soon reminder
+var dueSoonNotified = make(map[uuid.UUID]bool)
+
+// Outgoing email queue and frontend URL, set by ConfigureNotifications
+var mailQueue mailer.Sender
+var appURL = "http://localhost:5173"
+
+// ConfigureNotifications enables email delivery, digests and due date reminders
+func ConfigureNotifications(cfg *config.Config) {
+	queue := mailer.NewQueue(mailer.New(mailer.Config{
+		Host:     cfg.SMTPHost,
+		Port:     cfg.SMTPPort,
+		Username: cfg.SMTPUsername,
+		Password: cfg.SMTPPassword,
+		From:     cfg.SMTPFrom,
+	}), 5, 30*time.Second)
+
+	mailQueue = queue
+	appURL = strings.TrimSuffix(cfg.AppURL, "/")
+
+	notificationDispatcher.Register(notifier.NewEmailChannel(queue, cfg.AppURL))
+	notifier.NewDigestScheduler(queue, collectDigests, cfg.AppURL)
+
+	go watchDueSoonTasks()
+	go purgeReadNotifications(time.Duration(cfg.NotificationRetentionDays) * 24 * time.Hour)
+}
+
+// purgeReadNotifications periodically deletes read notifications older than the retention period
+func purgeReadNotifications(retention time.Duration) {
+	if retention <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+
+	for {
+		now := <-ticker.C
+		PurgeReadNotifications(now.Add(-retention))
+	}
+}
+
+// PurgeReadNotifications deletes the read notifications whose last event
+// was before cutoff
+func PurgeReadNotifications(cutoff time.Time) {
+	notificationsMu.Lock()
+	defer notificationsMu.Unlock()
+
+	for id, notification := range notifications {
+		if notification.Read && notification.LastOccurredAt.Before(cutoff) {
+			delete(notifications, id)
+		}
+	}
+}
+
+// collectDigests gathers unread notifications for users subscribed to a digest frequency
+func collectDigests(frequency string, since time.Time) []notifier.DigestBatch {
+	unread := make(map[uuid.UUID][]*models.Notification)
+	notificationsMu.RLock()
+	for _, notification := range notifications {
+		if !notification.Read && notification.LastOccurredAt.After(since) {
+			unread[notification.UserID] = append(unread[notification.UserID], notification)
+		}
+	}
+	notificationsMu.RUnlock()
+
+	var batches []notifier.DigestBatch
+	notificationPreferencesMu.RLock()
+	defer notificationPreferencesMu.RUnlock()
+	for userID, prefs := range notificationPreferences {
+		if prefs.DigestFrequency != frequency || len(unread[userID]) == 0 {
+			continue
+		}
+		recipient, ok := findUser(userID)
+		if !ok {
+			continue
+		}
+		batches = append(batches, notifier.DigestBatch{
+			Recipient:     recipient,
+			Notifications: unread[userID],
+		})
+	}
+	return batches
+}
+
+// watchDueSoonTasks reminds assignees of tasks due within the next 24 hours
+func watchDueSoonTasks() {
+	ticker := time.NewTicker(15 * time.Minute)
+	defer ticker.Stop()
+
+	for {
+		now := <-ticker.C
+		for _, reminder := range dueSoonReminders(now) {
+			notifyUser(reminder.assigneeID, models.NotificationTaskDueSoon, "Task due soon: "+reminder.title, &reminder.taskID)
+		}
+	}
+}
+
+// dueSoonReminder is a reminder collected by dueSoonReminders
+type dueSoonReminder struct {
+	taskID     uuid.UUID
+	assigneeID uuid.UUID
+	title      string
+}
+
+// dueSoonReminders collects the reminders for tasks due within 24 hours of
+// now that haven't had one yet. Only the watcher uses dueSoonNotified.
+func dueSoonReminders(now time.Time) []dueSoonReminder {
+	tasksMu.RLock()
+	defer tasksMu.RUnlock()
+
+	var reminders []dueSoonReminder
+	for taskID, task := range tasks {
+		if task.AssigneeID == nil || task.DueDate == nil || dueSoonNotified[taskID] {
+			continue
+		}
+		if task.DueDate.After(now) && task.DueDate.Before(now.Add(24*time.Hour)) {
+			reminders = append(reminders, dueSoonReminder{taskID: taskID, assigneeID: *task.AssigneeID, title: task.Title})
+			dueSoonNotified[taskID] = true
+		}
+	}
+	return reminders
+}
+
+// saveNotification stores an in-app notification. Repeated unread events of
+// the same type on the same RelatedID are grouped into a single notification,
+// which keeps its CreatedAt and moves its LastOccurredAt.
+func saveNotification(notification *models.Notification) {
+	notificationsMu.Lock()
+	defer notificationsMu.Unlock()
+
+	if notification.LastOccurredAt.IsZero() {
+		notification.LastOccurredAt = notification.CreatedAt
+	}
+
+	if notification.RelatedID != nil {
+		for _, existing := range notifications {
+			if existing.UserID == notification.UserID && !existing.Read &&
+				existing.Type == notification.Type &&
+				existing.RelatedID != nil && *existing.RelatedID == *notification.RelatedID {
+				existing.Count++
+				existing.Content = notification.Content
+				existing.LastOccurredAt = notification.LastOccurredAt
+				return
+			}
+		}
+	}
+
+	if notification.Count == 0 {
+		notification.Count = 1
+	}
+	notifications[notification.ID] = notification
+}
+
+// encodeNotificationCursor builds an opaque cursor pointing after a notification
+func encodeNotificationCursor(notification *models.Notification) string {
+	raw := strconv.FormatInt(notification.LastOccurredAt.UnixNano(), 10) + "_" + notification.ID.String()
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// decodeNotificationCursor parses a cursor produced by encodeNotificationCursor
+func decodeNotificationCursor(cursor string) (time.Time, uuid.UUID, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return time.Time{}, uuid.Nil, err
+	}
+	nanos, id, found := strings.Cut(string(raw), "_")
+	if !found {
+		return time.Time{}, uuid.Nil, errors.New("malformed cursor")
+	}
+	unixNano, err := strconv.ParseInt(nanos, 10, 64)
+	if err != nil {
+		return time.Time{}, uuid.Nil, err
+	}
+	notificationID, err := uuid.Parse(id)
+	if err != nil {
+		return time.Time{}, uuid.Nil, err
+	}
+	return time.Unix(0, unixNano), notificationID, nil
+}
+
+// newerNotification reports whether a sorts before b in newest-first order
+func newerNotification(a, b *models.Notification) bool {
+	if !a.LastOccurredAt.Equal(b.LastOccurredAt) {
+		return a.LastOccurredAt.After(b.LastOccurredAt)
+	}
+	return a.ID.String() > b.ID.String()
+}
+
+// getNotificationPreferences returns a user's saved preferences or the defaults
+func getNotificationPreferences(userID uuid.UUID) *models.NotificationPreferences {
+	notificationPreferencesMu.RLock()
+	defer notificationPreferencesMu.RUnlock()
+	if prefs, ok := notificationPreferences[userID]; ok {
+		return prefs
+	}
+	return models.DefaultNotificationPreferences(userID)
+}
+
+// notifyUser creates a notification and routes it through the delivery pipeline
+func notifyUser(userID uuid.UUID, notificationType, content string, relatedID *uuid.UUID) {
+	notification := &models.Notification{
+		ID:        uuid.New(),
+		UserID:    userID,
+		Content:   content,
+		Type:      notificationType,
+		Read:      false,
+		RelatedID: relatedID,
+		CreatedAt: time.Now(),
+	}
+
+	recipient, ok := findUser(userID)
+	if !ok {
+		return
+	}
+	notificationDispatcher.Dispatch(notification, recipient, getNotificationPreferences(userID))
+}
+
+// GetUserNotifications returns the current user's notifications newest first.
+// Supports filtering by type and read state and cursor pagination.
 func GetUserNotifications(c *fiber.Ctx) error {
 	// Get user ID from context (set by auth middleware)
 	userID, ok := c.Locals("userID").(uuid.UUID)
 	if !ok {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"error": "Unauthorized",
-		})
+		return apperr.ErrUnauthorized
+	}
+
+	// Parse pagination and filters
+	limit := c.QueryInt("limit", 20)
+	if limit < 1 || limit > 100 {
+		return apperr.Invalid("invalid_limit", "Limit must be between 1 and 100")
+	}
+
+	notificationType := c.Query("type")
+
+	var readFilter *bool
+	if value := c.Query("read"); value != "" {
+		read, err := strconv.ParseBool(value)
+		if err != nil {
+			return apperr.Invalid("invalid_read_filter", "Read filter must be true or false")
+		}
+		readFilter = &read
 	}
 
-	// Get all notifications for the user
-	var notificationList []*models.Notification
+	var after *models.Notification
+	if cursor := c.Query("cursor"); cursor != "" {
+		lastOccurredAt, id, err := decodeNotificationCursor(cursor)
+		if err != nil {
+			return apperr.Invalid("invalid_cursor", "Invalid cursor")
+		}
+		after = &models.Notification{ID: id, LastOccurredAt: lastOccurredAt}
+	}
+
+	// Collect matching notifications for the user
+	notificationList := []*models.Notification{}
+	notificationsMu.RLock()
 	for _, notification := range notifications {
-		if notification.UserID == userID {
-			notificationList = append(notificationList, notification)
+		if notification.UserID != userID {
+			continue
+		}
+		if notificationType != "" && notification.Type != notificationType {
+			continue
+		}
+		if readFilter != nil && notification.Read != *readFilter {
+			continue
 		}
+		if after != nil && !newerNotification(after, notification) {
+			continue
+		}
+		notificationList = append(notificationList, notification)
+	}
+	notificationsMu.RUnlock()
+
+	// Sort newest first and cut the page
+	sort.Slice(notificationList, func(i, j int) bool {
+		return newerNotification(notificationList[i], notificationList[j])
+	})
+
+	var nextCursor string
+	if len(notificationList) > limit {
+		notificationList = notificationList[:limit]
+		nextCursor = encodeNotificationCursor(notificationList[limit-1])
 	}
 
 	return c.Status(fiber.StatusOK).JSON(fiber.Map{
 		"notifications": notificationList,
+		"next_cursor":   nextCursor,
+	})
+}
+
+// GetUnreadNotificationCount returns the number of unread notifications for the current user
+func GetUnreadNotificationCount(c *fiber.Ctx) error {
+	// Get user ID from context (set by auth middleware)
+	userID, ok := c.Locals("userID").(uuid.UUID)
+	if !ok {
+		return apperr.ErrUnauthorized
+	}
+
+	count := 0
+	notificationsMu.RLock()
+	for _, notification := range notifications {
+		if notification.UserID == userID && !notification.Read {
+			count++
+		}
+	}
+	notificationsMu.RUnlock()
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"unread_count": count,
 	})
 }
 
@@ -37,41 +348,34 @@ func MarkNotificationRead(c *fiber.Ctx) error {
 	// Get user ID from context (set by auth middleware)
 	userID, ok := c.Locals("userID").(uuid.UUID)
 	if !ok {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"error": "Unauthorized",
-		})
+		return apperr.ErrUnauthorized
 	}
 
 	// Get notification ID from URL parameter
 	id := c.Params("id")
 	notificationID, err := uuid.Parse(id)
 	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid notification ID",
-		})
+		return apperr.Invalid("invalid_notification_id", "Invalid notification ID")
 	}
 
+	// Parse request body
+	var req models.MarkNotificationReadRequest
+	if err := parseBody(c, &req); err != nil {
+		return err
+	}
+
+	notificationsMu.Lock()
+	defer notificationsMu.Unlock()
+
 	// Find notification
 	notification, ok := notifications[notificationID]
 	if !ok {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-			"error": "Notification not found",
-		})
+		return apperr.NotFound("notification_not_found", "Notification not found")
 	}
 
 	// Check if notification belongs to the user
 	if notification.UserID != userID {
-		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
-			"error": "You don't have access to this notification",
-		})
-	}
-
-	// Parse request body
-	var req models.MarkNotificationReadRequest
-	if err := c.BodyParser(&req); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid request body",
-		})
+		return apperr.Forbidden("notification_access_denied", "You don't have access to this notification")
 	}
 
 	// Update notification
@@ -87,19 +391,193 @@ func MarkAllNotificationsRead(c *fiber.Ctx) error {
 	// Get user ID from context (set by auth middleware)
 	userID, ok := c.Locals("userID").(uuid.UUID)
 	if !ok {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"error": "Unauthorized",
-		})
+		return apperr.ErrUnauthorized
 	}
 
 	// Update all notifications for the user
+	notificationsMu.Lock()
 	for _, notification := range notifications {
 		if notification.UserID == userID {
 			notification.Read = true
 		}
 	}
+	notificationsMu.Unlock()
 
 	return c.Status(fiber.StatusOK).JSON(fiber.Map{
 		"message": "All notifications marked as read",
 	})
 }
+
+// DeleteNotification deletes a single notification
+func DeleteNotification(c *fiber.Ctx) error {
+	// Get user ID from context (set by auth middleware)
+	userID, ok := c.Locals("userID").(uuid.UUID)
+	if !ok {
+		return apperr.ErrUnauthorized
+	}
+
+	// Get notification ID from URL parameter
+	notificationID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return apperr.Invalid("invalid_notification_id", "Invalid notification ID")
+	}
+
+	notificationsMu.Lock()
+	defer notificationsMu.Unlock()
+
+	// Find notification
+	notification, ok := notifications[notificationID]
+	if !ok {
+		return apperr.NotFound("notification_not_found", "Notification not found")
+	}
+
+	// Check if notification belongs to the user
+	if notification.UserID != userID {
+		return apperr.Forbidden("notification_access_denied", "You don't have access to this notification")
+	}
+
+	delete(notifications, notificationID)
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Notification deleted successfully",
+	})
+}
+
+// DeleteNotifications deletes several notifications, or all read ones, for the current user
+func DeleteNotifications(c *fiber.Ctx) error {
+	// Get user ID from context (set by auth middleware)
+	userID, ok := c.Locals("userID").(uuid.UUID)
+	if !ok {
+		return apperr.ErrUnauthorized
+	}
+
+	// Parse request body
+	var req models.DeleteNotificationsRequest
+	if err := parseBody(c, &req); err != nil {
+		return err
+	}
+	if len(req.IDs) == 0 && !req.AllRead {
+		return apperr.Invalid("notification_ids_required", "Provide notification IDs or set all_read")
+	}
+
+	selected := make(map[uuid.UUID]bool, len(req.IDs))
+	for _, id := range req.IDs {
+		selected[id] = true
+	}
+
+	// Only the user's own notifications are ever deleted
+	deleted := 0
+	notificationsMu.Lock()
+	for id, notification := range notifications {
+		if notification.UserID != userID {
+			continue
+		}
+		if selected[id] || (req.AllRead && notification.Read) {
+			delete(notifications, id)
+			deleted++
+		}
+	}
+	notificationsMu.Unlock()
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"deleted": deleted,
+	})
+}
+
+// GetNotificationPreferences returns the current user's notification preferences
+func GetNotificationPreferences(c *fiber.Ctx) error {
+	// Get user ID from context (set by auth middleware)
+	userID, ok := c.Locals("userID").(uuid.UUID)
+	if !ok {
+		return apperr.ErrUnauthorized
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"preferences":        getNotificationPreferences(userID),
+		"notification_types": models.NotificationTypes,
+	})
+}
+
+// UpdateNotificationPreferences replaces the current user's notification preferences
+func UpdateNotificationPreferences(c *fiber.Ctx) error {
+	// Get user ID from context (set by auth middleware)
+	userID, ok := c.Locals("userID").(uuid.UUID)
+	if !ok {
+		return apperr.ErrUnauthorized
+	}
+
+	// Parse request body
+	var req models.UpdateNotificationPreferencesRequest
+	if err := parseBody(c, &req); err != nil {
+		return err
+	}
+
+	// Validate notification types
+	known := make(map[string]bool, len(models.NotificationTypes))
+	for _, notificationType := range models.NotificationTypes {
+		known[notificationType] = true
+	}
+	for notificationType, pref := range req.Types {
+		if !known[notificationType] {
+			return apperr.Invalid("unknown_notification_type", "Unknown notification type: "+notificationType)
+		}
+		if pref.Webhook && req.WebhookURL == "" {
+			return apperr.Invalid("webhook_url_required", "A webhook URL is required to enable webhook delivery")
+		}
+	}
+
+	// Validate webhook URL
+	if req.WebhookURL != "" {
+		if err := checkWebhookURL(c, req.WebhookURL); err != nil {
+			return err
+		}
+	}
+
+	// Validate quiet hours
+	if (req.QuietHoursStart == "") != (req.QuietHoursEnd == "") {
+		return apperr.Invalid("invalid_quiet_hours", "Quiet hours require both a start and an end time")
+	}
+	for _, value := range []string{req.QuietHoursStart, req.QuietHoursEnd} {
+		if value == "" {
+			continue
+		}
+		if _, err := time.Parse("15:04", value); err != nil {
+			return apperr.Invalid("invalid_quiet_hours", "Quiet hours must use the HH:MM format")
+		}
+	}
+	if req.Timezone != "" {
+		if _, err := time.LoadLocation(req.Timezone); err != nil {
+			return apperr.Invalid("invalid_timezone", "Invalid timezone")
+		}
+	}
+
+	// Validate digest frequency
+	switch req.DigestFrequency {
+	case "":
+		req.DigestFrequency = models.DigestNone
+	case models.DigestNone, models.DigestDaily, models.DigestWeekly:
+	default:
+		return apperr.Invalid("invalid_digest_frequency", "Digest frequency must be none, daily or weekly")
+	}
+
+	// Start from the defaults so types missing from the request keep sensible values
+	prefs := models.DefaultNotificationPreferences(userID)
+	for notificationType, pref := range req.Types {
+		prefs.Types[notificationType] = pref
+	}
+	prefs.WebhookURL = req.WebhookURL
+	prefs.QuietHoursStart = req.QuietHoursStart
+	prefs.QuietHoursEnd = req.QuietHoursEnd
+	prefs.Timezone = req.Timezone
+	prefs.DigestFrequency = req.DigestFrequency
+	prefs.UpdatedAt = time.Now()
+
+	// Save preferences (in-memory for development)
+	notificationPreferencesMu.Lock()
+	notificationPreferences[userID] = prefs
+	notificationPreferencesMu.Unlock()
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"preferences": prefs,
+	})
+}
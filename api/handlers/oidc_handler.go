@@ -0,0 +1,272 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/projectflow/apperr"
+	"github.com/projectflow/config"
+	"github.com/projectflow/models"
+	"github.com/projectflow/oidc"
+	"github.com/projectflow/utils/cache"
+)
+
+// How long a user has to finish logging in at the provider
+const oidcLoginTTL = 10 * time.Minute
+
+// oidcLogin is the state kept between redirecting to the provider and the callback
+type oidcLogin struct {
+	Provider     string
+	Nonce        string
+	CodeVerifier string
+}
+
+var (
+	// Configured single sign-on providers by name
+	oidcProviders = make(map[string]*oidc.Provider)
+
+	// Pending logins by state parameter
+	oidcLogins = cache.New()
+
+	// Linked provider accounts, "provider|subject" to user ID
+	userIdentities = make(map[string]uuid.UUID)
+)
+
+// ConfigureSSO registers the OpenID Connect providers from the configuration
+func ConfigureSSO(cfg *config.Config) {
+	for _, provider := range cfg.OIDCProviders {
+		oidcProviders[provider.Name] = oidc.NewProvider(oidc.Config{
+			Name:         provider.Name,
+			Issuer:       provider.Issuer,
+			ClientID:     provider.ClientID,
+			ClientSecret: provider.ClientSecret,
+			RedirectURL:  strings.TrimSuffix(cfg.APIURL, "/") + "/api/v1/auth/oidc/" + provider.Name + "/callback",
+			Scopes:       provider.Scopes,
+			GroupsClaim:  provider.GroupsClaim,
+			RoleMapping:  provider.RoleMapping,
+		})
+	}
+}
+
+// GetOIDCProviders lists the single sign-on providers users can log in with
+func GetOIDCProviders(c *fiber.Ctx) error {
+	names := []string{}
+	for name := range oidcProviders {
+		names = append(names, name)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"providers": names,
+	})
+}
+
+// StartOIDCLogin redirects the user to the provider's login page
+func StartOIDCLogin(c *fiber.Ctx) error {
+	provider, ok := oidcProviders[c.Params("provider")]
+	if !ok {
+		return apperr.NotFound("login_provider_not_found", "Unknown login provider")
+	}
+
+	state, err := oidc.RandomString()
+	if err != nil {
+		return apperr.Internal("internal_error", "Failed to start login").Wrap(err)
+	}
+	nonce, _ := oidc.RandomString()
+	verifier, _ := oidc.RandomString()
+
+	authURL, err := provider.AuthCodeURL(c.UserContext(), state, nonce, oidc.CodeChallenge(verifier))
+	if err != nil {
+		log.Printf("SSO login with %s failed: %v", provider.Config.Name, err)
+		return apperr.Unavailable("login_provider_unavailable", "Login provider is unavailable")
+	}
+
+	oidcLogins.Set(state, oidcLogin{
+		Provider:     provider.Config.Name,
+		Nonce:        nonce,
+		CodeVerifier: verifier,
+	}, oidcLoginTTL)
+
+	return c.Redirect(authURL, fiber.StatusFound)
+}
+
+// OIDCCallback completes the login, then redirects to the frontend with our own tokens
+func OIDCCallback(c *fiber.Ctx) error {
+	provider, ok := oidcProviders[c.Params("provider")]
+	if !ok {
+		return ssoFailed(c, "unknown_provider")
+	}
+	if c.Query("error") != "" {
+		return ssoFailed(c, "access_denied")
+	}
+
+	// Each state can only be used once
+	state := c.Query("state")
+	pending, found := oidcLogins.Get(state)
+	if !found || state == "" {
+		return ssoFailed(c, "invalid_state")
+	}
+	oidcLogins.Delete(state)
+	login := pending.(oidcLogin)
+	if login.Provider != provider.Config.Name {
+		return ssoFailed(c, "invalid_state")
+	}
+
+	tokens, err := provider.Exchange(c.UserContext(), c.Query("code"), login.CodeVerifier)
+	if err != nil {
+		log.Printf("SSO login with %s failed: %v", provider.Config.Name, err)
+		return ssoFailed(c, "exchange_failed")
+	}
+	claims, err := provider.VerifyIDToken(c.UserContext(), tokens.IDToken, login.Nonce)
+	if err != nil {
+		log.Printf("SSO login with %s failed: %v", provider.Config.Name, err)
+		return ssoFailed(c, "invalid_token")
+	}
+
+	user, err := findOrProvisionSSOUser(provider, claims)
+	if err != nil {
+		return ssoFailed(c, err.Error())
+	}
+	if !user.Active() {
+		return ssoFailed(c, "account_deactivated")
+	}
+
+	// The provider replaces the password, not the second factor. The
+	// challenge is completed on the login page like a password login.
+	if user.TwoFactorEnabled {
+		challengeToken, err := startTwoFactorChallenge(user)
+		if err != nil {
+			return ssoFailed(c, "token_failed")
+		}
+		recordLoginAttempt(c, user.Email, user, models.LoginTwoFactorRequired)
+		fragment := url.Values{
+			"two_factor_required": {"true"},
+			"challenge_token":     {challengeToken},
+			"expires_in":          {fmt.Sprint(int(twoFactorChallengeTTL.Seconds()))},
+		}
+		return c.Redirect(appURL+"/login#"+fragment.Encode(), fiber.StatusFound)
+	}
+
+	session, err := issueSession(user)
+	if err != nil {
+		return ssoFailed(c, "token_failed")
+	}
+
+	// Tokens go in the fragment so they never reach server logs
+	fragment := url.Values{
+		"token":         {session["token"].(string)},
+		"refresh_token": {session["refresh_token"].(string)},
+		"expires_in":    {fmt.Sprint(session["expires_in"])},
+	}
+	return c.Redirect(appURL+"/#"+fragment.Encode(), fiber.StatusFound)
+}
+
+// findOrProvisionSSOUser resolves the provider account to a user. Accounts
+// are linked by subject, then by verified email, and created otherwise.
+// The returned error is a short code shown to the user.
+func findOrProvisionSSOUser(provider *oidc.Provider, claims *oidc.Claims) (*models.User, error) {
+	identity := provider.Config.Name + "|" + claims.Subject
+	role := provider.MapRole(claims.Groups)
+
+	// Previously linked account
+	if userID, ok := userIdentities[identity]; ok {
+		if user, ok := users[userID]; ok {
+			syncSSORole(provider, user, role)
+			return user, nil
+		}
+		delete(userIdentities, identity)
+	}
+
+	// Only link or create accounts for emails the provider has verified
+	if claims.Email == "" || !claims.EmailVerified {
+		return nil, errors.New("email_not_verified")
+	}
+
+	for _, user := range users {
+		if strings.EqualFold(user.Email, claims.Email) {
+			userIdentities[identity] = user.ID
+			user.EmailVerified = true
+			syncSSORole(provider, user, role)
+			return user, nil
+		}
+	}
+
+	if role == "" {
+		role = "member"
+	}
+	name := claims.Name
+	if name == "" {
+		name = claims.Email
+	}
+
+	now := time.Now()
+	user := &models.User{
+		ID:        uuid.New(),
+		Username:  uniqueUsername(claims.PreferredUsername, claims.Email),
+		Email:     claims.Email,
+		FullName:  name,
+		Role:      role,
+		CreatedAt: now,
+		UpdatedAt: now,
+
+		EmailVerified: true,
+	}
+	addUser(user)
+	userIdentities[identity] = user.ID
+
+	return user, nil
+}
+
+// syncSSORole applies the provider's group mapping, which is the source of
+// truth for roles once configured
+func syncSSORole(provider *oidc.Provider, user *models.User, role string) {
+	if len(provider.Config.RoleMapping) == 0 {
+		return
+	}
+	if role == "" {
+		role = "member"
+	}
+	if user.Role != role {
+		user.Role = role
+		user.UpdatedAt = time.Now()
+	}
+}
+
+// uniqueUsername derives an unused username from the provider's claims
+func uniqueUsername(preferred, email string) string {
+	base := preferred
+	if base == "" {
+		base, _, _ = strings.Cut(email, "@")
+	}
+	if len(base) < 3 {
+		base = "user"
+	}
+	if len(base) > 40 {
+		base = base[:40]
+	}
+
+	taken := func(name string) bool {
+		for _, user := range users {
+			if strings.EqualFold(user.Username, name) {
+				return true
+			}
+		}
+		return false
+	}
+
+	username := base
+	for i := 2; taken(username); i++ {
+		username = fmt.Sprintf("%s%d", base, i)
+	}
+	return username
+}
+
+// ssoFailed sends the user back to the login page with an error code
+func ssoFailed(c *fiber.Ctx, code string) error {
+	return c.Redirect(appURL+"/login?sso_error="+url.QueryEscape(code), fiber.StatusFound)
+}
@@ -0,0 +1,386 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/projectflow/apperr"
+	"github.com/projectflow/models"
+)
+
+// How long an organization invitation can be accepted
+const organizationInviteTTL = 7 * 24 * time.Hour
+
+// In-memory storage for development
+var organizations = make(map[uuid.UUID]*models.Organization)
+var organizationMembers = make(map[uuid.UUID]map[uuid.UUID]*models.OrganizationMember)
+var organizationInvites = make(map[uuid.UUID]*models.OrganizationInvite)
+
+// createOrganization creates an organization with the user as its owner
+func createOrganization(name string, owner *models.User) *models.Organization {
+	now := time.Now()
+	org := &models.Organization{
+		ID:        uuid.New(),
+		Name:      name,
+		OwnerID:   owner.ID,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	organizations[org.ID] = org
+	addOrganizationMember(org.ID, owner.ID, models.OrgRoleOwner)
+	return org
+}
+
+// addOrganizationMember adds a user to an organization
+func addOrganizationMember(orgID, userID uuid.UUID, role string) {
+	if organizationMembers[orgID] == nil {
+		organizationMembers[orgID] = make(map[uuid.UUID]*models.OrganizationMember)
+	}
+	organizationMembers[orgID][userID] = &models.OrganizationMember{
+		OrganizationID: orgID,
+		UserID:         userID,
+		Role:           role,
+		JoinedAt:       time.Now(),
+	}
+}
+
+// currentOrganization returns the organization a user works in. When the
+// user left it, the oldest remaining membership is used, and users without
+// any get a personal workspace.
+func currentOrganization(user *models.User) uuid.UUID {
+	if organizationMembers[user.CurrentOrganizationID][user.ID] != nil {
+		return user.CurrentOrganizationID
+	}
+
+	var oldest *models.OrganizationMember
+	for _, members := range organizationMembers {
+		if member := members[user.ID]; member != nil && (oldest == nil || member.JoinedAt.Before(oldest.JoinedAt)) {
+			oldest = member
+		}
+	}
+	if oldest != nil {
+		user.CurrentOrganizationID = oldest.OrganizationID
+	} else {
+		name := user.FullName
+		if name == "" {
+			name = user.Username
+		}
+		user.CurrentOrganizationID = createOrganization(name+"'s workspace", user).ID
+	}
+	return user.CurrentOrganizationID
+}
+
+// isOrganizationAdmin reports whether the current user may manage the
+// current organization. Instance admins manage every organization they're in.
+func isOrganizationAdmin(c *fiber.Ctx) bool {
+	t := tenantOf(c)
+	member := organizationMembers[t.orgID][c.Locals("userID").(uuid.UUID)]
+	if member == nil {
+		return false
+	}
+	return member.Role != models.OrgRoleMember || c.Locals("role") == "admin"
+}
+
+// hashInviteToken returns the stored form of an invitation token
+func hashInviteToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// GetOrganizations lists the organizations of the current user
+func GetOrganizations(c *fiber.Ctx) error {
+	user, err := currentUser(c)
+	if err != nil {
+		return err
+	}
+
+	orgList := []fiber.Map{}
+	for orgID, members := range organizationMembers {
+		if member := members[user.ID]; member != nil {
+			orgList = append(orgList, fiber.Map{
+				"organization": organizations[orgID],
+				"role":         member.Role,
+				"current":      orgID == tenantOf(c).orgID,
+			})
+		}
+	}
+	sort.Slice(orgList, func(i, j int) bool {
+		return orgList[i]["organization"].(*models.Organization).Name < orgList[j]["organization"].(*models.Organization).Name
+	})
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"organizations": orgList,
+	})
+}
+
+// CreateOrganization creates an organization owned by the current user
+func CreateOrganization(c *fiber.Ctx) error {
+	user, err := currentUser(c)
+	if err != nil {
+		return err
+	}
+
+	// Parse request body
+	var req models.CreateOrganizationRequest
+	if err := parseBody(c, &req); err != nil {
+		return err
+	}
+
+	org := createOrganization(strings.TrimSpace(req.Name), user)
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"organization": org,
+	})
+}
+
+// SwitchOrganization moves the current user to another of their
+// organizations and returns a session carrying it in the org claim
+func SwitchOrganization(c *fiber.Ctx) error {
+	user, err := currentUser(c)
+	if err != nil {
+		return err
+	}
+
+	// Parse request body
+	var req models.SwitchOrganizationRequest
+	if err := parseBody(c, &req); err != nil {
+		return err
+	}
+
+	if organizationMembers[req.OrganizationID][user.ID] == nil {
+		return apperr.NotFound("organization_not_found", "Organization not found")
+	}
+	user.CurrentOrganizationID = req.OrganizationID
+
+	session, err := issueSession(user)
+	if err != nil {
+		return apperr.Internal("internal_error", "Failed to generate token").Wrap(err)
+	}
+	return c.Status(fiber.StatusOK).JSON(session)
+}
+
+// GetCurrentOrganization returns the current organization and its members
+func GetCurrentOrganization(c *fiber.Ctx) error {
+	t := tenantOf(c)
+	org, ok := organizations[t.orgID]
+	if !ok {
+		return apperr.NotFound("organization_not_found", "Organization not found")
+	}
+
+	memberList := []fiber.Map{}
+	for _, user := range t.users() {
+		memberList = append(memberList, fiber.Map{
+			"user": user.ToResponse(),
+			"role": organizationMembers[t.orgID][user.ID].Role,
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"organization": org,
+		"members":      memberList,
+	})
+}
+
+// UpdateCurrentOrganization renames the current organization
+func UpdateCurrentOrganization(c *fiber.Ctx) error {
+	if !isOrganizationAdmin(c) {
+		return apperr.ErrOrgAdminNeeded
+	}
+
+	// Parse request body
+	var req models.UpdateOrganizationRequest
+	if err := parseBody(c, &req); err != nil {
+		return err
+	}
+
+	org := organizations[tenantOf(c).orgID]
+	org.Name = strings.TrimSpace(req.Name)
+	org.UpdatedAt = time.Now()
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"organization": org,
+	})
+}
+
+// RemoveOrganizationMember removes a user from the current organization and
+// its projects
+func RemoveOrganizationMember(c *fiber.Ctx) error {
+	if !isOrganizationAdmin(c) {
+		return apperr.ErrOrgAdminNeeded
+	}
+
+	memberID, err := uuid.Parse(c.Params("userID"))
+	if err != nil {
+		return apperr.Invalid("invalid_user_id", "Invalid user ID")
+	}
+
+	t := tenantOf(c)
+	member := organizationMembers[t.orgID][memberID]
+	if member == nil {
+		return apperr.NotFound("member_not_found", "Member not found in this organization")
+	}
+	if member.Role == models.OrgRoleOwner {
+		return apperr.Forbidden("organization_owner_immutable", "Cannot remove the organization owner")
+	}
+
+	// Projects can't be left without their owner
+	orgProjects := t.projects()
+	for _, project := range orgProjects {
+		if project.OwnerID == memberID {
+			return apperr.Conflict("user_owns_projects", "The user still owns projects in this organization")
+		}
+	}
+
+	for _, project := range orgProjects {
+		delete(projectMembers[project.ID], memberID)
+	}
+	for _, team := range t.teams() {
+		delete(teamMembers[team.ID], memberID)
+	}
+	delete(organizationMembers[t.orgID], memberID)
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Member removed successfully",
+	})
+}
+
+// GetOrganizationInvites lists the open invitations of the current organization
+func GetOrganizationInvites(c *fiber.Ctx) error {
+	if !isOrganizationAdmin(c) {
+		return apperr.ErrOrgAdminNeeded
+	}
+
+	orgID := tenantOf(c).orgID
+	now := time.Now()
+	inviteList := []*models.OrganizationInvite{}
+	for _, invite := range organizationInvites {
+		if invite.OrganizationID == orgID && invite.AcceptedAt == nil && invite.ExpiresAt.After(now) {
+			inviteList = append(inviteList, invite)
+		}
+	}
+	sort.Slice(inviteList, func(i, j int) bool { return inviteList[i].CreatedAt.After(inviteList[j].CreatedAt) })
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"invites": inviteList,
+	})
+}
+
+// CreateOrganizationInvite emails an invitation to join the current organization
+func CreateOrganizationInvite(c *fiber.Ctx) error {
+	if !isOrganizationAdmin(c) {
+		return apperr.ErrOrgAdminNeeded
+	}
+
+	// Parse request body
+	var req models.InviteMemberRequest
+	if err := parseBody(c, &req); err != nil {
+		return err
+	}
+	if req.Role == "" {
+		req.Role = models.OrgRoleMember
+	}
+
+	t := tenantOf(c)
+	for _, user := range t.users() {
+		if strings.EqualFold(user.Email, req.Email) {
+			return apperr.Conflict("already_org_member", "User is already a member of this organization")
+		}
+	}
+
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return apperr.Internal("internal_error", "Failed to create invitation").Wrap(err)
+	}
+	token := base64.RawURLEncoding.EncodeToString(buf)
+
+	now := time.Now()
+	invite := &models.OrganizationInvite{
+		ID:             uuid.New(),
+		OrganizationID: t.orgID,
+		Email:          req.Email,
+		Role:           req.Role,
+		TokenHash:      hashInviteToken(token),
+		InvitedBy:      c.Locals("userID").(uuid.UUID),
+		CreatedAt:      now,
+		ExpiresAt:      now.Add(organizationInviteTTL),
+	}
+	organizationInvites[invite.ID] = invite
+
+	sendAccountEmail(&models.User{Email: req.Email, FullName: req.Email}, "organization_invite",
+		"You were invited to join "+organizations[t.orgID].Name+" on ProjectFlow. The invitation expires in 7 days.",
+		appURL+"/invites/accept?token="+url.QueryEscape(token))
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"invite": invite,
+	})
+}
+
+// RevokeOrganizationInvite withdraws an open invitation
+func RevokeOrganizationInvite(c *fiber.Ctx) error {
+	if !isOrganizationAdmin(c) {
+		return apperr.ErrOrgAdminNeeded
+	}
+
+	inviteID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return apperr.Invalid("invalid_invitation_id", "Invalid invitation ID")
+	}
+
+	invite, ok := organizationInvites[inviteID]
+	if !ok || invite.OrganizationID != tenantOf(c).orgID {
+		return apperr.NotFound("invitation_not_found", "Invitation not found")
+	}
+	delete(organizationInvites, inviteID)
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Invitation revoked",
+	})
+}
+
+// AcceptOrganizationInvite adds the current user to the organization of an
+// invitation sent to their email address
+func AcceptOrganizationInvite(c *fiber.Ctx) error {
+	user, err := currentUser(c)
+	if err != nil {
+		return err
+	}
+
+	// Parse request body
+	var req models.AcceptInviteRequest
+	if err := parseBody(c, &req); err != nil {
+		return err
+	}
+
+	hash := hashInviteToken(req.Token)
+	var invite *models.OrganizationInvite
+	for _, candidate := range organizationInvites {
+		if candidate.TokenHash == hash {
+			invite = candidate
+			break
+		}
+	}
+	if invite == nil || invite.AcceptedAt != nil || time.Now().After(invite.ExpiresAt) {
+		return apperr.Invalid("invalid_invitation", "Invalid or expired invitation")
+	}
+	if !strings.EqualFold(invite.Email, user.Email) {
+		return apperr.Forbidden("invitation_email_mismatch", "This invitation was sent to another email address")
+	}
+
+	now := time.Now()
+	invite.AcceptedAt = &now
+	if organizationMembers[invite.OrganizationID][user.ID] == nil {
+		addOrganizationMember(invite.OrganizationID, user.ID, invite.Role)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"organization": organizations[invite.OrganizationID],
+	})
+}
@@ -0,0 +1,284 @@
+package handlers
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	_ "time/tzdata" // Validate timezones on hosts without a zoneinfo database
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/projectflow/apperr"
+	"github.com/projectflow/models"
+	"github.com/projectflow/utils/accounttoken"
+)
+
+var (
+	// Language, optional script and optional region of a BCP 47 tag, e.g. pt-BR
+	localePattern = regexp.MustCompile(`^[a-z]{2,3}(-[A-Z][a-z]{3})?(-([A-Z]{2}|[0-9]{3}))?$`)
+
+	// Accepted profile picture types by sniffed content type
+	avatarExtensions = map[string]string{
+		"image/png":  ".png",
+		"image/jpeg": ".jpg",
+		"image/gif":  ".gif",
+		"image/webp": ".webp",
+	}
+)
+
+// invalidateUserProjects drops the cached project views that embed a user,
+// so profile changes show up everywhere at once
+func invalidateUserProjects(userID uuid.UUID) {
+	for projectID, members := range projectMembers {
+		project, ok := projects[projectID]
+		if _, member := members[userID]; !member && (!ok || project.OwnerID != userID) {
+			continue
+		}
+		for memberID := range members {
+			projectCache.Delete("project_" + projectID.String() + "_user_" + memberID.String())
+		}
+		if ok {
+			projectCache.Delete("project_" + projectID.String() + "_user_" + project.OwnerID.String())
+		}
+	}
+}
+
+// sendEmailChange emails a confirmation link to the address a user wants to
+// switch to
+func sendEmailChange(user *models.User) {
+	token, err := accountTokens.Issue(user.ID, accounttoken.PurposeChangeEmail, verifyEmailTTL)
+	if err != nil {
+		log.Printf("Failed to issue email change token: %v", err)
+		return
+	}
+
+	recipient := &models.User{Email: user.PendingEmail, FullName: user.FullName}
+	sendAccountEmail(recipient, "email_change",
+		"Please confirm that "+user.PendingEmail+" should become the email address of your ProjectFlow account. Until then you keep signing in with "+user.Email+". The link expires in 48 hours.",
+		appURL+"/verify-email?token="+url.QueryEscape(token))
+}
+
+// confirmEmailChange swaps in a user's pending address with an emailed token
+// and tells the previous address about it
+func confirmEmailChange(c *fiber.Ctx, token string) error {
+	userID, err := accountTokens.Consume(token, accounttoken.PurposeChangeEmail)
+	user, ok := users[userID]
+	if err != nil || !ok || user.PendingEmail == "" {
+		return apperr.Invalid("invalid_verification_token", "Invalid or expired verification link")
+	}
+
+	// Someone else may have taken the address since the link was sent
+	if other := userByEmail(user.PendingEmail); other != nil && other.ID != user.ID {
+		user.PendingEmail = ""
+		return apperr.Conflict("email_taken", "Email already registered")
+	}
+
+	previous := &models.User{Email: user.Email, FullName: user.FullName}
+	user.Email = user.PendingEmail
+	user.PendingEmail = ""
+	user.EmailVerified = true
+	user.UpdatedAt = time.Now()
+
+	// Reset links went to the previous address
+	accountTokens.Revoke(user.ID, accounttoken.PurposeResetPassword)
+	invalidateUserProjects(user.ID)
+
+	sendAccountEmail(previous, "email_changed",
+		"The email address of your ProjectFlow account was changed to "+user.Email+". If this wasn't you, contact your administrator.",
+		appURL+"/login")
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Email address changed",
+	})
+}
+
+// UpdateProfile changes the current user's name, username, email address and
+// preferences. A new email address only replaces the current one once the
+// link sent to it is used.
+func UpdateProfile(c *fiber.Ctx) error {
+	user, err := currentUser(c)
+	if err != nil {
+		return err
+	}
+
+	// Parse request body
+	var req models.UpdateProfileRequest
+	if err := parseBody(c, &req); err != nil {
+		return err
+	}
+
+	// Validate the fields that are being changed
+	var username, email, fullName string
+	if req.Username != nil {
+		username = strings.TrimSpace(*req.Username)
+		if len(username) < 3 || len(username) > 50 || strings.ContainsAny(username, " \t@") {
+			return apperr.Invalid("invalid_username", "Username must be 3 to 50 characters without spaces or @")
+		}
+	}
+	if req.Email != nil {
+		email = strings.TrimSpace(*req.Email)
+		if !strings.Contains(email, "@") {
+			return apperr.Invalid("invalid_email", "Invalid email address")
+		}
+	}
+	if req.FullName != nil {
+		fullName = strings.TrimSpace(*req.FullName)
+		if fullName == "" || len(fullName) > 100 {
+			return apperr.Invalid("invalid_full_name", "Full name must be 1 to 100 characters")
+		}
+	}
+	if req.Timezone != nil && *req.Timezone != "" {
+		if _, err := time.LoadLocation(*req.Timezone); err != nil || *req.Timezone == "Local" {
+			return apperr.Invalid("invalid_timezone", "Unknown timezone, use an IANA name such as Europe/Berlin")
+		}
+	}
+	if req.Locale != nil && *req.Locale != "" && !localePattern.MatchString(*req.Locale) {
+		return apperr.Invalid("invalid_locale", "Invalid locale, use a language tag such as en-US")
+	}
+
+	// Usernames and email addresses are unique regardless of case
+	emailChanged := req.Email != nil && !strings.EqualFold(email, user.Email)
+	for _, other := range users {
+		if other.ID == user.ID {
+			continue
+		}
+		if req.Username != nil && strings.EqualFold(other.Username, username) {
+			return apperr.Conflict("username_taken", "Username already taken")
+		}
+		if emailChanged && strings.EqualFold(other.Email, email) {
+			return apperr.Conflict("email_taken", "Email already registered")
+		}
+	}
+
+	if emailChanged && !strings.EqualFold(email, user.PendingEmail) {
+		if retryAfter := rateLimited(c, "change-email:user:"+user.ID.String(), accountEmailRule); retryAfter > 0 {
+			return tooManyAttempts(c, retryAfter)
+		}
+	}
+
+	// Apply the changes
+	if req.Username != nil {
+		user.Username = username
+	}
+	if req.FullName != nil {
+		user.FullName = fullName
+	}
+	if req.Timezone != nil {
+		user.Timezone = *req.Timezone
+	}
+	if req.Locale != nil {
+		user.Locale = *req.Locale
+	}
+	if req.Email != nil {
+		if emailChanged {
+			if !strings.EqualFold(email, user.PendingEmail) {
+				user.PendingEmail = email
+				sendEmailChange(user)
+			}
+		} else {
+			// Asking for the current address again cancels a pending change
+			user.PendingEmail = ""
+			accountTokens.Revoke(user.ID, accounttoken.PurposeChangeEmail)
+		}
+	}
+	user.UpdatedAt = time.Now()
+	invalidateUserProjects(user.ID)
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"user":          user.ToResponse(),
+		"pending_email": user.PendingEmail,
+	})
+}
+
+// removeAvatarFile deletes a user's uploaded profile picture, if any
+func removeAvatarFile(user *models.User) {
+	if !strings.HasPrefix(user.AvatarURL, avatarBaseURL) {
+		return
+	}
+	name := filepath.Base(strings.TrimPrefix(user.AvatarURL, avatarBaseURL))
+	if err := os.Remove(filepath.Join(avatarDir, name)); err != nil && !os.IsNotExist(err) {
+		log.Printf("Failed to remove profile picture %s: %v", name, err)
+	}
+}
+
+// UploadAvatar replaces the current user's profile picture with the image
+// uploaded in the avatar form field
+func UploadAvatar(c *fiber.Ctx) error {
+	user, err := currentUser(c)
+	if err != nil {
+		return err
+	}
+
+	header, err := c.FormFile("avatar")
+	if err != nil {
+		return apperr.Invalid("avatar_required", "Upload the picture in the avatar field")
+	}
+	tooLarge := func() error {
+		return apperr.TooLarge("avatar_too_large", fmt.Sprintf("Profile pictures may be at most %d KB", avatarMaxBytes/1024))
+	}
+	if header.Size > int64(avatarMaxBytes) {
+		return tooLarge()
+	}
+
+	file, err := header.Open()
+	if err != nil {
+		return apperr.Invalid("invalid_upload", "Failed to read the upload")
+	}
+	defer file.Close()
+	data, err := io.ReadAll(io.LimitReader(file, int64(avatarMaxBytes)+1))
+	if err != nil {
+		return apperr.Invalid("invalid_upload", "Failed to read the upload")
+	}
+	if len(data) > avatarMaxBytes {
+		return tooLarge()
+	}
+
+	// Trust the content, not the file name or the declared type
+	ext, ok := avatarExtensions[http.DetectContentType(data)]
+	if !ok {
+		return apperr.UnsupportedMedia("unsupported_avatar_type", "Profile pictures must be PNG, JPEG, GIF or WebP images")
+	}
+
+	// A new name per upload, so clients never see a cached old picture
+	name := user.ID.String() + "-" + strconv.FormatInt(time.Now().UnixNano(), 36) + ext
+	if err := os.MkdirAll(avatarDir, 0o755); err != nil {
+		return apperr.Internal("internal_error", "Failed to store profile picture").Wrap(err)
+	}
+	if err := os.WriteFile(filepath.Join(avatarDir, name), data, 0o644); err != nil {
+		return apperr.Internal("internal_error", "Failed to store profile picture").Wrap(err)
+	}
+
+	removeAvatarFile(user)
+	user.AvatarURL = avatarBaseURL + name
+	user.UpdatedAt = time.Now()
+	invalidateUserProjects(user.ID)
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"user": user.ToResponse(),
+	})
+}
+
+// DeleteAvatar removes the current user's profile picture
+func DeleteAvatar(c *fiber.Ctx) error {
+	user, err := currentUser(c)
+	if err != nil {
+		return err
+	}
+
+	removeAvatarFile(user)
+	user.AvatarURL = ""
+	user.UpdatedAt = time.Now()
+	invalidateUserProjects(user.ID)
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"user": user.ToResponse(),
+	})
+}
@@ -5,6 +5,7 @@ import (
 	
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
+	"github.com/projectflow/apperr"
 	"github.com/projectflow/models"
 	"github.com/projectflow/utils/cache"
 )
@@ -21,44 +22,57 @@ func CreateProject(c *fiber.Ctx) error {
 	// Get user ID from context (set by auth middleware)
 	userID, ok := c.Locals("userID").(uuid.UUID)
 	if !ok {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"error": "Unauthorized",
-		})
+		return apperr.ErrUnauthorized
+	}
+
+	opts, err := projectOptions(c)
+	if err != nil {
+		return err
 	}
 
 	// Parse request body
 	var req models.CreateProjectRequest
-	if err := c.BodyParser(&req); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid request body",
-		})
+	if err := parseBody(c, &req); err != nil {
+		return err
+	}
+
+	// Projects belong to the organization the user works in
+	orgID := tenantOf(c).orgID
+	if orgID == uuid.Nil {
+		return apperr.Forbidden("not_org_member", "You are not a member of this organization")
 	}
 
 	// Create project
 	projectID := uuid.New()
 	project := &models.Project{
-		ID:          projectID,
-		Name:        req.Name,
-		Description: req.Description,
-		OwnerID:     userID,
+		ID:             projectID,
+		Name:           req.Name,
+		Description:    req.Description,
+		OwnerID:        userID,
+		OrganizationID: orgID,
 	}
 
 	// Save project (in-memory for development)
 	projects[projectID] = project
 
-	// Add owner as a project member with admin role
+	// Add owner as a project member with the owner role
 	if projectMembers[projectID] == nil {
 		projectMembers[projectID] = make(map[uuid.UUID]*models.ProjectMember)
 	}
 	projectMembers[projectID][userID] = &models.ProjectMember{
 		ProjectID: projectID,
 		UserID:    userID,
-		Role:      "admin",
+		Role:      models.RoleOwner,
+		JoinedAt:  time.Now(),
 	}
 
 	// Return project data
+	body, err := opts.sparse(projectResponse(project, opts, userID))
+	if err != nil {
+		return err
+	}
 	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
-		"project": project,
+		"project": body,
 	})
 }
 
@@ -67,154 +81,131 @@ func GetAllProjects(c *fiber.Ctx) error {
 	// Get user ID from context (set by auth middleware)
 	userID, ok := c.Locals("userID").(uuid.UUID)
 	if !ok {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"error": "Unauthorized",
-		})
+		return apperr.ErrUnauthorized
 	}
 
 	// Get user's role
 	role := c.Locals("role").(string)
-	
-	// Create a cache key based on user ID and role
-	cacheKey := "projects_" + userID.String() + "_" + role
+	t := tenantOf(c)
+
+	opts, err := projectOptions(c)
+	if err != nil {
+		return err
+	}
+	respond := func(projectList []*models.Project) error {
+		body, err := opts.sparse(projectResponses(projectList, opts, userID))
+		if err != nil {
+			return err
+		}
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{
+			"projects": body,
+		})
+	}
+
+	// Project-scoped API keys only see their own project
+	if key, ok := c.Locals("apiKey").(*models.APIKey); ok && key.ProjectID != nil {
+		projectList := []*models.Project{}
+		if project, ok := t.project(*key.ProjectID); ok && projectRole(project.ID, userID) != nil {
+			projectList = append(projectList, project)
+		}
+		return respond(projectList)
+	}
+
+	// Create a cache key based on user ID, organization and role
+	cacheKey := "projects_" + userID.String() + "_" + t.orgID.String() + "_" + role
 	
 	// Try to get from cache first
 	if cachedProjects, found := projectCache.Get(cacheKey); found {
-		return c.Status(fiber.StatusOK).JSON(fiber.Map{
-			"projects": cachedProjects,
-		})
+		return respond(cachedProjects.([]*models.Project))
 	}
 
-	// If user is admin, return all projects
+	// If user is admin, return all projects of the organization.
+	// Otherwise, return only projects the user is a member of.
 	var projectList []*models.Project
-	if role == "admin" {
-		for _, project := range projects {
+	for _, project := range t.projects() {
+		if role == "admin" || projectRole(project.ID, userID) != nil {
 			projectList = append(projectList, project)
 		}
-	} else {
-		// Otherwise, return only projects the user is a member of
-		for projectID, members := range projectMembers {
-			if _, ok := members[userID]; ok {
-				projectList = append(projectList, projects[projectID])
-			}
-		}
 	}
 	
 	// Store in cache for 5 minutes
 	projectCache.Set(cacheKey, projectList, 5*time.Minute)
 
-	return c.Status(fiber.StatusOK).JSON(fiber.Map{
-		"projects": projectList,
-	})
+	return respond(projectList)
 }
 
 // GetProjectByID returns a project by ID
 func GetProjectByID(c *fiber.Ctx) error {
-	// Get user ID from context (set by auth middleware)
-	userID, ok := c.Locals("userID").(uuid.UUID)
-	if !ok {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"error": "Unauthorized",
-		})
-	}
+	userID := c.Locals("userID").(uuid.UUID)
+	projectID := c.Locals("projectID").(uuid.UUID)
+	role := c.Locals("projectRole").(*models.ProjectRole)
 
-	// Get project ID from URL parameter
-	id := c.Params("id")
-	projectID, err := uuid.Parse(id)
+	opts, err := projectOptions(c)
 	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid project ID",
-		})
+		return err
 	}
-	
+	body, err := opts.sparse(projectResponse(projects[projectID], opts, userID))
+	if err != nil {
+		return err
+	}
+	respond := func(data fiber.Map) error {
+		resp := fiber.Map{"project": body}
+		for key, value := range data {
+			resp[key] = value
+		}
+		return c.Status(fiber.StatusOK).JSON(resp)
+	}
+
 	// Create a cache key based on project ID and user ID
-	cacheKey := "project_" + id + "_user_" + userID.String()
+	cacheKey := "project_" + projectID.String() + "_user_" + userID.String()
 	
 	// Try to get from cache first
 	if cachedData, found := projectCache.Get(cacheKey); found {
-		return c.Status(fiber.StatusOK).JSON(cachedData)
-	}
-
-	// Find project
-	project, ok := projects[projectID]
-	if !ok {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-			"error": "Project not found",
-		})
-	}
-
-	// Check if user has access to the project
-	role := c.Locals("role").(string)
-	if role != "admin" {
-		if members, ok := projectMembers[projectID]; !ok || members[userID] == nil {
-			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
-				"error": "You don't have access to this project",
-			})
-		}
+		return respond(cachedData.(fiber.Map))
 	}
 
-	// Get project members
+	// Get project members, unless the role may not see them
 	var memberList []models.UserResponse
-	for memberID := range projectMembers[projectID] {
-		if user, ok := users[memberID]; ok {
-			memberList = append(memberList, user.ToResponse())
+	memberRoles := make(map[uuid.UUID]string)
+	if role.Has(models.PermMemberView) {
+		for memberID, member := range projectMembers[projectID] {
+			if user, ok := users[memberID]; ok {
+				memberList = append(memberList, user.ToResponse())
+				memberRoles[memberID] = member.Role
+			}
 		}
 	}
 
-	// Prepare response data
+	// Prepare response data. The project itself is built per request since
+	// ?include= and ?fields= change it.
 	responseData := fiber.Map{
-		"project": project,
-		"members": memberList,
+		"members":      memberList,
+		"member_roles": memberRoles,
+		"role":         role,
 	}
 	
 	// Store in cache for 5 minutes
 	projectCache.Set(cacheKey, responseData, 5*time.Minute)
 
 	// Return project data with members
-	return c.Status(fiber.StatusOK).JSON(responseData)
+	return respond(responseData)
 }
 
 // UpdateProject updates a project
 func UpdateProject(c *fiber.Ctx) error {
-	// Get user ID from context (set by auth middleware)
-	userID, ok := c.Locals("userID").(uuid.UUID)
-	if !ok {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"error": "Unauthorized",
-		})
-	}
+	userID := c.Locals("userID").(uuid.UUID)
+	projectID := c.Locals("projectID").(uuid.UUID)
+	project := projects[projectID]
 
-	// Get project ID from URL parameter
-	id := c.Params("id")
-	projectID, err := uuid.Parse(id)
+	opts, err := projectOptions(c)
 	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid project ID",
-		})
-	}
-
-	// Find project
-	project, ok := projects[projectID]
-	if !ok {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-			"error": "Project not found",
-		})
-	}
-
-	// Check if user is project owner or admin
-	role := c.Locals("role").(string)
-	if role != "admin" && project.OwnerID != userID {
-		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
-			"error": "Only project owner or admin can update the project",
-		})
+		return err
 	}
 
 	// Parse request body
 	var req models.UpdateProjectRequest
-	if err := c.BodyParser(&req); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid request body",
-		})
+	if err := parseBody(c, &req); err != nil {
+		return err
 	}
 
 	// Update project
@@ -222,55 +213,47 @@ func UpdateProject(c *fiber.Ctx) error {
 	project.Description = req.Description
 	
 	// Invalidate cache entries for this project
-	projectCache.Delete("project_" + id + "_user_" + userID.String())
+	projectCache.Delete("project_" + projectID.String() + "_user_" + userID.String())
 	
 	// Also invalidate the projects list cache for this user
-	projectCache.Delete("projects_" + userID.String() + "_" + role)
+	projectCache.Delete("projects_" + userID.String() + "_" + project.OrganizationID.String() + "_" + c.Locals("role").(string))
+
+	publishProjectEvent(projectID, models.WebhookProjectUpdated, project)
 
 	// Return updated project
+	body, err := opts.sparse(projectResponse(project, opts, userID))
+	if err != nil {
+		return err
+	}
 	return c.Status(fiber.StatusOK).JSON(fiber.Map{
-		"project": project,
+		"project": body,
 	})
 }
 
+// projectOptions reads the ?include= and ?fields= parameters of project responses
+func projectOptions(c *fiber.Ctx) (responseOptions, error) {
+	return parseResponseOptions(c, models.ProjectIncludes, models.ProjectResponse{})
+}
+
 // DeleteProject deletes a project
 func DeleteProject(c *fiber.Ctx) error {
-	// Get user ID from context (set by auth middleware)
-	userID, ok := c.Locals("userID").(uuid.UUID)
-	if !ok {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"error": "Unauthorized",
-		})
-	}
-
-	// Get project ID from URL parameter
-	id := c.Params("id")
-	projectID, err := uuid.Parse(id)
-	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid project ID",
-		})
-	}
-
-	// Find project
-	project, ok := projects[projectID]
-	if !ok {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-			"error": "Project not found",
-		})
-	}
+	projectID := c.Locals("projectID").(uuid.UUID)
+	project := projects[projectID]
 
-	// Check if user is project owner or admin
-	role := c.Locals("role").(string)
-	if role != "admin" && project.OwnerID != userID {
-		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
-			"error": "Only project owner or admin can delete the project",
-		})
-	}
+	// Notify subscribers before the project's webhooks are removed with it
+	publishProjectEvent(projectID, models.WebhookProjectDeleted, project)
 
 	// Delete project
 	delete(projects, projectID)
 	delete(projectMembers, projectID)
+	delete(projectRoles, projectID)
+	delete(projectTeams, projectID)
+	for inviteID, invite := range projectInvites {
+		if invite.ProjectID == projectID {
+			delete(projectInvites, inviteID)
+		}
+	}
+	webhookStore.DeleteProject(projectID)
 
 	return c.Status(fiber.StatusOK).JSON(fiber.Map{
 		"message": "Project deleted successfully",
@@ -279,61 +262,32 @@ func DeleteProject(c *fiber.Ctx) error {
 
 // AddProjectMember adds a member to a project
 func AddProjectMember(c *fiber.Ctx) error {
-	// Get user ID from context (set by auth middleware)
-	userID, ok := c.Locals("userID").(uuid.UUID)
-	if !ok {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"error": "Unauthorized",
-		})
-	}
-
-	// Get project ID from URL parameter
-	id := c.Params("id")
-	projectID, err := uuid.Parse(id)
-	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid project ID",
-		})
-	}
+	projectID := c.Locals("projectID").(uuid.UUID)
 
-	// Find project
-	project, ok := projects[projectID]
-	if !ok {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-			"error": "Project not found",
-		})
+	// Parse request body
+	var req models.AddMemberRequest
+	if err := parseBody(c, &req); err != nil {
+		return err
 	}
-
-	// Check if user is project owner or admin
-	role := c.Locals("role").(string)
-	if role != "admin" && project.OwnerID != userID {
-		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
-			"error": "Only project owner or admin can add members",
-		})
+	if req.Role == "" {
+		req.Role = models.RoleMember
 	}
 
-	// Parse request body
-	var req models.AddMemberRequest
-	if err := c.BodyParser(&req); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid request body",
-		})
+	// Check the role can be handed out by the current user
+	if err := checkAssignableRole(c, projectID, req.Role); err != nil {
+		return err
 	}
 
-	// Check if user exists
-	if _, ok := users[req.UserID]; !ok {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-			"error": "User not found",
-		})
+	// Check if user exists, only members of the organization can join
+	if _, ok := tenantOf(c).user(req.UserID); !ok {
+		return apperr.ErrUserNotFound
 	}
 
 	// Check if user is already a member
 	if projectMembers[projectID] == nil {
 		projectMembers[projectID] = make(map[uuid.UUID]*models.ProjectMember)
 	} else if _, ok := projectMembers[projectID][req.UserID]; ok {
-		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
-			"error": "User is already a member of this project",
-		})
+		return apperr.Conflict("already_project_member", "User is already a member of this project")
 	}
 
 	// Add member
@@ -341,72 +295,99 @@ func AddProjectMember(c *fiber.Ctx) error {
 		ProjectID: projectID,
 		UserID:    req.UserID,
 		Role:      req.Role,
+		JoinedAt:  time.Now(),
 	}
 
+	publishProjectEvent(projectID, models.WebhookMemberAdded, projectMembers[projectID][req.UserID])
+
 	return c.Status(fiber.StatusOK).JSON(fiber.Map{
 		"message": "Member added successfully",
 	})
 }
 
-// RemoveProjectMember removes a member from a project
-func RemoveProjectMember(c *fiber.Ctx) error {
-	// Get user ID from context (set by auth middleware)
-	userID, ok := c.Locals("userID").(uuid.UUID)
-	if !ok {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"error": "Unauthorized",
-		})
-	}
-
-	// Get project ID from URL parameter
-	projectID, err := uuid.Parse(c.Params("id"))
-	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid project ID",
-		})
-	}
+// UpdateProjectMemberRole changes the role of a project member
+func UpdateProjectMemberRole(c *fiber.Ctx) error {
+	projectID := c.Locals("projectID").(uuid.UUID)
 
 	// Get member ID from URL parameter
 	memberID, err := uuid.Parse(c.Params("memberID"))
 	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid member ID",
-		})
+		return apperr.Invalid("invalid_member_id", "Invalid member ID")
 	}
 
-	// Find project
-	project, ok := projects[projectID]
-	if !ok {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-			"error": "Project not found",
-		})
+	// Check if member exists
+	member := projectMembers[projectID][memberID]
+	if member == nil {
+		return apperr.NotFound("member_not_found", "Member not found in this project")
 	}
 
-	// Check if user is project owner or admin
-	role := c.Locals("role").(string)
-	if role != "admin" && project.OwnerID != userID {
-		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
-			"error": "Only project owner or admin can remove members",
-		})
+	// The owner keeps the owner role
+	if memberID == projects[projectID].OwnerID {
+		return apperr.Forbidden("project_owner_immutable", "Cannot change the role of the project owner")
+	}
+
+	// Parse request body
+	var req models.UpdateMemberRoleRequest
+	if err := parseBody(c, &req); err != nil {
+		return err
+	}
+
+	// Both the current and the new role must be within the user's own permissions
+	if err := checkAssignableRole(c, projectID, member.Role); err != nil {
+		return err
+	}
+	if err := checkAssignableRole(c, projectID, req.Role); err != nil {
+		return err
+	}
+
+	// A role set by hand makes a team membership a direct one
+	member.Role = req.Role
+	member.TeamID = nil
+	projectCache.Delete("project_" + projectID.String() + "_user_" + memberID.String())
+
+	publishProjectEvent(projectID, models.WebhookMemberUpdated, member)
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"member": member,
+	})
+}
+
+// RemoveProjectMember removes a member from a project
+func RemoveProjectMember(c *fiber.Ctx) error {
+	projectID := c.Locals("projectID").(uuid.UUID)
+
+	// Get member ID from URL parameter
+	memberID, err := uuid.Parse(c.Params("memberID"))
+	if err != nil {
+		return apperr.Invalid("invalid_member_id", "Invalid member ID")
 	}
 
 	// Check if member exists
 	if projectMembers[projectID] == nil || projectMembers[projectID][memberID] == nil {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-			"error": "Member not found in this project",
-		})
+		return apperr.NotFound("member_not_found", "Member not found in this project")
 	}
 
 	// Cannot remove the project owner
-	if memberID == project.OwnerID {
-		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
-			"error": "Cannot remove the project owner",
-		})
+	if memberID == projects[projectID].OwnerID {
+		return apperr.Forbidden("project_owner_immutable", "Cannot remove the project owner")
+	}
+
+	// Members with more permissions than the user can't be removed by them
+	if err := checkAssignableRole(c, projectID, projectMembers[projectID][memberID].Role); err != nil {
+		return err
+	}
+
+	// Team members leave the project with their team
+	if projectMembers[projectID][memberID].TeamID != nil {
+		return apperr.Conflict("member_via_team", "Member belongs to the project through a team")
 	}
 
 	// Remove member
+	removed := projectMembers[projectID][memberID]
 	delete(projectMembers[projectID], memberID)
 
+	publishProjectEvent(projectID, models.WebhookMemberRemoved, removed)
+
 	return c.Status(fiber.StatusOK).JSON(fiber.Map{
 		"message": "Member removed successfully",
 	})
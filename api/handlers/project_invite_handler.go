@@ -0,0 +1,369 @@
+package handlers
+
+import (
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/projectflow/apperr"
+	"github.com/projectflow/models"
+	"github.com/projectflow/utils"
+	"github.com/projectflow/utils/apikey"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// How long a project invitation can be accepted after it was last sent
+const projectInviteTTL = 7 * 24 * time.Hour
+
+// In-memory storage for development
+var projectInvites = make(map[uuid.UUID]*models.ProjectInvite)
+
+// userByEmail returns the user with an email address, ignoring case
+func userByEmail(email string) *models.User {
+	for _, user := range users {
+		if strings.EqualFold(user.Email, email) {
+			return user
+		}
+	}
+	return nil
+}
+
+// sendProjectInvite emails a new link for an invitation. The link replaces
+// any sent before and is valid for another projectInviteTTL.
+func sendProjectInvite(invite *models.ProjectInvite) error {
+	now := time.Now()
+	tokenID := uuid.NewString()
+	expiresAt := now.Add(projectInviteTTL)
+	token, err := utils.GenerateInviteToken(invite.ID, tokenID, expiresAt)
+	if err != nil {
+		return err
+	}
+
+	invite.TokenID = tokenID
+	invite.ExpiresAt = expiresAt
+	invite.SentAt = now
+	invite.SendCount++
+
+	inviter := "Someone"
+	if user, ok := users[invite.InvitedBy]; ok {
+		inviter = user.FullName
+		if inviter == "" {
+			inviter = user.Username
+		}
+	}
+	sendAccountEmail(&models.User{Email: invite.Email, FullName: invite.Email}, "project_invite",
+		inviter+" invited you to join the project "+projects[invite.ProjectID].Name+" on ProjectFlow as "+invite.Role+". The invitation expires in 7 days.",
+		appURL+"/invites/project?token="+url.QueryEscape(token))
+	return nil
+}
+
+// pendingInvite returns the invitation of a token if it can still be accepted
+func pendingInvite(token string) *models.ProjectInvite {
+	claims, err := utils.ValidateInviteToken(token)
+	if err != nil {
+		return nil
+	}
+
+	// Only the latest link of an invitation works
+	invite, ok := projectInvites[claims.InviteID]
+	if !ok || invite.TokenID != claims.ID || invite.Status(time.Now()) != models.InviteStatusPending {
+		return nil
+	}
+	if _, ok := projects[invite.ProjectID]; !ok {
+		return nil
+	}
+	return invite
+}
+
+// signedInUser returns the user of the access token sent with a request to
+// a public route, if there is a valid one
+func signedInUser(c *fiber.Ctx) *models.User {
+	token := strings.TrimPrefix(c.Get("Authorization"), "Bearer ")
+	if token == "" || apikey.IsAPIKey(token) {
+		return nil
+	}
+	claims, err := utils.ValidateToken(token)
+	if err != nil {
+		return nil
+	}
+	return users[claims.UserID]
+}
+
+// findProjectInvite loads the invitation in the :inviteID parameter. It
+// returns an error if it isn't an invitation to the project.
+func findProjectInvite(c *fiber.Ctx) (*models.ProjectInvite, error) {
+	inviteID, err := uuid.Parse(c.Params("inviteID"))
+	if err != nil {
+		return nil, apperr.Invalid("invalid_invitation_id", "Invalid invitation ID")
+	}
+
+	invite, ok := projectInvites[inviteID]
+	if !ok || invite.ProjectID != c.Locals("projectID").(uuid.UUID) {
+		return nil, apperr.NotFound("invitation_not_found", "Invitation not found")
+	}
+	return invite, nil
+}
+
+// GetProjectInvites lists the invitations of a project, newest first. Only
+// pending invitations are listed unless a status or "all" is asked for.
+func GetProjectInvites(c *fiber.Ctx) error {
+	projectID := c.Locals("projectID").(uuid.UUID)
+	status := c.Query("status", models.InviteStatusPending)
+
+	now := time.Now()
+	inviteList := []models.ProjectInviteResponse{}
+	for _, invite := range projectInvites {
+		if invite.ProjectID == projectID && (status == "all" || invite.Status(now) == status) {
+			inviteList = append(inviteList, invite.ToResponse())
+		}
+	}
+	sort.Slice(inviteList, func(i, j int) bool { return inviteList[i].CreatedAt.After(inviteList[j].CreatedAt) })
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"invites": inviteList,
+	})
+}
+
+// CreateProjectInvite emails an invitation to join a project with a role.
+// The invited person doesn't need an account yet.
+func CreateProjectInvite(c *fiber.Ctx) error {
+	projectID := c.Locals("projectID").(uuid.UUID)
+
+	// Parse request body
+	var req models.CreateProjectInviteRequest
+	if err := parseBody(c, &req); err != nil {
+		return err
+	}
+	req.Email = normalizeEmail(req.Email)
+	if req.Role == "" {
+		req.Role = models.RoleMember
+	}
+
+	// Check the role can be handed out by the current user
+	if err := checkAssignableRole(c, projectID, req.Role); err != nil {
+		return err
+	}
+
+	if user := userByEmail(req.Email); user != nil && projectRole(projectID, user.ID) != nil {
+		return apperr.Conflict("already_project_member", "User is already a member of this project")
+	}
+	now := time.Now()
+	for _, invite := range projectInvites {
+		if invite.ProjectID == projectID && invite.Email == req.Email && invite.Status(now) == models.InviteStatusPending {
+			return apperr.Conflict("invitation_pending", "This email address already has a pending invitation")
+		}
+	}
+
+	// Limit invitation emails per address
+	if retryAfter := rateLimited(c, "project-invite:email:"+req.Email, accountEmailRule); retryAfter > 0 {
+		return tooManyAttempts(c, retryAfter)
+	}
+
+	invite := &models.ProjectInvite{
+		ID:        uuid.New(),
+		ProjectID: projectID,
+		Email:     req.Email,
+		Role:      req.Role,
+		InvitedBy: c.Locals("userID").(uuid.UUID),
+		CreatedAt: now,
+	}
+	if err := sendProjectInvite(invite); err != nil {
+		return apperr.Internal("internal_error", "Failed to create invitation").Wrap(err)
+	}
+	projectInvites[invite.ID] = invite
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"invite": invite.ToResponse(),
+	})
+}
+
+// ResendProjectInvite emails a new link for a pending or expired invitation
+// and extends it. Links sent earlier stop working.
+func ResendProjectInvite(c *fiber.Ctx) error {
+	invite, err := findProjectInvite(c)
+	if err != nil {
+		return err
+	}
+
+	status := invite.Status(time.Now())
+	if status != models.InviteStatusPending && status != models.InviteStatusExpired {
+		return apperr.Conflict("invitation_closed", "Invitation was already "+status)
+	}
+	if err := checkAssignableRole(c, invite.ProjectID, invite.Role); err != nil {
+		return err
+	}
+
+	// Limit invitation emails per address
+	if retryAfter := rateLimited(c, "project-invite:email:"+invite.Email, accountEmailRule); retryAfter > 0 {
+		return tooManyAttempts(c, retryAfter)
+	}
+
+	if err := sendProjectInvite(invite); err != nil {
+		return apperr.Internal("internal_error", "Failed to resend invitation").Wrap(err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"invite": invite.ToResponse(),
+	})
+}
+
+// RevokeProjectInvite withdraws an invitation. It stays listed as revoked.
+func RevokeProjectInvite(c *fiber.Ctx) error {
+	invite, err := findProjectInvite(c)
+	if err != nil {
+		return err
+	}
+
+	if status := invite.Status(time.Now()); status == models.InviteStatusAccepted || status == models.InviteStatusRevoked {
+		return apperr.Conflict("invitation_closed", "Invitation was already "+status)
+	}
+
+	now := time.Now()
+	userID := c.Locals("userID").(uuid.UUID)
+	invite.RevokedAt = &now
+	invite.RevokedBy = &userID
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"invite": invite.ToResponse(),
+	})
+}
+
+// GetProjectInviteByToken describes the invitation of a token, so the app can
+// ask for account details when the invited email has no account yet
+func GetProjectInviteByToken(c *fiber.Ctx) error {
+	invite := pendingInvite(c.Query("token"))
+	if invite == nil {
+		return apperr.Invalid("invalid_invitation", "Invalid or expired invitation")
+	}
+
+	inviter := ""
+	if user, ok := users[invite.InvitedBy]; ok {
+		inviter = user.FullName
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"project_name":   projects[invite.ProjectID].Name,
+		"email":          invite.Email,
+		"role":           invite.Role,
+		"invited_by":     inviter,
+		"expires_at":     invite.ExpiresAt,
+		"account_exists": userByEmail(invite.Email) != nil,
+	})
+}
+
+// AcceptProjectInvite adds the invited person to the project. Without an
+// account for the invited email one is created and signed in; the token
+// proves the email address, so it counts as verified.
+func AcceptProjectInvite(c *fiber.Ctx) error {
+	// Parse request body
+	var req models.AcceptProjectInviteRequest
+	if err := parseBody(c, &req); err != nil {
+		return err
+	}
+
+	invite := pendingInvite(req.Token)
+	if invite == nil {
+		return apperr.Invalid("invalid_invitation", "Invalid or expired invitation")
+	}
+	project := projects[invite.ProjectID]
+
+	// Someone signed in as another user opened a link sent to someone else
+	if current := signedInUser(c); current != nil && !strings.EqualFold(current.Email, invite.Email) {
+		return apperr.Forbidden("invitation_email_mismatch", "This invitation was sent to another email address")
+	}
+
+	// Custom roles may have been deleted since the invitation was sent
+	if findProjectRole(project.ID, invite.Role) == nil {
+		return apperr.Conflict("invitation_role_missing", "The invited role no longer exists, please ask for a new invitation")
+	}
+
+	user := userByEmail(invite.Email)
+	if user != nil && !user.Active() {
+		return apperr.Forbidden("account_deactivated", "This account has been deactivated")
+	}
+	created := user == nil
+	if created {
+		if req.Username == "" || req.Password == "" {
+			return apperr.Invalid("account_required", "Username and password are required to create your account").
+				With("account_required", true)
+		}
+		for _, existing := range users {
+			if existing.Username == req.Username {
+				return apperr.Conflict("username_taken", "Username already taken")
+			}
+		}
+		if err := checkPassword(req.Password, &models.User{Username: req.Username, Email: invite.Email}); err != nil {
+			return err
+		}
+
+		hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+		if err != nil {
+			return apperr.Internal("internal_error", "Failed to hash password").Wrap(err)
+		}
+
+		now := time.Now()
+		user = &models.User{
+			ID:                    uuid.New(),
+			Username:              req.Username,
+			Email:                 invite.Email,
+			PasswordHash:          string(hashedPassword),
+			FullName:              req.FullName,
+			Role:                  "member",
+			EmailVerified:         true,
+			CreatedAt:             now,
+			UpdatedAt:             now,
+			CurrentOrganizationID: project.OrganizationID,
+		}
+		addUser(user)
+	}
+
+	// Project members have to be in the project's organization
+	if organizationMembers[project.OrganizationID][user.ID] == nil {
+		addOrganizationMember(project.OrganizationID, user.ID, models.OrgRoleMember)
+	}
+
+	// Someone may have added the user in the meantime; their role stays
+	member := projectMembers[project.ID][user.ID]
+	if member == nil && project.OwnerID != user.ID {
+		if projectMembers[project.ID] == nil {
+			projectMembers[project.ID] = make(map[uuid.UUID]*models.ProjectMember)
+		}
+		inviter := invite.InvitedBy
+		member = &models.ProjectMember{
+			ProjectID: project.ID,
+			UserID:    user.ID,
+			Role:      invite.Role,
+			JoinedAt:  time.Now(),
+			InvitedBy: &inviter,
+		}
+		projectMembers[project.ID][user.ID] = member
+		projectCache.Delete("project_" + project.ID.String() + "_user_" + user.ID.String())
+
+		publishProjectEvent(project.ID, models.WebhookMemberAdded, member)
+	}
+
+	now := time.Now()
+	acceptedBy := user.ID
+	invite.AcceptedAt = &now
+	invite.AcceptedBy = &acceptedBy
+
+	joined := projectResponse(project, responseOptions{}, user.ID)
+	if !created {
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{
+			"project": joined,
+			"member":  member,
+		})
+	}
+
+	// New accounts are signed in right away
+	session, err := issueSession(user)
+	if err != nil {
+		return apperr.Internal("internal_error", "Failed to generate token").Wrap(err)
+	}
+	session["project"] = joined
+	session["member"] = member
+	return c.Status(fiber.StatusCreated).JSON(session)
+}
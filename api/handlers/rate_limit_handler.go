@@ -0,0 +1,44 @@
+package handlers
+
+import (
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/projectflow/utils/ratelimit"
+)
+
+// RateLimitUsage is one bucket split into its route group and subject
+type RateLimitUsage struct {
+	Group   string `json:"group"`
+	Subject string `json:"subject"` // key:<id>, user:<id> or ip:<address>
+	ratelimit.BucketUsage
+}
+
+// GetRateLimitUsage shows the current API rate limit consumption, busiest
+// subjects first. Results can be filtered by group and subject.
+func GetRateLimitUsage(c *fiber.Ctx) error {
+	group := c.Query("group")
+	subject := c.Query("subject")
+	limit := c.QueryInt("limit", 100)
+	if limit < 1 || limit > 1000 {
+		limit = 100
+	}
+
+	result := []RateLimitUsage{}
+	for _, usage := range ratelimit.DefaultBuckets.Usage() {
+		entry := RateLimitUsage{BucketUsage: usage}
+		entry.Group, entry.Subject, _ = strings.Cut(usage.Key, ":")
+		if (group != "" && entry.Group != group) || (subject != "" && entry.Subject != subject) {
+			continue
+		}
+
+		result = append(result, entry)
+		if len(result) == limit {
+			break
+		}
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"usage": result,
+	})
+}
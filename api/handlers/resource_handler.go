@@ -2,13 +2,18 @@ package handlers
 
 import (
 	"database/sql"
+	"encoding/base64"
+	"errors"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
+	"github.com/amorin24/projecflow/apperr"
 	"github.com/amorin24/projecflow/models"
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
 // ResourceHandler handles resource allocation and availability endpoints
@@ -21,56 +26,265 @@ func NewResourceHandler(db *sql.DB) *ResourceHandler {
 	return &ResourceHandler{DB: db}
 }
 
-// GetResourceAllocations returns all resource allocations with optional filtering
+// AllocationProject resolves the project of the allocation in the :id route
+// parameter for the Authorize middleware
+func (h *ResourceHandler) AllocationProject(c *fiber.Ctx, userID uuid.UUID) (uuid.UUID, *models.ProjectRole, error) {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return uuid.Nil, nil, apperr.Invalid("invalid_allocation_id", "Invalid allocation ID")
+	}
+
+	var projectID uuid.UUID
+	err = h.DB.QueryRow("SELECT project_id FROM resource_allocations WHERE id = $1", id).Scan(&projectID)
+	if err == sql.ErrNoRows {
+		return uuid.Nil, nil, apperr.NotFound("allocation_not_found", "Resource allocation not found")
+	}
+	if err != nil {
+		return uuid.Nil, nil, apperr.Internal("internal_error", "Failed to fetch resource allocation").Wrap(err)
+	}
+	if _, ok := tenantOf(c).project(projectID); !ok {
+		return uuid.Nil, nil, apperr.NotFound("allocation_not_found", "Resource allocation not found")
+	}
+	return projectID, projectRole(projectID, userID), nil
+}
+
+// ownUserID fills in the current user when no user ID was given. Only
+// instance admins may act for someone else in their organization.
+func ownUserID(c *fiber.Ctx, userID *uuid.UUID) bool {
+	current := c.Locals("userID").(uuid.UUID)
+	if *userID == uuid.Nil {
+		*userID = current
+	}
+	if *userID == current {
+		return true
+	}
+	_, member := tenantOf(c).user(*userID)
+	return member && c.Locals("role") == "admin"
+}
+
+// Page sizes of allocation and time off listings
+const (
+	resourcePageLimit    = 50
+	resourcePageMaxLimit = 200
+)
+
+// resourcePage holds the paging and ?include= parameters of allocation and
+// time off listings
+type resourcePage struct {
+	limit   int
+	after   *resourceCursor // nil on the first page
+	include map[string]bool
+}
+
+// resourceCursor points after the last row of a page. Listings are ordered
+// by start date and ID, newest first.
+type resourceCursor struct {
+	startDate string // YYYY-MM-DD
+	id        int
+}
+
+// parseResourcePage reads ?limit=, ?cursor= and ?include= of a listing.
+// Without ?include= every related record is embedded, as before includes
+// could be chosen; an empty ?include= embeds none.
+func parseResourcePage(c *fiber.Ctx, includes []string) (resourcePage, error) {
+	page := resourcePage{limit: c.QueryInt("limit", resourcePageLimit), include: make(map[string]bool)}
+	if page.limit < 1 || page.limit > resourcePageMaxLimit {
+		return page, apperr.Invalid("invalid_limit", "Limit must be between 1 and "+strconv.Itoa(resourcePageMaxLimit))
+	}
+
+	if cursor := c.Query("cursor"); cursor != "" {
+		after, err := decodeResourceCursor(cursor)
+		if err != nil {
+			return page, apperr.Invalid("invalid_cursor", "Invalid cursor")
+		}
+		page.after = &after
+	}
+
+	if !c.Context().QueryArgs().Has("include") {
+		for _, name := range includes {
+			page.include[name] = true
+		}
+		return page, nil
+	}
+	for _, name := range splitList(c.Query("include")) {
+		if !containsString(includes, name) {
+			return page, apperr.Invalid("unknown_include", "Unknown include: "+name).With("allowed", includes)
+		}
+		page.include[name] = true
+	}
+	return page, nil
+}
+
+// where appends the cursor condition and the limit to a listing query. One
+// row more than the page is fetched to tell whether another page follows.
+func (p resourcePage) where(query string, args []interface{}) (string, []interface{}) {
+	if p.after != nil {
+		query += " AND (start_date, id) < ($" + strconv.Itoa(len(args)+1) + "::date, $" + strconv.Itoa(len(args)+2) + ")"
+		args = append(args, p.after.startDate, p.after.id)
+	}
+	query += " ORDER BY start_date DESC, id DESC LIMIT $" + strconv.Itoa(len(args)+1)
+	return query, append(args, p.limit+1)
+}
+
+// encodeResourceCursor builds an opaque cursor pointing after a row
+func encodeResourceCursor(startDate time.Time, id int) string {
+	raw := startDate.Format("2006-01-02") + "_" + strconv.Itoa(id)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// decodeResourceCursor parses a cursor produced by encodeResourceCursor
+func decodeResourceCursor(cursor string) (resourceCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return resourceCursor{}, err
+	}
+	date, id, found := strings.Cut(string(raw), "_")
+	if !found {
+		return resourceCursor{}, errors.New("malformed cursor")
+	}
+	if _, err := time.Parse("2006-01-02", date); err != nil {
+		return resourceCursor{}, err
+	}
+	rowID, err := strconv.Atoi(id)
+	if err != nil {
+		return resourceCursor{}, err
+	}
+	return resourceCursor{startDate: date, id: rowID}, nil
+}
+
+// uuidArray turns a set of IDs into a uuid[] query parameter
+func uuidArray(ids map[uuid.UUID]bool) interface{} {
+	list := make([]string, 0, len(ids))
+	for id := range ids {
+		list = append(list, id.String())
+	}
+	return pq.Array(list)
+}
+
+// loadUsers fetches a batch of users with a single query. Users that no
+// longer exist are missing from the result.
+func (h *ResourceHandler) loadUsers(ids map[uuid.UUID]bool) (map[uuid.UUID]*models.User, error) {
+	found := make(map[uuid.UUID]*models.User, len(ids))
+	if len(ids) == 0 {
+		return found, nil
+	}
+	rows, err := h.DB.Query(`
+		SELECT id, username, email, full_name, role, created_at, avatar_url, timezone, locale
+		FROM users WHERE id = ANY($1::uuid[])
+	`, uuidArray(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		user := &models.User{}
+		err := rows.Scan(
+			&user.ID,
+			&user.Username,
+			&user.Email,
+			&user.FullName,
+			&user.Role,
+			&user.CreatedAt,
+			&user.AvatarURL,
+			&user.Timezone,
+			&user.Locale,
+		)
+		if err != nil {
+			return nil, err
+		}
+		found[user.ID] = user
+	}
+	return found, rows.Err()
+}
+
+// loadProjects fetches a batch of projects with a single query
+func (h *ResourceHandler) loadProjects(ids map[uuid.UUID]bool) (map[uuid.UUID]*models.Project, error) {
+	found := make(map[uuid.UUID]*models.Project, len(ids))
+	if len(ids) == 0 {
+		return found, nil
+	}
+	rows, err := h.DB.Query(`
+		SELECT id, name, description, owner_id, created_at, updated_at
+		FROM projects WHERE id = ANY($1::uuid[])
+	`, uuidArray(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		project := &models.Project{}
+		err := rows.Scan(
+			&project.ID,
+			&project.Name,
+			&project.Description,
+			&project.OwnerID,
+			&project.CreatedAt,
+			&project.UpdatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		found[project.ID] = project
+	}
+	return found, rows.Err()
+}
+
+// GetResourceAllocations returns a page of resource allocations with optional
+// filtering. The users and projects they refer to are loaded with one query
+// each for the whole page.
 func (h *ResourceHandler) GetResourceAllocations(c *fiber.Ctx) error {
 	// Get query parameters for filtering
 	userID := c.Query("user_id")
 	projectID := c.Query("project_id")
-	
+
+	page, err := parseResourcePage(c, models.AllocationIncludes)
+	if err != nil {
+		return err
+	}
+
+	// Allocations of other organizations are never returned
+	tenantProjects := make(map[uuid.UUID]bool)
+	for _, project := range tenantOf(c).projects() {
+		tenantProjects[project.ID] = true
+	}
+
 	query := `SELECT id, user_id, project_id, allocation_percentage, 
 			  start_date, end_date, created_at, updated_at 
-			  FROM resource_allocations WHERE 1=1`
-	
-	args := []interface{}{}
-	
+			  FROM resource_allocations WHERE project_id = ANY($1::uuid[])`
+
+	args := []interface{}{uuidArray(tenantProjects)}
+
 	if userID != "" {
 		query += " AND user_id = $" + strconv.Itoa(len(args)+1)
 		userUUID, err := uuid.Parse(userID)
 		if err != nil {
-			return c.Status(http.StatusBadRequest).JSON(fiber.Map{
-				"error": "Invalid user ID",
-			})
+			return apperr.Invalid("invalid_user_id", "Invalid user ID")
 		}
 		args = append(args, userUUID)
 	}
-	
+
 	if projectID != "" {
 		query += " AND project_id = $" + strconv.Itoa(len(args)+1)
 		projectUUID, err := uuid.Parse(projectID)
 		if err != nil {
-			return c.Status(http.StatusBadRequest).JSON(fiber.Map{
-				"error": "Invalid project ID",
-			})
+			return apperr.Invalid("invalid_project_id", "Invalid project ID")
 		}
 		args = append(args, projectUUID)
 	}
-	
-	query += " ORDER BY start_date DESC"
-	
+
+	query, args = page.where(query, args)
+
 	rows, err := h.DB.Query(query, args...)
 	if err != nil {
-		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to fetch resource allocations",
-		})
+		return apperr.Internal("internal_error", "Failed to fetch resource allocations").Wrap(err)
 	}
 	defer rows.Close()
-	
+
 	allocations := []models.ResourceAllocation{}
-	
 	for rows.Next() {
 		var allocation models.ResourceAllocation
 		var endDateNull sql.NullTime
-		
+
 		err := rows.Scan(
 			&allocation.ID,
 			&allocation.UserID,
@@ -81,61 +295,57 @@ func (h *ResourceHandler) GetResourceAllocations(c *fiber.Ctx) error {
 			&allocation.CreatedAt,
 			&allocation.UpdatedAt,
 		)
-		
+
 		if err != nil {
-			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
-				"error": "Failed to scan resource allocation",
-			})
+			return apperr.Internal("internal_error", "Failed to scan resource allocation").Wrap(err)
 		}
-		
+
 		if endDateNull.Valid {
 			allocation.EndDate = endDateNull.Time
 		}
-		
-		// Fetch user and project details
-		allocation.User = &models.User{}
-		err = h.DB.QueryRow(`
-			SELECT id, username, email, full_name, role, created_at 
-			FROM users WHERE id = $1
-		`, allocation.UserID).Scan(
-			&allocation.User.ID,
-			&allocation.User.Username,
-			&allocation.User.Email,
-			&allocation.User.FullName,
-			&allocation.User.Role,
-			&allocation.User.CreatedAt,
-		)
-		
+
+		allocations = append(allocations, allocation)
+	}
+	if err := rows.Err(); err != nil {
+		return apperr.Internal("internal_error", "Failed to fetch resource allocations").Wrap(err)
+	}
+
+	var nextCursor string
+	if len(allocations) > page.limit {
+		allocations = allocations[:page.limit]
+		last := allocations[page.limit-1]
+		nextCursor = encodeResourceCursor(last.StartDate, last.ID)
+	}
+
+	// Load the users and projects of the page in one go
+	userIDs := make(map[uuid.UUID]bool)
+	projectIDs := make(map[uuid.UUID]bool)
+	for _, allocation := range allocations {
+		userIDs[allocation.UserID] = true
+		projectIDs[allocation.ProjectID] = true
+	}
+	if page.include[models.ResourceIncludeUser] {
+		userMap, err := h.loadUsers(userIDs)
 		if err != nil {
-			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
-				"error": "Failed to fetch user details",
-			})
+			return apperr.Internal("internal_error", "Failed to fetch user details").Wrap(err)
 		}
-		
-		allocation.Project = &models.Project{}
-		err = h.DB.QueryRow(`
-			SELECT id, name, description, owner_id, created_at, updated_at 
-			FROM projects WHERE id = $1
-		`, allocation.ProjectID).Scan(
-			&allocation.Project.ID,
-			&allocation.Project.Name,
-			&allocation.Project.Description,
-			&allocation.Project.OwnerID,
-			&allocation.Project.CreatedAt,
-			&allocation.Project.UpdatedAt,
-		)
-		
+		for i := range allocations {
+			allocations[i].User = userMap[allocations[i].UserID]
+		}
+	}
+	if page.include[models.ResourceIncludeProject] {
+		projectMap, err := h.loadProjects(projectIDs)
 		if err != nil {
-			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
-				"error": "Failed to fetch project details",
-			})
+			return apperr.Internal("internal_error", "Failed to fetch project details").Wrap(err)
+		}
+		for i := range allocations {
+			allocations[i].Project = projectMap[allocations[i].ProjectID]
 		}
-		
-		allocations = append(allocations, allocation)
 	}
-	
+
 	return c.JSON(fiber.Map{
 		"allocations": allocations,
+		"next_cursor": nextCursor,
 	})
 }
 
@@ -143,18 +353,21 @@ func (h *ResourceHandler) GetResourceAllocations(c *fiber.Ctx) error {
 func (h *ResourceHandler) CreateResourceAllocation(c *fiber.Ctx) error {
 	var allocation models.ResourceAllocation
 	
-	if err := c.BodyParser(&allocation); err != nil {
-		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
-			"error": "Failed to parse request body",
-		})
+	if err := parseBody(c, &allocation); err != nil {
+		return err
 	}
 	
-	// Validate required fields
-	if allocation.UserID == uuid.Nil || allocation.ProjectID == uuid.Nil || 
-		allocation.AllocationPercentage <= 0 || allocation.AllocationPercentage > 100 {
-		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid allocation data",
-		})
+	// The user and project are required when creating, not when updating
+	if allocation.UserID == uuid.Nil || allocation.ProjectID == uuid.Nil {
+		return apperr.Invalid("invalid_allocation", "Invalid allocation data")
+	}
+	
+	// Only members of the project can be allocated to it
+	if _, ok := tenantOf(c).user(allocation.UserID); !ok {
+		return apperr.ErrUserNotFound
+	}
+	if projectRole(allocation.ProjectID, allocation.UserID) == nil {
+		return apperr.Invalid("not_project_member", "User is not a member of this project")
 	}
 	
 	// Check for overlapping allocations
@@ -171,15 +384,11 @@ func (h *ResourceHandler) CreateResourceAllocation(c *fiber.Ctx) error {
 	`, allocation.UserID, allocation.StartDate, allocation.EndDate).Scan(&totalAllocation)
 	
 	if err != nil {
-		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to check allocation overlap",
-		})
+		return apperr.Internal("internal_error", "Failed to check allocation overlap").Wrap(err)
 	}
 	
 	if totalAllocation+allocation.AllocationPercentage > 100 {
-		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
-			"error": "Total allocation percentage exceeds 100%",
-		})
+		return apperr.Invalid("overallocated", "Total allocation percentage exceeds 100%")
 	}
 	
 	query := `
@@ -208,9 +417,7 @@ func (h *ResourceHandler) CreateResourceAllocation(c *fiber.Ctx) error {
 	)
 	
 	if err != nil {
-		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to create resource allocation",
-		})
+		return apperr.Internal("internal_error", "Failed to create resource allocation").Wrap(err)
 	}
 	
 	return c.Status(http.StatusCreated).JSON(fiber.Map{
@@ -222,23 +429,19 @@ func (h *ResourceHandler) CreateResourceAllocation(c *fiber.Ctx) error {
 func (h *ResourceHandler) UpdateResourceAllocation(c *fiber.Ctx) error {
 	id, err := strconv.Atoi(c.Params("id"))
 	if err != nil {
-		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid allocation ID",
-		})
+		return apperr.Invalid("invalid_allocation_id", "Invalid allocation ID")
 	}
 	
 	var allocation models.ResourceAllocation
-	if err := c.BodyParser(&allocation); err != nil {
-		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
-			"error": "Failed to parse request body",
-		})
+	if err := parseBody(c, &allocation); err != nil {
+		return err
 	}
 	
-	// Validate allocation percentage
-	if allocation.AllocationPercentage <= 0 || allocation.AllocationPercentage > 100 {
-		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid allocation percentage",
-		})
+	// The allocated user and project can't be changed
+	allocation.ProjectID = c.Locals("projectID").(uuid.UUID)
+	err = h.DB.QueryRow("SELECT user_id FROM resource_allocations WHERE id = $1", id).Scan(&allocation.UserID)
+	if err != nil {
+		return apperr.Internal("internal_error", "Failed to fetch resource allocation").Wrap(err)
 	}
 	
 	// Check for overlapping allocations excluding current allocation
@@ -256,15 +459,11 @@ func (h *ResourceHandler) UpdateResourceAllocation(c *fiber.Ctx) error {
 	`, allocation.UserID, id, allocation.StartDate, allocation.EndDate).Scan(&totalAllocation)
 	
 	if err != nil {
-		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to check allocation overlap",
-		})
+		return apperr.Internal("internal_error", "Failed to check allocation overlap").Wrap(err)
 	}
 	
 	if totalAllocation+allocation.AllocationPercentage > 100 {
-		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
-			"error": "Total allocation percentage exceeds 100%",
-		})
+		return apperr.Invalid("overallocated", "Total allocation percentage exceeds 100%")
 	}
 	
 	var endDateParam interface{} = nil
@@ -292,13 +491,9 @@ func (h *ResourceHandler) UpdateResourceAllocation(c *fiber.Ctx) error {
 	
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return c.Status(http.StatusNotFound).JSON(fiber.Map{
-				"error": "Resource allocation not found",
-			})
+			return apperr.NotFound("allocation_not_found", "Resource allocation not found")
 		}
-		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to update resource allocation",
-		})
+		return apperr.Internal("internal_error", "Failed to update resource allocation").Wrap(err)
 	}
 	
 	allocation.ID = id
@@ -311,29 +506,21 @@ func (h *ResourceHandler) UpdateResourceAllocation(c *fiber.Ctx) error {
 func (h *ResourceHandler) DeleteResourceAllocation(c *fiber.Ctx) error {
 	id, err := strconv.Atoi(c.Params("id"))
 	if err != nil {
-		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid allocation ID",
-		})
+		return apperr.Invalid("invalid_allocation_id", "Invalid allocation ID")
 	}
 	
 	result, err := h.DB.Exec("DELETE FROM resource_allocations WHERE id = $1", id)
 	if err != nil {
-		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to delete resource allocation",
-		})
+		return apperr.Internal("internal_error", "Failed to delete resource allocation").Wrap(err)
 	}
 	
 	rowsAffected, err := result.RowsAffected()
 	if err != nil {
-		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to get rows affected",
-		})
+		return apperr.Internal("internal_error", "Failed to get rows affected").Wrap(err)
 	}
 	
 	if rowsAffected == 0 {
-		return c.Status(http.StatusNotFound).JSON(fiber.Map{
-			"error": "Resource allocation not found",
-		})
+		return apperr.NotFound("allocation_not_found", "Resource allocation not found")
 	}
 	
 	return c.SendStatus(http.StatusNoContent)
@@ -343,16 +530,15 @@ func (h *ResourceHandler) DeleteResourceAllocation(c *fiber.Ctx) error {
 func (h *ResourceHandler) GetUserAvailability(c *fiber.Ctx) error {
 	userID := c.Query("user_id")
 	if userID == "" {
-		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
-			"error": "User ID is required",
-		})
+		return apperr.Invalid("user_id_required", "User ID is required")
 	}
 	
 	userUUID, err := uuid.Parse(userID)
 	if err != nil {
-		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid user ID",
-		})
+		return apperr.Invalid("invalid_user_id", "Invalid user ID")
+	}
+	if _, ok := tenantOf(c).user(userUUID); !ok {
+		return apperr.ErrUserNotFound
 	}
 	
 	rows, err := h.DB.Query(`
@@ -363,9 +549,7 @@ func (h *ResourceHandler) GetUserAvailability(c *fiber.Ctx) error {
 	`, userUUID)
 	
 	if err != nil {
-		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to fetch user availability",
-		})
+		return apperr.Internal("internal_error", "Failed to fetch user availability").Wrap(err)
 	}
 	defer rows.Close()
 	
@@ -383,9 +567,7 @@ func (h *ResourceHandler) GetUserAvailability(c *fiber.Ctx) error {
 		)
 		
 		if err != nil {
-			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
-				"error": "Failed to scan user availability",
-			})
+			return apperr.Internal("internal_error", "Failed to scan user availability").Wrap(err)
 		}
 		
 		schedule.UserID = userUUID
@@ -401,18 +583,13 @@ func (h *ResourceHandler) GetUserAvailability(c *fiber.Ctx) error {
 func (h *ResourceHandler) SetUserAvailability(c *fiber.Ctx) error {
 	var availability models.UserAvailability
 	
-	if err := c.BodyParser(&availability); err != nil {
-		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
-			"error": "Failed to parse request body",
-		})
+	if err := parseBody(c, &availability); err != nil {
+		return err
 	}
 	
-	// Validate fields
-	if availability.UserID == uuid.Nil || 
-		availability.DayOfWeek < 0 || availability.DayOfWeek > 6 {
-		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid availability data",
-		})
+	// Users set their own availability
+	if !ownUserID(c, &availability.UserID) {
+		return apperr.Forbidden("availability_self_only", "You can only set your own availability")
 	}
 	
 	// Check for overlapping time slots
@@ -430,15 +607,11 @@ func (h *ResourceHandler) SetUserAvailability(c *fiber.Ctx) error {
 	`, availability.UserID, availability.DayOfWeek, availability.StartTime, availability.EndTime).Scan(&count)
 	
 	if err != nil {
-		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to check time slot overlap",
-		})
+		return apperr.Internal("internal_error", "Failed to check time slot overlap").Wrap(err)
 	}
 	
 	if count > 0 {
-		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
-			"error": "Overlapping time slot exists",
-		})
+		return apperr.Invalid("availability_overlap", "Overlapping time slot exists")
 	}
 	
 	query := `
@@ -461,9 +634,7 @@ func (h *ResourceHandler) SetUserAvailability(c *fiber.Ctx) error {
 	)
 	
 	if err != nil {
-		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to set user availability",
-		})
+		return apperr.Internal("internal_error", "Failed to set user availability").Wrap(err)
 	}
 	
 	return c.Status(http.StatusCreated).JSON(fiber.Map{
@@ -475,23 +646,13 @@ func (h *ResourceHandler) SetUserAvailability(c *fiber.Ctx) error {
 func (h *ResourceHandler) CreateTimeOffRequest(c *fiber.Ctx) error {
 	var request models.TimeOffRequest
 	
-	if err := c.BodyParser(&request); err != nil {
-		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
-			"error": "Failed to parse request body",
-		})
-	}
-	
-	// Validate fields
-	if request.UserID == uuid.Nil || request.StartDate.IsZero() || request.EndDate.IsZero() {
-		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid request data",
-		})
+	if err := parseBody(c, &request); err != nil {
+		return err
 	}
 	
-	if request.StartDate.After(request.EndDate) {
-		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
-			"error": "Start date must be before end date",
-		})
+	// Users request time off for themselves
+	if !ownUserID(c, &request.UserID) {
+		return apperr.Forbidden("time_off_self_only", "You can only request time off for yourself")
 	}
 	
 	// Check for overlapping requests
@@ -509,15 +670,11 @@ func (h *ResourceHandler) CreateTimeOffRequest(c *fiber.Ctx) error {
 	`, request.UserID, request.StartDate, request.EndDate).Scan(&count)
 	
 	if err != nil {
-		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to check request overlap",
-		})
+		return apperr.Internal("internal_error", "Failed to check request overlap").Wrap(err)
 	}
 	
 	if count > 0 {
-		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
-			"error": "Overlapping time off request exists",
-		})
+		return apperr.Invalid("time_off_overlap", "Overlapping time off request exists")
 	}
 	
 	query := `
@@ -542,9 +699,7 @@ func (h *ResourceHandler) CreateTimeOffRequest(c *fiber.Ctx) error {
 	)
 	
 	if err != nil {
-		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to create time off request",
-		})
+		return apperr.Internal("internal_error", "Failed to create time off request").Wrap(err)
 	}
 	
 	return c.Status(http.StatusCreated).JSON(fiber.Map{
@@ -556,38 +711,53 @@ func (h *ResourceHandler) CreateTimeOffRequest(c *fiber.Ctx) error {
 func (h *ResourceHandler) UpdateTimeOffRequestStatus(c *fiber.Ctx) error {
 	id, err := strconv.Atoi(c.Params("id"))
 	if err != nil {
-		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid request ID",
-		})
+		return apperr.Invalid("invalid_time_off_id", "Invalid request ID")
 	}
 	
-	var statusUpdate struct {
-		Status string `json:"status"`
+	var statusUpdate models.UpdateTimeOffStatusRequest
+	
+	if err := parseBody(c, &statusUpdate); err != nil {
+		return err
 	}
 	
-	if err := c.BodyParser(&statusUpdate); err != nil {
-		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
-			"error": "Failed to parse request body",
-		})
+	// Find the requester
+	var requesterID uuid.UUID
+	err = h.DB.QueryRow("SELECT user_id FROM time_off_requests WHERE id = $1", id).Scan(&requesterID)
+	if err == sql.ErrNoRows {
+		return apperr.NotFound("time_off_not_found", "Time off request not found")
+	}
+	if err != nil {
+		return apperr.Internal("internal_error", "Failed to fetch time off request").Wrap(err)
 	}
 	
-	// Validate status
-	validStatuses := map[string]bool{"pending": true, "approved": true, "rejected": true}
-	if !validStatuses[statusUpdate.Status] {
-		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid status",
-		})
+	// Nobody decides on their own time off. Managers of the requester's
+	// projects and instance admins decide on everyone else's.
+	approverID := c.Locals("userID").(uuid.UUID)
+	if approverID == requesterID {
+		return apperr.Forbidden("time_off_self_decision", "You can't decide on your own time off request")
+	}
+	t := tenantOf(c)
+	if _, ok := t.user(requesterID); !ok {
+		return apperr.NotFound("time_off_not_found", "Time off request not found")
+	}
+	if c.Locals("role") != "admin" && !t.manages(approverID, requesterID) {
+		return apperr.Forbidden("time_off_manager_required", "Only managers of the requester's projects can decide on time off")
 	}
 	
+	// Moving a request back to pending clears the decision
 	query := `
 		UPDATE time_off_requests
 		SET status = $1,
+			approver_id = CASE WHEN $1 = 'pending' THEN NULL ELSE $3::uuid END,
+			decided_at = CASE WHEN $1 = 'pending' THEN NULL ELSE NOW() END,
 			updated_at = NOW()
 		WHERE id = $2
-		RETURNING user_id, start_date, end_date, request_type, notes, created_at, updated_at
+		RETURNING user_id, start_date, end_date, request_type, notes, approver_id, decided_at, created_at, updated_at
 	`
 	
 	var request models.TimeOffRequest
+	var approver uuid.NullUUID
+	var decidedAt sql.NullTime
 	request.ID = id
 	request.Status = statusUpdate.Status
 	
@@ -595,25 +765,38 @@ func (h *ResourceHandler) UpdateTimeOffRequestStatus(c *fiber.Ctx) error {
 		query,
 		statusUpdate.Status,
 		id,
+		approverID,
 	).Scan(
 		&request.UserID,
 		&request.StartDate,
 		&request.EndDate,
 		&request.RequestType,
 		&request.Notes,
+		&approver,
+		&decidedAt,
 		&request.CreatedAt,
 		&request.UpdatedAt,
 	)
 	
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return c.Status(http.StatusNotFound).JSON(fiber.Map{
-				"error": "Time off request not found",
-			})
+			return apperr.NotFound("time_off_not_found", "Time off request not found")
 		}
-		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to update time off request status",
-		})
+		return apperr.Internal("internal_error", "Failed to update time off request status").Wrap(err)
+	}
+	
+	if approver.Valid {
+		request.ApproverID = &approver.UUID
+	}
+	if decidedAt.Valid {
+		request.DecidedAt = &decidedAt.Time
+	}
+	
+	// Let the requester know about the decision
+	if request.Status != "pending" {
+		notifyUser(request.UserID, models.NotificationTimeOffDecision,
+			"Your "+request.RequestType+" request from "+request.StartDate.Format("Jan 2")+
+				" to "+request.EndDate.Format("Jan 2")+" was "+request.Status, nil)
 	}
 	
 	return c.JSON(fiber.Map{
@@ -621,49 +804,59 @@ func (h *ResourceHandler) UpdateTimeOffRequestStatus(c *fiber.Ctx) error {
 	})
 }
 
-// GetTimeOffRequests returns time off requests with optional filtering
+// GetTimeOffRequests returns a page of time off requests with optional
+// filtering. The users they belong to are loaded with one query for the
+// whole page.
 func (h *ResourceHandler) GetTimeOffRequests(c *fiber.Ctx) error {
 	userID := c.Query("user_id")
 	status := c.Query("status")
-	
+
+	page, err := parseResourcePage(c, models.TimeOffIncludes)
+	if err != nil {
+		return err
+	}
+
+	// Requests of users outside the organization are never returned
+	tenantUsers := make(map[uuid.UUID]bool)
+	for _, user := range tenantOf(c).users() {
+		tenantUsers[user.ID] = true
+	}
+
 	query := `
-		SELECT id, user_id, start_date, end_date, status, request_type, notes, created_at, updated_at
+		SELECT id, user_id, start_date, end_date, status, request_type, notes, approver_id, decided_at, created_at, updated_at
 		FROM time_off_requests
-		WHERE 1=1
+		WHERE user_id = ANY($1::uuid[])
 	`
-	
-	args := []interface{}{}
-	
+
+	args := []interface{}{uuidArray(tenantUsers)}
+
 	if userID != "" {
 		query += " AND user_id = $" + strconv.Itoa(len(args)+1)
 		userUUID, err := uuid.Parse(userID)
 		if err != nil {
-			return c.Status(http.StatusBadRequest).JSON(fiber.Map{
-				"error": "Invalid user ID",
-			})
+			return apperr.Invalid("invalid_user_id", "Invalid user ID")
 		}
 		args = append(args, userUUID)
 	}
-	
+
 	if status != "" {
 		query += " AND status = $" + strconv.Itoa(len(args)+1)
 		args = append(args, status)
 	}
-	
-	query += " ORDER BY start_date DESC"
-	
+
+	query, args = page.where(query, args)
+
 	rows, err := h.DB.Query(query, args...)
 	if err != nil {
-		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to fetch time off requests",
-		})
+		return apperr.Internal("internal_error", "Failed to fetch time off requests").Wrap(err)
 	}
 	defer rows.Close()
-	
+
 	requests := []models.TimeOffRequest{}
-	
 	for rows.Next() {
 		var request models.TimeOffRequest
+		var approver uuid.NullUUID
+		var decidedAt sql.NullTime
 		err := rows.Scan(
 			&request.ID,
 			&request.UserID,
@@ -672,40 +865,53 @@ func (h *ResourceHandler) GetTimeOffRequests(c *fiber.Ctx) error {
 			&request.Status,
 			&request.RequestType,
 			&request.Notes,
+			&approver,
+			&decidedAt,
 			&request.CreatedAt,
 			&request.UpdatedAt,
 		)
-		
+
 		if err != nil {
-			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
-				"error": "Failed to scan time off request",
-			})
+			return apperr.Internal("internal_error", "Failed to scan time off request").Wrap(err)
 		}
-		
-		// Fetch user details
-		request.User = &models.User{}
-		err = h.DB.QueryRow(`
-			SELECT id, username, email, full_name, role, created_at
-			FROM users WHERE id = $1
-		`, request.UserID).Scan(
-			&request.User.ID,
-			&request.User.Username,
-			&request.User.Email,
-			&request.User.FullName,
-			&request.User.Role,
-			&request.User.CreatedAt,
-		)
-		
-		if err != nil {
-			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
-				"error": "Failed to fetch user details",
-			})
+
+		if approver.Valid {
+			request.ApproverID = &approver.UUID
 		}
-		
+		if decidedAt.Valid {
+			request.DecidedAt = &decidedAt.Time
+		}
+
 		requests = append(requests, request)
 	}
-	
+	if err := rows.Err(); err != nil {
+		return apperr.Internal("internal_error", "Failed to fetch time off requests").Wrap(err)
+	}
+
+	var nextCursor string
+	if len(requests) > page.limit {
+		requests = requests[:page.limit]
+		last := requests[page.limit-1]
+		nextCursor = encodeResourceCursor(last.StartDate, last.ID)
+	}
+
+	// Load the users of the page in one go
+	if page.include[models.ResourceIncludeUser] {
+		userIDs := make(map[uuid.UUID]bool)
+		for _, request := range requests {
+			userIDs[request.UserID] = true
+		}
+		userMap, err := h.loadUsers(userIDs)
+		if err != nil {
+			return apperr.Internal("internal_error", "Failed to fetch user details").Wrap(err)
+		}
+		for i := range requests {
+			requests[i].User = userMap[requests[i].UserID]
+		}
+	}
+
 	return c.JSON(fiber.Map{
-		"requests": requests,
+		"requests":    requests,
+		"next_cursor": nextCursor,
 	})
 }
@@ -0,0 +1,273 @@
+package handlers
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/projectflow/apperr"
+	"github.com/projectflow/models"
+)
+
+// responseOptions holds the ?include= and ?fields= parameters shaping task
+// and project responses
+type responseOptions struct {
+	include map[string]bool
+	fields  []string // JSON names to keep, everything if empty
+}
+
+// parseResponseOptions reads ?include= and ?fields= for responses like
+// example, refusing names the response doesn't have
+func parseResponseOptions(c *fiber.Ctx, includes []string, example interface{}) (responseOptions, error) {
+	opts := responseOptions{include: make(map[string]bool)}
+	for _, name := range splitList(c.Query("include")) {
+		if !containsString(includes, name) {
+			return opts, apperr.Invalid("unknown_include", "Unknown include: "+name).With("allowed", includes)
+		}
+		opts.include[name] = true
+	}
+
+	fields := splitList(c.Query("fields"))
+	if len(fields) == 0 {
+		return opts, nil
+	}
+	known := jsonFieldNames(reflect.TypeOf(example))
+	for _, name := range fields {
+		if !containsString(known, name) {
+			return opts, apperr.Invalid("unknown_field", "Unknown field: "+name).With("allowed", known)
+		}
+	}
+	// The ID is always kept so sparse records can be told apart
+	opts.fields = append(fields, "id")
+	return opts, nil
+}
+
+// sparse returns a record, or a list of records, with only the requested
+// fields. Without ?fields= the value is returned as is.
+func (o responseOptions) sparse(value interface{}) (interface{}, error) {
+	if len(o.fields) == 0 {
+		return value, nil
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+
+	if kind := reflect.TypeOf(value).Kind(); kind == reflect.Slice || kind == reflect.Array {
+		var records []map[string]json.RawMessage
+		if err := json.Unmarshal(data, &records); err != nil {
+			return nil, err
+		}
+		for _, record := range records {
+			o.keepFields(record)
+		}
+		return records, nil
+	}
+
+	var record map[string]json.RawMessage
+	if err := json.Unmarshal(data, &record); err != nil {
+		return nil, err
+	}
+	o.keepFields(record)
+	return record, nil
+}
+
+func (o responseOptions) keepFields(record map[string]json.RawMessage) {
+	for name := range record {
+		if !containsString(o.fields, name) {
+			delete(record, name)
+		}
+	}
+}
+
+// jsonFieldNames lists the JSON names of a struct's fields, including those
+// of embedded structs, sorted
+func jsonFieldNames(t reflect.Type) []string {
+	if t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice {
+		return jsonFieldNames(t.Elem())
+	}
+	var names []string
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		switch {
+		case name == "-":
+		case field.Anonymous && name == "" && field.Type.Kind() == reflect.Struct:
+			names = append(names, jsonFieldNames(field.Type)...)
+		case !field.IsExported():
+		case name == "":
+			names = append(names, field.Name)
+		default:
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// splitList splits a comma separated query parameter, dropping blanks
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func containsString(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
+
+// usersByID looks up a batch of users at once. Removed users are missing
+// from the result.
+func usersByID(ids map[uuid.UUID]bool) map[uuid.UUID]*models.UserResponse {
+	found := make(map[uuid.UUID]*models.UserResponse, len(ids))
+	for id := range ids {
+		if user, ok := users[id]; ok {
+			resp := user.ToResponse()
+			found[id] = &resp
+		}
+	}
+	return found
+}
+
+// taskResponses builds the responses of a batch of tasks. The users,
+// statuses, projects and comments they refer to are collected first and
+// each looked up once for the whole batch, not once per task.
+func taskResponses(list []*models.Task, opts responseOptions) []models.TaskResponse {
+	userIDs := make(map[uuid.UUID]bool)
+	projectIDs := make(map[uuid.UUID]bool)
+	for _, task := range list {
+		userIDs[task.ReporterID] = true
+		if task.AssigneeID != nil {
+			userIDs[*task.AssigneeID] = true
+		}
+		projectIDs[task.ProjectID] = true
+	}
+
+	comments := make(map[uuid.UUID][]*models.TaskComment)
+	if opts.include[models.TaskIncludeComments] {
+		for _, task := range list {
+			comments[task.ID] = taskComments[task.ID]
+			for _, comment := range comments[task.ID] {
+				userIDs[comment.UserID] = true
+			}
+		}
+	}
+
+	var projectList []*models.Project
+	if opts.include[models.TaskIncludeProject] {
+		for projectID := range projectIDs {
+			if project, ok := projects[projectID]; ok {
+				projectList = append(projectList, project)
+				userIDs[project.OwnerID] = true
+			}
+		}
+	}
+
+	// Load everything referred to in one go
+	userMap := usersByID(userIDs)
+	statuses := make(map[uuid.UUID]map[int]*models.TaskStatus, len(projectIDs))
+	for projectID := range projectIDs {
+		statuses[projectID] = make(map[int]*models.TaskStatus)
+		for _, status := range taskStatuses[projectID] {
+			statuses[projectID][status.ID] = status
+		}
+	}
+	projectMap := make(map[uuid.UUID]*models.ProjectResponse, len(projectList))
+	for _, project := range projectList {
+		projectMap[project.ID] = &models.ProjectResponse{Project: *project, Owner: userMap[project.OwnerID]}
+	}
+
+	responses := make([]models.TaskResponse, 0, len(list))
+	for _, task := range list {
+		resp := models.TaskResponse{
+			Task:     *task,
+			Status:   statuses[task.ProjectID][task.StatusID],
+			Reporter: userMap[task.ReporterID],
+			Project:  projectMap[task.ProjectID],
+		}
+		if task.AssigneeID != nil {
+			resp.Assignee = userMap[*task.AssigneeID]
+		}
+		if opts.include[models.TaskIncludeComments] {
+			resp.Comments = commentResponses(comments[task.ID], userMap)
+		}
+		responses = append(responses, resp)
+	}
+	return responses
+}
+
+// taskResponse builds the response of a single task
+func taskResponse(task *models.Task, opts responseOptions) models.TaskResponse {
+	return taskResponses([]*models.Task{task}, opts)[0]
+}
+
+// commentResponses builds the responses of comments whose authors are in
+// userMap; pass nil to look them up
+func commentResponses(list []*models.TaskComment, userMap map[uuid.UUID]*models.UserResponse) []models.CommentResponse {
+	if userMap == nil {
+		userIDs := make(map[uuid.UUID]bool)
+		for _, comment := range list {
+			userIDs[comment.UserID] = true
+		}
+		userMap = usersByID(userIDs)
+	}
+	responses := make([]models.CommentResponse, 0, len(list))
+	for _, comment := range list {
+		responses = append(responses, models.CommentResponse{TaskComment: *comment, User: userMap[comment.UserID]})
+	}
+	return responses
+}
+
+// projectResponses builds the responses of a batch of projects, looking up
+// the owners and included members once for the whole batch. Members are only
+// listed for projects where the user's role may see them.
+func projectResponses(list []*models.Project, opts responseOptions, userID uuid.UUID) []models.ProjectResponse {
+	withMembers := make(map[uuid.UUID]bool)
+	userIDs := make(map[uuid.UUID]bool)
+	for _, project := range list {
+		userIDs[project.OwnerID] = true
+		if !opts.include[models.ProjectIncludeMembers] {
+			continue
+		}
+		if role := projectRole(project.ID, userID); role != nil && role.Has(models.PermMemberView) {
+			withMembers[project.ID] = true
+			for memberID := range projectMembers[project.ID] {
+				userIDs[memberID] = true
+			}
+		}
+	}
+	userMap := usersByID(userIDs)
+
+	responses := make([]models.ProjectResponse, 0, len(list))
+	for _, project := range list {
+		resp := models.ProjectResponse{Project: *project, Owner: userMap[project.OwnerID]}
+		if withMembers[project.ID] {
+			resp.Members = []models.UserResponse{}
+			for memberID := range projectMembers[project.ID] {
+				if member, ok := userMap[memberID]; ok {
+					resp.Members = append(resp.Members, *member)
+				}
+			}
+			sort.Slice(resp.Members, func(i, j int) bool { return resp.Members[i].Username < resp.Members[j].Username })
+		}
+		responses = append(responses, resp)
+	}
+	return responses
+}
+
+// projectResponse builds the response of a single project
+func projectResponse(project *models.Project, opts responseOptions, userID uuid.UUID) models.ProjectResponse {
+	return projectResponses([]*models.Project{project}, opts, userID)[0]
+}
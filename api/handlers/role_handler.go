@@ -0,0 +1,243 @@
+package handlers
+
+import (
+	"regexp"
+	"sort"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/projectflow/api/middleware"
+	"github.com/projectflow/apperr"
+	"github.com/projectflow/models"
+)
+
+// Custom role names, distinct from user-facing descriptions
+var roleNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{1,49}$`)
+
+// In-memory storage for development: custom roles by project and name
+var projectRoles = make(map[uuid.UUID]map[string]*models.ProjectRole)
+
+// findProjectRole returns a built-in role or a custom role of the project, or nil
+func findProjectRole(projectID uuid.UUID, name string) *models.ProjectRole {
+	if role := models.BuiltInRole(name); role != nil {
+		return role
+	}
+	return projectRoles[projectID][name]
+}
+
+// projectRole returns a user's role in a project, or nil if they aren't a member
+func projectRole(projectID, userID uuid.UUID) *models.ProjectRole {
+	project, ok := projects[projectID]
+	if !ok {
+		return nil
+	}
+	if project.OwnerID == userID {
+		return models.BuiltInRole(models.RoleOwner)
+	}
+
+	member := projectMembers[projectID][userID]
+	if member == nil {
+		return nil
+	}
+	return findProjectRole(projectID, member.Role)
+}
+
+// ProjectParam resolves the project in a route parameter for the Authorize middleware
+func ProjectParam(param string) middleware.ProjectResolver {
+	return func(c *fiber.Ctx, userID uuid.UUID) (uuid.UUID, *models.ProjectRole, error) {
+		projectID, err := uuid.Parse(c.Params(param))
+		if err != nil {
+			return uuid.Nil, nil, apperr.Invalid("invalid_project_id", "Invalid project ID")
+		}
+		if _, ok := tenantOf(c).project(projectID); !ok {
+			return uuid.Nil, nil, apperr.NotFound("project_not_found", "Project not found")
+		}
+		return projectID, projectRole(projectID, userID), nil
+	}
+}
+
+// TaskProject resolves the project of the task in the :id route parameter
+func TaskProject(c *fiber.Ctx, userID uuid.UUID) (uuid.UUID, *models.ProjectRole, error) {
+	taskID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return uuid.Nil, nil, apperr.Invalid("invalid_task_id", "Invalid task ID")
+	}
+	task, ok := tenantOf(c).task(taskID)
+	if !ok {
+		return uuid.Nil, nil, apperr.NotFound("task_not_found", "Task not found")
+	}
+	return task.ProjectID, projectRole(task.ProjectID, userID), nil
+}
+
+// ProjectInBody resolves the project in the project_id field of the request body
+func ProjectInBody(c *fiber.Ctx, userID uuid.UUID) (uuid.UUID, *models.ProjectRole, error) {
+	var body struct {
+		ProjectID uuid.UUID `json:"project_id"`
+	}
+	if err := c.BodyParser(&body); err != nil {
+		return uuid.Nil, nil, apperr.ErrInvalidBody
+	}
+	if _, ok := tenantOf(c).project(body.ProjectID); !ok {
+		return uuid.Nil, nil, apperr.NotFound("project_not_found", "Project not found")
+	}
+	return body.ProjectID, projectRole(body.ProjectID, userID), nil
+}
+
+// checkPermissions returns the problem with a permission list, or nil if
+// every permission exists and the current user holds it
+func checkPermissions(c *fiber.Ctx, permissions []string) error {
+	known := make(map[string]bool, len(models.Permissions))
+	for _, permission := range models.Permissions {
+		known[permission] = true
+	}
+
+	actor := c.Locals("projectRole").(*models.ProjectRole)
+	for _, permission := range permissions {
+		if !known[permission] {
+			return apperr.Invalid("unknown_permission", "Unknown permission: "+permission)
+		}
+		if !actor.Has(permission) {
+			return apperr.Forbidden("permission_not_grantable", "You can't grant the "+permission+" permission, your role doesn't have it")
+		}
+	}
+	return nil
+}
+
+// checkAssignableRole makes sure a role exists, isn't the owner role and
+// grants nothing the current user lacks. It returns the problem otherwise.
+func checkAssignableRole(c *fiber.Ctx, projectID uuid.UUID, name string) error {
+	role := findProjectRole(projectID, name)
+	if role == nil {
+		return apperr.Invalid("unknown_role", "Unknown role: "+name)
+	}
+	if role.Name == models.RoleOwner {
+		return apperr.Invalid("owner_role_not_assignable", "The owner role can't be assigned")
+	}
+	return checkPermissions(c, role.Permissions)
+}
+
+// GetProjectRoles lists the built-in and custom roles of a project
+func GetProjectRoles(c *fiber.Ctx) error {
+	projectID := c.Locals("projectID").(uuid.UUID)
+
+	custom := []*models.ProjectRole{}
+	for _, role := range projectRoles[projectID] {
+		custom = append(custom, role)
+	}
+	sort.Slice(custom, func(i, j int) bool { return custom[i].Name < custom[j].Name })
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"roles":       append(append([]*models.ProjectRole{}, models.BuiltInRoles...), custom...),
+		"permissions": models.Permissions,
+	})
+}
+
+// CreateProjectRole adds a custom role to a project
+func CreateProjectRole(c *fiber.Ctx) error {
+	projectID := c.Locals("projectID").(uuid.UUID)
+
+	// Parse request body
+	var req models.CreateRoleRequest
+	if err := parseBody(c, &req); err != nil {
+		return err
+	}
+
+	if !roleNamePattern.MatchString(req.Name) {
+		return apperr.Invalid("invalid_role_name", "Role names are 2 to 50 lowercase letters, digits, dashes or underscores")
+	}
+	if findProjectRole(projectID, req.Name) != nil {
+		return apperr.Conflict("role_exists", "A role with this name already exists")
+	}
+	if err := checkPermissions(c, req.Permissions); err != nil {
+		return err
+	}
+
+	role := &models.ProjectRole{
+		Name:        req.Name,
+		Description: req.Description,
+		Permissions: req.Permissions,
+		ProjectID:   &projectID,
+		CreatedAt:   time.Now(),
+	}
+	if projectRoles[projectID] == nil {
+		projectRoles[projectID] = make(map[string]*models.ProjectRole)
+	}
+	projectRoles[projectID][role.Name] = role
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"role": role,
+	})
+}
+
+// findCustomRole loads the custom role in the :role parameter. Built-in roles
+// can't be changed. It returns an error if the role isn't found or is out of
+// the current user's reach.
+func findCustomRole(c *fiber.Ctx, projectID uuid.UUID) (*models.ProjectRole, error) {
+	name := c.Params("role")
+	if models.BuiltInRole(name) != nil {
+		return nil, apperr.Invalid("builtin_role_immutable", "Built-in roles can't be changed")
+	}
+
+	role := projectRoles[projectID][name]
+	if role == nil {
+		return nil, apperr.NotFound("role_not_found", "Role not found")
+	}
+
+	// Roles with permissions the user lacks are out of their reach
+	if err := checkPermissions(c, role.Permissions); err != nil {
+		return nil, err
+	}
+	return role, nil
+}
+
+// UpdateProjectRole changes the description and permissions of a custom role
+func UpdateProjectRole(c *fiber.Ctx) error {
+	projectID := c.Locals("projectID").(uuid.UUID)
+	role, err := findCustomRole(c, projectID)
+	if err != nil {
+		return err
+	}
+
+	// Parse request body
+	var req models.UpdateRoleRequest
+	if err := parseBody(c, &req); err != nil {
+		return err
+	}
+	if err := checkPermissions(c, req.Permissions); err != nil {
+		return err
+	}
+
+	role.Description = req.Description
+	role.Permissions = req.Permissions
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"role": role,
+	})
+}
+
+// DeleteProjectRole removes a custom role that no member or team has
+func DeleteProjectRole(c *fiber.Ctx) error {
+	projectID := c.Locals("projectID").(uuid.UUID)
+	role, err := findCustomRole(c, projectID)
+	if err != nil {
+		return err
+	}
+
+	for _, member := range projectMembers[projectID] {
+		if member.Role == role.Name {
+			return apperr.Conflict("role_in_use", "The role is still assigned to members")
+		}
+	}
+	for _, link := range projectTeams[projectID] {
+		if link.Role == role.Name {
+			return apperr.Conflict("role_in_use", "The role is still assigned to a team")
+		}
+	}
+
+	delete(projectRoles[projectID], role.Name)
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Role deleted successfully",
+	})
+}
@@ -1,16 +1,37 @@
 package handlers
 
 import (
+	"regexp"
+	"strings"
+	"sync"
+
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
+	"github.com/projectflow/apperr"
 	"github.com/projectflow/models"
 )
 
 // In-memory storage for development
 var tasks = make(map[uuid.UUID]*models.Task)
+
+// Guards tasks and the fields of the tasks in it, which the due date
+// watcher reads in the background
+var tasksMu sync.RWMutex
 var taskComments = make(map[uuid.UUID][]*models.TaskComment)
 var taskStatuses = make(map[uuid.UUID][]*models.TaskStatus)
 
+// Matches @username mentions in comments
+var mentionPattern = regexp.MustCompile(`@([A-Za-z0-9_.-]{3,50})`)
+
+// findTask looks a task up by ID
+func findTask(id uuid.UUID) (*models.Task, bool) {
+	tasksMu.RLock()
+	defer tasksMu.RUnlock()
+
+	task, ok := tasks[id]
+	return task, ok
+}
+
 // Initialize default task statuses for a project
 func initializeTaskStatuses(projectID uuid.UUID) {
 	// Check if statuses already exist for this project
@@ -31,34 +52,18 @@ func CreateTask(c *fiber.Ctx) error {
 	// Get user ID from context (set by auth middleware)
 	userID, ok := c.Locals("userID").(uuid.UUID)
 	if !ok {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"error": "Unauthorized",
-		})
+		return apperr.ErrUnauthorized
 	}
 
-	// Parse request body
-	var req models.CreateTaskRequest
-	if err := c.BodyParser(&req); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid request body",
-		})
-	}
-
-	// Check if project exists
-	if _, ok := projects[req.ProjectID]; !ok {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-			"error": "Project not found",
-		})
+	opts, err := taskOptions(c)
+	if err != nil {
+		return err
 	}
 
-	// Check if user has access to the project
-	role := c.Locals("role").(string)
-	if role != "admin" {
-		if members, ok := projectMembers[req.ProjectID]; !ok || members[userID] == nil {
-			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
-				"error": "You don't have access to this project",
-			})
-		}
+	// Parse request body
+	var req models.CreateTaskRequest
+	if err := parseBody(c, &req); err != nil {
+		return err
 	}
 
 	// Initialize task statuses if not already done
@@ -73,25 +78,19 @@ func CreateTask(c *fiber.Ctx) error {
 		}
 	}
 	if !validStatus {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid status ID",
-		})
+		return apperr.Invalid("invalid_status_id", "Invalid status ID")
 	}
 
 	// Validate assignee if provided
 	if req.AssigneeID != nil {
 		// Check if assignee exists
-		if _, ok := users[*req.AssigneeID]; !ok {
-			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-				"error": "Assignee not found",
-			})
+		if _, ok := tenantOf(c).user(*req.AssigneeID); !ok {
+			return apperr.NotFound("assignee_not_found", "Assignee not found")
 		}
 
 		// Check if assignee is a member of the project
 		if members, ok := projectMembers[req.ProjectID]; !ok || members[*req.AssigneeID] == nil {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-				"error": "Assignee is not a member of this project",
-			})
+			return apperr.Invalid("assignee_not_member", "Assignee is not a member of this project")
 		}
 	}
 
@@ -125,11 +124,7 @@ func CreateTask(c *fiber.Ctx) error {
 		}
 		
 		if dueDate == nil {
-			// Return error for invalid date format
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-				"error": "Invalid date format or value",
-				"details": "Please provide a valid date in YYYY-MM-DD format (e.g., 2025-03-20)",
-			})
+			return apperr.Invalid("invalid_due_date", "Due date must be a valid date in YYYY-MM-DD format, e.g. 2025-03-20")
 		}
 	}
 	
@@ -148,197 +143,117 @@ func CreateTask(c *fiber.Ctx) error {
 	}
 
 	// Save task (in-memory for development)
+	tasksMu.Lock()
 	tasks[taskID] = task
+	tasksMu.Unlock()
 
 	// Create notification for assignee if assigned
 	if req.AssigneeID != nil {
-		// In a real app, we would send an email notification here
-		// For now, just create an in-memory notification
-		notificationID := uuid.New()
-		notification := &models.Notification{
-			ID:        notificationID,
-			UserID:    *req.AssigneeID,
-			Content:   "You have been assigned a new task: " + req.Title,
-			Type:      "task_assigned",
-			Read:      false,
-			RelatedID: &taskID,
-		}
-		notifications[notificationID] = notification
+		notifyUser(*req.AssigneeID, models.NotificationTaskAssigned, "You have been assigned a new task: "+req.Title, &taskID)
 	}
 
+	publishProjectEvent(task.ProjectID, models.WebhookTaskCreated, task)
+
+	body, err := opts.sparse(taskResponse(task, opts))
+	if err != nil {
+		return err
+	}
 	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
-		"task": task,
+		"task": body,
 	})
 }
 
 // GetAllTasks returns all tasks in a project
 func GetAllTasks(c *fiber.Ctx) error {
-	// Get user ID from context (set by auth middleware)
-	userID, ok := c.Locals("userID").(uuid.UUID)
-	if !ok {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"error": "Unauthorized",
-		})
-	}
-
-	// Get project ID from URL parameter
-	id := c.Params("projectID")
-	projectID, err := uuid.Parse(id)
+	projectID := c.Locals("projectID").(uuid.UUID)
+	opts, err := taskOptions(c)
 	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid project ID",
-		})
-	}
-
-	// Check if project exists
-	if _, ok := projects[projectID]; !ok {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-			"error": "Project not found",
-		})
-	}
-
-	// Check if user has access to the project
-	role := c.Locals("role").(string)
-	if role != "admin" {
-		if members, ok := projectMembers[projectID]; !ok || members[userID] == nil {
-			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
-				"error": "You don't have access to this project",
-			})
-		}
+		return err
 	}
 
 	// Get all tasks for the project
 	var taskList []*models.Task
+	tasksMu.RLock()
 	for _, task := range tasks {
 		if task.ProjectID == projectID {
 			taskList = append(taskList, task)
 		}
 	}
+	tasksMu.RUnlock()
 
+	body, err := opts.sparse(taskResponses(taskList, opts))
+	if err != nil {
+		return err
+	}
 	return c.Status(fiber.StatusOK).JSON(fiber.Map{
-		"tasks": taskList,
+		"tasks": body,
 	})
 }
 
 // GetTaskByID returns a task by ID
 func GetTaskByID(c *fiber.Ctx) error {
-	// Get user ID from context (set by auth middleware)
-	userID, ok := c.Locals("userID").(uuid.UUID)
-	if !ok {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"error": "Unauthorized",
-		})
-	}
-
 	// Get task ID from URL parameter
 	id := c.Params("id")
 	taskID, err := uuid.Parse(id)
 	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid task ID",
-		})
+		return apperr.Invalid("invalid_task_id", "Invalid task ID")
 	}
 
 	// Find task
-	task, ok := tasks[taskID]
+	task, ok := findTask(taskID)
 	if !ok {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-			"error": "Task not found",
-		})
+		return apperr.NotFound("task_not_found", "Task not found")
 	}
 
-	// Check if user has access to the project
-	role := c.Locals("role").(string)
-	if role != "admin" {
-		if members, ok := projectMembers[task.ProjectID]; !ok || members[userID] == nil {
-			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
-				"error": "You don't have access to this task",
-			})
-		}
-	}
-
-	// Get task comments
-	comments := taskComments[taskID]
-
-	// Get task status
-	var status *models.TaskStatus
-	for _, s := range taskStatuses[task.ProjectID] {
-		if s.ID == task.StatusID {
-			status = s
-			break
-		}
+	opts, err := taskOptions(c)
+	if err != nil {
+		return err
 	}
-
-	// Get assignee if assigned
-	var assignee *models.UserResponse
-	if task.AssigneeID != nil {
-		if user, ok := users[*task.AssigneeID]; ok {
-			userResp := user.ToResponse()
-			assignee = &userResp
-		}
+	resp := taskResponse(task, opts)
+	body, err := opts.sparse(resp)
+	if err != nil {
+		return err
 	}
 
-	// Get reporter
-	reporter, ok := users[task.ReporterID]
-	if !ok {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Reporter not found",
-		})
+	// The status, users and comments are repeated next to the task for
+	// clients written before they were embedded in it
+	comments := resp.Comments
+	if comments == nil {
+		comments = commentResponses(taskComments[taskID], nil)
 	}
-
-	// Return task data with comments
 	return c.Status(fiber.StatusOK).JSON(fiber.Map{
-		"task":     task,
-		"status":   status,
-		"assignee": assignee,
-		"reporter": reporter.ToResponse(),
+		"task":     body,
+		"status":   resp.Status,
+		"assignee": resp.Assignee,
+		"reporter": resp.Reporter,
 		"comments": comments,
 	})
 }
 
 // UpdateTask updates a task
 func UpdateTask(c *fiber.Ctx) error {
-	// Get user ID from context (set by auth middleware)
-	userID, ok := c.Locals("userID").(uuid.UUID)
-	if !ok {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"error": "Unauthorized",
-		})
-	}
-
 	// Get task ID from URL parameter
 	id := c.Params("id")
 	taskID, err := uuid.Parse(id)
 	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid task ID",
-		})
+		return apperr.Invalid("invalid_task_id", "Invalid task ID")
 	}
 
 	// Find task
-	task, ok := tasks[taskID]
+	task, ok := findTask(taskID)
 	if !ok {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-			"error": "Task not found",
-		})
+		return apperr.NotFound("task_not_found", "Task not found")
 	}
 
-	// Check if user has access to the project
-	role := c.Locals("role").(string)
-	if role != "admin" {
-		if members, ok := projectMembers[task.ProjectID]; !ok || members[userID] == nil {
-			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
-				"error": "You don't have access to this task",
-			})
-		}
+	opts, err := taskOptions(c)
+	if err != nil {
+		return err
 	}
 
 	// Parse request body
 	var req models.UpdateTaskRequest
-	if err := c.BodyParser(&req); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid request body",
-		})
+	if err := parseBody(c, &req); err != nil {
+		return err
 	}
 
 	// Validate status ID
@@ -350,25 +265,19 @@ func UpdateTask(c *fiber.Ctx) error {
 		}
 	}
 	if !validStatus {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid status ID",
-		})
+		return apperr.Invalid("invalid_status_id", "Invalid status ID")
 	}
 
 	// Validate assignee if provided
 	if req.AssigneeID != nil {
 		// Check if assignee exists
-		if _, ok := users[*req.AssigneeID]; !ok {
-			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-				"error": "Assignee not found",
-			})
+		if _, ok := tenantOf(c).user(*req.AssigneeID); !ok {
+			return apperr.NotFound("assignee_not_found", "Assignee not found")
 		}
 
 		// Check if assignee is a member of the project
 		if members, ok := projectMembers[task.ProjectID]; !ok || members[*req.AssigneeID] == nil {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-				"error": "Assignee is not a member of this project",
-			})
+			return apperr.Invalid("assignee_not_member", "Assignee is not a member of this project")
 		}
 	}
 
@@ -379,77 +288,55 @@ func UpdateTask(c *fiber.Ctx) error {
 	}
 
 	// Update task
+	tasksMu.Lock()
 	task.Title = req.Title
 	task.Description = req.Description
 	task.StatusID = req.StatusID
 	task.AssigneeID = req.AssigneeID
 	task.DueDate = req.DueDate
 	task.Priority = req.Priority
+	tasksMu.Unlock()
 
 	// Create notification for new assignee if changed
 	if req.AssigneeID != nil && (oldAssigneeID == nil || *oldAssigneeID != *req.AssigneeID) {
-		// In a real app, we would send an email notification here
-		// For now, just create an in-memory notification
-		notificationID := uuid.New()
-		notification := &models.Notification{
-			ID:        notificationID,
-			UserID:    *req.AssigneeID,
-			Content:   "You have been assigned a task: " + task.Title,
-			Type:      "task_assigned",
-			Read:      false,
-			RelatedID: &taskID,
-		}
-		notifications[notificationID] = notification
+		notifyUser(*req.AssigneeID, models.NotificationTaskAssigned, "You have been assigned a task: "+task.Title, &taskID)
 	}
 
+	publishProjectEvent(task.ProjectID, models.WebhookTaskUpdated, task)
+
+	body, err := opts.sparse(taskResponse(task, opts))
+	if err != nil {
+		return err
+	}
 	return c.Status(fiber.StatusOK).JSON(fiber.Map{
-		"task": task,
+		"task": body,
 	})
 }
 
 // UpdateTaskStatus updates a task's status
 func UpdateTaskStatus(c *fiber.Ctx) error {
-	// Get user ID from context (set by auth middleware)
-	userID, ok := c.Locals("userID").(uuid.UUID)
-	if !ok {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"error": "Unauthorized",
-		})
-	}
-
 	// Get task ID from URL parameter
 	id := c.Params("id")
 	taskID, err := uuid.Parse(id)
 	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid task ID",
-		})
+		return apperr.Invalid("invalid_task_id", "Invalid task ID")
 	}
 
 	// Find task
-	task, ok := tasks[taskID]
+	task, ok := findTask(taskID)
 	if !ok {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-			"error": "Task not found",
-		})
+		return apperr.NotFound("task_not_found", "Task not found")
 	}
 
-	// Check if user has access to the project
-	role := c.Locals("role").(string)
-	if role != "admin" {
-		if members, ok := projectMembers[task.ProjectID]; !ok || members[userID] == nil {
-			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
-				"error": "You don't have access to this task",
-			})
-		}
+	opts, err := taskOptions(c)
+	if err != nil {
+		return err
 	}
 
 	// Parse request body
 	var req models.UpdateTaskStatusRequest
-	if err := c.BodyParser(&req); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid request body",
-		})
+	if err := parseBody(c, &req); err != nil {
+		return err
 	}
 
 	// Validate status ID
@@ -461,16 +348,28 @@ func UpdateTaskStatus(c *fiber.Ctx) error {
 		}
 	}
 	if !validStatus {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid status ID",
-		})
+		return apperr.Invalid("invalid_status_id", "Invalid status ID")
 	}
 
 	// Update task status
+	tasksMu.Lock()
+	previousStatusID := task.StatusID
 	task.StatusID = req.StatusID
+	tasksMu.Unlock()
+
+	if previousStatusID != task.StatusID {
+		publishProjectEvent(task.ProjectID, models.WebhookTaskStatusChanged, fiber.Map{
+			"task":               task,
+			"previous_status_id": previousStatusID,
+		})
+	}
 
+	body, err := opts.sparse(taskResponse(task, opts))
+	if err != nil {
+		return err
+	}
 	return c.Status(fiber.StatusOK).JSON(fiber.Map{
-		"task": task,
+		"task": body,
 	})
 }
 
@@ -479,47 +378,37 @@ func DeleteTask(c *fiber.Ctx) error {
 	// Get user ID from context (set by auth middleware)
 	userID, ok := c.Locals("userID").(uuid.UUID)
 	if !ok {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"error": "Unauthorized",
-		})
+		return apperr.ErrUnauthorized
 	}
 
 	// Get task ID from URL parameter
 	id := c.Params("id")
 	taskID, err := uuid.Parse(id)
 	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid task ID",
-		})
+		return apperr.Invalid("invalid_task_id", "Invalid task ID")
 	}
 
 	// Find task
-	task, ok := tasks[taskID]
+	task, ok := findTask(taskID)
 	if !ok {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-			"error": "Task not found",
-		})
+		return apperr.NotFound("task_not_found", "Task not found")
 	}
 
-	// Check if user is project owner, task reporter, or admin
-	role := c.Locals("role").(string)
-	project, ok := projects[task.ProjectID]
-	if !ok {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Project not found",
-		})
-	}
-
-	if role != "admin" && project.OwnerID != userID && task.ReporterID != userID {
-		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
-			"error": "Only project owner, task reporter, or admin can delete the task",
-		})
+	// Reporters may delete their own tasks without the task:delete permission
+	role := c.Locals("projectRole").(*models.ProjectRole)
+	if !role.Has(models.PermTaskDelete) && task.ReporterID != userID {
+		return apperr.Forbidden("permission_denied", "Your project role doesn't have the "+models.PermTaskDelete+" permission").
+			With("permission", models.PermTaskDelete)
 	}
 
 	// Delete task
+	tasksMu.Lock()
 	delete(tasks, taskID)
+	tasksMu.Unlock()
 	delete(taskComments, taskID)
 
+	publishProjectEvent(task.ProjectID, models.WebhookTaskDeleted, task)
+
 	return c.Status(fiber.StatusOK).JSON(fiber.Map{
 		"message": "Task deleted successfully",
 	})
@@ -530,44 +419,26 @@ func AddTaskComment(c *fiber.Ctx) error {
 	// Get user ID from context (set by auth middleware)
 	userID, ok := c.Locals("userID").(uuid.UUID)
 	if !ok {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"error": "Unauthorized",
-		})
+		return apperr.ErrUnauthorized
 	}
 
 	// Get task ID from URL parameter
 	id := c.Params("id")
 	taskID, err := uuid.Parse(id)
 	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid task ID",
-		})
+		return apperr.Invalid("invalid_task_id", "Invalid task ID")
 	}
 
 	// Find task
-	task, ok := tasks[taskID]
+	task, ok := findTask(taskID)
 	if !ok {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-			"error": "Task not found",
-		})
-	}
-
-	// Check if user has access to the project
-	role := c.Locals("role").(string)
-	if role != "admin" {
-		if members, ok := projectMembers[task.ProjectID]; !ok || members[userID] == nil {
-			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
-				"error": "You don't have access to this task",
-			})
-		}
+		return apperr.NotFound("task_not_found", "Task not found")
 	}
 
 	// Parse request body
 	var req models.CreateCommentRequest
-	if err := c.BodyParser(&req); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid request body",
-		})
+	if err := parseBody(c, &req); err != nil {
+		return err
 	}
 
 	// Create comment
@@ -587,76 +458,42 @@ func AddTaskComment(c *fiber.Ctx) error {
 
 	// Create notification for task assignee if different from commenter
 	if task.AssigneeID != nil && *task.AssigneeID != userID {
-		// In a real app, we would send an email notification here
-		// For now, just create an in-memory notification
-		notificationID := uuid.New()
-		notification := &models.Notification{
-			ID:        notificationID,
-			UserID:    *task.AssigneeID,
-			Content:   "New comment on task: " + task.Title,
-			Type:      "comment_added",
-			Read:      false,
-			RelatedID: &taskID,
-		}
-		notifications[notificationID] = notification
+		notifyUser(*task.AssigneeID, models.NotificationCommentAdded, "New comment on task: "+task.Title, &taskID)
 	}
 
 	// Create notification for task reporter if different from commenter and assignee
 	if task.ReporterID != userID && (task.AssigneeID == nil || task.ReporterID != *task.AssigneeID) {
-		// In a real app, we would send an email notification here
-		// For now, just create an in-memory notification
-		notificationID := uuid.New()
-		notification := &models.Notification{
-			ID:        notificationID,
-			UserID:    task.ReporterID,
-			Content:   "New comment on task: " + task.Title,
-			Type:      "comment_added",
-			Read:      false,
-			RelatedID: &taskID,
+		notifyUser(task.ReporterID, models.NotificationCommentAdded, "New comment on task: "+task.Title, &taskID)
+	}
+
+	publishProjectEvent(task.ProjectID, models.WebhookCommentCreated, comment)
+
+	// Notify project members mentioned in the comment
+	mentioned := make(map[uuid.UUID]bool)
+	for _, match := range mentionPattern.FindAllStringSubmatch(req.Content, -1) {
+		for memberID := range projectMembers[task.ProjectID] {
+			member, ok := users[memberID]
+			if !ok || memberID == userID || mentioned[memberID] || !strings.EqualFold(member.Username, match[1]) {
+				continue
+			}
+			mentioned[memberID] = true
+			notifyUser(memberID, models.NotificationMention, "You were mentioned in a comment on task: "+task.Title, &taskID)
 		}
-		notifications[notificationID] = notification
 	}
 
 	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
-		"comment": comment,
+		"comment": commentResponses([]*models.TaskComment{comment}, nil)[0],
 	})
 }
 
+// taskOptions reads the ?include= and ?fields= parameters of task responses
+func taskOptions(c *fiber.Ctx) (responseOptions, error) {
+	return parseResponseOptions(c, models.TaskIncludes, models.TaskResponse{})
+}
+
 // GetTaskStatuses returns all task statuses for a project
 func GetTaskStatuses(c *fiber.Ctx) error {
-	// Get user ID from context (set by auth middleware)
-	userID, ok := c.Locals("userID").(uuid.UUID)
-	if !ok {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"error": "Unauthorized",
-		})
-	}
-
-	// Get project ID from URL parameter
-	id := c.Params("projectID")
-	projectID, err := uuid.Parse(id)
-	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid project ID",
-		})
-	}
-
-	// Check if project exists
-	if _, ok := projects[projectID]; !ok {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-			"error": "Project not found",
-		})
-	}
-
-	// Check if user has access to the project
-	role := c.Locals("role").(string)
-	if role != "admin" {
-		if members, ok := projectMembers[projectID]; !ok || members[userID] == nil {
-			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
-				"error": "You don't have access to this project",
-			})
-		}
-	}
+	projectID := c.Locals("projectID").(uuid.UUID)
 
 	// Initialize task statuses if not already done
 	initializeTaskStatuses(projectID)
@@ -0,0 +1,647 @@
+package handlers
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/projectflow/apperr"
+	"github.com/projectflow/models"
+)
+
+// In-memory storage for development
+var teams = make(map[uuid.UUID]*models.Team)
+var teamMembers = make(map[uuid.UUID]map[uuid.UUID]*models.TeamMember)
+var projectTeams = make(map[uuid.UUID]map[uuid.UUID]*models.ProjectTeam)
+
+// syncProjectMember brings a user's project membership in line with the
+// teams linked to the project. The oldest linked team the user is in decides
+// the role. Direct memberships and the owner are left alone.
+func syncProjectMember(projectID, userID uuid.UUID) {
+	project, ok := projects[projectID]
+	if !ok || project.OwnerID == userID {
+		return
+	}
+	member := projectMembers[projectID][userID]
+	if member != nil && member.TeamID == nil {
+		return
+	}
+
+	var link *models.ProjectTeam
+	for teamID, projectTeam := range projectTeams[projectID] {
+		if teamMembers[teamID][userID] != nil && (link == nil || projectTeam.AddedAt.Before(link.AddedAt)) {
+			link = projectTeam
+		}
+	}
+
+	switch {
+	case link == nil && member != nil:
+		delete(projectMembers[projectID], userID)
+		publishProjectEvent(projectID, models.WebhookMemberRemoved, member)
+	case link != nil && member == nil:
+		if projectMembers[projectID] == nil {
+			projectMembers[projectID] = make(map[uuid.UUID]*models.ProjectMember)
+		}
+		teamID := link.TeamID
+		member = &models.ProjectMember{
+			ProjectID: projectID,
+			UserID:    userID,
+			Role:      link.Role,
+			JoinedAt:  time.Now(),
+			TeamID:    &teamID,
+		}
+		projectMembers[projectID][userID] = member
+		publishProjectEvent(projectID, models.WebhookMemberAdded, member)
+	case link != nil && (*member.TeamID != link.TeamID || member.Role != link.Role):
+		teamID := link.TeamID
+		member.TeamID = &teamID
+		member.Role = link.Role
+		publishProjectEvent(projectID, models.WebhookMemberUpdated, member)
+	default:
+		return
+	}
+	projectCache.Delete("project_" + projectID.String() + "_user_" + userID.String())
+}
+
+// syncTeamMember syncs a user in every project the team is linked to
+func syncTeamMember(teamID, userID uuid.UUID) {
+	for projectID, links := range projectTeams {
+		if links[teamID] != nil {
+			syncProjectMember(projectID, userID)
+		}
+	}
+}
+
+// canManageTeam reports whether the current user may change a team's
+// members. Organization admins manage every team, leads their own.
+func canManageTeam(c *fiber.Ctx, teamID uuid.UUID) bool {
+	if isOrganizationAdmin(c) {
+		return true
+	}
+	member := teamMembers[teamID][c.Locals("userID").(uuid.UUID)]
+	return member != nil && member.Role == models.TeamRoleLead
+}
+
+// checkTeamProjects makes sure the current user may change who belongs to
+// the projects a team is linked to, since adding or removing a team member
+// adds or removes them there too. Team leads who aren't organization admins
+// need member:manage in each project and every permission of the role the
+// team has in it, as if they changed the project's members directly.
+func checkTeamProjects(c *fiber.Ctx, teamID uuid.UUID) error {
+	if isOrganizationAdmin(c) {
+		return nil
+	}
+
+	userID := c.Locals("userID").(uuid.UUID)
+	for projectID, links := range projectTeams {
+		link := links[teamID]
+		if link == nil {
+			continue
+		}
+		actor := projectRole(projectID, userID)
+		if actor == nil || !actor.Has(models.PermMemberManage) {
+			return apperr.Forbidden("project_member_manage_required", "Changing this team changes the members of a project you can't manage").
+				With("project_id", projectID)
+		}
+		if role := findProjectRole(projectID, link.Role); role != nil {
+			for _, permission := range role.Permissions {
+				if !actor.Has(permission) {
+					return apperr.Forbidden("permission_not_grantable", "The team's role in a project grants the "+permission+" permission, your role doesn't have it").
+						With("project_id", projectID)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// findTeam loads the team in the :id parameter. It returns an error if the
+// team isn't in the current organization.
+func findTeam(c *fiber.Ctx) (*models.Team, error) {
+	teamID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return nil, apperr.Invalid("invalid_team_id", "Invalid team ID")
+	}
+	team, ok := tenantOf(c).team(teamID)
+	if !ok {
+		return nil, apperr.NotFound("team_not_found", "Team not found")
+	}
+	return team, nil
+}
+
+// openWork counts the unfinished tasks assigned to a user in the
+// organization's projects. A task is finished in its project's last status.
+func openWork(t tenant, userID uuid.UUID) (open, overdue, high int) {
+	now := time.Now()
+	tasksMu.RLock()
+	defer tasksMu.RUnlock()
+	for _, task := range tasks {
+		if task.AssigneeID == nil || *task.AssigneeID != userID {
+			continue
+		}
+		if _, ok := t.project(task.ProjectID); !ok || isDoneStatus(task.ProjectID, task.StatusID) {
+			continue
+		}
+		open++
+		if task.DueDate != nil && task.DueDate.Before(now) {
+			overdue++
+		}
+		if task.Priority == "high" {
+			high++
+		}
+	}
+	return open, overdue, high
+}
+
+// isDoneStatus reports whether a status is the last column of a project's board
+func isDoneStatus(projectID uuid.UUID, statusID int) bool {
+	var last *models.TaskStatus
+	for _, status := range taskStatuses[projectID] {
+		if last == nil || status.DisplayOrder > last.DisplayOrder {
+			last = status
+		}
+	}
+	return last != nil && last.ID == statusID
+}
+
+// workloadLoad returns open tasks per 40 hours of weekly capacity, or nil
+// without capacity
+func workloadLoad(open, capacityHours int) *float64 {
+	if capacityHours <= 0 {
+		return nil
+	}
+	load := float64(open) * models.DefaultCapacityHours / float64(capacityHours)
+	return &load
+}
+
+// teamWorkload computes the workload report of a team
+func teamWorkload(t tenant, team *models.Team, withMembers bool) models.TeamWorkload {
+	report := models.TeamWorkload{Team: team}
+	for userID, member := range teamMembers[team.ID] {
+		user, ok := t.user(userID)
+		if !ok {
+			continue
+		}
+		open, overdue, high := openWork(t, userID)
+
+		report.MemberCount++
+		report.CapacityHours += member.CapacityHours
+		report.OpenTasks += open
+		report.OverdueTasks += overdue
+		report.HighPriority += high
+		if withMembers {
+			report.Members = append(report.Members, models.MemberWorkload{
+				User:          user.ToResponse(),
+				Role:          member.Role,
+				CapacityHours: member.CapacityHours,
+				OpenTasks:     open,
+				OverdueTasks:  overdue,
+				HighPriority:  high,
+				Load:          workloadLoad(open, member.CapacityHours),
+			})
+		}
+	}
+	report.Load = workloadLoad(report.OpenTasks, report.CapacityHours)
+
+	sort.Slice(report.Members, func(i, j int) bool {
+		return report.Members[i].User.Username < report.Members[j].User.Username
+	})
+	return report
+}
+
+// GetTeams lists the teams of the current organization
+func GetTeams(c *fiber.Ctx) error {
+	teamList := tenantOf(c).teams()
+	sort.Slice(teamList, func(i, j int) bool { return teamList[i].Name < teamList[j].Name })
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"teams": teamList,
+	})
+}
+
+// CreateTeam creates a team in the current organization
+func CreateTeam(c *fiber.Ctx) error {
+	if !isOrganizationAdmin(c) {
+		return apperr.ErrOrgAdminNeeded
+	}
+
+	// Parse request body
+	var req models.CreateTeamRequest
+	if err := parseBody(c, &req); err != nil {
+		return err
+	}
+
+	t := tenantOf(c)
+	for _, team := range t.teams() {
+		if strings.EqualFold(team.Name, strings.TrimSpace(req.Name)) {
+			return apperr.Conflict("team_exists", "A team with this name already exists")
+		}
+	}
+
+	now := time.Now()
+	team := &models.Team{
+		ID:             uuid.New(),
+		OrganizationID: t.orgID,
+		Name:           strings.TrimSpace(req.Name),
+		Description:    req.Description,
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	}
+	teams[team.ID] = team
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"team": team,
+	})
+}
+
+// GetTeam returns a team with its members and projects
+func GetTeam(c *fiber.Ctx) error {
+	team, err := findTeam(c)
+	if err != nil {
+		return err
+	}
+
+	t := tenantOf(c)
+	memberList := []fiber.Map{}
+	for userID, member := range teamMembers[team.ID] {
+		if user, ok := t.user(userID); ok {
+			memberList = append(memberList, fiber.Map{
+				"user":           user.ToResponse(),
+				"role":           member.Role,
+				"capacity_hours": member.CapacityHours,
+				"joined_at":      member.JoinedAt,
+			})
+		}
+	}
+
+	projectIDs := []uuid.UUID{}
+	for projectID, links := range projectTeams {
+		if links[team.ID] != nil {
+			projectIDs = append(projectIDs, projectID)
+		}
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"team":        team,
+		"members":     memberList,
+		"project_ids": projectIDs,
+	})
+}
+
+// UpdateTeam renames a team
+func UpdateTeam(c *fiber.Ctx) error {
+	team, err := findTeam(c)
+	if err != nil {
+		return err
+	}
+	if !canManageTeam(c, team.ID) {
+		return apperr.Forbidden("team_lead_required", "Only organization admins and team leads can change the team")
+	}
+
+	// Parse request body
+	var req models.UpdateTeamRequest
+	if err := parseBody(c, &req); err != nil {
+		return err
+	}
+
+	team.Name = strings.TrimSpace(req.Name)
+	team.Description = req.Description
+	team.UpdatedAt = time.Now()
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"team": team,
+	})
+}
+
+// DeleteTeam deletes a team and removes it from its projects
+func DeleteTeam(c *fiber.Ctx) error {
+	team, err := findTeam(c)
+	if err != nil {
+		return err
+	}
+	if !isOrganizationAdmin(c) {
+		return apperr.ErrOrgAdminNeeded
+	}
+
+	for projectID, links := range projectTeams {
+		if links[team.ID] == nil {
+			continue
+		}
+		delete(links, team.ID)
+		for userID := range teamMembers[team.ID] {
+			syncProjectMember(projectID, userID)
+		}
+	}
+	delete(teamMembers, team.ID)
+	delete(teams, team.ID)
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Team deleted successfully",
+	})
+}
+
+// AddTeamMember adds a member of the organization to a team and to the
+// team's projects. Team leads can only add members while they could add
+// them to each of those projects themselves.
+func AddTeamMember(c *fiber.Ctx) error {
+	team, err := findTeam(c)
+	if err != nil {
+		return err
+	}
+	if !canManageTeam(c, team.ID) {
+		return apperr.Forbidden("team_lead_required", "Only organization admins and team leads can change the team")
+	}
+
+	// Parse request body
+	var req models.AddTeamMemberRequest
+	if err := parseBody(c, &req); err != nil {
+		return err
+	}
+	if req.Role == "" {
+		req.Role = models.TeamRoleMember
+	}
+	if req.Role == models.TeamRoleLead && !isOrganizationAdmin(c) {
+		return apperr.Forbidden("organization_admin_required", "Only organization admins can appoint team leads")
+	}
+	capacity := models.DefaultCapacityHours
+	if req.CapacityHours != nil {
+		capacity = *req.CapacityHours
+	}
+
+	if _, ok := tenantOf(c).user(req.UserID); !ok {
+		return apperr.ErrUserNotFound
+	}
+	if err := checkTeamProjects(c, team.ID); err != nil {
+		return err
+	}
+	if teamMembers[team.ID] == nil {
+		teamMembers[team.ID] = make(map[uuid.UUID]*models.TeamMember)
+	} else if teamMembers[team.ID][req.UserID] != nil {
+		return apperr.Conflict("already_team_member", "User is already a member of this team")
+	}
+
+	member := &models.TeamMember{
+		TeamID:        team.ID,
+		UserID:        req.UserID,
+		Role:          req.Role,
+		CapacityHours: capacity,
+		JoinedAt:      time.Now(),
+	}
+	teamMembers[team.ID][req.UserID] = member
+	syncTeamMember(team.ID, req.UserID)
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"member": member,
+	})
+}
+
+// UpdateTeamMember changes a member's team role or capacity
+func UpdateTeamMember(c *fiber.Ctx) error {
+	team, err := findTeam(c)
+	if err != nil {
+		return err
+	}
+	if !canManageTeam(c, team.ID) {
+		return apperr.Forbidden("team_lead_required", "Only organization admins and team leads can change the team")
+	}
+
+	memberID, err := uuid.Parse(c.Params("userID"))
+	if err != nil {
+		return apperr.Invalid("invalid_user_id", "Invalid user ID")
+	}
+	member := teamMembers[team.ID][memberID]
+	if member == nil {
+		return apperr.NotFound("member_not_found", "Member not found in this team")
+	}
+
+	// Parse request body
+	var req models.UpdateTeamMemberRequest
+	if err := parseBody(c, &req); err != nil {
+		return err
+	}
+
+	if req.Role != "" && req.Role != member.Role {
+		if !isOrganizationAdmin(c) {
+			return apperr.Forbidden("organization_admin_required", "Only organization admins can appoint team leads")
+		}
+		member.Role = req.Role
+	}
+	if req.CapacityHours != nil {
+		member.CapacityHours = *req.CapacityHours
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"member": member,
+	})
+}
+
+// RemoveTeamMember removes a member from a team and from the projects they
+// only belonged to through it
+func RemoveTeamMember(c *fiber.Ctx) error {
+	team, err := findTeam(c)
+	if err != nil {
+		return err
+	}
+	if !canManageTeam(c, team.ID) {
+		return apperr.Forbidden("team_lead_required", "Only organization admins and team leads can change the team")
+	}
+
+	memberID, err := uuid.Parse(c.Params("userID"))
+	if err != nil {
+		return apperr.Invalid("invalid_user_id", "Invalid user ID")
+	}
+	if teamMembers[team.ID][memberID] == nil {
+		return apperr.NotFound("member_not_found", "Member not found in this team")
+	}
+	if err := checkTeamProjects(c, team.ID); err != nil {
+		return err
+	}
+
+	delete(teamMembers[team.ID], memberID)
+	syncTeamMember(team.ID, memberID)
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Member removed successfully",
+	})
+}
+
+// GetTeamWorkload reports the open work and capacity of a team's members
+func GetTeamWorkload(c *fiber.Ctx) error {
+	team, err := findTeam(c)
+	if err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"workload": teamWorkload(tenantOf(c), team, true),
+	})
+}
+
+// GetTeamsWorkload reports the open work and capacity of every team
+func GetTeamsWorkload(c *fiber.Ctx) error {
+	t := tenantOf(c)
+	reports := []models.TeamWorkload{}
+	for _, team := range t.teams() {
+		reports = append(reports, teamWorkload(t, team, false))
+	}
+	sort.Slice(reports, func(i, j int) bool { return reports[i].Team.Name < reports[j].Team.Name })
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"teams": reports,
+	})
+}
+
+// GetProjectTeams lists the teams linked to a project
+func GetProjectTeams(c *fiber.Ctx) error {
+	projectID := c.Locals("projectID").(uuid.UUID)
+
+	teamList := []fiber.Map{}
+	for teamID, link := range projectTeams[projectID] {
+		teamList = append(teamList, fiber.Map{
+			"team":     teams[teamID],
+			"role":     link.Role,
+			"added_at": link.AddedAt,
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"teams": teamList,
+	})
+}
+
+// AddProjectTeam adds all members of a team to a project. Members who join
+// or leave the team later are added or removed as well.
+func AddProjectTeam(c *fiber.Ctx) error {
+	projectID := c.Locals("projectID").(uuid.UUID)
+
+	// Parse request body
+	var req models.AddProjectTeamRequest
+	if err := parseBody(c, &req); err != nil {
+		return err
+	}
+	if req.Role == "" {
+		req.Role = models.RoleMember
+	}
+
+	team, ok := tenantOf(c).team(req.TeamID)
+	if !ok {
+		return apperr.NotFound("team_not_found", "Team not found")
+	}
+
+	// Check the role can be handed out by the current user
+	if err := checkAssignableRole(c, projectID, req.Role); err != nil {
+		return err
+	}
+
+	if projectTeams[projectID] == nil {
+		projectTeams[projectID] = make(map[uuid.UUID]*models.ProjectTeam)
+	} else if projectTeams[projectID][team.ID] != nil {
+		return apperr.Conflict("team_already_in_project", "Team is already part of this project")
+	}
+
+	link := &models.ProjectTeam{
+		ProjectID: projectID,
+		TeamID:    team.ID,
+		Role:      req.Role,
+		AddedAt:   time.Now(),
+	}
+	projectTeams[projectID][team.ID] = link
+	for userID := range teamMembers[team.ID] {
+		syncProjectMember(projectID, userID)
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"team": link,
+	})
+}
+
+// RemoveProjectTeam removes a team from a project along with the members
+// who only belonged to the project through it
+func RemoveProjectTeam(c *fiber.Ctx) error {
+	projectID := c.Locals("projectID").(uuid.UUID)
+
+	teamID, err := uuid.Parse(c.Params("teamID"))
+	if err != nil {
+		return apperr.Invalid("invalid_team_id", "Invalid team ID")
+	}
+	link := projectTeams[projectID][teamID]
+	if link == nil {
+		return apperr.NotFound("team_not_in_project", "Team is not part of this project")
+	}
+
+	// Members with more permissions than the user can't be removed by them
+	if err := checkAssignableRole(c, projectID, link.Role); err != nil {
+		return err
+	}
+
+	delete(projectTeams[projectID], teamID)
+	for userID := range teamMembers[teamID] {
+		syncProjectMember(projectID, userID)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Team removed successfully",
+	})
+}
+
+// GetAssignmentSuggestions ranks the members of a project's teams by how
+// much room they have for another task, least loaded first
+func GetAssignmentSuggestions(c *fiber.Ctx) error {
+	projectID := c.Locals("projectID").(uuid.UUID)
+	t := tenantOf(c)
+
+	// Optionally only suggest members of one team
+	var onlyTeam uuid.UUID
+	if teamParam := c.Query("team_id"); teamParam != "" {
+		teamID, err := uuid.Parse(teamParam)
+		if err != nil || projectTeams[projectID][teamID] == nil {
+			return apperr.Invalid("team_not_in_project", "Team is not part of this project")
+		}
+		onlyTeam = teamID
+	}
+
+	seen := make(map[uuid.UUID]bool)
+	suggestions := []models.AssignmentSuggestion{}
+	for teamID := range projectTeams[projectID] {
+		if onlyTeam != uuid.Nil && teamID != onlyTeam {
+			continue
+		}
+		for userID, member := range teamMembers[teamID] {
+			// Only project members can be assigned, and only with capacity left
+			user, ok := t.user(userID)
+			if !ok || seen[userID] || projectMembers[projectID][userID] == nil || member.CapacityHours <= 0 {
+				continue
+			}
+			seen[userID] = true
+
+			open, overdue, _ := openWork(t, userID)
+			suggestions = append(suggestions, models.AssignmentSuggestion{
+				User:          user.ToResponse(),
+				TeamID:        teamID,
+				CapacityHours: member.CapacityHours,
+				OpenTasks:     open,
+				OverdueTasks:  overdue,
+				Load:          *workloadLoad(open, member.CapacityHours),
+			})
+		}
+	}
+
+	sort.Slice(suggestions, func(i, j int) bool {
+		if suggestions[i].Load != suggestions[j].Load {
+			return suggestions[i].Load < suggestions[j].Load
+		}
+		if suggestions[i].OverdueTasks != suggestions[j].OverdueTasks {
+			return suggestions[i].OverdueTasks < suggestions[j].OverdueTasks
+		}
+		return suggestions[i].User.Username < suggestions[j].User.Username
+	})
+	if limit := c.QueryInt("limit", 5); limit > 0 && len(suggestions) > limit {
+		suggestions = suggestions[:limit]
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"suggestions": suggestions,
+	})
+}
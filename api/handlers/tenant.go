@@ -0,0 +1,117 @@
+package handlers
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/projectflow/models"
+)
+
+// tenant scopes reads of the in-memory stores to the organization a request
+// acts in. Handlers look projects, tasks and users up through it, so records
+// of other organizations are never found.
+type tenant struct {
+	orgID uuid.UUID
+}
+
+// tenantOf returns the tenant of the current request. Users who are no
+// longer members of the organization in their token see nothing.
+func tenantOf(c *fiber.Ctx) tenant {
+	orgID, _ := c.Locals("orgID").(uuid.UUID)
+	userID, _ := c.Locals("userID").(uuid.UUID)
+	if organizationMembers[orgID][userID] == nil {
+		return tenant{}
+	}
+	return tenant{orgID: orgID}
+}
+
+// project returns a project of the organization
+func (t tenant) project(id uuid.UUID) (*models.Project, bool) {
+	project, ok := projects[id]
+	if !ok || t.orgID == uuid.Nil || project.OrganizationID != t.orgID {
+		return nil, false
+	}
+	return project, true
+}
+
+// projects returns every project of the organization
+func (t tenant) projects() []*models.Project {
+	projectList := []*models.Project{}
+	if t.orgID == uuid.Nil {
+		return projectList
+	}
+	for _, project := range projects {
+		if project.OrganizationID == t.orgID {
+			projectList = append(projectList, project)
+		}
+	}
+	return projectList
+}
+
+// task returns a task in one of the organization's projects
+func (t tenant) task(id uuid.UUID) (*models.Task, bool) {
+	task, ok := findTask(id)
+	if !ok {
+		return nil, false
+	}
+	if _, ok := t.project(task.ProjectID); !ok {
+		return nil, false
+	}
+	return task, true
+}
+
+// user returns a member of the organization
+func (t tenant) user(id uuid.UUID) (*models.User, bool) {
+	if organizationMembers[t.orgID][id] == nil {
+		return nil, false
+	}
+	user, ok := users[id]
+	return user, ok
+}
+
+// users returns every member of the organization
+func (t tenant) users() []*models.User {
+	userList := []*models.User{}
+	for userID := range organizationMembers[t.orgID] {
+		if user, ok := users[userID]; ok {
+			userList = append(userList, user)
+		}
+	}
+	return userList
+}
+
+// team returns a team of the organization
+func (t tenant) team(id uuid.UUID) (*models.Team, bool) {
+	team, ok := teams[id]
+	if !ok || t.orgID == uuid.Nil || team.OrganizationID != t.orgID {
+		return nil, false
+	}
+	return team, true
+}
+
+// teams returns every team of the organization
+func (t tenant) teams() []*models.Team {
+	teamList := []*models.Team{}
+	if t.orgID == uuid.Nil {
+		return teamList
+	}
+	for _, team := range teams {
+		if team.OrganizationID == t.orgID {
+			teamList = append(teamList, team)
+		}
+	}
+	return teamList
+}
+
+// manages reports whether a user holds allocation:manage in any of the
+// organization's projects another user belongs to
+func (t tenant) manages(managerID, userID uuid.UUID) bool {
+	for _, project := range t.projects() {
+		if projectRole(project.ID, userID) == nil {
+			continue
+		}
+		if role := projectRole(project.ID, managerID); role != nil && role.Has(models.PermAllocationManage) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,359 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/projectflow/apperr"
+	"github.com/projectflow/models"
+	"github.com/projectflow/utils"
+	"github.com/projectflow/utils/cache"
+	"github.com/projectflow/utils/totp"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	// Issuer shown in authenticator apps
+	totpIssuer = "ProjectFlow"
+	// How long a password-verified login waits for the second factor
+	twoFactorChallengeTTL = 5 * time.Minute
+	// Wrong codes allowed per challenge before the login must restart
+	twoFactorMaxAttempts = 5
+	// Recovery codes issued at enrollment
+	recoveryCodeCount = 10
+)
+
+// twoFactorChallenge is a login that passed the password check
+type twoFactorChallenge struct {
+	UserID   uuid.UUID
+	Attempts int
+}
+
+var (
+	// Instance-wide authentication rules
+	securitySettings = models.SecuritySettings{}
+
+	// Pending two-step logins by challenge token
+	twoFactorChallenges = cache.New()
+)
+
+// enrollmentRequired reports whether a user must enroll in two-factor
+// authentication before using the API
+func enrollmentRequired(user *models.User) bool {
+	return securitySettings.RequireAdminTwoFactor && user.Role == "admin" && !user.TwoFactorEnabled
+}
+
+// generateAccessToken issues an access token, restricted to enrollment if needed
+func generateAccessToken(user *models.User) (string, error) {
+	orgID := currentOrganization(user)
+	if enrollmentRequired(user) {
+		return utils.GenerateEnrollmentToken(user.ID, orgID, user.Role)
+	}
+	return utils.GenerateToken(user.ID, orgID, user.Role)
+}
+
+// startTwoFactorChallenge creates the challenge token returned after the password check
+func startTwoFactorChallenge(user *models.User) (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(buf)
+	twoFactorChallenges.Set(token, &twoFactorChallenge{UserID: user.ID}, twoFactorChallengeTTL)
+	return token, nil
+}
+
+// verifySecondFactor accepts a TOTP code or consumes a recovery code
+func verifySecondFactor(user *models.User, code, recoveryCode string) bool {
+	if code != "" {
+		step, ok := totp.Validate(user.TOTPSecret, code, time.Now())
+		if !ok || step <= user.TOTPLastStep {
+			return false
+		}
+		user.TOTPLastStep = step
+		return true
+	}
+
+	if recoveryCode != "" {
+		hash := hashRecoveryCode(recoveryCode)
+		for i, stored := range user.RecoveryCodeHashes {
+			if subtle.ConstantTimeCompare([]byte(stored), []byte(hash)) == 1 {
+				user.RecoveryCodeHashes = append(user.RecoveryCodeHashes[:i], user.RecoveryCodeHashes[i+1:]...)
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// generateRecoveryCodes returns new plaintext recovery codes and their hashes
+func generateRecoveryCodes() ([]string, []string, error) {
+	encoding := base32.StdEncoding.WithPadding(base32.NoPadding)
+	codes := make([]string, 0, recoveryCodeCount)
+	hashes := make([]string, 0, recoveryCodeCount)
+
+	for i := 0; i < recoveryCodeCount; i++ {
+		buf := make([]byte, 7)
+		if _, err := rand.Read(buf); err != nil {
+			return nil, nil, err
+		}
+		raw := strings.ToLower(encoding.EncodeToString(buf))[:10]
+		code := raw[:5] + "-" + raw[5:]
+		codes = append(codes, code)
+		hashes = append(hashes, hashRecoveryCode(code))
+	}
+
+	return codes, hashes, nil
+}
+
+// hashRecoveryCode normalises a recovery code and returns its SHA-256
+func hashRecoveryCode(code string) string {
+	normalized := strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:])
+}
+
+// CompleteTwoFactorLogin exchanges a challenge token and a second factor for a session
+func CompleteTwoFactorLogin(c *fiber.Ctx) error {
+	// Parse request body
+	var req models.TwoFactorLoginRequest
+	if err := parseBody(c, &req); err != nil {
+		return err
+	}
+
+	cached, found := twoFactorChallenges.Get(req.ChallengeToken)
+	if !found {
+		return apperr.Unauthenticated("login_expired", "Login expired, please sign in again")
+	}
+	challenge := cached.(*twoFactorChallenge)
+
+	user, ok := users[challenge.UserID]
+	if !ok || !user.TwoFactorEnabled || !user.Active() {
+		twoFactorChallenges.Delete(req.ChallengeToken)
+		return apperr.Unauthenticated("login_expired", "Login expired, please sign in again")
+	}
+
+	// Wrong codes count towards the account lockout, so new challenges
+	// don't give unlimited guesses
+	if locked := lockedFor(c, user.Email); locked > 0 {
+		twoFactorChallenges.Delete(req.ChallengeToken)
+		recordLoginAttempt(c, user.Email, user, models.LoginLocked)
+		return tooManyAttempts(c, locked)
+	}
+	if !verifySecondFactor(user, req.Code, req.RecoveryCode) {
+		challenge.Attempts++
+		if challenge.Attempts >= twoFactorMaxAttempts {
+			twoFactorChallenges.Delete(req.ChallengeToken)
+		}
+		loginFailed(c, user.Email)
+		recordLoginAttempt(c, user.Email, user, models.LoginTwoFactorFailed)
+		return apperr.Unauthenticated("invalid_totp_code", "Invalid authentication code")
+	}
+
+	// Each challenge completes exactly one login
+	twoFactorChallenges.Delete(req.ChallengeToken)
+
+	session, err := issueSession(user)
+	if err != nil {
+		return apperr.Internal("internal_error", "Failed to generate token").Wrap(err)
+	}
+
+	loginSucceeded(c, user.Email)
+	recordLoginAttempt(c, user.Email, user, models.LoginSucceeded)
+
+	session["recovery_codes_remaining"] = len(user.RecoveryCodeHashes)
+	return c.Status(fiber.StatusOK).JSON(session)
+}
+
+// GetTwoFactorStatus returns the current user's two-factor settings
+func GetTwoFactorStatus(c *fiber.Ctx) error {
+	user, err := currentUser(c)
+	if err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"enabled":                  user.TwoFactorEnabled,
+		"required":                 securitySettings.RequireAdminTwoFactor && user.Role == "admin",
+		"recovery_codes_remaining": len(user.RecoveryCodeHashes),
+	})
+}
+
+// SetupTwoFactor starts enrollment by generating a new secret
+func SetupTwoFactor(c *fiber.Ctx) error {
+	user, err := currentUser(c)
+	if err != nil {
+		return err
+	}
+
+	if user.TwoFactorEnabled {
+		return apperr.Conflict("totp_already_enabled", "Two-factor authentication is already enabled")
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return apperr.Internal("internal_error", "Failed to generate secret").Wrap(err)
+	}
+	user.PendingTOTPSecret = secret
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"secret":      secret,
+		"otpauth_uri": totp.URI(totpIssuer, user.Email, secret),
+	})
+}
+
+// ConfirmTwoFactor enables two-factor authentication once a code from the
+// new secret is entered, and returns the recovery codes
+func ConfirmTwoFactor(c *fiber.Ctx) error {
+	user, err := currentUser(c)
+	if err != nil {
+		return err
+	}
+
+	// Parse request body
+	var req models.TwoFactorCodeRequest
+	if err := parseBody(c, &req); err != nil {
+		return err
+	}
+
+	if user.PendingTOTPSecret == "" {
+		return apperr.Invalid("totp_setup_required", "Start two-factor setup first")
+	}
+
+	step, ok := totp.Validate(user.PendingTOTPSecret, req.Code, time.Now())
+	if !ok {
+		return apperr.Invalid("invalid_totp_code", "Invalid authentication code")
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return apperr.Internal("internal_error", "Failed to generate recovery codes").Wrap(err)
+	}
+
+	user.TOTPSecret = user.PendingTOTPSecret
+	user.PendingTOTPSecret = ""
+	user.TOTPLastStep = step
+	user.TwoFactorEnabled = true
+	user.RecoveryCodeHashes = hashes
+	user.UpdatedAt = time.Now()
+
+	// Recovery codes are only ever shown once
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message":        "Two-factor authentication enabled",
+		"recovery_codes": codes,
+	})
+}
+
+// DisableTwoFactor turns two-factor authentication off
+func DisableTwoFactor(c *fiber.Ctx) error {
+	user, err := currentUser(c)
+	if err != nil {
+		return err
+	}
+
+	// Parse request body
+	var req models.DisableTwoFactorRequest
+	if err := parseBody(c, &req); err != nil {
+		return err
+	}
+
+	if !user.TwoFactorEnabled {
+		return apperr.Invalid("totp_not_enabled", "Two-factor authentication is not enabled")
+	}
+	if securitySettings.RequireAdminTwoFactor && user.Role == "admin" {
+		return apperr.Forbidden("totp_required", "Two-factor authentication is required for admins")
+	}
+
+	// Require both the password and a second factor
+	if bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.Password)) != nil ||
+		!verifySecondFactor(user, req.Code, req.RecoveryCode) {
+		return apperr.Unauthenticated("invalid_credentials", "Invalid password or authentication code")
+	}
+
+	user.TwoFactorEnabled = false
+	user.TOTPSecret = ""
+	user.TOTPLastStep = 0
+	user.RecoveryCodeHashes = nil
+	user.UpdatedAt = time.Now()
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Two-factor authentication disabled",
+	})
+}
+
+// RegenerateRecoveryCodes replaces all recovery codes
+func RegenerateRecoveryCodes(c *fiber.Ctx) error {
+	user, err := currentUser(c)
+	if err != nil {
+		return err
+	}
+
+	// Parse request body
+	var req models.TwoFactorCodeRequest
+	if err := parseBody(c, &req); err != nil {
+		return err
+	}
+
+	if !user.TwoFactorEnabled || !verifySecondFactor(user, req.Code, "") {
+		return apperr.Unauthenticated("invalid_totp_code", "Invalid authentication code")
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return apperr.Internal("internal_error", "Failed to generate recovery codes").Wrap(err)
+	}
+	user.RecoveryCodeHashes = hashes
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"recovery_codes": codes,
+	})
+}
+
+// GetSecuritySettings returns the instance-wide authentication rules (admin only)
+func GetSecuritySettings(c *fiber.Ctx) error {
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"settings": securitySettings,
+	})
+}
+
+// UpdateSecuritySettings changes the instance-wide authentication rules (admin only)
+func UpdateSecuritySettings(c *fiber.Ctx) error {
+	// Parse request body
+	var req models.UpdateSecuritySettingsRequest
+	if err := parseBody(c, &req); err != nil {
+		return err
+	}
+
+	if req.RequireAdminTwoFactor != nil {
+		securitySettings.RequireAdminTwoFactor = *req.RequireAdminTwoFactor
+	}
+	securitySettings.UpdatedAt = time.Now()
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"settings": securitySettings,
+	})
+}
+
+// currentUser loads the authenticated user, or returns the error to send
+func currentUser(c *fiber.Ctx) (*models.User, error) {
+	// Get user ID from context (set by auth middleware)
+	userID, ok := c.Locals("userID").(uuid.UUID)
+	if !ok {
+		return nil, apperr.ErrUnauthorized
+	}
+
+	user, ok := users[userID]
+	if !ok {
+		return nil, apperr.ErrUserNotFound
+	}
+	return user, nil
+}
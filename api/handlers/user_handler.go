@@ -1,77 +1,101 @@
 package handlers
 
 import (
+	"sync"
+
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
+	"github.com/projectflow/apperr"
 	"github.com/projectflow/models"
-	"github.com/projectflow/utils"
 	"golang.org/x/crypto/bcrypt"
 )
 
 // In-memory storage for development
 var users = make(map[uuid.UUID]*models.User)
 
+// Guards adding to users, which the digest and due date jobs read in the
+// background
+var usersMu sync.RWMutex
+
+// findUser looks up a user outside of a request
+func findUser(id uuid.UUID) (*models.User, bool) {
+	usersMu.RLock()
+	defer usersMu.RUnlock()
+
+	user, ok := users[id]
+	return user, ok
+}
+
+// addUser stores a new user
+func addUser(user *models.User) {
+	usersMu.Lock()
+	users[user.ID] = user
+	usersMu.Unlock()
+}
+
 // RegisterUser handles user registration
 func RegisterUser(c *fiber.Ctx) error {
 	// Parse request body
 	var req models.CreateUserRequest
-	if err := c.BodyParser(&req); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid request body",
-		})
+	if err := parseBody(c, &req); err != nil {
+		return err
 	}
 
-	// Validate request
-	// In a real app, we would use a validation library here
+	// Limit sign ups per client address
+	if retryAfter := rateLimited(c, "register:ip:"+c.IP(), registerIPRule); retryAfter > 0 {
+		return tooManyAttempts(c, retryAfter)
+	}
 
-	// Check if email already exists
+	// Usernames are public, so a taken one can be reported
+	var existing *models.User
 	for _, user := range users {
-		if user.Email == req.Email {
-			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
-				"error": "Email already registered",
-			})
-		}
 		if user.Username == req.Username {
-			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
-				"error": "Username already taken",
-			})
+			return apperr.Conflict("username_taken", "Username already taken")
+		}
+		if normalizeEmail(user.Email) == normalizeEmail(req.Email) {
+			existing = user
 		}
 	}
 
-	// Hash password
+	// Enforce the password policy
+	if err := checkPassword(req.Password, &models.User{Username: req.Username, Email: req.Email}); err != nil {
+		return err
+	}
+
+	// Hash password, also for taken emails so both take as long
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to hash password",
-		})
+		return apperr.Internal("internal_error", "Failed to hash password").Wrap(err)
 	}
 
-	// Create user
-	userID := uuid.New()
-	user := &models.User{
-		ID:           userID,
-		Username:     req.Username,
-		Email:        req.Email,
-		PasswordHash: string(hashedPassword),
-		FullName:     req.FullName,
-		Role:         req.Role,
-	}
+	if existing != nil {
+		// Tell the account holder instead of the caller
+		sendAccountEmail(existing, "signup_attempt",
+			"Someone tried to create a new account with this email address. If it was you, sign in instead, or reset your password from the sign in page if you've forgotten it. Otherwise you can ignore this email.",
+			appURL+"/login")
+	} else {
+		// Create user
+		userID := uuid.New()
+		user := &models.User{
+			ID:           userID,
+			Username:     req.Username,
+			Email:        req.Email,
+			PasswordHash: string(hashedPassword),
+			FullName:     req.FullName,
+			Role:         "member",
+		}
 
-	// Save user (in-memory for development)
-	users[userID] = user
+		// Save user (in-memory for development)
+		addUser(user)
 
-	// Generate JWT token
-	token, err := utils.GenerateToken(userID, user.Role)
-	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to generate token",
-		})
+		// Ask the user to confirm their email address
+		sendVerificationEmail(user)
 	}
 
-	// Return user data and token
-	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
-		"user":  user.ToResponse(),
-		"token": token,
+	// The response is the same whether or not the email was taken, so it
+	// doesn't reveal who has an account. New users sign in afterwards.
+	return c.Status(fiber.StatusAccepted).JSON(fiber.Map{
+		"message": "Check your email to finish signing up",
 	})
 }
 
@@ -79,49 +103,79 @@ func RegisterUser(c *fiber.Ctx) error {
 func Login(c *fiber.Ctx) error {
 	// Parse request body
 	var req models.LoginRequest
-	if err := c.BodyParser(&req); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid request body",
-		})
+	if err := parseBody(c, &req); err != nil {
+		return err
+	}
+
+	// Throttle by client address and by account. Unknown emails are limited
+	// and locked exactly like real ones, so responses reveal nothing.
+	email := normalizeEmail(req.Email)
+	if retryAfter := rateLimited(c, "login:ip:"+c.IP(), loginIPRule); retryAfter > 0 {
+		recordLoginAttempt(c, email, nil, models.LoginRateLimited)
+		return tooManyAttempts(c, retryAfter)
+	}
+	if retryAfter := rateLimited(c, "login:account:"+email, loginAccountRule); retryAfter > 0 {
+		recordLoginAttempt(c, email, nil, models.LoginRateLimited)
+		return tooManyAttempts(c, retryAfter)
+	}
+	if locked := lockedFor(c, email); locked > 0 {
+		recordLoginAttempt(c, email, nil, models.LoginLocked)
+		return tooManyAttempts(c, locked)
 	}
 
 	// Find user by email
 	var user *models.User
 	for _, u := range users {
-		if u.Email == req.Email {
+		if normalizeEmail(u.Email) == email {
 			user = u
 			break
 		}
 	}
 
-	// Check if user exists
-	if user == nil {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"error": "Invalid email or password",
-		})
+	// Verify password
+	if !comparePassword(user, req.Password) {
+		loginFailed(c, email)
+		recordLoginAttempt(c, email, user, models.LoginInvalid)
+		return apperr.Unauthenticated("invalid_credentials", "Invalid email or password")
 	}
 
-	// Verify password
-	err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.Password))
-	if err != nil {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"error": "Invalid email or password",
+	// Deactivated accounts can't sign in
+	if !user.Active() {
+		recordLoginAttempt(c, email, user, models.LoginDeactivated)
+		return apperr.Forbidden("account_deactivated", "This account has been deactivated")
+	}
+
+	// Optionally refuse accounts that haven't confirmed their email
+	if requireEmailVerification && !user.EmailVerified {
+		recordLoginAttempt(c, email, user, models.LoginEmailNotVerified)
+		return apperr.Forbidden("email_not_verified", "Please verify your email address before signing in").
+			With("email_not_verified", true)
+	}
+
+	// With two-factor authentication enabled the password is only the first step
+	if user.TwoFactorEnabled {
+		challengeToken, err := startTwoFactorChallenge(user)
+		if err != nil {
+			return apperr.Internal("internal_error", "Failed to start login").Wrap(err)
+		}
+		recordLoginAttempt(c, email, user, models.LoginTwoFactorRequired)
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{
+			"two_factor_required": true,
+			"challenge_token":     challengeToken,
+			"expires_in":          int(twoFactorChallengeTTL.Seconds()),
 		})
 	}
 
-	// Generate JWT token
-	token, err := utils.GenerateToken(user.ID, user.Role)
+	// Generate access and refresh tokens
+	session, err := issueSession(user)
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to generate token",
-		})
+		return apperr.Internal("internal_error", "Failed to generate token").Wrap(err)
 	}
+	loginSucceeded(c, email)
+	recordLoginAttempt(c, email, user, models.LoginSucceeded)
 
-	// Return user data and token
-	return c.Status(fiber.StatusOK).JSON(fiber.Map{
-		"user":  user.ToResponse(),
-		"token": token,
-	})
+	// Return user data and tokens
+	return c.Status(fiber.StatusOK).JSON(session)
 }
 
 // GetCurrentUser returns the current authenticated user
@@ -129,30 +183,27 @@ func GetCurrentUser(c *fiber.Ctx) error {
 	// Get user ID from context (set by auth middleware)
 	userID, ok := c.Locals("userID").(uuid.UUID)
 	if !ok {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"error": "Unauthorized",
-		})
+		return apperr.ErrUnauthorized
 	}
 
-	// Find user
-	user, ok := users[userID]
+	// Find user among the members of the organization
+	user, ok := tenantOf(c).user(userID)
 	if !ok {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-			"error": "User not found",
-		})
+		return apperr.ErrUserNotFound
 	}
 
-	// Return user data
+	// Return user data, with an email change waiting for confirmation
 	return c.Status(fiber.StatusOK).JSON(fiber.Map{
-		"user": user.ToResponse(),
+		"user":          user.ToResponse(),
+		"pending_email": user.PendingEmail,
 	})
 }
 
-// GetAllUsers returns all users (admin only)
+// GetAllUsers returns all users of the organization (admin only)
 func GetAllUsers(c *fiber.Ctx) error {
 	// Convert users map to slice
 	var userList []models.UserResponse
-	for _, user := range users {
+	for _, user := range tenantOf(c).users() {
 		userList = append(userList, user.ToResponse())
 	}
 
@@ -167,17 +218,13 @@ func GetUserByID(c *fiber.Ctx) error {
 	id := c.Params("id")
 	userID, err := uuid.Parse(id)
 	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid user ID",
-		})
+		return apperr.Invalid("invalid_user_id", "Invalid user ID")
 	}
 
-	// Find user
-	user, ok := users[userID]
+	// Users of other organizations are not found
+	user, ok := tenantOf(c).user(userID)
 	if !ok {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-			"error": "User not found",
-		})
+		return apperr.ErrUserNotFound
 	}
 
 	// Return user data
@@ -0,0 +1,40 @@
+package handlers
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/projectflow/apperr"
+	"github.com/projectflow/utils/safehttp"
+	"github.com/projectflow/utils/validate"
+)
+
+// parseBody parses a request body into out and checks its validate tags.
+// An unreadable body is an invalid_body error and broken rules a
+// validation_failed error listing every field.
+func parseBody(c *fiber.Ctx, out interface{}) error {
+	if err := c.BodyParser(out); err != nil {
+		return apperr.ErrInvalidBody
+	}
+
+	if errs := validate.Struct(out); errs != nil {
+		return apperr.New(apperr.KindValidation, "validation_failed", errs.Error()).With("fields", errs)
+	}
+	return nil
+}
+
+// checkWebhookURL checks a URL that webhooks will be posted to. Its host
+// must resolve, and only to public addresses, so webhooks can't be used to
+// reach services on the server's own network.
+func checkWebhookURL(c *fiber.Ctx, rawURL string) error {
+	switch err := safehttp.CheckURL(c.UserContext(), rawURL); {
+	case err == nil:
+		return nil
+	case errors.Is(err, safehttp.ErrInvalidURL):
+		return apperr.Invalid("invalid_webhook_url", "Invalid webhook URL")
+	case errors.Is(err, safehttp.ErrForbiddenAddress):
+		return apperr.Invalid("forbidden_webhook_url", "Webhook URLs may not point to local or private network addresses")
+	default:
+		return apperr.Invalid("unresolvable_webhook_url", "The host of the webhook URL could not be resolved").Wrap(err)
+	}
+}
@@ -0,0 +1,212 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/projectflow/apperr"
+	"github.com/projectflow/models"
+	"github.com/projectflow/webhooks"
+)
+
+// Webhook subscriptions and their asynchronous delivery pipeline
+var webhookStore = webhooks.NewMemoryStore()
+var webhookDispatcher = webhooks.NewDispatcher(webhookStore)
+
+// publishProjectEvent sends an event to the project's webhook subscribers
+func publishProjectEvent(projectID uuid.UUID, event string, data interface{}) {
+	webhookDispatcher.Publish(projectID, event, data)
+}
+
+// generateWebhookSecret returns a random signing secret
+func generateWebhookSecret() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return "whsec_" + hex.EncodeToString(buf), nil
+}
+
+// validateWebhookInput checks the endpoint URL and subscribed events
+func validateWebhookInput(c *fiber.Ctx, rawURL string, events []string) error {
+	if err := checkWebhookURL(c, rawURL); err != nil {
+		return err
+	}
+	if len(events) == 0 {
+		return apperr.Invalid("webhook_events_required", "At least one event is required")
+	}
+
+	known := map[string]bool{"*": true}
+	for _, event := range models.WebhookEvents {
+		known[event] = true
+	}
+	for _, event := range events {
+		if !known[event] {
+			return apperr.Invalid("unknown_webhook_event", "Unknown webhook event: "+event)
+		}
+	}
+	return nil
+}
+
+// GetProjectWebhooks returns the webhooks registered on a project
+func GetProjectWebhooks(c *fiber.Ctx) error {
+	projectID := c.Locals("projectID").(uuid.UUID)
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"webhooks": webhookStore.ListByProject(projectID),
+		"events":   models.WebhookEvents,
+	})
+}
+
+// CreateProjectWebhook registers a webhook on a project
+func CreateProjectWebhook(c *fiber.Ctx) error {
+	projectID := c.Locals("projectID").(uuid.UUID)
+	userID := c.Locals("userID").(uuid.UUID)
+
+	// Parse request body
+	var req models.CreateWebhookRequest
+	if err := parseBody(c, &req); err != nil {
+		return err
+	}
+	if err := validateWebhookInput(c, req.URL, req.Events); err != nil {
+		return err
+	}
+
+	// Generate a signing secret unless the caller supplied one
+	secret := req.Secret
+	if secret == "" {
+		var err error
+		secret, err = generateWebhookSecret()
+		if err != nil {
+			return apperr.Internal("internal_error", "Failed to generate webhook secret").Wrap(err)
+		}
+	}
+
+	now := time.Now()
+	webhook := &models.Webhook{
+		ID:        uuid.New(),
+		ProjectID: projectID,
+		URL:       req.URL,
+		Secret:    secret,
+		Events:    req.Events,
+		Active:    true,
+		CreatedBy: userID,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	webhookStore.Save(webhook)
+
+	// The secret is only ever returned once
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"webhook": webhook,
+		"secret":  secret,
+	})
+}
+
+// UpdateProjectWebhook updates a webhook. Re-activating it clears its failure streak.
+func UpdateProjectWebhook(c *fiber.Ctx) error {
+	projectID := c.Locals("projectID").(uuid.UUID)
+
+	webhook, ok := findWebhook(c, projectID)
+	if !ok {
+		return apperr.NotFound("webhook_not_found", "Webhook not found")
+	}
+
+	// Parse request body
+	var req models.UpdateWebhookRequest
+	if err := parseBody(c, &req); err != nil {
+		return err
+	}
+	if err := validateWebhookInput(c, req.URL, req.Events); err != nil {
+		return err
+	}
+
+	// Change only the requested fields, leaving delivery results recorded
+	// since the webhook was loaded intact
+	updated, ok := webhookStore.Update(webhook.ID, func(webhook *models.Webhook) {
+		if req.Active && !webhook.Active {
+			webhook.ConsecutiveFailures = 0
+			webhook.DisabledAt = nil
+		}
+		webhook.URL = req.URL
+		webhook.Events = req.Events
+		webhook.Active = req.Active
+		webhook.UpdatedAt = time.Now()
+	})
+	if !ok {
+		return apperr.NotFound("webhook_not_found", "Webhook not found")
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"webhook": updated,
+	})
+}
+
+// DeleteProjectWebhook removes a webhook and its delivery log
+func DeleteProjectWebhook(c *fiber.Ctx) error {
+	projectID := c.Locals("projectID").(uuid.UUID)
+
+	webhook, ok := findWebhook(c, projectID)
+	if !ok {
+		return apperr.NotFound("webhook_not_found", "Webhook not found")
+	}
+	webhookStore.Delete(webhook.ID)
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Webhook deleted successfully",
+	})
+}
+
+// GetWebhookDeliveries returns a webhook's delivery log, newest first
+func GetWebhookDeliveries(c *fiber.Ctx) error {
+	projectID := c.Locals("projectID").(uuid.UUID)
+
+	webhook, ok := findWebhook(c, projectID)
+	if !ok {
+		return apperr.NotFound("webhook_not_found", "Webhook not found")
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"deliveries": webhookStore.Deliveries(webhook.ID),
+	})
+}
+
+// RedeliverWebhookDelivery sends a logged delivery again
+func RedeliverWebhookDelivery(c *fiber.Ctx) error {
+	projectID := c.Locals("projectID").(uuid.UUID)
+
+	webhook, ok := findWebhook(c, projectID)
+	if !ok {
+		return apperr.NotFound("webhook_not_found", "Webhook not found")
+	}
+
+	deliveryID, err := uuid.Parse(c.Params("deliveryID"))
+	if err != nil {
+		return apperr.Invalid("invalid_delivery_id", "Invalid delivery ID")
+	}
+
+	delivery, err := webhookDispatcher.Redeliver(webhook.ID, deliveryID)
+	if err != nil {
+		return apperr.NotFound("delivery_not_found", "Delivery not found")
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"delivery": delivery,
+	})
+}
+
+// findWebhook loads the webhook named in the URL and checks it belongs to the project
+func findWebhook(c *fiber.Ctx, projectID uuid.UUID) (*models.Webhook, bool) {
+	webhookID, err := uuid.Parse(c.Params("webhookID"))
+	if err != nil {
+		return nil, false
+	}
+	webhook, ok := webhookStore.Get(webhookID)
+	if !ok || webhook.ProjectID != projectID {
+		return nil, false
+	}
+	return webhook, true
+}
@@ -4,41 +4,76 @@ import (
 	"strings"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/projectflow/apperr"
+	"github.com/projectflow/models"
 	"github.com/projectflow/utils"
+	"github.com/projectflow/utils/apikey"
 )
 
-// Protected is a middleware that checks if the user is authenticated
+// Protected is a middleware that checks if the user is authenticated.
+// It accepts JWTs from login as well as API keys, either as a bearer
+// token or in the X-API-Key header.
 func Protected() fiber.Handler {
 	return func(c *fiber.Ctx) error {
+		// API keys may be sent in their own header
+		if key := c.Get("X-API-Key"); key != "" {
+			return authenticateAPIKey(c, key)
+		}
+
 		// Get authorization header
 		authHeader := c.Get("Authorization")
 		
 		// Check if authorization header exists and has the correct format
 		if authHeader == "" || !strings.HasPrefix(authHeader, "Bearer ") {
-			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-				"error": "Unauthorized",
-			})
+			return apperr.ErrUnauthorized
 		}
 
 		// Extract token
 		tokenString := strings.TrimPrefix(authHeader, "Bearer ")
 
+		// Bearer credentials with the API key prefix are not JWTs
+		if apikey.IsAPIKey(tokenString) {
+			return authenticateAPIKey(c, tokenString)
+		}
+
 		// Validate token
 		claims, err := utils.ValidateToken(tokenString)
 		if err != nil {
-			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-				"error": "Invalid or expired token",
-			})
+			return apperr.Unauthenticated("invalid_token", "Invalid or expired token")
+		}
+
+		// Users who still have to enroll in two-factor authentication
+		// may only reach the enrollment endpoints
+		if claims.EnrollmentRequired && !enrollmentAllowed(c.Path()) {
+			return apperr.Forbidden("totp_enrollment_required", "Two-factor authentication must be enabled for your account").
+				With("enrollment_required", true)
 		}
 
 		// Set user ID and role in context for later use
 		c.Locals("userID", claims.UserID)
 		c.Locals("role", claims.Role)
+		c.Locals("orgID", claims.OrganizationID)
+		c.Locals("claims", claims)
+		if claims.ImpersonatorID != nil {
+			c.Locals("impersonatorID", *claims.ImpersonatorID)
+		}
 
 		return c.Next()
 	}
 }
 
+// Paths usable with a token that requires two-factor enrollment
+var enrollmentPaths = []string{"/me/2fa", "/auth/me", "/auth/logout"}
+
+func enrollmentAllowed(path string) bool {
+	for _, prefix := range enrollmentPaths {
+		if strings.HasPrefix(APIPath(path), prefix) {
+			return true
+		}
+	}
+	return false
+}
+
 // AdminOnly is a middleware that checks if the user is an admin
 func AdminOnly() fiber.Handler {
 	return func(c *fiber.Ctx) error {
@@ -47,9 +82,71 @@ func AdminOnly() fiber.Handler {
 
 		// Check if user is admin
 		if role != "admin" {
-			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
-				"error": "Admin access required",
-			})
+			return apperr.Forbidden("admin_required", "Admin access required")
+		}
+
+		return c.Next()
+	}
+}
+
+// authenticateAPIKey validates an API key and sets the key owner in context.
+// API keys always act with member privileges, never as an admin.
+func authenticateAPIKey(c *fiber.Ctx, key string) error {
+	apiKey, err := apikey.DefaultStore.Authenticate(key)
+	if err != nil {
+		return apperr.Unauthenticated("invalid_api_key", "Invalid, expired or revoked API key")
+	}
+
+	c.Locals("userID", apiKey.UserID)
+	c.Locals("role", "member")
+	c.Locals("orgID", apiKey.OrganizationID)
+	c.Locals("apiKey", apiKey)
+
+	return c.Next()
+}
+
+// RequireScope is a middleware that checks an API key was granted a scope.
+// Requests authenticated with a JWT are not restricted by scopes.
+func RequireScope(scope string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		apiKey, ok := c.Locals("apiKey").(*models.APIKey)
+		if !ok {
+			return c.Next()
+		}
+
+		if !apiKey.HasScope(scope) {
+			return apperr.Forbidden("api_key_scope_missing", "API key is missing the "+scope+" scope")
+		}
+
+		return c.Next()
+	}
+}
+
+// RequireResourceScope is a middleware that requires read:<resource> for
+// safe methods and write:<resource> for everything else
+func RequireResourceScope(resource string) fiber.Handler {
+	read := RequireScope("read:" + resource)
+	write := RequireScope("write:" + resource)
+
+	return func(c *fiber.Ctx) error {
+		switch c.Method() {
+		case fiber.MethodGet, fiber.MethodHead, fiber.MethodOptions:
+			return read(c)
+		default:
+			return write(c)
+		}
+	}
+}
+
+// SessionOnly is a middleware that rejects requests authenticated with an
+// API key or made while an admin impersonates the user
+func SessionOnly() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if _, ok := c.Locals("apiKey").(*models.APIKey); ok {
+			return apperr.Forbidden("session_required", "This endpoint cannot be used with an API key")
+		}
+		if c.Locals("impersonatorID") != nil {
+			return apperr.Forbidden("impersonation_not_allowed", "This endpoint cannot be used while impersonating a user")
 		}
 
 		return c.Next()
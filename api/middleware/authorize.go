@@ -0,0 +1,52 @@
+package middleware
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/projectflow/apperr"
+	"github.com/projectflow/models"
+)
+
+// ProjectResolver finds the project a request acts on and the user's role
+// in it. The role is nil when the user isn't a member. Errors, e.g. for a
+// malformed ID or a missing resource, go to the error handler.
+type ProjectResolver func(c *fiber.Ctx, userID uuid.UUID) (uuid.UUID, *models.ProjectRole, error)
+
+// Authorize is a middleware that requires a permission in the project the
+// request acts on. Instance admins have every permission in every project.
+// The project ID and the effective role are stored as "projectID" and
+// "projectRole" for the handler.
+func Authorize(permission string, resolve ProjectResolver) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID, ok := c.Locals("userID").(uuid.UUID)
+		if !ok {
+			return apperr.ErrUnauthorized
+		}
+
+		projectID, role, err := resolve(c, userID)
+		if err != nil {
+			return err
+		}
+
+		// Project-scoped API keys only reach their own project
+		if key, ok := c.Locals("apiKey").(*models.APIKey); ok && key.ProjectID != nil && *key.ProjectID != projectID {
+			return apperr.Forbidden("api_key_wrong_project", "API key is not scoped to this project")
+		}
+
+		if c.Locals("role") == "admin" {
+			role = models.BuiltInRole(models.RoleOwner)
+		}
+		if role == nil {
+			return apperr.Forbidden("project_access_denied", "You don't have access to this project")
+		}
+		if !role.Has(permission) {
+			return apperr.Forbidden("permission_denied", "Your project role doesn't have the "+permission+" permission").
+				With("permission", permission)
+		}
+
+		c.Locals("projectID", projectID)
+		c.Locals("projectRole", role)
+
+		return c.Next()
+	}
+}
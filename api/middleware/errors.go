@@ -0,0 +1,107 @@
+package middleware
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/requestid"
+	"github.com/projectflow/apperr"
+	"github.com/projectflow/utils/validate"
+)
+
+// ProblemContentType is the media type of error responses (RFC 7807)
+const ProblemContentType = "application/problem+json"
+
+// Problem types are URNs built from the error code
+const problemTypePrefix = "urn:projectflow:problem:"
+
+// HTTP status of each kind of application error
+var kindStatus = map[apperr.Kind]int{
+	apperr.KindInternal:         fiber.StatusInternalServerError,
+	apperr.KindInvalid:          fiber.StatusBadRequest,
+	apperr.KindUnauthenticated:  fiber.StatusUnauthorized,
+	apperr.KindForbidden:        fiber.StatusForbidden,
+	apperr.KindNotFound:         fiber.StatusNotFound,
+	apperr.KindConflict:         fiber.StatusConflict,
+	apperr.KindGone:             fiber.StatusGone,
+	apperr.KindTooLarge:         fiber.StatusRequestEntityTooLarge,
+	apperr.KindUnsupportedMedia: fiber.StatusUnsupportedMediaType,
+	apperr.KindValidation:       fiber.StatusUnprocessableEntity,
+	apperr.KindRateLimited:      fiber.StatusTooManyRequests,
+	apperr.KindUnavailable:      fiber.StatusBadGateway,
+}
+
+// RequestID tags every request with an ID, taken from the X-Request-ID
+// header if the client or a proxy sent one, and echoes it in the response
+func RequestID() fiber.Handler {
+	return requestid.New()
+}
+
+// ErrorHandler renders the errors handlers return as problem documents.
+// Application errors map to a status by kind; Fiber errors, such as an
+// unknown route, keep their status; anything else is an internal error.
+func ErrorHandler(c *fiber.Ctx, err error) error {
+	appErr := toAppError(err)
+	status, ok := kindStatus[appErr.Kind]
+	var fiberErr *fiber.Error
+	if errors.As(err, &fiberErr) {
+		status = fiberErr.Code
+	} else if !ok {
+		status = fiber.StatusInternalServerError
+	}
+
+	if status >= fiber.StatusInternalServerError {
+		log.Printf("Request %s %s %s failed: %v", requestIDOf(c), c.Method(), c.Path(), err)
+	}
+
+	problem := fiber.Map{
+		"type":       problemTypePrefix + appErr.Code,
+		"title":      http.StatusText(status),
+		"status":     status,
+		"detail":     appErr.Detail,
+		"instance":   c.OriginalURL(),
+		"code":       appErr.Code,
+		"request_id": requestIDOf(c),
+		// The detail under the name clients used before problem documents
+		"error": appErr.Detail,
+	}
+	for key, value := range appErr.Extra {
+		problem[key] = value
+	}
+	return c.Status(status).JSON(problem, ProblemContentType)
+}
+
+// toAppError converts any error into an application error
+func toAppError(err error) *apperr.Error {
+	if appErr, ok := apperr.As(err); ok {
+		return appErr
+	}
+
+	var fields validate.Errors
+	if errors.As(err, &fields) {
+		return apperr.New(apperr.KindValidation, "validation_failed", fields.Error()).With("fields", fields)
+	}
+
+	var fiberErr *fiber.Error
+	if errors.As(err, &fiberErr) {
+		// Fiber's messages are safe to show, e.g. "Cannot GET /api/nope"
+		code := strings.ReplaceAll(strings.ToLower(http.StatusText(fiberErr.Code)), " ", "_")
+		if code == "" {
+			code = "error"
+		}
+		return apperr.New(apperr.KindInternal, code, fiberErr.Message)
+	}
+
+	return apperr.Internal("internal_error", "Internal server error").Wrap(err)
+}
+
+// requestIDOf returns the ID of the current request
+func requestIDOf(c *fiber.Ctx) string {
+	if id, ok := c.Locals("requestid").(string); ok {
+		return id
+	}
+	return c.GetRespHeader(fiber.HeaderXRequestID)
+}
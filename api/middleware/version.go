@@ -0,0 +1,59 @@
+package middleware
+
+import (
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/projectflow/apperr"
+	"github.com/projectflow/config"
+)
+
+// When deprecated routes stop working, see ConfigureDeprecation
+var legacySunset = time.Date(2027, time.April, 30, 0, 0, 0, 0, time.UTC)
+
+// The API prefix of a path, with the version if it has one
+var apiPrefix = regexp.MustCompile(`^/api(/v[0-9]+)?`)
+
+// ConfigureDeprecation applies the sunset date of the unversioned API paths
+func ConfigureDeprecation(cfg *config.Config) {
+	if !cfg.LegacyAPISunset.IsZero() {
+		legacySunset = cfg.LegacyAPISunset
+	}
+}
+
+// Deprecated marks the routes of a group as aliases of the routes below
+// successor. Responses carry a Deprecation header (RFC 9745) with the date
+// since, a Sunset header (RFC 8594) and a Link to the successor route. From
+// the sunset date on the routes answer 410 Gone.
+//
+// A group matches every path below its prefix, so paths below successor are
+// passed through untouched.
+func Deprecated(since time.Time, prefix, successor string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		path := c.Path()
+		if path == successor || strings.HasPrefix(path, successor+"/") {
+			return c.Next()
+		}
+
+		replacement := successor + strings.TrimPrefix(path, prefix)
+		if !time.Now().Before(legacySunset) {
+			return apperr.Gone("api_version_sunset", "This path was removed, use "+replacement).
+				With("successor", replacement)
+		}
+
+		c.Set("Deprecation", "@"+strconv.FormatInt(since.Unix(), 10))
+		c.Set("Sunset", legacySunset.UTC().Format(http.TimeFormat))
+		c.Append(fiber.HeaderLink, "<"+replacement+`>; rel="successor-version"`)
+		return c.Next()
+	}
+}
+
+// APIPath returns a request path without the API prefix and version, so
+// /api/me/2fa and /api/v1/me/2fa are both /me/2fa
+func APIPath(path string) string {
+	return apiPrefix.ReplaceAllString(path, "")
+}
@@ -0,0 +1,56 @@
+package openapi
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"html/template"
+	"sync"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+//go:embed viewer.html
+var viewerHTML string
+
+var viewerTemplate = template.Must(template.New("viewer").Parse(viewerHTML))
+
+// JSON encodes a document the way it's served and committed
+func JSON(doc *Document) ([]byte, error) {
+	body, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(body, '\n'), nil
+}
+
+// Handler serves the document built by build, which runs once on the first
+// request
+func Handler(build func() *Document) fiber.Handler {
+	var (
+		once sync.Once
+		body []byte
+		err  error
+	)
+	return func(c *fiber.Ctx) error {
+		once.Do(func() { body, err = JSON(build()) })
+		if err != nil {
+			return err
+		}
+		c.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSONCharsetUTF8)
+		return c.Send(body)
+	}
+}
+
+// Viewer serves an interactive viewer for the document at specURL
+func Viewer(title, specURL string) fiber.Handler {
+	var page bytes.Buffer
+	err := viewerTemplate.Execute(&page, struct{ Title, SpecURL string }{title, specURL})
+	return func(c *fiber.Ctx) error {
+		if err != nil {
+			return err
+		}
+		c.Set(fiber.HeaderContentType, fiber.MIMETextHTMLCharsetUTF8)
+		return c.Send(page.Bytes())
+	}
+}
@@ -0,0 +1,256 @@
+// Package openapi describes the API as an OpenAPI 3 document. Routes list
+// their operations in a table, and the schemas of request and response
+// bodies are reflected from the Go values handlers decode and encode, so
+// the document follows the model structs as they change.
+package openapi
+
+import (
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Version of the OpenAPI specification the document follows
+const Version = "3.0.3"
+
+// Security says which credentials an operation accepts
+type Security int
+
+const (
+	Token       Security = iota // An access token or an API key
+	SessionOnly                 // An access token; API keys are refused
+	Public                      // No credentials
+)
+
+// Operation describes one route
+type Operation struct {
+	ID       string // Unique operationId, used as the method name by client generators
+	Method   string
+	Path     string // Fiber route syntax below the API prefix, e.g. /tasks/:id
+	Root     bool   // Path is not below the API prefix
+	Tag      string
+	Summary  string
+	Security Security
+
+	// Query parameters, and path parameters that aren't UUIDs
+	Params []Param
+
+	Body   interface{} // Example of the JSON request body
+	Upload string      // Name of the file field of a multipart request body
+
+	// Example bodies of the success responses by status; nil for no body
+	Responses map[int]interface{}
+	// Media type of the success responses, JSON by default
+	ContentType string
+}
+
+// Param is a query or path parameter
+type Param struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+// Query describes an optional query parameter of the type of the example
+func Query(name, description string, example interface{}) Param {
+	return Param{Name: name, In: "query", Description: description, Schema: newSchemas().of(example)}
+}
+
+// PathParam describes a path parameter of the type of the example
+func PathParam(name, description string, example interface{}) Param {
+	return Param{Name: name, In: "path", Description: description, Required: true, Schema: newSchemas().of(example)}
+}
+
+// Document is an OpenAPI document
+type Document struct {
+	OpenAPI    string                          `json:"openapi"`
+	Info       Info                            `json:"info"`
+	Servers    []Server                        `json:"servers"`
+	Security   []map[string][]string           `json:"security"`
+	Tags       []Tag                           `json:"tags"`
+	Paths      map[string]map[string]*document `json:"paths"`
+	Components Components                      `json:"components"`
+}
+
+// Info is the title and version of the API
+type Info struct {
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	Version     string `json:"version"`
+}
+
+// Server is a base URL of the API
+type Server struct {
+	URL         string `json:"url"`
+	Description string `json:"description,omitempty"`
+}
+
+// Tag groups operations
+type Tag struct {
+	Name string `json:"name"`
+}
+
+// Components holds the shared schemas, responses and security schemes
+type Components struct {
+	Schemas         map[string]*Schema                `json:"schemas"`
+	Responses       map[string]interface{}            `json:"responses"`
+	SecuritySchemes map[string]map[string]interface{} `json:"securitySchemes"`
+}
+
+// document is an operation object of the document
+type document struct {
+	OperationID string                 `json:"operationId"`
+	Summary     string                 `json:"summary,omitempty"`
+	Tags        []string               `json:"tags,omitempty"`
+	Security    []map[string][]string  `json:"security,omitempty"`
+	Parameters  []Param                `json:"parameters,omitempty"`
+	RequestBody map[string]interface{} `json:"requestBody,omitempty"`
+	Responses   map[string]interface{} `json:"responses"`
+}
+
+var routeParam = regexp.MustCompile(`:(\w+)`)
+
+// Build creates the document for operations served below prefix, e.g. /api
+func Build(info Info, prefix string, operations []Operation) *Document {
+	s := newSchemas()
+	doc := &Document{
+		OpenAPI: Version,
+		Info:    info,
+		Servers: []Server{{URL: "/"}},
+		Security: []map[string][]string{
+			{"bearerAuth": {}},
+			{"apiKeyAuth": {}},
+		},
+		Paths: make(map[string]map[string]*document),
+	}
+
+	tags := make(map[string]bool)
+	for _, op := range operations {
+		path := op.Path
+		if !op.Root {
+			path = strings.TrimSuffix(prefix+op.Path, "/")
+		}
+		template := routeParam.ReplaceAllString(path, "{$1}")
+		item := doc.Paths[template]
+		if item == nil {
+			item = make(map[string]*document)
+			doc.Paths[template] = item
+		}
+		item[strings.ToLower(op.Method)] = op.document(s, path)
+
+		if op.Tag != "" && !tags[op.Tag] {
+			tags[op.Tag] = true
+			doc.Tags = append(doc.Tags, Tag{Name: op.Tag})
+		}
+	}
+	sort.Slice(doc.Tags, func(i, j int) bool { return doc.Tags[i].Name < doc.Tags[j].Name })
+
+	doc.Components = Components{
+		Schemas: s.components,
+		Responses: map[string]interface{}{
+			"Problem": map[string]interface{}{
+				"description": "An error, described by a problem document",
+				"content": map[string]interface{}{
+					"application/problem+json": map[string]interface{}{"schema": problemSchema()},
+				},
+			},
+		},
+		SecuritySchemes: map[string]map[string]interface{}{
+			"bearerAuth": {"type": "http", "scheme": "bearer", "bearerFormat": "JWT"},
+			"apiKeyAuth": {"type": "apiKey", "in": "header", "name": "X-API-Key"},
+		},
+	}
+	return doc
+}
+
+func (op Operation) document(s *schemas, path string) *document {
+	d := &document{
+		OperationID: op.ID,
+		Summary:     op.Summary,
+		Responses:   make(map[string]interface{}),
+	}
+	if op.Tag != "" {
+		d.Tags = []string{op.Tag}
+	}
+	switch op.Security {
+	case SessionOnly:
+		d.Security = []map[string][]string{{"bearerAuth": {}}}
+	case Public:
+		d.Security = []map[string][]string{{}}
+	}
+
+	// Path parameters are UUIDs unless declared otherwise
+	declared := make(map[string]bool)
+	for _, param := range op.Params {
+		declared[param.In+":"+param.Name] = true
+	}
+	for _, match := range routeParam.FindAllStringSubmatch(path, -1) {
+		if !declared["path:"+match[1]] {
+			d.Parameters = append(d.Parameters, PathParam(match[1], "", &Schema{Type: "string", Format: "uuid"}))
+		}
+	}
+	d.Parameters = append(d.Parameters, op.Params...)
+
+	switch {
+	case op.Upload != "":
+		d.RequestBody = map[string]interface{}{
+			"required": true,
+			"content": map[string]interface{}{
+				"multipart/form-data": map[string]interface{}{
+					"schema": &Schema{
+						Type:       "object",
+						Properties: map[string]*Schema{op.Upload: {Type: "string", Format: "binary"}},
+						Required:   []string{op.Upload},
+					},
+				},
+			},
+		}
+	case op.Body != nil:
+		d.RequestBody = map[string]interface{}{
+			"required": true,
+			"content": map[string]interface{}{
+				"application/json": map[string]interface{}{"schema": s.of(op.Body)},
+			},
+		}
+	}
+
+	contentType := op.ContentType
+	if contentType == "" {
+		contentType = "application/json"
+	}
+	for status, body := range op.Responses {
+		response := map[string]interface{}{"description": http.StatusText(status)}
+		if body != nil {
+			response["content"] = map[string]interface{}{
+				contentType: map[string]interface{}{"schema": s.of(body)},
+			}
+		}
+		d.Responses[strconv.Itoa(status)] = response
+	}
+	d.Responses["default"] = map[string]interface{}{"$ref": "#/components/responses/Problem"}
+	return d
+}
+
+// problemSchema describes the error responses of api/middleware
+func problemSchema() *Schema {
+	str := func(description string) *Schema { return &Schema{Type: "string", Description: description} }
+	return &Schema{
+		Type: "object",
+		Properties: map[string]*Schema{
+			"type":       str("URN of the problem type, built from the code"),
+			"title":      str("Status text"),
+			"status":     {Type: "integer", Format: "int32"},
+			"detail":     str("Explanation for people, may change"),
+			"instance":   str("Path of the request"),
+			"code":       str("Stable machine-readable error code"),
+			"request_id": str("ID of the request, also in the X-Request-ID header"),
+			"error":      str("Same as detail"),
+		},
+		Required:             []string{"code", "detail", "status", "title", "type"},
+		AdditionalProperties: &Schema{},
+	}
+}
@@ -0,0 +1,295 @@
+package openapi
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Schema is an OpenAPI 3.0 schema object
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Nullable             bool               `json:"nullable,omitempty"`
+	Enum                 []interface{}      `json:"enum,omitempty"`
+	MinLength            *int               `json:"minLength,omitempty"`
+	MaxLength            *int               `json:"maxLength,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+	MinItems             *int               `json:"minItems,omitempty"`
+	MaxItems             *int               `json:"maxItems,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	OneOf                []*Schema          `json:"oneOf,omitempty"`
+}
+
+// Object describes a JSON object by example, for responses that wrap their
+// data in an envelope. Each value is reflected into the schema of its member;
+// members are required unless wrapped in Optional.
+type Object map[string]interface{}
+
+type optional struct{ value interface{} }
+
+// Optional marks an Object member that is only sometimes present
+func Optional(value interface{}) interface{} {
+	return optional{value}
+}
+
+type oneOf []interface{}
+
+// OneOf describes a body that takes one of several shapes
+func OneOf(values ...interface{}) interface{} {
+	return oneOf(values)
+}
+
+var (
+	timeType    = reflect.TypeOf(time.Time{})
+	uuidType    = reflect.TypeOf(uuid.UUID{})
+	rawJSONType = reflect.TypeOf(json.RawMessage{})
+)
+
+// schemas reflects Go types into schemas. Named struct types become
+// components referenced by name.
+type schemas struct {
+	components map[string]*Schema
+	types      map[string]reflect.Type
+}
+
+func newSchemas() *schemas {
+	return &schemas{
+		components: make(map[string]*Schema),
+		types:      make(map[string]reflect.Type),
+	}
+}
+
+// of returns the schema of an example value
+func (s *schemas) of(value interface{}) *Schema {
+	switch v := value.(type) {
+	case nil:
+		return &Schema{}
+	case Object:
+		return s.object(v)
+	case oneOf:
+		schema := &Schema{}
+		for _, option := range v {
+			schema.OneOf = append(schema.OneOf, s.of(option))
+		}
+		return schema
+	case *Schema:
+		return v
+	}
+	return s.ofType(reflect.TypeOf(value))
+}
+
+func (s *schemas) object(obj Object) *Schema {
+	schema := &Schema{Type: "object", Properties: make(map[string]*Schema, len(obj))}
+	for name, value := range obj {
+		if opt, ok := value.(optional); ok {
+			schema.Properties[name] = s.of(opt.value)
+			continue
+		}
+		schema.Properties[name] = s.of(value)
+		schema.Required = append(schema.Required, name)
+	}
+	sort.Strings(schema.Required)
+	return schema
+}
+
+func (s *schemas) ofType(t reflect.Type) *Schema {
+	if t.Kind() == reflect.Ptr {
+		schema := s.ofType(t.Elem())
+		if schema.Ref != "" {
+			// $ref siblings are ignored in OpenAPI 3.0
+			return &Schema{OneOf: []*Schema{schema}, Nullable: true}
+		}
+		schema.Nullable = true
+		return schema
+	}
+
+	switch t {
+	case timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case uuidType:
+		return &Schema{Type: "string", Format: "uuid"}
+	case rawJSONType:
+		return &Schema{}
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Int64, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: s.ofType(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: s.ofType(t.Elem())}
+	case reflect.Interface:
+		return &Schema{}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return s.structSchema(t)
+		}
+		return s.component(t)
+	}
+	panic("openapi: can't describe " + t.String())
+}
+
+// component registers a named struct type and returns a reference to it
+func (s *schemas) component(t reflect.Type) *Schema {
+	name := t.Name()
+	if existing, ok := s.types[name]; ok && existing != t {
+		// Same name in another package
+		name = strings.ReplaceAll(t.String(), ".", "")
+		name = strings.ToUpper(name[:1]) + name[1:]
+	}
+	ref := &Schema{Ref: "#/components/schemas/" + name}
+	if _, ok := s.types[name]; ok {
+		return ref
+	}
+
+	// Register before reflecting the fields so recursive types terminate
+	s.types[name] = t
+	s.components[name] = &Schema{}
+	*s.components[name] = *s.structSchema(t)
+	return ref
+}
+
+// structSchema describes the JSON encoding of a struct. Structs with validate
+// tags are request bodies, whose required fields follow their rules; other
+// structs are responses, which always contain the fields without omitempty.
+func (s *schemas) structSchema(t reflect.Type) *Schema {
+	schema := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+	input := hasValidateTags(t)
+	s.addFields(schema, t, input)
+	sort.Strings(schema.Required)
+	return schema
+}
+
+func (s *schemas) addFields(schema *Schema, t reflect.Type, input bool) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" || (!field.IsExported() && !field.Anonymous) {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+
+		// Embedded structs without a name are flattened, like encoding/json does
+		if field.Anonymous && name == "" && field.Type.Kind() == reflect.Struct {
+			s.addFields(schema, field.Type, input)
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		fieldSchema := s.ofType(field.Type)
+		rules := field.Tag.Get("validate")
+		applyRules(fieldSchema, rules)
+		schema.Properties[name] = fieldSchema
+
+		required := !strings.Contains(opts, "omitempty")
+		if input {
+			required = hasRule(rules, "required")
+		}
+		if required {
+			schema.Required = append(schema.Required, name)
+		}
+	}
+}
+
+// applyRules adds the constraints of validate rules to a schema
+func applyRules(schema *Schema, rules string) {
+	if rules == "" || schema.Ref != "" || len(schema.OneOf) > 0 {
+		return
+	}
+	for _, rule := range strings.Split(rules, ",") {
+		rule, param, _ := strings.Cut(rule, "=")
+		switch rule {
+		case "min", "max", "len":
+			limit, err := strconv.ParseFloat(param, 64)
+			if err != nil {
+				continue
+			}
+			n := int(limit)
+			switch schema.Type {
+			case "string":
+				if rule != "max" {
+					schema.MinLength = &n
+				}
+				if rule != "min" {
+					schema.MaxLength = &n
+				}
+			case "array":
+				if rule != "max" {
+					schema.MinItems = &n
+				}
+				if rule != "min" {
+					schema.MaxItems = &n
+				}
+			case "integer", "number":
+				if rule != "max" {
+					schema.Minimum = &limit
+				}
+				if rule != "min" {
+					schema.Maximum = &limit
+				}
+			}
+		case "oneof":
+			for _, option := range strings.Fields(param) {
+				if schema.Type == "integer" {
+					if n, err := strconv.Atoi(option); err == nil {
+						schema.Enum = append(schema.Enum, n)
+						continue
+					}
+				}
+				schema.Enum = append(schema.Enum, option)
+			}
+		case "email":
+			schema.Format = "email"
+		case "url":
+			schema.Format = "uri"
+		case "uuid":
+			schema.Format = "uuid"
+		case "date":
+			schema.Format = "date"
+		}
+	}
+}
+
+func hasValidateTags(t reflect.Type) bool {
+	for i := 0; i < t.NumField(); i++ {
+		if t.Field(i).Tag.Get("validate") != "" {
+			return true
+		}
+	}
+	return false
+}
+
+func hasRule(rules, name string) bool {
+	for _, rule := range strings.Split(rules, ",") {
+		if rule == name {
+			return true
+		}
+	}
+	return false
+}
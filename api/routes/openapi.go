@@ -0,0 +1,439 @@
+package routes
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/projectflow/api/handlers"
+	"github.com/projectflow/api/openapi"
+	"github.com/projectflow/models"
+	"github.com/projectflow/utils/password"
+)
+
+// Prefixes of the API routes
+const (
+	apiPrefix = "/api"
+	v1Prefix  = apiPrefix + "/v1"
+)
+
+// OpenAPI describes the routes of version 1 of the API. Add an operation
+// here when adding a route; the unit tests fail until both match.
+func OpenAPI() *openapi.Document {
+	return openapi.Build(openapi.Info{
+		Title:       "ProjectFlow API",
+		Description: "Project and task management. Errors are problem documents, see docs/API.md.",
+		Version:     "1.0.0",
+	}, v1Prefix, operations)
+}
+
+type (
+	op  = openapi.Operation
+	obj = openapi.Object
+)
+
+var (
+	message = obj{"message": ""}
+	session = obj{
+		"user":                           models.UserResponse{},
+		"token":                          "",
+		"refresh_token":                  "",
+		"expires_in":                     0,
+		"two_factor_enrollment_required": openapi.Optional(true),
+	}
+	userEnvelope = obj{"user": models.UserResponse{}}
+	limitParam   = func(max int) openapi.Param {
+		return openapi.Query("limit", "Maximum number of results, at most "+strconv.Itoa(max), 0)
+	}
+
+	// Shaping of task and project responses
+	fieldsParam = openapi.Query("fields", "Comma separated fields to return; the id is always returned", "")
+	taskParams  = []openapi.Param{
+		openapi.Query("include", "Comma separated related records to embed: "+strings.Join(models.TaskIncludes, ", "), ""),
+		fieldsParam,
+	}
+	projectParams = []openapi.Param{
+		openapi.Query("include", "Comma separated related records to embed: "+strings.Join(models.ProjectIncludes, ", "), ""),
+		fieldsParam,
+	}
+)
+
+// ok describes a 200 response
+func ok(body interface{}) map[int]interface{} {
+	return map[int]interface{}{http.StatusOK: body}
+}
+
+// created describes a 201 response
+func created(body interface{}) map[int]interface{} {
+	return map[int]interface{}{http.StatusCreated: body}
+}
+
+// accepted describes a 202 response
+func accepted(body interface{}) map[int]interface{} {
+	return map[int]interface{}{http.StatusAccepted: body}
+}
+
+// withSession adds the members of a session to an object
+func withSession(members obj) obj {
+	merged := obj{}
+	for name, value := range session {
+		merged[name] = value
+	}
+	for name, value := range members {
+		merged[name] = value
+	}
+	return merged
+}
+
+var operations = []op{
+	{ID: "getJWKS", Method: "GET", Path: "/.well-known/jwks.json", Root: true, Tag: "auth", Summary: "Public keys for verifying access tokens",
+		Security: openapi.Public, Responses: ok(obj{"keys": []map[string]string{}})},
+	{ID: "getOpenAPI", Method: "GET", Path: "/openapi.json", Tag: "docs", Summary: "This document",
+		Security: openapi.Public, Responses: ok(map[string]interface{}{})},
+	{ID: "getAPIDocs", Method: "GET", Path: "/docs", Tag: "docs", Summary: "Interactive API documentation",
+		Security: openapi.Public, Responses: ok(""), ContentType: "text/html"},
+
+	// Auth
+	{ID: "register", Method: "POST", Path: "/auth/register", Tag: "auth", Summary: "Create an account, or email the holder of a taken address",
+		Security: openapi.Public, Body: models.CreateUserRequest{}, Responses: accepted(message)},
+	{ID: "login", Method: "POST", Path: "/auth/login", Tag: "auth", Summary: "Sign in, or start a two-factor login",
+		Security: openapi.Public, Body: models.LoginRequest{}, Responses: ok(openapi.OneOf(session, obj{
+			"two_factor_required": true,
+			"challenge_token":     "",
+			"expires_in":          0,
+		}))},
+	{ID: "completeTwoFactorLogin", Method: "POST", Path: "/auth/login/2fa", Tag: "auth", Summary: "Finish a two-factor login",
+		Security: openapi.Public, Body: models.TwoFactorLoginRequest{}, Responses: ok(session)},
+	{ID: "getCurrentUser", Method: "GET", Path: "/auth/me", Tag: "auth", Summary: "The signed-in user",
+		Responses: ok(obj{"user": models.UserResponse{}, "pending_email": ""})},
+	{ID: "refreshSession", Method: "POST", Path: "/auth/refresh", Tag: "auth", Summary: "Exchange a refresh token",
+		Security: openapi.Public, Body: models.RefreshTokenRequest{}, Responses: ok(obj{"token": "", "refresh_token": "", "expires_in": 0})},
+	{ID: "getPasswordPolicy", Method: "GET", Path: "/auth/password-policy", Tag: "auth", Summary: "Rules for new passwords",
+		Security: openapi.Public, Responses: ok(obj{"policy": password.Policy{}})},
+	{ID: "verifyEmail", Method: "POST", Path: "/auth/verify-email", Tag: "auth", Summary: "Confirm an email address",
+		Security: openapi.Public, Body: models.VerifyEmailRequest{}, Responses: ok(message)},
+	{ID: "resendVerificationEmail", Method: "POST", Path: "/auth/verify-email/resend", Tag: "auth", Summary: "Send the verification email again",
+		Security: openapi.SessionOnly, Responses: ok(message)},
+	{ID: "forgotPassword", Method: "POST", Path: "/auth/forgot-password", Tag: "auth", Summary: "Email a password reset link",
+		Security: openapi.Public, Body: models.ForgotPasswordRequest{}, Responses: ok(message)},
+	{ID: "resetPassword", Method: "POST", Path: "/auth/reset-password", Tag: "auth", Summary: "Set a new password with a reset link",
+		Security: openapi.Public, Body: models.ResetPasswordRequest{}, Responses: ok(message)},
+	{ID: "logout", Method: "POST", Path: "/auth/logout", Tag: "auth", Summary: "Revoke a refresh token",
+		Security: openapi.SessionOnly, Body: models.RefreshTokenRequest{}, Responses: ok(message)},
+	{ID: "logoutAll", Method: "POST", Path: "/auth/logout-all", Tag: "auth", Summary: "Revoke every session",
+		Security: openapi.SessionOnly, Responses: ok(message)},
+	{ID: "getOIDCProviders", Method: "GET", Path: "/auth/oidc", Tag: "auth", Summary: "Single sign-on providers",
+		Security: openapi.Public, Responses: ok(obj{"providers": []string{}})},
+	{ID: "startOIDCLogin", Method: "GET", Path: "/auth/oidc/:provider", Tag: "auth", Summary: "Redirect to a sign-on provider",
+		Security: openapi.Public, Params: []openapi.Param{openapi.PathParam("provider", "Provider name", "")},
+		Responses: map[int]interface{}{http.StatusFound: nil}},
+	{ID: "oidcCallback", Method: "GET", Path: "/auth/oidc/:provider/callback", Tag: "auth", Summary: "Return from a sign-on provider",
+		Security: openapi.Public, Params: []openapi.Param{
+			openapi.PathParam("provider", "Provider name", ""),
+			openapi.Query("code", "Authorization code", ""),
+			openapi.Query("state", "Login state", ""),
+			openapi.Query("error", "Error reported by the provider", ""),
+		},
+		Responses: map[int]interface{}{http.StatusFound: nil}},
+
+	// Current user
+	{ID: "getNotificationPreferences", Method: "GET", Path: "/me/notification-preferences", Tag: "me", Summary: "Notification preferences",
+		Responses: ok(obj{"preferences": models.NotificationPreferences{}, "notification_types": []string{}})},
+	{ID: "updateNotificationPreferences", Method: "PUT", Path: "/me/notification-preferences", Tag: "me", Summary: "Change notification preferences",
+		Body: models.UpdateNotificationPreferencesRequest{}, Responses: ok(obj{"preferences": models.NotificationPreferences{}})},
+	{ID: "updateProfile", Method: "PATCH", Path: "/me/", Tag: "me", Summary: "Change the profile",
+		Security: openapi.SessionOnly, Body: models.UpdateProfileRequest{}, Responses: ok(obj{"user": models.UserResponse{}, "pending_email": ""})},
+	{ID: "uploadAvatar", Method: "PUT", Path: "/me/avatar", Tag: "me", Summary: "Upload a profile picture",
+		Security: openapi.SessionOnly, Upload: "avatar", Responses: ok(userEnvelope)},
+	{ID: "deleteAvatar", Method: "DELETE", Path: "/me/avatar", Tag: "me", Summary: "Remove the profile picture",
+		Security: openapi.SessionOnly, Responses: ok(userEnvelope)},
+	{ID: "changePassword", Method: "PUT", Path: "/me/password", Tag: "me", Summary: "Change the password, ending other sessions",
+		Security: openapi.SessionOnly, Body: models.ChangePasswordRequest{}, Responses: ok(session)},
+	{ID: "getAPIKeys", Method: "GET", Path: "/me/api-keys/", Tag: "me", Summary: "API keys",
+		Security: openapi.SessionOnly, Responses: ok(obj{"api_keys": []models.APIKey{}, "scopes": []string{}})},
+	{ID: "createAPIKey", Method: "POST", Path: "/me/api-keys/", Tag: "me", Summary: "Create an API key, returned only once",
+		Security: openapi.SessionOnly, Body: models.CreateAPIKeyRequest{}, Responses: created(obj{"api_key": models.APIKey{}, "key": ""})},
+	{ID: "revokeAPIKey", Method: "DELETE", Path: "/me/api-keys/:id", Tag: "me", Summary: "Revoke an API key",
+		Security: openapi.SessionOnly, Responses: ok(message)},
+	{ID: "getTwoFactorStatus", Method: "GET", Path: "/me/2fa/", Tag: "me", Summary: "Two-factor authentication status",
+		Security: openapi.SessionOnly, Responses: ok(obj{"enabled": true, "required": true, "recovery_codes_remaining": 0})},
+	{ID: "setupTwoFactor", Method: "POST", Path: "/me/2fa/setup", Tag: "me", Summary: "Start two-factor enrollment",
+		Security: openapi.SessionOnly, Responses: ok(obj{"secret": "", "otpauth_uri": ""})},
+	{ID: "confirmTwoFactor", Method: "POST", Path: "/me/2fa/confirm", Tag: "me", Summary: "Finish two-factor enrollment",
+		Security: openapi.SessionOnly, Body: models.TwoFactorCodeRequest{}, Responses: ok(obj{"message": "", "recovery_codes": []string{}})},
+	{ID: "disableTwoFactor", Method: "POST", Path: "/me/2fa/disable", Tag: "me", Summary: "Turn off two-factor authentication",
+		Security: openapi.SessionOnly, Body: models.DisableTwoFactorRequest{}, Responses: ok(message)},
+	{ID: "regenerateRecoveryCodes", Method: "POST", Path: "/me/2fa/recovery-codes", Tag: "me", Summary: "Replace the recovery codes",
+		Security: openapi.SessionOnly, Body: models.TwoFactorCodeRequest{}, Responses: ok(obj{"recovery_codes": []string{}})},
+
+	// Organizations
+	{ID: "getOrganizations", Method: "GET", Path: "/organizations/", Tag: "organizations", Summary: "Organizations of the current user",
+		Security: openapi.SessionOnly, Responses: ok(obj{"organizations": []obj{{"organization": models.Organization{}, "role": "", "current": true}}})},
+	{ID: "createOrganization", Method: "POST", Path: "/organizations/", Tag: "organizations", Summary: "Create an organization",
+		Security: openapi.SessionOnly, Body: models.CreateOrganizationRequest{}, Responses: created(obj{"organization": models.Organization{}})},
+	{ID: "switchOrganization", Method: "POST", Path: "/organizations/switch", Tag: "organizations", Summary: "Switch the current organization",
+		Security: openapi.SessionOnly, Body: models.SwitchOrganizationRequest{}, Responses: ok(session)},
+	{ID: "acceptOrganizationInvite", Method: "POST", Path: "/organizations/invites/accept", Tag: "organizations", Summary: "Join an organization",
+		Security: openapi.SessionOnly, Body: models.AcceptInviteRequest{}, Responses: ok(obj{"organization": models.Organization{}})},
+	{ID: "getCurrentOrganization", Method: "GET", Path: "/organizations/current", Tag: "organizations", Summary: "The current organization and its members",
+		Security: openapi.SessionOnly, Responses: ok(obj{"organization": models.Organization{}, "members": []obj{{"user": models.UserResponse{}, "role": ""}}})},
+	{ID: "updateCurrentOrganization", Method: "PUT", Path: "/organizations/current", Tag: "organizations", Summary: "Rename the current organization",
+		Security: openapi.SessionOnly, Body: models.UpdateOrganizationRequest{}, Responses: ok(obj{"organization": models.Organization{}})},
+	{ID: "removeOrganizationMember", Method: "DELETE", Path: "/organizations/current/members/:userID", Tag: "organizations", Summary: "Remove a member",
+		Security: openapi.SessionOnly, Responses: ok(message)},
+	{ID: "getOrganizationInvites", Method: "GET", Path: "/organizations/current/invites", Tag: "organizations", Summary: "Pending invitations",
+		Security: openapi.SessionOnly, Responses: ok(obj{"invites": []models.OrganizationInvite{}})},
+	{ID: "createOrganizationInvite", Method: "POST", Path: "/organizations/current/invites", Tag: "organizations", Summary: "Invite someone by email",
+		Security: openapi.SessionOnly, Body: models.InviteMemberRequest{}, Responses: created(obj{"invite": models.OrganizationInvite{}})},
+	{ID: "revokeOrganizationInvite", Method: "DELETE", Path: "/organizations/current/invites/:id", Tag: "organizations", Summary: "Revoke an invitation",
+		Security: openapi.SessionOnly, Responses: ok(message)},
+
+	// Project invitations
+	{ID: "getProjectInviteByToken", Method: "GET", Path: "/project-invites/", Tag: "projects", Summary: "Look up a project invitation",
+		Security: openapi.Public, Params: []openapi.Param{openapi.Query("token", "Token from the invitation email", "")},
+		Responses: ok(obj{
+			"project_name":   "",
+			"email":          "",
+			"role":           "",
+			"invited_by":     "",
+			"expires_at":     time.Time{},
+			"account_exists": true,
+		})},
+	{ID: "acceptProjectInvite", Method: "POST", Path: "/project-invites/accept", Tag: "projects", Summary: "Join a project, creating an account if needed",
+		Security: openapi.Public, Body: models.AcceptProjectInviteRequest{}, Responses: map[int]interface{}{
+			http.StatusOK:      obj{"project": models.ProjectResponse{}, "member": models.ProjectMember{}},
+			http.StatusCreated: withSession(obj{"project": models.ProjectResponse{}, "member": models.ProjectMember{}}),
+		}},
+
+	// Administration
+	{ID: "getSecuritySettings", Method: "GET", Path: "/admin/security", Tag: "admin", Summary: "Security settings",
+		Responses: ok(obj{"settings": models.SecuritySettings{}})},
+	{ID: "updateSecuritySettings", Method: "PUT", Path: "/admin/security", Tag: "admin", Summary: "Change security settings",
+		Body: models.UpdateSecuritySettingsRequest{}, Responses: ok(obj{"settings": models.SecuritySettings{}})},
+	{ID: "getLoginAttempts", Method: "GET", Path: "/admin/login-attempts", Tag: "admin", Summary: "Recent login attempts",
+		Params: []openapi.Param{
+			openapi.Query("email", "Only attempts for this email", ""),
+			openapi.Query("ip", "Only attempts from this address", ""),
+			openapi.Query("outcome", "Only attempts with this outcome", ""),
+			limitParam(1000),
+		},
+		Responses: ok(obj{"attempts": []models.LoginAttempt{}})},
+	{ID: "getLockout", Method: "GET", Path: "/admin/lockouts", Tag: "admin", Summary: "Whether an account is locked out",
+		Params:    []openapi.Param{openapi.Query("email", "Email of the account", "")},
+		Responses: ok(obj{"email": "", "locked": true, "remaining_seconds": 0})},
+	{ID: "clearLockout", Method: "DELETE", Path: "/admin/lockouts", Tag: "admin", Summary: "Unlock an account",
+		Params:    []openapi.Param{openapi.Query("email", "Email of the account", "")},
+		Responses: ok(message)},
+	{ID: "getRateLimitUsage", Method: "GET", Path: "/admin/rate-limits", Tag: "admin", Summary: "Active rate limit buckets, busiest first",
+		Params: []openapi.Param{
+			openapi.Query("group", "Only this route group", ""),
+			openapi.Query("subject", "Only this subject, e.g. user:<id>", ""),
+			limitParam(1000),
+		},
+		Responses: ok(obj{"usage": []handlers.RateLimitUsage{}})},
+	{ID: "updateUserProfile", Method: "PUT", Path: "/admin/users/:id", Tag: "admin", Summary: "Change a user's profile",
+		Body: models.AdminUpdateUserRequest{}, Responses: ok(userEnvelope)},
+	{ID: "updateUserRole", Method: "PUT", Path: "/admin/users/:id/role", Tag: "admin", Summary: "Change a user's role",
+		Body: models.UpdateUserRoleRequest{}, Responses: ok(userEnvelope)},
+	{ID: "deactivateUser", Method: "POST", Path: "/admin/users/:id/deactivate", Tag: "admin", Summary: "Deactivate a user",
+		Responses: ok(userEnvelope)},
+	{ID: "reactivateUser", Method: "POST", Path: "/admin/users/:id/reactivate", Tag: "admin", Summary: "Reactivate a user",
+		Responses: ok(userEnvelope)},
+	{ID: "reassignUserWork", Method: "POST", Path: "/admin/users/:id/reassign", Tag: "admin", Summary: "Hand a user's tasks and allocations to someone else",
+		Body: models.ReassignWorkRequest{}, Responses: ok(obj{
+			"reassigned_tasks":       []uuid.UUID{},
+			"skipped_tasks":          []uuid.UUID{},
+			"reassigned_allocations": []int{},
+			"skipped_allocations":    []int{},
+		})},
+	{ID: "startImpersonation", Method: "POST", Path: "/admin/users/:id/impersonate", Tag: "admin", Summary: "Act as a user for a while",
+		Security: openapi.SessionOnly, Body: models.StartImpersonationRequest{},
+		Responses: created(obj{"impersonation": models.Impersonation{}, "token": "", "expires_in": 0})},
+	{ID: "getImpersonations", Method: "GET", Path: "/admin/impersonations", Tag: "admin", Summary: "Impersonation audit log",
+		Responses: ok(obj{"impersonations": []models.Impersonation{}})},
+	{ID: "endImpersonation", Method: "DELETE", Path: "/admin/impersonations/:id", Tag: "admin", Summary: "End an impersonation",
+		Responses: ok(obj{"impersonation": models.Impersonation{}})},
+
+	// Users
+	{ID: "getUsers", Method: "GET", Path: "/users/", Tag: "users", Summary: "Users of the organization",
+		Responses: ok(obj{"users": []models.UserResponse{}})},
+	{ID: "getUser", Method: "GET", Path: "/users/:id", Tag: "users", Summary: "A user",
+		Responses: ok(userEnvelope)},
+
+	// Projects
+	{ID: "createProject", Method: "POST", Path: "/projects/", Tag: "projects", Summary: "Create a project",
+		Params: projectParams, Body: models.CreateProjectRequest{}, Responses: created(obj{"project": models.ProjectResponse{}})},
+	{ID: "getProjects", Method: "GET", Path: "/projects/", Tag: "projects", Summary: "Projects the user is a member of",
+		Params: projectParams, Responses: ok(obj{"projects": []models.ProjectResponse{}})},
+	{ID: "getProject", Method: "GET", Path: "/projects/:id", Tag: "projects", Summary: "A project with its members",
+		Params: projectParams, Responses: ok(obj{
+			"project":      models.ProjectResponse{},
+			"members":      []models.UserResponse{},
+			"member_roles": map[string]string{},
+			"role":         models.ProjectRole{},
+		})},
+	{ID: "updateProject", Method: "PUT", Path: "/projects/:id", Tag: "projects", Summary: "Change a project",
+		Params: projectParams, Body: models.UpdateProjectRequest{}, Responses: ok(obj{"project": models.ProjectResponse{}})},
+	{ID: "deleteProject", Method: "DELETE", Path: "/projects/:id", Tag: "projects", Summary: "Delete a project",
+		Responses: ok(message)},
+	{ID: "addProjectMember", Method: "POST", Path: "/projects/:id/members", Tag: "projects", Summary: "Add a member",
+		Body: models.AddMemberRequest{}, Responses: ok(message)},
+	{ID: "updateProjectMemberRole", Method: "PUT", Path: "/projects/:id/members/:memberID", Tag: "projects", Summary: "Change a member's role",
+		Body: models.UpdateMemberRoleRequest{}, Responses: ok(obj{"member": models.ProjectMember{}})},
+	{ID: "removeProjectMember", Method: "DELETE", Path: "/projects/:id/members/:memberID", Tag: "projects", Summary: "Remove a member",
+		Responses: ok(message)},
+	{ID: "getProjectRoles", Method: "GET", Path: "/projects/:id/roles", Tag: "projects", Summary: "Built-in and custom roles",
+		Responses: ok(obj{"roles": []models.ProjectRole{}, "permissions": []string{}})},
+	{ID: "createProjectRole", Method: "POST", Path: "/projects/:id/roles", Tag: "projects", Summary: "Create a custom role",
+		Body: models.CreateRoleRequest{}, Responses: created(obj{"role": models.ProjectRole{}})},
+	{ID: "updateProjectRole", Method: "PUT", Path: "/projects/:id/roles/:role", Tag: "projects", Summary: "Change a custom role",
+		Params: []openapi.Param{openapi.PathParam("role", "Role name", "")},
+		Body:   models.UpdateRoleRequest{}, Responses: ok(obj{"role": models.ProjectRole{}})},
+	{ID: "deleteProjectRole", Method: "DELETE", Path: "/projects/:id/roles/:role", Tag: "projects", Summary: "Delete an unused custom role",
+		Params:    []openapi.Param{openapi.PathParam("role", "Role name", "")},
+		Responses: ok(message)},
+	{ID: "getProjectWebhooks", Method: "GET", Path: "/projects/:id/webhooks", Tag: "webhooks", Summary: "Webhooks of a project",
+		Responses: ok(obj{"webhooks": []models.Webhook{}, "events": []string{}})},
+	{ID: "createProjectWebhook", Method: "POST", Path: "/projects/:id/webhooks", Tag: "webhooks", Summary: "Register a webhook, the secret is returned only once",
+		Body: models.CreateWebhookRequest{}, Responses: created(obj{"webhook": models.Webhook{}, "secret": ""})},
+	{ID: "updateProjectWebhook", Method: "PUT", Path: "/projects/:id/webhooks/:webhookID", Tag: "webhooks", Summary: "Change a webhook",
+		Body: models.UpdateWebhookRequest{}, Responses: ok(obj{"webhook": models.Webhook{}})},
+	{ID: "deleteProjectWebhook", Method: "DELETE", Path: "/projects/:id/webhooks/:webhookID", Tag: "webhooks", Summary: "Delete a webhook",
+		Responses: ok(message)},
+	{ID: "getWebhookDeliveries", Method: "GET", Path: "/projects/:id/webhooks/:webhookID/deliveries", Tag: "webhooks", Summary: "Delivery log, newest first",
+		Responses: ok(obj{"deliveries": []models.WebhookDelivery{}})},
+	{ID: "redeliverWebhookDelivery", Method: "POST", Path: "/projects/:id/webhooks/:webhookID/deliveries/:deliveryID/redeliver", Tag: "webhooks", Summary: "Send a delivery again",
+		Responses: ok(obj{"delivery": models.WebhookDelivery{}})},
+	{ID: "getProjectInvites", Method: "GET", Path: "/projects/:id/invites", Tag: "projects", Summary: "Invitations to a project",
+		Params:    []openapi.Param{openapi.Query("status", "pending (default), accepted, revoked, expired or all", "")},
+		Responses: ok(obj{"invites": []models.ProjectInviteResponse{}})},
+	{ID: "createProjectInvite", Method: "POST", Path: "/projects/:id/invites", Tag: "projects", Summary: "Invite someone by email",
+		Body: models.CreateProjectInviteRequest{}, Responses: created(obj{"invite": models.ProjectInviteResponse{}})},
+	{ID: "resendProjectInvite", Method: "POST", Path: "/projects/:id/invites/:inviteID/resend", Tag: "projects", Summary: "Send an invitation again",
+		Responses: ok(obj{"invite": models.ProjectInviteResponse{}})},
+	{ID: "revokeProjectInvite", Method: "DELETE", Path: "/projects/:id/invites/:inviteID", Tag: "projects", Summary: "Revoke an invitation",
+		Responses: ok(obj{"invite": models.ProjectInviteResponse{}})},
+	{ID: "getProjectTeams", Method: "GET", Path: "/projects/:id/teams", Tag: "teams", Summary: "Teams in a project",
+		Responses: ok(obj{"teams": []obj{{"team": models.Team{}, "role": "", "added_at": time.Time{}}}})},
+	{ID: "addProjectTeam", Method: "POST", Path: "/projects/:id/teams", Tag: "teams", Summary: "Add a team's members to a project",
+		Body: models.AddProjectTeamRequest{}, Responses: created(obj{"team": models.ProjectTeam{}})},
+	{ID: "removeProjectTeam", Method: "DELETE", Path: "/projects/:id/teams/:teamID", Tag: "teams", Summary: "Remove a team from a project",
+		Responses: ok(message)},
+	{ID: "getAssignmentSuggestions", Method: "GET", Path: "/projects/:id/assignment-suggestions", Tag: "teams", Summary: "Team members with room for another task",
+		Params: []openapi.Param{
+			openapi.Query("team_id", "Only members of this team", uuid.UUID{}),
+			openapi.Query("limit", "Maximum number of suggestions, 5 by default", 0),
+		},
+		Responses: ok(obj{"suggestions": []models.AssignmentSuggestion{}})},
+
+	// Teams
+	{ID: "getTeams", Method: "GET", Path: "/teams/", Tag: "teams", Summary: "Teams of the organization",
+		Responses: ok(obj{"teams": []models.Team{}})},
+	{ID: "createTeam", Method: "POST", Path: "/teams/", Tag: "teams", Summary: "Create a team",
+		Body: models.CreateTeamRequest{}, Responses: created(obj{"team": models.Team{}})},
+	{ID: "getTeamsWorkload", Method: "GET", Path: "/teams/workload", Tag: "teams", Summary: "Workload of every team",
+		Responses: ok(obj{"teams": []models.TeamWorkload{}})},
+	{ID: "getTeam", Method: "GET", Path: "/teams/:id", Tag: "teams", Summary: "A team with its members and projects",
+		Responses: ok(obj{
+			"team":        models.Team{},
+			"members":     []obj{{"user": models.UserResponse{}, "role": "", "capacity_hours": 0, "joined_at": time.Time{}}},
+			"project_ids": []uuid.UUID{},
+		})},
+	{ID: "updateTeam", Method: "PUT", Path: "/teams/:id", Tag: "teams", Summary: "Change a team",
+		Body: models.UpdateTeamRequest{}, Responses: ok(obj{"team": models.Team{}})},
+	{ID: "deleteTeam", Method: "DELETE", Path: "/teams/:id", Tag: "teams", Summary: "Delete a team",
+		Responses: ok(message)},
+	{ID: "addTeamMember", Method: "POST", Path: "/teams/:id/members", Tag: "teams", Summary: "Add a member",
+		Body: models.AddTeamMemberRequest{}, Responses: created(obj{"member": models.TeamMember{}})},
+	{ID: "updateTeamMember", Method: "PUT", Path: "/teams/:id/members/:userID", Tag: "teams", Summary: "Change a member's role or capacity",
+		Body: models.UpdateTeamMemberRequest{}, Responses: ok(obj{"member": models.TeamMember{}})},
+	{ID: "removeTeamMember", Method: "DELETE", Path: "/teams/:id/members/:userID", Tag: "teams", Summary: "Remove a member",
+		Responses: ok(message)},
+	{ID: "getTeamWorkload", Method: "GET", Path: "/teams/:id/workload", Tag: "teams", Summary: "Workload of a team per member",
+		Responses: ok(obj{"workload": models.TeamWorkload{}})},
+
+	// Tasks
+	{ID: "createTask", Method: "POST", Path: "/tasks/", Tag: "tasks", Summary: "Create a task",
+		Params: taskParams, Body: models.CreateTaskRequest{}, Responses: created(obj{"task": models.TaskResponse{}})},
+	{ID: "getProjectTasks", Method: "GET", Path: "/tasks/project/:projectID", Tag: "tasks", Summary: "Tasks of a project",
+		Params: taskParams, Responses: ok(obj{"tasks": []models.TaskResponse{}})},
+	{ID: "getTask", Method: "GET", Path: "/tasks/:id", Tag: "tasks", Summary: "A task with its comments",
+		Params: taskParams, Responses: ok(obj{
+			"task": models.TaskResponse{},
+			// Also in the task; kept for older clients
+			"status":   &models.TaskStatus{},
+			"assignee": &models.UserResponse{},
+			"reporter": &models.UserResponse{},
+			"comments": []models.CommentResponse{},
+		})},
+	{ID: "updateTask", Method: "PUT", Path: "/tasks/:id", Tag: "tasks", Summary: "Change a task",
+		Params: taskParams, Body: models.UpdateTaskRequest{}, Responses: ok(obj{"task": models.TaskResponse{}})},
+	{ID: "updateTaskStatus", Method: "PATCH", Path: "/tasks/:id/status", Tag: "tasks", Summary: "Move a task to another status",
+		Params: taskParams, Body: models.UpdateTaskStatusRequest{}, Responses: ok(obj{"task": models.TaskResponse{}})},
+	{ID: "deleteTask", Method: "DELETE", Path: "/tasks/:id", Tag: "tasks", Summary: "Delete a task",
+		Responses: ok(message)},
+	{ID: "addTaskComment", Method: "POST", Path: "/tasks/:id/comments", Tag: "tasks", Summary: "Comment on a task",
+		Body: models.CreateCommentRequest{}, Responses: created(obj{"comment": models.CommentResponse{}})},
+	{ID: "getTaskStatuses", Method: "GET", Path: "/statuses/project/:projectID", Tag: "tasks", Summary: "Status columns of a project",
+		Responses: ok(obj{"statuses": []models.TaskStatus{}})},
+
+	// Notifications
+	{ID: "getNotifications", Method: "GET", Path: "/notifications/", Tag: "notifications", Summary: "Notifications, newest first",
+		Params: []openapi.Param{
+			openapi.Query("type", "Only notifications of this type", ""),
+			openapi.Query("read", "Only read (true) or unread (false) notifications", true),
+			openapi.Query("cursor", "next_cursor of the previous page", ""),
+			limitParam(100),
+		},
+		Responses: ok(obj{"notifications": []models.Notification{}, "next_cursor": ""})},
+	{ID: "getUnreadNotificationCount", Method: "GET", Path: "/notifications/unread-count", Tag: "notifications", Summary: "Number of unread notifications",
+		Responses: ok(obj{"unread_count": 0})},
+	{ID: "markNotificationRead", Method: "PATCH", Path: "/notifications/:id", Tag: "notifications", Summary: "Mark a notification read or unread",
+		Body: models.MarkNotificationReadRequest{}, Responses: ok(obj{"notification": models.Notification{}})},
+	{ID: "markAllNotificationsRead", Method: "PATCH", Path: "/notifications/", Tag: "notifications", Summary: "Mark every notification read",
+		Responses: ok(message)},
+	{ID: "deleteNotification", Method: "DELETE", Path: "/notifications/:id", Tag: "notifications", Summary: "Delete a notification",
+		Responses: ok(message)},
+	{ID: "deleteNotifications", Method: "DELETE", Path: "/notifications/", Tag: "notifications", Summary: "Delete several notifications, or all read ones",
+		Body: models.DeleteNotificationsRequest{}, Responses: ok(obj{"deleted": 0})},
+
+	// Resources
+	{ID: "getResourceAllocations", Method: "GET", Path: "/resources/allocations", Tag: "resources", Summary: "Resource allocations, newest first",
+		Params: []openapi.Param{
+			openapi.Query("user_id", "Only allocations of this user", uuid.UUID{}),
+			openapi.Query("project_id", "Only allocations to this project", uuid.UUID{}),
+			openapi.Query("include", "Related records to embed: user, project. Both without the parameter, none when empty", ""),
+			openapi.Query("cursor", "next_cursor of the previous page", ""),
+			limitParam(200),
+		},
+		Responses: ok(obj{"allocations": []models.ResourceAllocation{}, "next_cursor": ""})},
+	{ID: "createResourceAllocation", Method: "POST", Path: "/resources/allocations", Tag: "resources", Summary: "Allocate a user to a project",
+		Body: models.ResourceAllocation{}, Responses: created(obj{"allocation": models.ResourceAllocation{}})},
+	{ID: "updateResourceAllocation", Method: "PUT", Path: "/resources/allocations/:id", Tag: "resources", Summary: "Change an allocation",
+		Params: []openapi.Param{openapi.PathParam("id", "Allocation ID", 0)},
+		Body:   models.ResourceAllocation{}, Responses: ok(obj{"allocation": models.ResourceAllocation{}})},
+	{ID: "deleteResourceAllocation", Method: "DELETE", Path: "/resources/allocations/:id", Tag: "resources", Summary: "Delete an allocation",
+		Params:    []openapi.Param{openapi.PathParam("id", "Allocation ID", 0)},
+		Responses: map[int]interface{}{http.StatusNoContent: nil}},
+	{ID: "getUserAvailability", Method: "GET", Path: "/resources/availability", Tag: "resources", Summary: "Weekly availability",
+		Params:    []openapi.Param{openapi.Query("user_id", "User, the current user by default", uuid.UUID{})},
+		Responses: ok(obj{"availability": []models.UserAvailability{}})},
+	{ID: "setUserAvailability", Method: "POST", Path: "/resources/availability", Tag: "resources", Summary: "Add an availability slot",
+		Body: models.UserAvailability{}, Responses: created(obj{"availability": models.UserAvailability{}})},
+	{ID: "getTimeOffRequests", Method: "GET", Path: "/resources/timeoff", Tag: "resources", Summary: "Time off requests, newest first",
+		Params: []openapi.Param{
+			openapi.Query("user_id", "Only requests of this user", uuid.UUID{}),
+			openapi.Query("status", "pending, approved or rejected", ""),
+			openapi.Query("include", "Related records to embed: user. Included without the parameter, not when empty", ""),
+			openapi.Query("cursor", "next_cursor of the previous page", ""),
+			limitParam(200),
+		},
+		Responses: ok(obj{"requests": []models.TimeOffRequest{}, "next_cursor": ""})},
+	{ID: "createTimeOffRequest", Method: "POST", Path: "/resources/timeoff", Tag: "resources", Summary: "Request time off",
+		Body: models.TimeOffRequest{}, Responses: created(obj{"request": models.TimeOffRequest{}})},
+	{ID: "updateTimeOffRequestStatus", Method: "PUT", Path: "/resources/timeoff/:id", Tag: "resources", Summary: "Approve or reject time off",
+		Params: []openapi.Param{openapi.PathParam("id", "Time off request ID", 0)},
+		Body:   models.UpdateTimeOffStatusRequest{}, Responses: ok(obj{"request": models.TimeOffRequest{}})},
+}
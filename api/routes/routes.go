@@ -2,73 +2,212 @@ package routes
 
 import (
 	"database/sql"
+	"time"
+
 	"github.com/gofiber/fiber/v2"
 	"github.com/projectflow/api/handlers"
 	"github.com/projectflow/api/middleware"
+	"github.com/projectflow/api/openapi"
+	"github.com/projectflow/models"
 )
 
+// When the unversioned /api paths became aliases of /api/v1
+var legacyDeprecatedAt = time.Date(2026, time.October, 18, 0, 0, 0, 0, time.UTC)
+
 // SetupRoutes sets up all the routes for the application
 func SetupRoutes(app *fiber.App, db *sql.DB) {
 	// Initialize handlers
 	resourceHandler := handlers.NewResourceHandler(db)
-	// API group
-	api := app.Group("/api")
+	// Public keys for verifying access tokens
+	app.Get("/.well-known/jwks.json", handlers.GetJWKS)
+
+	// Record what impersonated sessions do, once for any API path
+	app.Use(apiPrefix, handlers.AuditImpersonation())
+
+	// Each API version registers its routes below its own prefix. A version
+	// that changes response shapes gets its own register function, reusing
+	// the handlers of the routes that stay the same.
+	registerV1(app.Group(v1Prefix), resourceHandler)
+
+	// The unversioned paths of the first release, now deprecated aliases of
+	// v1. Registered last since the group also matches the versioned paths.
+	legacy := app.Group(apiPrefix, middleware.Deprecated(legacyDeprecatedAt, apiPrefix, v1Prefix))
+	registerV1(legacy, resourceHandler)
+}
+
+// registerV1 adds the routes of version 1 of the API
+func registerV1(api fiber.Router, resourceHandler *handlers.ResourceHandler) {
+	// API description, see openapi.go
+	api.Get("/openapi.json", openapi.Handler(OpenAPI))
+	api.Get("/docs", openapi.Viewer("ProjectFlow API", v1Prefix+"/openapi.json"))
 
 	// Auth routes
-	auth := api.Group("/auth")
+	auth := api.Group("/auth", middleware.RateLimit("auth"))
 	auth.Post("/register", handlers.RegisterUser)
 	auth.Post("/login", handlers.Login)
-	auth.Get("/me", middleware.Protected(), handlers.GetCurrentUser)
+	auth.Post("/login/2fa", handlers.CompleteTwoFactorLogin)
+	auth.Get("/me", middleware.Protected(), middleware.RequireScope(models.ScopeReadUsers), handlers.GetCurrentUser)
+	auth.Post("/refresh", handlers.RefreshSession)
+	auth.Get("/password-policy", handlers.GetPasswordPolicy)
+	auth.Post("/verify-email", handlers.VerifyEmail)
+	auth.Post("/verify-email/resend", middleware.Protected(), middleware.SessionOnly(), handlers.ResendVerificationEmail)
+	auth.Post("/forgot-password", handlers.ForgotPassword)
+	auth.Post("/reset-password", handlers.ResetPassword)
+	auth.Post("/logout", middleware.Protected(), middleware.SessionOnly(), handlers.Logout)
+	auth.Post("/logout-all", middleware.Protected(), middleware.SessionOnly(), handlers.LogoutAll)
+
+	// Single sign-on routes
+	auth.Get("/oidc", handlers.GetOIDCProviders)
+	auth.Get("/oidc/:provider", handlers.StartOIDCLogin)
+	auth.Get("/oidc/:provider/callback", handlers.OIDCCallback)
+
+	// Current user routes
+	me := api.Group("/me", middleware.Protected(), middleware.RateLimit("default"))
+	me.Get("/notification-preferences", middleware.RequireScope(models.ScopeReadNotifications), handlers.GetNotificationPreferences)
+	me.Put("/notification-preferences", middleware.RequireScope(models.ScopeWriteNotifications), handlers.UpdateNotificationPreferences)
+	me.Patch("/", middleware.SessionOnly(), handlers.UpdateProfile)
+	me.Put("/avatar", middleware.SessionOnly(), handlers.UploadAvatar)
+	me.Delete("/avatar", middleware.SessionOnly(), handlers.DeleteAvatar)
+	me.Put("/password", middleware.SessionOnly(), handlers.ChangePassword)
+
+	// API key routes (only manageable from a signed-in session)
+	apiKeys := me.Group("/api-keys", middleware.SessionOnly())
+	apiKeys.Get("/", handlers.GetAPIKeys)
+	apiKeys.Post("/", handlers.CreateAPIKey)
+	apiKeys.Delete("/:id", handlers.RevokeAPIKey)
+
+	// Two-factor authentication routes
+	twoFactor := me.Group("/2fa", middleware.SessionOnly())
+	twoFactor.Get("/", handlers.GetTwoFactorStatus)
+	twoFactor.Post("/setup", handlers.SetupTwoFactor)
+	twoFactor.Post("/confirm", handlers.ConfirmTwoFactor)
+	twoFactor.Post("/disable", handlers.DisableTwoFactor)
+	twoFactor.Post("/recovery-codes", handlers.RegenerateRecoveryCodes)
+
+	// Organization routes (only manageable from a signed-in session)
+	orgs := api.Group("/organizations", middleware.Protected(), middleware.RateLimit("default"), middleware.SessionOnly())
+	orgs.Get("/", handlers.GetOrganizations)
+	orgs.Post("/", handlers.CreateOrganization)
+	orgs.Post("/switch", handlers.SwitchOrganization)
+	orgs.Post("/invites/accept", handlers.AcceptOrganizationInvite)
+	orgs.Get("/current", handlers.GetCurrentOrganization)
+	orgs.Put("/current", handlers.UpdateCurrentOrganization)
+	orgs.Delete("/current/members/:userID", handlers.RemoveOrganizationMember)
+	orgs.Get("/current/invites", handlers.GetOrganizationInvites)
+	orgs.Post("/current/invites", handlers.CreateOrganizationInvite)
+	orgs.Delete("/current/invites/:id", handlers.RevokeOrganizationInvite)
+
+	// Project invitation routes. The token is all that's needed to accept.
+	projectInvites := api.Group("/project-invites", middleware.RateLimit("auth"))
+	projectInvites.Get("/", handlers.GetProjectInviteByToken)
+	projectInvites.Post("/accept", handlers.AcceptProjectInvite)
+
+	// Admin routes
+	admin := api.Group("/admin", middleware.Protected(), middleware.RateLimit("default"), middleware.AdminOnly())
+	admin.Get("/security", handlers.GetSecuritySettings)
+	admin.Put("/security", handlers.UpdateSecuritySettings)
+	admin.Get("/login-attempts", handlers.GetLoginAttempts)
+	admin.Get("/lockouts", handlers.GetLockout)
+	admin.Delete("/lockouts", handlers.ClearLockout)
+	admin.Get("/rate-limits", handlers.GetRateLimitUsage)
+
+	// Admin user management, within the admin's organization
+	admin.Put("/users/:id", handlers.UpdateUserProfile)
+	admin.Put("/users/:id/role", handlers.UpdateUserRole)
+	admin.Post("/users/:id/deactivate", handlers.DeactivateUser)
+	admin.Post("/users/:id/reactivate", handlers.ReactivateUser)
+	admin.Post("/users/:id/reassign", resourceHandler.ReassignUserWork)
+	admin.Post("/users/:id/impersonate", middleware.SessionOnly(), handlers.StartImpersonation)
+	admin.Get("/impersonations", handlers.GetImpersonations)
+	admin.Delete("/impersonations/:id", handlers.EndImpersonation)
 
 	// User routes
-	users := api.Group("/users", middleware.Protected())
+	users := api.Group("/users", middleware.Protected(), middleware.RateLimit("default"), middleware.RequireScope(models.ScopeReadUsers))
 	users.Get("/", middleware.AdminOnly(), handlers.GetAllUsers)
 	users.Get("/:id", handlers.GetUserByID)
 
-	// Project routes
-	projects := api.Group("/projects", middleware.Protected())
+	// Project routes. Access within a project follows the member's project role.
+	can := middleware.Authorize
+	project := handlers.ProjectParam("id")
+	projects := api.Group("/projects", middleware.Protected(), middleware.RateLimit("default"), middleware.RequireResourceScope("projects"))
 	projects.Post("/", handlers.CreateProject)
 	projects.Get("/", handlers.GetAllProjects)
-	projects.Get("/:id", handlers.GetProjectByID)
-	projects.Put("/:id", handlers.UpdateProject)
-	projects.Delete("/:id", handlers.DeleteProject)
-	projects.Post("/:id/members", handlers.AddProjectMember)
-	projects.Delete("/:id/members/:memberID", handlers.RemoveProjectMember)
+	projects.Get("/:id", can(models.PermProjectView, project), handlers.GetProjectByID)
+	projects.Put("/:id", can(models.PermProjectEdit, project), handlers.UpdateProject)
+	projects.Delete("/:id", can(models.PermProjectDelete, project), handlers.DeleteProject)
+	projects.Post("/:id/members", can(models.PermMemberManage, project), handlers.AddProjectMember)
+	projects.Put("/:id/members/:memberID", can(models.PermMemberManage, project), handlers.UpdateProjectMemberRole)
+	projects.Delete("/:id/members/:memberID", can(models.PermMemberManage, project), handlers.RemoveProjectMember)
+	projects.Get("/:id/roles", can(models.PermProjectView, project), handlers.GetProjectRoles)
+	projects.Post("/:id/roles", can(models.PermRoleManage, project), handlers.CreateProjectRole)
+	projects.Put("/:id/roles/:role", can(models.PermRoleManage, project), handlers.UpdateProjectRole)
+	projects.Delete("/:id/roles/:role", can(models.PermRoleManage, project), handlers.DeleteProjectRole)
+	projects.Get("/:id/webhooks", can(models.PermWebhookManage, project), handlers.GetProjectWebhooks)
+	projects.Post("/:id/webhooks", can(models.PermWebhookManage, project), handlers.CreateProjectWebhook)
+	projects.Put("/:id/webhooks/:webhookID", can(models.PermWebhookManage, project), handlers.UpdateProjectWebhook)
+	projects.Delete("/:id/webhooks/:webhookID", can(models.PermWebhookManage, project), handlers.DeleteProjectWebhook)
+	projects.Get("/:id/webhooks/:webhookID/deliveries", can(models.PermWebhookManage, project), handlers.GetWebhookDeliveries)
+	projects.Post("/:id/webhooks/:webhookID/deliveries/:deliveryID/redeliver", can(models.PermWebhookManage, project), handlers.RedeliverWebhookDelivery)
+	projects.Get("/:id/invites", can(models.PermMemberManage, project), handlers.GetProjectInvites)
+	projects.Post("/:id/invites", can(models.PermMemberManage, project), handlers.CreateProjectInvite)
+	projects.Post("/:id/invites/:inviteID/resend", can(models.PermMemberManage, project), handlers.ResendProjectInvite)
+	projects.Delete("/:id/invites/:inviteID", can(models.PermMemberManage, project), handlers.RevokeProjectInvite)
+	projects.Get("/:id/teams", can(models.PermProjectView, project), handlers.GetProjectTeams)
+	projects.Post("/:id/teams", can(models.PermMemberManage, project), handlers.AddProjectTeam)
+	projects.Delete("/:id/teams/:teamID", can(models.PermMemberManage, project), handlers.RemoveProjectTeam)
+	projects.Get("/:id/assignment-suggestions", can(models.PermTaskCreate, project), handlers.GetAssignmentSuggestions)
+
+	// Team routes. Organization admins manage teams, leads their team's members.
+	teams := api.Group("/teams", middleware.Protected(), middleware.RateLimit("default"), middleware.RequireResourceScope("projects"))
+	teams.Get("/", handlers.GetTeams)
+	teams.Post("/", handlers.CreateTeam)
+	teams.Get("/workload", handlers.GetTeamsWorkload)
+	teams.Get("/:id", handlers.GetTeam)
+	teams.Put("/:id", handlers.UpdateTeam)
+	teams.Delete("/:id", handlers.DeleteTeam)
+	teams.Post("/:id/members", handlers.AddTeamMember)
+	teams.Put("/:id/members/:userID", handlers.UpdateTeamMember)
+	teams.Delete("/:id/members/:userID", handlers.RemoveTeamMember)
+	teams.Get("/:id/workload", handlers.GetTeamWorkload)
 
 	// Task routes
-	tasks := api.Group("/tasks", middleware.Protected())
-	tasks.Post("/", handlers.CreateTask)
-	tasks.Get("/project/:projectID", handlers.GetAllTasks)
-	tasks.Get("/:id", handlers.GetTaskByID)
-	tasks.Put("/:id", handlers.UpdateTask)
-	tasks.Patch("/:id/status", handlers.UpdateTaskStatus)
-	tasks.Delete("/:id", handlers.DeleteTask)
-	tasks.Post("/:id/comments", handlers.AddTaskComment)
+	taskProject := handlers.TaskProject
+	tasks := api.Group("/tasks", middleware.Protected(), middleware.RateLimit("default"), middleware.RequireResourceScope("tasks"))
+	tasks.Post("/", can(models.PermTaskCreate, handlers.ProjectInBody), handlers.CreateTask)
+	tasks.Get("/project/:projectID", can(models.PermProjectView, handlers.ProjectParam("projectID")), handlers.GetAllTasks)
+	tasks.Get("/:id", can(models.PermProjectView, taskProject), handlers.GetTaskByID)
+	tasks.Put("/:id", can(models.PermTaskEdit, taskProject), handlers.UpdateTask)
+	tasks.Patch("/:id/status", can(models.PermTaskEdit, taskProject), handlers.UpdateTaskStatus)
+	tasks.Delete("/:id", can(models.PermTaskEdit, taskProject), handlers.DeleteTask) // Also checks task:delete
+	tasks.Post("/:id/comments", can(models.PermTaskComment, taskProject), handlers.AddTaskComment)
 
 	// Task status routes
-	statuses := api.Group("/statuses", middleware.Protected())
-	statuses.Get("/project/:projectID", handlers.GetTaskStatuses)
+	statuses := api.Group("/statuses", middleware.Protected(), middleware.RateLimit("default"), middleware.RequireResourceScope("tasks"))
+	statuses.Get("/project/:projectID", can(models.PermProjectView, handlers.ProjectParam("projectID")), handlers.GetTaskStatuses)
 
 	// Notification routes
-	notifications := api.Group("/notifications", middleware.Protected())
+	notifications := api.Group("/notifications", middleware.Protected(), middleware.RateLimit("default"), middleware.RequireResourceScope("notifications"))
 	notifications.Get("/", handlers.GetUserNotifications)
+	notifications.Get("/unread-count", handlers.GetUnreadNotificationCount)
 	notifications.Patch("/:id", handlers.MarkNotificationRead)
 	notifications.Patch("/", handlers.MarkAllNotificationsRead)
+	notifications.Delete("/:id", handlers.DeleteNotification)
+	notifications.Delete("/", handlers.DeleteNotifications)
 
 	// Resource management routes
-	resources := api.Group("/resources", middleware.Protected())
+	resources := api.Group("/resources", middleware.Protected(), middleware.RateLimit("resources"), middleware.RequireResourceScope("resources"))
 	
 	// Resource allocation routes
 	resources.Get("/allocations", resourceHandler.GetResourceAllocations)
-	resources.Post("/allocations", resourceHandler.CreateResourceAllocation)
-	resources.Put("/allocations/:id", resourceHandler.UpdateResourceAllocation)
-	resources.Delete("/allocations/:id", resourceHandler.DeleteResourceAllocation)
+	resources.Post("/allocations", can(models.PermAllocationManage, handlers.ProjectInBody), resourceHandler.CreateResourceAllocation)
+	resources.Put("/allocations/:id", can(models.PermAllocationManage, resourceHandler.AllocationProject), resourceHandler.UpdateResourceAllocation)
+	resources.Delete("/allocations/:id", can(models.PermAllocationManage, resourceHandler.AllocationProject), resourceHandler.DeleteResourceAllocation)
 
 	// User availability routes
 	resources.Get("/availability", resourceHandler.GetUserAvailability)
 	resources.Post("/availability", resourceHandler.SetUserAvailability)
 
-	// Time off request routes
+	// Time off request routes. Decisions are limited to the requester's managers.
 	resources.Get("/timeoff", resourceHandler.GetTimeOffRequests)
 	resources.Post("/timeoff", resourceHandler.CreateTimeOffRequest)
 	resources.Put("/timeoff/:id", resourceHandler.UpdateTimeOffRequestStatus)
@@ -0,0 +1,131 @@
+// Package apperr defines the errors handlers return instead of writing error
+// responses themselves. Every error has a kind, which decides the HTTP
+// status, and a stable machine-readable code clients can rely on. The detail
+// is meant for people and may change.
+package apperr
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Kind classifies an error independently of the transport
+type Kind int
+
+const (
+	KindInternal         Kind = iota // Something failed on our side
+	KindInvalid                      // The request is malformed or breaks a rule
+	KindUnauthenticated              // Credentials are missing or wrong
+	KindForbidden                    // The caller may not do this
+	KindNotFound                     // The resource doesn't exist or isn't visible
+	KindConflict                     // The resource's current state doesn't allow it
+	KindTooLarge                     // The upload is too big
+	KindUnsupportedMedia             // The upload has the wrong type
+	KindValidation                   // Request fields break their validate rules
+	KindRateLimited                  // Too many requests, try again later
+	KindUnavailable                  // A service we depend on failed
+	KindGone                         // The resource or route was removed for good
+)
+
+// Error is an application error with a stable code
+type Error struct {
+	Kind   Kind
+	Code   string
+	Detail string
+	// Extra members for the response, e.g. the broken fields of a request
+	Extra map[string]interface{}
+
+	// What caused the error, logged but never sent to clients
+	cause error
+}
+
+// New creates an error of a kind
+func New(kind Kind, code, detail string) *Error {
+	return &Error{Kind: kind, Code: code, Detail: detail}
+}
+
+// Internal reports a failure on our side. The detail is sent to clients, so
+// causes belong in Wrap.
+func Internal(code, detail string) *Error { return New(KindInternal, code, detail) }
+
+// Invalid reports a malformed request or one that breaks a rule
+func Invalid(code, detail string) *Error { return New(KindInvalid, code, detail) }
+
+// Unauthenticated reports missing or wrong credentials
+func Unauthenticated(code, detail string) *Error { return New(KindUnauthenticated, code, detail) }
+
+// Forbidden reports an action the caller may not take
+func Forbidden(code, detail string) *Error { return New(KindForbidden, code, detail) }
+
+// NotFound reports a resource that doesn't exist or isn't visible to the caller
+func NotFound(code, detail string) *Error { return New(KindNotFound, code, detail) }
+
+// Conflict reports an action the current state of a resource doesn't allow
+func Conflict(code, detail string) *Error { return New(KindConflict, code, detail) }
+
+// TooLarge reports an upload over the size limit
+func TooLarge(code, detail string) *Error { return New(KindTooLarge, code, detail) }
+
+// UnsupportedMedia reports an upload of the wrong type
+func UnsupportedMedia(code, detail string) *Error { return New(KindUnsupportedMedia, code, detail) }
+
+// RateLimited reports that the caller has to slow down
+func RateLimited(code, detail string) *Error { return New(KindRateLimited, code, detail) }
+
+// Unavailable reports a failure of a service we depend on
+func Unavailable(code, detail string) *Error { return New(KindUnavailable, code, detail) }
+
+// Gone reports a resource or route that was removed for good
+func Gone(code, detail string) *Error { return New(KindGone, code, detail) }
+
+// Errors used in many places
+var (
+	ErrUnauthorized   = Unauthenticated("unauthorized", "Unauthorized")
+	ErrInvalidBody    = Invalid("invalid_body", "Invalid request body")
+	ErrUserNotFound   = NotFound("user_not_found", "User not found")
+	ErrOrgAdminNeeded = Forbidden("organization_admin_required", "Organization admin access required")
+)
+
+func (e *Error) Error() string {
+	if e.cause != nil {
+		return fmt.Sprintf("%s: %s: %v", e.Code, e.Detail, e.cause)
+	}
+	return e.Code + ": " + e.Detail
+}
+
+// Unwrap returns the cause
+func (e *Error) Unwrap() error {
+	return e.cause
+}
+
+// Is matches errors by code, so copies made by With and Wrap still match
+// the error they came from
+func (e *Error) Is(target error) bool {
+	var other *Error
+	return errors.As(target, &other) && other.Code == e.Code
+}
+
+// With returns a copy of the error with an extra response member
+func (e *Error) With(key string, value interface{}) *Error {
+	copied := *e
+	copied.Extra = make(map[string]interface{}, len(e.Extra)+1)
+	for k, v := range e.Extra {
+		copied.Extra[k] = v
+	}
+	copied.Extra[key] = value
+	return &copied
+}
+
+// Wrap returns a copy of the error that records its cause
+func (e *Error) Wrap(cause error) *Error {
+	copied := *e
+	copied.cause = cause
+	return &copied
+}
+
+// As finds the application error in an error chain
+func As(err error) (*Error, bool) {
+	var appErr *Error
+	ok := errors.As(err, &appErr)
+	return appErr, ok
+}
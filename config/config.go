@@ -1,13 +1,19 @@
 package config
 
 import (
+	"errors"
 	"log"
 	"os"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
 
+// Development fallback for JWT_SECRET, refused in production
+const defaultJWTSecret = "your_jwt_secret_key"
+
 // Config holds all configuration for the application
 type Config struct {
 	DBHost     string
@@ -18,6 +24,81 @@ type Config struct {
 	ServerPort string
 	JWTSecret  string
 	Env        string
+
+	// Access token signing. The private key signs new tokens; the
+	// verification keys are older keys still accepted during rotation.
+	JWTPrivateKeyFile       string
+	JWTVerificationKeyFiles []string
+
+	// Email delivery (defaults target a local MailHog sink)
+	SMTPHost     string
+	SMTPPort     int
+	SMTPUsername string
+	SMTPPassword string
+	SMTPFrom     string
+	AppURL       string
+
+	// Read notifications older than this are purged
+	NotificationRetentionDays int
+
+	// Public base URL of this API, used for OAuth redirect URIs
+	APIURL string
+
+	// Single sign-on providers, from OIDC_PROVIDERS
+	OIDCProviders []OIDCProvider
+
+	// Password policy
+	PasswordMinLength        int
+	PasswordRequireMixedCase bool
+	PasswordRequireDigit     bool
+	PasswordRequireSymbol    bool
+	PasswordBreachedListFile string
+	RequireEmailVerification bool
+
+	// Profile pictures are stored in AvatarDir and served from /avatars
+	AvatarDir   string
+	AvatarMaxKB int
+
+	// Login throttling. "memory" counts per process, "postgres" shares the
+	// counts between replicas.
+	RateLimitBackend        string
+	LoginRateWindowMinutes  int
+	LoginIPLimit            int
+	LoginAccountLimit       int
+	RegisterIPLimit         int // Per hour
+	LockoutThreshold        int
+	LockoutBaseDelaySeconds int
+	LockoutMaxDelayMinutes  int
+
+	// Header holding the client address when running behind a proxy
+	ProxyHeader string
+
+	// API rate limits per route group, from RATE_LIMITS
+	RateLimitEnabled bool
+	RateLimits       map[string]RateLimit
+
+	// Date the unversioned /api paths stop working, from LEGACY_API_SUNSET
+	LegacyAPISunset time.Time
+}
+
+// RateLimit allows Requests per Period for one route group. RATE_LIMITS
+// lists them as "group=requests/period", e.g. "resources=120/1m".
+type RateLimit struct {
+	Requests int
+	Period   time.Duration
+}
+
+// OIDCProvider configures one OpenID Connect provider. Settings are read
+// from OIDC_<NAME>_ISSUER, _CLIENT_ID, _CLIENT_SECRET, _SCOPES, _GROUPS_CLAIM
+// and _ROLE_MAPPING ("group:role,group:role").
+type OIDCProvider struct {
+	Name         string
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	Scopes       []string
+	GroupsClaim  string
+	RoleMapping  map[string]string
 }
 
 // LoadConfig loads the configuration from environment variables
@@ -35,9 +116,122 @@ func LoadConfig() *Config {
 		DBPassword: getEnv("DB_PASSWORD", "postgres"),
 		DBName:     getEnv("DB_NAME", "projectflow"),
 		ServerPort: getEnv("SERVER_PORT", "8080"),
-		JWTSecret:  getEnv("JWT_SECRET", "your_jwt_secret_key"),
+		JWTSecret:  getEnv("JWT_SECRET", defaultJWTSecret),
 		Env:        getEnv("ENV", "development"),
+
+		JWTPrivateKeyFile:       getEnv("JWT_PRIVATE_KEY_FILE", ""),
+		JWTVerificationKeyFiles: getEnvAsList("JWT_VERIFICATION_KEY_FILES"),
+
+		SMTPHost:     getEnv("SMTP_HOST", "localhost"),
+		SMTPPort:     getEnvAsInt("SMTP_PORT", 1025),
+		SMTPUsername: getEnv("SMTP_USERNAME", ""),
+		SMTPPassword: getEnv("SMTP_PASSWORD", ""),
+		SMTPFrom:     getEnv("SMTP_FROM", "ProjectFlow <no-reply@projectflow.local>"),
+		AppURL:       getEnv("APP_URL", "http://localhost:5173"),
+
+		NotificationRetentionDays: getEnvAsInt("NOTIFICATION_RETENTION_DAYS", 30),
+
+		APIURL:        getEnv("API_URL", "http://localhost:8080"),
+		OIDCProviders: loadOIDCProviders(),
+
+		PasswordMinLength:        getEnvAsInt("PASSWORD_MIN_LENGTH", 10),
+		PasswordRequireMixedCase: getEnvAsBool("PASSWORD_REQUIRE_MIXED_CASE", false),
+		PasswordRequireDigit:     getEnvAsBool("PASSWORD_REQUIRE_DIGIT", false),
+		PasswordRequireSymbol:    getEnvAsBool("PASSWORD_REQUIRE_SYMBOL", false),
+		PasswordBreachedListFile: getEnv("PASSWORD_BREACHED_LIST_FILE", ""),
+		RequireEmailVerification: getEnvAsBool("REQUIRE_EMAIL_VERIFICATION", false),
+
+		AvatarDir:   getEnv("AVATAR_DIR", "uploads/avatars"),
+		AvatarMaxKB: getEnvAsInt("AVATAR_MAX_KB", 1024),
+
+		RateLimitBackend:        getEnv("RATE_LIMIT_BACKEND", "memory"),
+		LoginRateWindowMinutes:  getEnvAsInt("LOGIN_RATE_WINDOW_MINUTES", 15),
+		LoginIPLimit:            getEnvAsInt("LOGIN_IP_LIMIT", 20),
+		LoginAccountLimit:       getEnvAsInt("LOGIN_ACCOUNT_LIMIT", 10),
+		RegisterIPLimit:         getEnvAsInt("REGISTER_IP_LIMIT", 10),
+		LockoutThreshold:        getEnvAsInt("LOCKOUT_THRESHOLD", 5),
+		LockoutBaseDelaySeconds: getEnvAsInt("LOCKOUT_BASE_DELAY_SECONDS", 60),
+		LockoutMaxDelayMinutes:  getEnvAsInt("LOCKOUT_MAX_DELAY_MINUTES", 60),
+
+		ProxyHeader: getEnv("PROXY_HEADER", ""),
+
+		RateLimitEnabled: getEnvAsBool("RATE_LIMIT_ENABLED", true),
+		RateLimits:       loadRateLimits(),
+
+		LegacyAPISunset: getEnvAsDate("LEGACY_API_SUNSET", "2027-04-30"),
+	}
+}
+
+// loadOIDCProviders reads the providers listed in OIDC_PROVIDERS
+func loadOIDCProviders() []OIDCProvider {
+	var providers []OIDCProvider
+	for _, name := range getEnvAsList("OIDC_PROVIDERS") {
+		name = strings.ToLower(name)
+		prefix := "OIDC_" + strings.ToUpper(name) + "_"
+
+		provider := OIDCProvider{
+			Name:         name,
+			Issuer:       getEnv(prefix+"ISSUER", ""),
+			ClientID:     getEnv(prefix+"CLIENT_ID", ""),
+			ClientSecret: getEnv(prefix+"CLIENT_SECRET", ""),
+			Scopes:       strings.Fields(getEnv(prefix+"SCOPES", "openid email profile")),
+			GroupsClaim:  getEnv(prefix+"GROUPS_CLAIM", "groups"),
+			RoleMapping:  make(map[string]string),
+		}
+		for _, pair := range getEnvAsList(prefix + "ROLE_MAPPING") {
+			if group, role, ok := strings.Cut(pair, ":"); ok {
+				provider.RoleMapping[strings.TrimSpace(group)] = strings.TrimSpace(role)
+			}
+		}
+
+		if provider.Issuer == "" || provider.ClientID == "" {
+			log.Printf("Warning: OIDC provider %q is missing an issuer or client ID, skipping", name)
+			continue
+		}
+		providers = append(providers, provider)
+	}
+	return providers
+}
+
+// loadRateLimits reads the per group limits listed in RATE_LIMITS
+func loadRateLimits() map[string]RateLimit {
+	limits := make(map[string]RateLimit)
+	for _, entry := range getEnvAsList("RATE_LIMITS") {
+		group, spec, _ := strings.Cut(entry, "=")
+		requests, period, _ := strings.Cut(spec, "/")
+
+		limit := RateLimit{}
+		limit.Requests, _ = strconv.Atoi(strings.TrimSpace(requests))
+		limit.Period, _ = time.ParseDuration(strings.TrimSpace(period))
+		if group == "" || limit.Requests <= 0 || limit.Period <= 0 {
+			log.Printf("Warning: invalid rate limit %q, expected group=requests/period", entry)
+			continue
+		}
+		limits[strings.TrimSpace(group)] = limit
+	}
+	return limits
+}
+
+// IsProduction reports whether the app runs in production
+func (c *Config) IsProduction() bool {
+	return c.Env == "production"
+}
+
+// Validate refuses invalid settings and those only acceptable in development
+func (c *Config) Validate() error {
+	if c.RateLimitBackend != "memory" && c.RateLimitBackend != "postgres" {
+		return errors.New(`RATE_LIMIT_BACKEND must be "memory" or "postgres"`)
+	}
+	if !c.IsProduction() {
+		return nil
+	}
+	if c.JWTSecret == "" || c.JWTSecret == defaultJWTSecret {
+		return errors.New("JWT_SECRET must be set to a non-default value in production")
 	}
+	if c.JWTPrivateKeyFile == "" {
+		return errors.New("JWT_PRIVATE_KEY_FILE must be set in production")
+	}
+	return nil
 }
 
 // Helper function to get an environment variable or a default value
@@ -49,6 +243,16 @@ func getEnv(key, defaultValue string) string {
 	return value
 }
 
+// Helper function to get an environment variable as a YYYY-MM-DD date (UTC)
+func getEnvAsDate(key, defaultValue string) time.Time {
+	if value, err := time.Parse("2006-01-02", getEnv(key, defaultValue)); err == nil {
+		return value
+	}
+	log.Printf("Warning: %s is not a YYYY-MM-DD date, using %s", key, defaultValue)
+	value, _ := time.Parse("2006-01-02", defaultValue)
+	return value
+}
+
 // Helper function to get an environment variable as an integer
 func getEnvAsInt(key string, defaultValue int) int {
 	valueStr := getEnv(key, "")
@@ -57,3 +261,22 @@ func getEnvAsInt(key string, defaultValue int) int {
 	}
 	return defaultValue
 }
+
+// Helper function to get an environment variable as a boolean
+func getEnvAsBool(key string, defaultValue bool) bool {
+	if value, err := strconv.ParseBool(getEnv(key, "")); err == nil {
+		return value
+	}
+	return defaultValue
+}
+
+// Helper function to get a comma separated environment variable as a list
+func getEnvAsList(key string) []string {
+	var values []string
+	for _, value := range strings.Split(getEnv(key, ""), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
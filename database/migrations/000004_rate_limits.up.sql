@@ -0,0 +1,10 @@
+-- Events counted by the sliding window rate limiter, shared by all replicas
+CREATE TABLE IF NOT EXISTS rate_limit_events (
+  id BIGSERIAL PRIMARY KEY,
+  key VARCHAR(255) NOT NULL,
+  occurred_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+-- Window counts look up the recent events of one key
+CREATE INDEX idx_rate_limit_events_key_occurred_at ON rate_limit_events(key, occurred_at);
+CREATE INDEX idx_rate_limit_events_occurred_at ON rate_limit_events(occurred_at);
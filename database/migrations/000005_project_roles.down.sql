@@ -0,0 +1,8 @@
+-- Fold the new roles back into admin and member
+UPDATE project_members SET role = 'admin' WHERE role IN ('owner', 'admin');
+UPDATE project_members SET role = 'member' WHERE role <> 'admin';
+
+ALTER TABLE project_members ALTER COLUMN role TYPE VARCHAR(20);
+ALTER TABLE project_members ADD CONSTRAINT project_members_role_check CHECK (role IN ('admin', 'member'));
+
+DROP TABLE IF EXISTS project_roles;
@@ -0,0 +1,19 @@
+-- Custom roles defined by a project, next to the built-in owner, admin,
+-- member, viewer and guest roles
+CREATE TABLE IF NOT EXISTS project_roles (
+  project_id UUID NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+  name VARCHAR(50) NOT NULL,
+  description TEXT,
+  permissions TEXT[] NOT NULL DEFAULT '{}',
+  created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (project_id, name)
+);
+
+-- Members may now hold any built-in or custom role
+ALTER TABLE project_members DROP CONSTRAINT IF EXISTS project_members_role_check;
+ALTER TABLE project_members ALTER COLUMN role TYPE VARCHAR(50);
+
+-- Owners get the owner role
+UPDATE project_members pm SET role = 'owner'
+FROM projects p
+WHERE p.id = pm.project_id AND p.owner_id = pm.user_id;
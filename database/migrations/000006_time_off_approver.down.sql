@@ -0,0 +1,3 @@
+ALTER TABLE time_off_requests
+  DROP COLUMN IF EXISTS decided_at,
+  DROP COLUMN IF EXISTS approver_id;
@@ -0,0 +1,4 @@
+-- Record who decided on a time off request and when
+ALTER TABLE time_off_requests
+  ADD COLUMN IF NOT EXISTS approver_id UUID REFERENCES users(id) ON DELETE SET NULL,
+  ADD COLUMN IF NOT EXISTS decided_at TIMESTAMP WITH TIME ZONE;
@@ -0,0 +1,57 @@
+-- Organizations are workspaces that own projects
+CREATE TABLE IF NOT EXISTS organizations (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  name VARCHAR(100) NOT NULL,
+  owner_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS organization_members (
+  organization_id UUID NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  role VARCHAR(20) NOT NULL CHECK (role IN ('owner', 'admin', 'member')),
+  joined_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (organization_id, user_id)
+);
+
+-- Invitations store a hash of the emailed token
+CREATE TABLE IF NOT EXISTS organization_invites (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  organization_id UUID NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+  email VARCHAR(100) NOT NULL,
+  role VARCHAR(20) NOT NULL CHECK (role IN ('admin', 'member')),
+  token_hash CHAR(64) NOT NULL UNIQUE,
+  invited_by UUID REFERENCES users(id) ON DELETE SET NULL,
+  created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+  expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+  accepted_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX idx_organization_members_user_id ON organization_members(user_id);
+CREATE INDEX idx_organization_invites_organization_id ON organization_invites(organization_id);
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS current_organization_id UUID REFERENCES organizations(id) ON DELETE SET NULL;
+ALTER TABLE projects ADD COLUMN IF NOT EXISTS organization_id UUID REFERENCES organizations(id) ON DELETE CASCADE;
+
+-- Every existing user gets a personal workspace holding the projects they own
+INSERT INTO organizations (name, owner_id)
+SELECT full_name || '''s workspace', id FROM users;
+
+INSERT INTO organization_members (organization_id, user_id, role)
+SELECT id, owner_id, 'owner' FROM organizations;
+
+UPDATE users u SET current_organization_id = o.id
+FROM organizations o WHERE o.owner_id = u.id;
+
+UPDATE projects p SET organization_id = o.id
+FROM organizations o WHERE o.owner_id = p.owner_id;
+
+-- Project members join the organization of the project
+INSERT INTO organization_members (organization_id, user_id, role)
+SELECT DISTINCT p.organization_id, pm.user_id, 'member'
+FROM project_members pm JOIN projects p ON p.id = pm.project_id
+ON CONFLICT DO NOTHING;
+
+ALTER TABLE projects ALTER COLUMN organization_id SET NOT NULL;
+CREATE INDEX idx_projects_organization_id ON projects(organization_id);
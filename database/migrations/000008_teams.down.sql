@@ -0,0 +1,5 @@
+ALTER TABLE project_members DROP COLUMN IF EXISTS team_id;
+
+DROP TABLE IF EXISTS project_teams;
+DROP TABLE IF EXISTS team_members;
+DROP TABLE IF EXISTS teams;
@@ -0,0 +1,3 @@
+ALTER TABLE project_members DROP COLUMN IF EXISTS invited_by;
+
+DROP TABLE IF EXISTS project_invites;
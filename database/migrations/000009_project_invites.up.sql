@@ -0,0 +1,22 @@
+-- Email invitations to a project. Accepted and revoked invitations are kept
+-- as a record of who invited whom.
+CREATE TABLE IF NOT EXISTS project_invites (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  project_id UUID NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+  email VARCHAR(100) NOT NULL,
+  role VARCHAR(50) NOT NULL,
+  token_id UUID NOT NULL, -- jti of the token in the latest email
+  invited_by UUID REFERENCES users(id) ON DELETE SET NULL,
+  created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+  expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+  sent_at TIMESTAMP WITH TIME ZONE NOT NULL,
+  send_count INTEGER NOT NULL DEFAULT 1,
+  accepted_at TIMESTAMP WITH TIME ZONE,
+  accepted_by UUID REFERENCES users(id) ON DELETE SET NULL,
+  revoked_at TIMESTAMP WITH TIME ZONE,
+  revoked_by UUID REFERENCES users(id) ON DELETE SET NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_project_invites_project_id ON project_invites(project_id);
+
+ALTER TABLE project_members ADD COLUMN IF NOT EXISTS invited_by UUID REFERENCES users(id) ON DELETE SET NULL;
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS impersonated_requests;
+DROP TABLE IF EXISTS impersonations;
+
+ALTER TABLE users DROP COLUMN IF EXISTS deactivated_at;
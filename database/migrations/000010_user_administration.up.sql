@@ -0,0 +1,27 @@
+-- Deactivated users can't sign in
+ALTER TABLE users ADD COLUMN IF NOT EXISTS deactivated_at TIMESTAMP WITH TIME ZONE;
+
+-- Audit records of admins acting as other users
+CREATE TABLE IF NOT EXISTS impersonations (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  admin_id UUID REFERENCES users(id) ON DELETE SET NULL,
+  user_id UUID REFERENCES users(id) ON DELETE SET NULL,
+  organization_id UUID NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+  reason TEXT NOT NULL,
+  token_id UUID NOT NULL,
+  started_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+  expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+  ended_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE TABLE IF NOT EXISTS impersonated_requests (
+  id SERIAL PRIMARY KEY,
+  impersonation_id UUID NOT NULL REFERENCES impersonations(id) ON DELETE CASCADE,
+  method VARCHAR(10) NOT NULL,
+  path TEXT NOT NULL,
+  status INTEGER NOT NULL,
+  created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_impersonations_organization_id ON impersonations(organization_id);
+CREATE INDEX IF NOT EXISTS idx_impersonated_requests_impersonation_id ON impersonated_requests(impersonation_id);
@@ -0,0 +1,7 @@
+-- Profile pictures and display preferences
+ALTER TABLE users ADD COLUMN IF NOT EXISTS avatar_url TEXT NOT NULL DEFAULT '';
+ALTER TABLE users ADD COLUMN IF NOT EXISTS timezone VARCHAR(64) NOT NULL DEFAULT '';
+ALTER TABLE users ADD COLUMN IF NOT EXISTS locale VARCHAR(35) NOT NULL DEFAULT '';
+
+-- New email address waiting for confirmation
+ALTER TABLE users ADD COLUMN IF NOT EXISTS pending_email VARCHAR(255);
@@ -0,0 +1,104 @@
+package mailer
+
+import (
+	"bytes"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Config holds the SMTP connection settings
+type Config struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+}
+
+// Message represents an email with plain text and HTML bodies
+type Message struct {
+	To      string
+	Subject string
+	Text    string
+	HTML    string
+}
+
+// Sender sends a single email
+type Sender interface {
+	Send(msg Message) error
+}
+
+// Mailer sends email through an SMTP server
+type Mailer struct {
+	config Config
+}
+
+// New creates a new SMTP mailer
+func New(config Config) *Mailer {
+	return &Mailer{config: config}
+}
+
+// Send delivers a message over SMTP. Authentication is only attempted when a
+// username is configured, so local sinks such as MailHog work without credentials.
+func (m *Mailer) Send(msg Message) error {
+	body, err := buildMIME(m.config.From, msg)
+	if err != nil {
+		return err
+	}
+
+	var auth smtp.Auth
+	if m.config.Username != "" {
+		auth = smtp.PlainAuth("", m.config.Username, m.config.Password, m.config.Host)
+	}
+
+	addr := m.config.Host + ":" + strconv.Itoa(m.config.Port)
+	return smtp.SendMail(addr, auth, m.config.From, []string{msg.To}, body)
+}
+
+// encodeSubject makes a subject safe for the header. Subjects contain user
+// text such as task titles, so line breaks are removed to prevent header
+// injection, and non-ASCII text is encoded as RFC 2047 words.
+func encodeSubject(subject string) string {
+	subject = strings.Join(strings.FieldsFunc(subject, func(r rune) bool { return r == '\r' || r == '\n' }), " ")
+	return mime.QEncoding.Encode("utf-8", subject)
+}
+
+// buildMIME renders a multipart/alternative message with text and HTML parts
+func buildMIME(from string, msg Message) ([]byte, error) {
+	var buf bytes.Buffer
+	writer := multipart.NewWriter(&buf)
+
+	header := fmt.Sprintf("From: %s\r\nTo: %s\r\nSubject: %s\r\nDate: %s\r\nMIME-Version: 1.0\r\nContent-Type: multipart/alternative; boundary=%s\r\n\r\n",
+		from, msg.To, encodeSubject(msg.Subject), time.Now().Format(time.RFC1123Z), writer.Boundary())
+
+	parts := []struct {
+		contentType string
+		content     string
+	}{
+		{"text/plain; charset=UTF-8", msg.Text},
+		{"text/html; charset=UTF-8", msg.HTML},
+	}
+	for _, part := range parts {
+		if part.content == "" {
+			continue
+		}
+		w, err := writer.CreatePart(textproto.MIMEHeader{"Content-Type": {part.contentType}})
+		if err != nil {
+			return nil, err
+		}
+		if _, err := w.Write([]byte(part.content)); err != nil {
+			return nil, err
+		}
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+
+	return append([]byte(header), buf.Bytes()...), nil
+}
@@ -0,0 +1,131 @@
+package mailer
+
+import (
+	"log"
+	"sync"
+	"time"
+)
+
+// queuedMessage is a message waiting for another delivery attempt
+type queuedMessage struct {
+	msg         Message
+	attempts    int
+	nextAttempt time.Time
+}
+
+// Number of messages that can wait for their first delivery attempt
+const outgoingCapacity = 1024
+
+// Queue sends messages in the background and retries failed sends with
+// exponential backoff
+type Queue struct {
+	sender      Sender
+	maxAttempts int
+	baseDelay   time.Duration
+	outgoing    chan Message
+	pending     []queuedMessage
+	mu          sync.Mutex
+}
+
+// NewQueue creates a retrying queue around a sender
+func NewQueue(sender Sender, maxAttempts int, baseDelay time.Duration) *Queue {
+	q := &Queue{
+		sender:      sender,
+		maxAttempts: maxAttempts,
+		baseDelay:   baseDelay,
+		outgoing:    make(chan Message, outgoingCapacity),
+	}
+
+	// Start the workers that send new messages and retry failed sends
+	go q.sendOutgoing()
+	go q.worker()
+
+	return q
+}
+
+// Send queues a message for delivery and returns without waiting for the
+// SMTP server, so callers take as long whether or not they send anything.
+// When the queue is full the message waits for the retry worker instead.
+func (q *Queue) Send(msg Message) error {
+	select {
+	case q.outgoing <- msg:
+	default:
+		log.Printf("mailer: queue full, delaying %q to %s", msg.Subject, msg.To)
+		q.retryLater(queuedMessage{msg: msg, nextAttempt: time.Now()})
+	}
+	return nil
+}
+
+// sendOutgoing makes the first delivery attempt of every queued message
+func (q *Queue) sendOutgoing() {
+	for msg := range q.outgoing {
+		if err := q.sender.Send(msg); err != nil {
+			log.Printf("mailer: sending %q to %s failed, queued for retry: %v", msg.Subject, msg.To, err)
+			q.retryLater(queuedMessage{
+				msg:         msg,
+				attempts:    1,
+				nextAttempt: time.Now().Add(q.baseDelay),
+			})
+		}
+	}
+}
+
+// retryLater adds a message to the messages waiting for a retry
+func (q *Queue) retryLater(item queuedMessage) {
+	q.mu.Lock()
+	q.pending = append(q.pending, item)
+	q.mu.Unlock()
+}
+
+// Pending returns the number of messages waiting for a retry
+func (q *Queue) Pending() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	return len(q.pending)
+}
+
+func (q *Queue) worker() {
+	ticker := time.NewTicker(q.baseDelay)
+	defer ticker.Stop()
+
+	for {
+		<-ticker.C
+		q.retryDue()
+	}
+}
+
+// retryDue retries every queued message whose backoff has elapsed
+func (q *Queue) retryDue() {
+	now := time.Now()
+
+	q.mu.Lock()
+	var due []queuedMessage
+	remaining := q.pending[:0]
+	for _, item := range q.pending {
+		if now.Before(item.nextAttempt) {
+			remaining = append(remaining, item)
+		} else {
+			due = append(due, item)
+		}
+	}
+	q.pending = remaining
+	q.mu.Unlock()
+
+	for _, item := range due {
+		err := q.sender.Send(item.msg)
+		if err == nil {
+			continue
+		}
+
+		item.attempts++
+		if item.attempts >= q.maxAttempts {
+			log.Printf("mailer: giving up on %q to %s after %d attempts: %v", item.msg.Subject, item.msg.To, item.attempts, err)
+			continue
+		}
+
+		// Double the delay after every failed attempt
+		item.nextAttempt = time.Now().Add(q.baseDelay << uint(item.attempts-1))
+		q.retryLater(item)
+	}
+}
@@ -0,0 +1,62 @@
+package mailer
+
+import (
+	"bytes"
+	"embed"
+	htmltemplate "html/template"
+	"strings"
+	"time"
+	texttemplate "text/template"
+)
+
+//go:embed templates/*.tmpl
+var templateFS embed.FS
+
+var (
+	htmlTemplates = htmltemplate.Must(htmltemplate.ParseFS(templateFS, "templates/*.html.tmpl"))
+	textTemplates = texttemplate.Must(texttemplate.ParseFS(templateFS, "templates/*.txt.tmpl"))
+)
+
+// Render builds a message from the named template pair. The first line of the
+// text template is used as the subject and must start with "Subject: ".
+func Render(name, to string, data interface{}) (Message, error) {
+	var text bytes.Buffer
+	if err := textTemplates.ExecuteTemplate(&text, name+".txt.tmpl", data); err != nil {
+		return Message{}, err
+	}
+
+	var html bytes.Buffer
+	if err := htmlTemplates.ExecuteTemplate(&html, name+".html.tmpl", data); err != nil {
+		return Message{}, err
+	}
+
+	subject, body, _ := strings.Cut(text.String(), "\n")
+	return Message{
+		To:      to,
+		Subject: strings.TrimSpace(strings.TrimPrefix(subject, "Subject:")),
+		Text:    strings.TrimLeft(body, "\n"),
+		HTML:    html.String(),
+	}, nil
+}
+
+// EventData is the template data for single-event notification emails
+type EventData struct {
+	RecipientName string
+	Content       string
+	ActionURL     string
+	AppURL        string
+}
+
+// DigestItem is a single notification listed in a digest email
+type DigestItem struct {
+	Content   string
+	CreatedAt time.Time
+}
+
+// DigestData is the template data for digest emails
+type DigestData struct {
+	RecipientName string
+	Frequency     string
+	Items         []DigestItem
+	AppURL        string
+}
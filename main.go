@@ -9,9 +9,13 @@ import (
 	"github.com/gofiber/fiber/v2/middleware/logger"
 	"github.com/gofiber/fiber/v2/middleware/recover"
 	"github.com/joho/godotenv"
+	"github.com/projectflow/api/handlers"
+	"github.com/projectflow/api/middleware"
 	"github.com/projectflow/api/routes"
 	"github.com/projectflow/config"
 	"github.com/projectflow/database"
+	"github.com/projectflow/utils"
+	"github.com/projectflow/utils/ratelimit"
 )
 
 func main() {
@@ -22,7 +26,21 @@ func main() {
 	}
 
 	// Load configuration
-	config.LoadConfig()
+	cfg := config.LoadConfig()
+	if err := cfg.Validate(); err != nil {
+		log.Fatalf("Invalid configuration: %v", err)
+	}
+
+	// Load access token signing keys
+	if cfg.JWTPrivateKeyFile != "" {
+		err = utils.ConfigureSigningKeys(cfg.JWTPrivateKeyFile, cfg.JWTVerificationKeyFiles)
+	} else {
+		log.Println("Warning: JWT_PRIVATE_KEY_FILE not set, signing tokens with an ephemeral key")
+		err = utils.UseEphemeralSigningKey()
+	}
+	if err != nil {
+		log.Fatalf("Failed to load JWT signing keys: %v", err)
+	}
 
 	// Initialize database
 	err = database.Initialize()
@@ -31,13 +49,45 @@ func main() {
 	}
 	defer database.Close()
 
+	// Start email delivery, digests and due date reminders
+	handlers.ConfigureNotifications(cfg)
+
+	// Register single sign-on providers
+	handlers.ConfigureSSO(cfg)
+
+	// Apply the password policy and email verification settings
+	if err := handlers.ConfigureAccounts(cfg); err != nil {
+		log.Fatalf("Failed to load password policy: %v", err)
+	}
+
+	// Throttle logins, sharing the counts between replicas through Postgres if configured
+	var limiter ratelimit.Limiter = ratelimit.NewMemoryLimiter()
+	if cfg.RateLimitBackend == "postgres" {
+		if database.DB == nil {
+			log.Fatalf("RATE_LIMIT_BACKEND=postgres needs a database connection")
+		}
+		limiter = ratelimit.NewPostgresLimiter(database.DB)
+	}
+	handlers.ConfigureLoginProtection(cfg, limiter)
+
+	// Apply the API rate limits per route group
+	middleware.ConfigureRateLimits(cfg)
+
+	// Set when the deprecated unversioned API paths stop working
+	middleware.ConfigureDeprecation(cfg)
+
 	// Create Fiber app
 	app := fiber.New(fiber.Config{
-		AppName: "ProjectFlow API",
+		AppName:      "ProjectFlow API",
+		ProxyHeader:  cfg.ProxyHeader,
+		ErrorHandler: middleware.ErrorHandler,
 	})
 
 	// Middleware
-	app.Use(logger.New())
+	app.Use(middleware.RequestID())
+	app.Use(logger.New(logger.Config{
+		Format: "${time} | ${status} | ${latency} | ${ip} | ${method} | ${path} | ${locals:requestid} | ${error}\n",
+	}))
 	app.Use(recover.New())
 	// Get allowed origins from environment variable or use defaults
 	allowedOrigins := os.Getenv("ALLOWED_ORIGINS")
@@ -48,13 +98,17 @@ func main() {
 	app.Use(cors.New(cors.Config{
 		AllowOrigins:     allowedOrigins,
 		AllowMethods:     "GET,POST,PUT,DELETE,PATCH",
-		AllowHeaders:     "Origin, Content-Type, Accept, Authorization",
+		AllowHeaders:     "Origin, Content-Type, Accept, Authorization, X-API-Key",
+		ExposeHeaders:    "RateLimit-Limit, RateLimit-Remaining, RateLimit-Reset, RateLimit-Policy, Retry-After, X-Request-ID, Deprecation, Sunset, Link",
 		AllowCredentials: true,
 		MaxAge:           300, // 5 minutes
 	}))
 
 	// Setup routes
-	routes.SetupRoutes(app)
+	routes.SetupRoutes(app, database.DB)
+
+	// Serve uploaded profile pictures
+	app.Static("/avatars", cfg.AvatarDir, fiber.Static{MaxAge: 86400})
 
 	// Add a health check endpoint
 	app.Get("/health", func(c *fiber.Ctx) error {
@@ -63,13 +117,6 @@ func main() {
 		})
 	})
 
-	// Add API documentation endpoint
-	app.Get("/api/docs", func(c *fiber.Ctx) error {
-		return c.Status(fiber.StatusOK).JSON(fiber.Map{
-			"message": "API documentation will be available here",
-		})
-	})
-
 	// Start server
 	port := os.Getenv("SERVER_PORT")
 	if port == "" {
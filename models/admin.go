@@ -0,0 +1,59 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Longest an admin can impersonate a user in one go
+const MaxImpersonationMinutes = 60
+
+// Impersonation is the audit record of an admin acting as another user
+type Impersonation struct {
+	ID             uuid.UUID  `json:"id"`
+	AdminID        uuid.UUID  `json:"admin_id"`
+	UserID         uuid.UUID  `json:"user_id"`
+	OrganizationID uuid.UUID  `json:"organization_id"`
+	Reason         string     `json:"reason"`
+	TokenID        string     `json:"-"` // jti of the impersonation token
+	StartedAt      time.Time  `json:"started_at"`
+	ExpiresAt      time.Time  `json:"expires_at"`
+	EndedAt        *time.Time `json:"ended_at,omitempty"`
+	// Requests made with the token, oldest first
+	Requests []ImpersonatedRequest `json:"requests"`
+}
+
+// ImpersonatedRequest is one request made while impersonating
+type ImpersonatedRequest struct {
+	Method string    `json:"method"`
+	Path   string    `json:"path"`
+	Status int       `json:"status"`
+	At     time.Time `json:"at"`
+}
+
+// AdminUpdateUserRequest changes a user's profile
+type AdminUpdateUserRequest struct {
+	Username string `json:"username" validate:"required,min=3,max=50"`
+	Email    string `json:"email" validate:"required,email"`
+	FullName string `json:"full_name"`
+}
+
+// UpdateUserRoleRequest changes a user's global role
+type UpdateUserRoleRequest struct {
+	Role string `json:"role" validate:"required,oneof=admin member"`
+}
+
+// ReassignWorkRequest hands a user's tasks and allocations to someone else
+type ReassignWorkRequest struct {
+	ToUserID uuid.UUID `json:"to_user_id" validate:"required"`
+	// Add the new user to projects they aren't a member of yet instead of
+	// skipping the work in those projects
+	AddToProjects bool `json:"add_to_projects"`
+}
+
+// StartImpersonationRequest starts acting as another user
+type StartImpersonationRequest struct {
+	Reason  string `json:"reason" validate:"required"`
+	Minutes int    `json:"minutes" validate:"omitempty,min=1,max=60"` // 15 by default, at most MaxImpersonationMinutes
+}
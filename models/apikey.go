@@ -0,0 +1,75 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// API key scopes
+const (
+	ScopeReadProjects       = "read:projects"
+	ScopeWriteProjects      = "write:projects"
+	ScopeReadTasks          = "read:tasks"
+	ScopeWriteTasks         = "write:tasks"
+	ScopeReadNotifications  = "read:notifications"
+	ScopeWriteNotifications = "write:notifications"
+	ScopeReadResources      = "read:resources"
+	ScopeWriteResources     = "write:resources"
+	ScopeReadUsers          = "read:users"
+)
+
+// APIKeyScopes lists every scope an API key can be granted
+var APIKeyScopes = []string{
+	ScopeReadProjects,
+	ScopeWriteProjects,
+	ScopeReadTasks,
+	ScopeWriteTasks,
+	ScopeReadNotifications,
+	ScopeWriteNotifications,
+	ScopeReadResources,
+	ScopeWriteResources,
+	ScopeReadUsers,
+}
+
+// APIKey represents a personal or project-scoped API key used by integrations
+type APIKey struct {
+	ID             uuid.UUID  `json:"id"`
+	UserID         uuid.UUID  `json:"user_id"`
+	ProjectID      *uuid.UUID `json:"project_id,omitempty"` // Restricts the key to one project when set
+	OrganizationID uuid.UUID  `json:"organization_id"`      // The key only acts in this organization
+	Name           string     `json:"name"`
+	Prefix         string     `json:"prefix"` // Public part of the key, shown to identify it
+	KeyHash        string     `json:"-"`      // SHA-256 of the full key, the key itself is never stored
+	Scopes         []string   `json:"scopes"`
+	ExpiresAt      *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt     *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt      *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
+
+// HasScope reports whether the key was granted a scope
+func (k *APIKey) HasScope(scope string) bool {
+	for _, s := range k.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// Usable reports whether the key is neither revoked nor expired at t
+func (k *APIKey) Usable(t time.Time) bool {
+	if k.RevokedAt != nil {
+		return false
+	}
+	return k.ExpiresAt == nil || t.Before(*k.ExpiresAt)
+}
+
+// CreateAPIKeyRequest represents the request to create an API key
+type CreateAPIKeyRequest struct {
+	Name          string     `json:"name" validate:"required,min=3,max=100"`
+	ProjectID     *uuid.UUID `json:"project_id"`
+	Scopes        []string   `json:"scopes" validate:"required,min=1"`
+	ExpiresInDays int        `json:"expires_in_days" validate:"omitempty,min=1,max=365"` // 0 means the key never expires
+}
@@ -0,0 +1,30 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Outcomes of a login attempt
+const (
+	LoginSucceeded         = "success"
+	LoginInvalid           = "invalid_credentials"
+	LoginTwoFactorRequired = "two_factor_required"
+	LoginTwoFactorFailed   = "invalid_second_factor"
+	LoginEmailNotVerified  = "email_not_verified"
+	LoginDeactivated       = "deactivated"
+	LoginLocked            = "locked"
+	LoginRateLimited       = "rate_limited"
+)
+
+// LoginAttempt is an audit record of one password login attempt
+type LoginAttempt struct {
+	ID        uuid.UUID  `json:"id"`
+	Email     string     `json:"email"`             // As submitted, lowercased
+	UserID    *uuid.UUID `json:"user_id,omitempty"` // Set when the email belongs to a user
+	IPAddress string     `json:"ip_address"`
+	UserAgent string     `json:"user_agent"`
+	Outcome   string     `json:"outcome"`
+	CreatedAt time.Time  `json:"created_at"`
+}
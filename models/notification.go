@@ -14,7 +14,10 @@ type Notification struct {
 	Type      string     `json:"type"` // task_assigned, comment_added, etc.
 	Read      bool       `json:"read"`
 	RelatedID *uuid.UUID `json:"related_id"` // Can be task_id, project_id, etc.
-	CreatedAt time.Time  `json:"created_at"`
+	Count     int        `json:"count"`      // Number of grouped events on the same RelatedID
+	CreatedAt time.Time  `json:"created_at"` // Time of the first grouped event
+	// Time of the most recent grouped event, which orders notifications
+	LastOccurredAt time.Time `json:"last_occurred_at"`
 }
 
 // NotificationResponse represents a notification with additional information
@@ -27,7 +30,140 @@ type NotificationResponse struct {
 	CreatedAt time.Time  `json:"created_at"`
 }
 
+// DeleteNotificationsRequest represents the request to delete notifications in bulk
+type DeleteNotificationsRequest struct {
+	IDs     []uuid.UUID `json:"ids"`
+	AllRead bool        `json:"all_read"` // Delete every read notification instead of specific IDs
+}
+
 // MarkNotificationReadRequest represents the request to mark a notification as read
 type MarkNotificationReadRequest struct {
 	Read bool `json:"read"`
 }
+
+// Notification delivery channels
+const (
+	ChannelInApp   = "in_app"
+	ChannelEmail   = "email"
+	ChannelWebhook = "webhook"
+)
+
+// Notification types
+const (
+	NotificationTaskAssigned    = "task_assigned"
+	NotificationCommentAdded    = "comment_added"
+	NotificationMention         = "mention"
+	NotificationTaskDueSoon     = "task_due_soon"
+	NotificationTimeOffDecision = "time_off_decision"
+)
+
+// Digest frequencies for batching unread notifications into a single email
+const (
+	DigestNone   = "none"
+	DigestDaily  = "daily"
+	DigestWeekly = "weekly"
+)
+
+// NotificationTypes lists every notification type users can configure
+var NotificationTypes = []string{
+	NotificationTaskAssigned,
+	NotificationCommentAdded,
+	NotificationMention,
+	NotificationTaskDueSoon,
+	NotificationTimeOffDecision,
+}
+
+// ChannelPreference toggles the delivery channels for a notification type
+type ChannelPreference struct {
+	InApp   bool `json:"in_app"`
+	Email   bool `json:"email"`
+	Webhook bool `json:"webhook"`
+}
+
+// NotificationPreferences represents a user's notification settings
+type NotificationPreferences struct {
+	UserID          uuid.UUID                    `json:"user_id"`
+	Types           map[string]ChannelPreference `json:"types"`
+	WebhookURL      string                       `json:"webhook_url,omitempty"`
+	QuietHoursStart string                       `json:"quiet_hours_start,omitempty"` // HH:MM in Timezone
+	QuietHoursEnd   string                       `json:"quiet_hours_end,omitempty"`   // HH:MM in Timezone
+	Timezone        string                       `json:"timezone,omitempty"`          // IANA name, defaults to UTC
+	DigestFrequency string                       `json:"digest_frequency"`            // none, daily or weekly
+	UpdatedAt       time.Time                    `json:"updated_at"`
+}
+
+// UpdateNotificationPreferencesRequest represents the request to update notification preferences
+type UpdateNotificationPreferencesRequest struct {
+	Types           map[string]ChannelPreference `json:"types"`
+	WebhookURL      string                       `json:"webhook_url"`
+	QuietHoursStart string                       `json:"quiet_hours_start"`
+	QuietHoursEnd   string                       `json:"quiet_hours_end"`
+	Timezone        string                       `json:"timezone"`
+	DigestFrequency string                       `json:"digest_frequency"`
+}
+
+// DefaultNotificationPreferences returns the preferences used until a user saves their own
+func DefaultNotificationPreferences(userID uuid.UUID) *NotificationPreferences {
+	types := make(map[string]ChannelPreference, len(NotificationTypes))
+	for _, notificationType := range NotificationTypes {
+		types[notificationType] = ChannelPreference{InApp: true, Email: true}
+	}
+	return &NotificationPreferences{
+		UserID:          userID,
+		Types:           types,
+		DigestFrequency: DigestNone,
+	}
+}
+
+// ChannelsFor returns the enabled channels for a notification type.
+// Types without an explicit preference are delivered in-app only.
+func (p *NotificationPreferences) ChannelsFor(notificationType string) ChannelPreference {
+	if pref, ok := p.Types[notificationType]; ok {
+		return pref
+	}
+	return ChannelPreference{InApp: true}
+}
+
+// QuietHoursEndAfter returns when the quiet hours window containing t ends.
+// The second return value is false when t is outside quiet hours or none are configured.
+func (p *NotificationPreferences) QuietHoursEndAfter(t time.Time) (time.Time, bool) {
+	if p.QuietHoursStart == "" || p.QuietHoursEnd == "" {
+		return time.Time{}, false
+	}
+	loc := time.UTC
+	if p.Timezone != "" {
+		if l, err := time.LoadLocation(p.Timezone); err == nil {
+			loc = l
+		}
+	}
+	start, err := time.Parse("15:04", p.QuietHoursStart)
+	if err != nil {
+		return time.Time{}, false
+	}
+	end, err := time.Parse("15:04", p.QuietHoursEnd)
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	local := t.In(loc)
+	minutes := local.Hour()*60 + local.Minute()
+	startMinutes := start.Hour()*60 + start.Minute()
+	endMinutes := end.Hour()*60 + end.Minute()
+
+	// The window may wrap past midnight (e.g. 22:00-07:00)
+	var inside bool
+	if startMinutes <= endMinutes {
+		inside = minutes >= startMinutes && minutes < endMinutes
+	} else {
+		inside = minutes >= startMinutes || minutes < endMinutes
+	}
+	if !inside {
+		return time.Time{}, false
+	}
+
+	endsAt := time.Date(local.Year(), local.Month(), local.Day(), end.Hour(), end.Minute(), 0, 0, loc)
+	if !endsAt.After(local) {
+		endsAt = endsAt.AddDate(0, 0, 1)
+	}
+	return endsAt, true
+}
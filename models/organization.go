@@ -0,0 +1,71 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Organization roles
+const (
+	OrgRoleOwner  = "owner"
+	OrgRoleAdmin  = "admin"
+	OrgRoleMember = "member"
+)
+
+// Organization is a workspace that owns projects. Users belong to one or
+// more organizations and work in one of them at a time.
+type Organization struct {
+	ID        uuid.UUID `json:"id"`
+	Name      string    `json:"name"`
+	OwnerID   uuid.UUID `json:"owner_id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// OrganizationMember represents a user's membership in an organization
+type OrganizationMember struct {
+	OrganizationID uuid.UUID `json:"organization_id"`
+	UserID         uuid.UUID `json:"user_id"`
+	Role           string    `json:"role"` // owner, admin or member
+	JoinedAt       time.Time `json:"joined_at"`
+}
+
+// OrganizationInvite invites an email address to join an organization
+type OrganizationInvite struct {
+	ID             uuid.UUID  `json:"id"`
+	OrganizationID uuid.UUID  `json:"organization_id"`
+	Email          string     `json:"email"`
+	Role           string     `json:"role"`
+	TokenHash      string     `json:"-"` // SHA-256 of the emailed token
+	InvitedBy      uuid.UUID  `json:"invited_by"`
+	CreatedAt      time.Time  `json:"created_at"`
+	ExpiresAt      time.Time  `json:"expires_at"`
+	AcceptedAt     *time.Time `json:"accepted_at,omitempty"`
+}
+
+// CreateOrganizationRequest represents the request to create an organization
+type CreateOrganizationRequest struct {
+	Name string `json:"name" validate:"required,min=2,max=100"`
+}
+
+// UpdateOrganizationRequest represents the request to rename an organization
+type UpdateOrganizationRequest struct {
+	Name string `json:"name" validate:"required,min=2,max=100"`
+}
+
+// SwitchOrganizationRequest selects the organization to work in
+type SwitchOrganizationRequest struct {
+	OrganizationID uuid.UUID `json:"organization_id" validate:"required"`
+}
+
+// InviteMemberRequest represents the request to invite someone to an organization
+type InviteMemberRequest struct {
+	Email string `json:"email" validate:"required,email"`
+	Role  string `json:"role" validate:"omitempty,oneof=admin member"`
+}
+
+// AcceptInviteRequest accepts an invitation with the emailed token
+type AcceptInviteRequest struct {
+	Token string `json:"token" validate:"required"`
+}
@@ -0,0 +1,135 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Project permissions
+const (
+	PermProjectView      = "project:view"
+	PermProjectEdit      = "project:edit"
+	PermProjectDelete    = "project:delete"
+	PermMemberView       = "member:view"
+	PermMemberManage     = "member:manage"
+	PermRoleManage       = "role:manage"
+	PermTaskCreate       = "task:create"
+	PermTaskEdit         = "task:edit"
+	PermTaskDelete       = "task:delete"
+	PermTaskComment      = "task:comment"
+	PermWebhookManage    = "webhook:manage"
+	PermAllocationManage = "allocation:manage"
+)
+
+// Permissions lists every project permission a role can be granted
+var Permissions = []string{
+	PermProjectView,
+	PermProjectEdit,
+	PermProjectDelete,
+	PermMemberView,
+	PermMemberManage,
+	PermRoleManage,
+	PermTaskCreate,
+	PermTaskEdit,
+	PermTaskDelete,
+	PermTaskComment,
+	PermWebhookManage,
+	PermAllocationManage,
+}
+
+// Built-in project roles
+const (
+	RoleOwner  = "owner"
+	RoleAdmin  = "admin"
+	RoleMember = "member"
+	RoleViewer = "viewer"
+	RoleGuest  = "guest"
+)
+
+// ProjectRole is a named set of permissions. Built-in roles exist in every
+// project; custom roles belong to one project.
+type ProjectRole struct {
+	Name        string     `json:"name"`
+	Description string     `json:"description"`
+	Permissions []string   `json:"permissions"`
+	BuiltIn     bool       `json:"built_in"`
+	ProjectID   *uuid.UUID `json:"project_id,omitempty"` // Set for custom roles
+	CreatedAt   time.Time  `json:"created_at,omitempty"`
+}
+
+// Has reports whether the role grants a permission
+func (r *ProjectRole) Has(permission string) bool {
+	for _, p := range r.Permissions {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// BuiltInRoles are available in every project, from most to least privileged
+var BuiltInRoles = []*ProjectRole{
+	{
+		Name:        RoleOwner,
+		Description: "Full control, including deleting the project",
+		Permissions: Permissions,
+		BuiltIn:     true,
+	},
+	{
+		Name:        RoleAdmin,
+		Description: "Manages the project, its members, roles and webhooks",
+		Permissions: []string{
+			PermProjectView, PermProjectEdit, PermMemberView, PermMemberManage, PermRoleManage,
+			PermTaskCreate, PermTaskEdit, PermTaskDelete, PermTaskComment,
+			PermWebhookManage, PermAllocationManage,
+		},
+		BuiltIn: true,
+	},
+	{
+		Name:        RoleMember,
+		Description: "Creates and works on tasks",
+		Permissions: []string{PermProjectView, PermMemberView, PermTaskCreate, PermTaskEdit, PermTaskComment},
+		BuiltIn:     true,
+	},
+	{
+		Name:        RoleViewer,
+		Description: "Reads the project and comments on tasks",
+		Permissions: []string{PermProjectView, PermMemberView, PermTaskComment},
+		BuiltIn:     true,
+	},
+	{
+		Name:        RoleGuest,
+		Description: "Reads the project's tasks, without seeing its members",
+		Permissions: []string{PermProjectView},
+		BuiltIn:     true,
+	},
+}
+
+// BuiltInRole returns the built-in role with a name, or nil
+func BuiltInRole(name string) *ProjectRole {
+	for _, role := range BuiltInRoles {
+		if role.Name == name {
+			return role
+		}
+	}
+	return nil
+}
+
+// CreateRoleRequest represents the request to create a custom project role
+type CreateRoleRequest struct {
+	Name        string   `json:"name" validate:"required,min=2,max=50"`
+	Description string   `json:"description"`
+	Permissions []string `json:"permissions" validate:"required"`
+}
+
+// UpdateRoleRequest represents the request to change a custom project role
+type UpdateRoleRequest struct {
+	Description string   `json:"description"`
+	Permissions []string `json:"permissions" validate:"required"`
+}
+
+// UpdateMemberRoleRequest represents the request to change a member's role
+type UpdateMemberRoleRequest struct {
+	Role string `json:"role" validate:"required"`
+}
@@ -12,16 +12,22 @@ type Project struct {
 	Name        string    `json:"name"`
 	Description string    `json:"description"`
 	OwnerID     uuid.UUID `json:"owner_id"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
+	// Organization the project belongs to
+	OrganizationID uuid.UUID `json:"organization_id"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
 }
 
 // ProjectMember represents a user's membership in a project
 type ProjectMember struct {
 	ProjectID uuid.UUID `json:"project_id"`
 	UserID    uuid.UUID `json:"user_id"`
-	Role      string    `json:"role"` // admin or member
+	Role      string    `json:"role"` // A built-in or custom project role
 	JoinedAt  time.Time `json:"joined_at"`
+	// Set when the user is a member through a team rather than directly
+	TeamID *uuid.UUID `json:"team_id,omitempty"`
+	// Set when the user joined by accepting an invitation
+	InvitedBy *uuid.UUID `json:"invited_by,omitempty"`
 }
 
 // CreateProjectRequest represents the request to create a new project
@@ -36,19 +42,22 @@ type UpdateProjectRequest struct {
 	Description string `json:"description"`
 }
 
-// ProjectResponse represents the project data with additional information
+// Related records a project response can include with ?include=
+const ProjectIncludeMembers = "members"
+
+// ProjectIncludes lists the includes of project responses
+var ProjectIncludes = []string{ProjectIncludeMembers}
+
+// ProjectResponse represents the project data with additional information.
+// Members are only embedded when included and visible to the caller.
 type ProjectResponse struct {
-	ID          uuid.UUID      `json:"id"`
-	Name        string         `json:"name"`
-	Description string         `json:"description"`
-	Owner       UserResponse   `json:"owner"`
-	Members     []UserResponse `json:"members,omitempty"`
-	CreatedAt   time.Time      `json:"created_at"`
-	UpdatedAt   time.Time      `json:"updated_at"`
+	Project
+	Owner   *UserResponse  `json:"owner"` // Nil if the owner was removed
+	Members []UserResponse `json:"members,omitempty"`
 }
 
 // AddMemberRequest represents the request to add a member to a project
 type AddMemberRequest struct {
 	UserID uuid.UUID `json:"user_id" validate:"required"`
-	Role   string    `json:"role" validate:"required,oneof=admin member"`
+	Role   string    `json:"role" validate:"omitempty,min=2,max=50"` // member by default
 }
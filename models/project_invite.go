@@ -0,0 +1,75 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// States of a project invitation
+const (
+	InviteStatusPending  = "pending"
+	InviteStatusAccepted = "accepted"
+	InviteStatusRevoked  = "revoked"
+	InviteStatusExpired  = "expired"
+)
+
+// ProjectInvite invites an email address to join a project with a role.
+// Accepted and revoked invitations are kept as a record of who invited whom.
+type ProjectInvite struct {
+	ID        uuid.UUID `json:"id"`
+	ProjectID uuid.UUID `json:"project_id"`
+	Email     string    `json:"email"`
+	Role      string    `json:"role"`
+	TokenID   string    `json:"-"` // ID of the token in the latest email
+	InvitedBy uuid.UUID `json:"invited_by"`
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+	// When the invitation was last emailed and how often
+	SentAt     time.Time  `json:"sent_at"`
+	SendCount  int        `json:"send_count"`
+	AcceptedAt *time.Time `json:"accepted_at,omitempty"`
+	AcceptedBy *uuid.UUID `json:"accepted_by,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	RevokedBy  *uuid.UUID `json:"revoked_by,omitempty"`
+}
+
+// Status returns the state of the invitation at a point in time
+func (i *ProjectInvite) Status(now time.Time) string {
+	switch {
+	case i.AcceptedAt != nil:
+		return InviteStatusAccepted
+	case i.RevokedAt != nil:
+		return InviteStatusRevoked
+	case now.After(i.ExpiresAt):
+		return InviteStatusExpired
+	default:
+		return InviteStatusPending
+	}
+}
+
+// ProjectInviteResponse is an invitation with its current state
+type ProjectInviteResponse struct {
+	*ProjectInvite
+	Status string `json:"status"`
+}
+
+// ToResponse converts a ProjectInvite to a ProjectInviteResponse
+func (i *ProjectInvite) ToResponse() ProjectInviteResponse {
+	return ProjectInviteResponse{ProjectInvite: i, Status: i.Status(time.Now())}
+}
+
+// CreateProjectInviteRequest represents the request to invite someone to a project
+type CreateProjectInviteRequest struct {
+	Email string `json:"email" validate:"required,email"`
+	Role  string `json:"role"` // member by default
+}
+
+// AcceptProjectInviteRequest accepts a project invitation. Username and
+// password are only needed when no account exists for the invited email.
+type AcceptProjectInviteRequest struct {
+	Token    string `json:"token" validate:"required"`
+	Username string `json:"username"`
+	FullName string `json:"full_name"`
+	Password string `json:"password"`
+}
@@ -10,9 +10,9 @@ import (
 type UserAvailability struct {
   ID        int       `json:"id" db:"id"`
   UserID    uuid.UUID `json:"user_id" db:"user_id"`
-  DayOfWeek int       `json:"day_of_week" db:"day_of_week"` // 0-6 for Sunday-Saturday
-  StartTime time.Time `json:"start_time" db:"start_time"`
-  EndTime   time.Time `json:"end_time" db:"end_time"`
+  DayOfWeek int       `json:"day_of_week" db:"day_of_week" validate:"min=0,max=6"` // 0-6 for Sunday-Saturday
+  StartTime time.Time `json:"start_time" db:"start_time" validate:"required"`
+  EndTime   time.Time `json:"end_time" db:"end_time" validate:"required,gtfield=StartTime"`
   CreatedAt time.Time `json:"created_at" db:"created_at"`
   UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
 }
@@ -22,9 +22,9 @@ type ResourceAllocation struct {
   ID                   int       `json:"id" db:"id"`
   UserID               uuid.UUID `json:"user_id" db:"user_id"`
   ProjectID            uuid.UUID `json:"project_id" db:"project_id"`
-  AllocationPercentage int       `json:"allocation_percentage" db:"allocation_percentage"`
-  StartDate            time.Time `json:"start_date" db:"start_date"`
-  EndDate              time.Time `json:"end_date,omitempty" db:"end_date"`
+  AllocationPercentage int       `json:"allocation_percentage" db:"allocation_percentage" validate:"min=1,max=100"`
+  StartDate            time.Time `json:"start_date" db:"start_date" validate:"required"`
+  EndDate              time.Time `json:"end_date,omitempty" db:"end_date" validate:"omitempty,gtefield=StartDate"` // Open-ended when empty
   CreatedAt            time.Time `json:"created_at" db:"created_at"`
   UpdatedAt            time.Time `json:"updated_at" db:"updated_at"`
   
@@ -37,18 +37,39 @@ type ResourceAllocation struct {
 type TimeOffRequest struct {
   ID          int       `json:"id" db:"id"`
   UserID      uuid.UUID `json:"user_id" db:"user_id"`
-  StartDate   time.Time `json:"start_date" db:"start_date"`
-  EndDate     time.Time `json:"end_date" db:"end_date"`
+  StartDate   time.Time `json:"start_date" db:"start_date" validate:"required"`
+  EndDate     time.Time `json:"end_date" db:"end_date" validate:"required,gtefield=StartDate"`
   Status      string    `json:"status" db:"status"` // pending, approved, rejected
-  RequestType string    `json:"request_type" db:"request_type"`
+  RequestType string    `json:"request_type" db:"request_type" validate:"required,max=20"` // vacation, sick, personal, etc.
   Notes       string    `json:"notes,omitempty" db:"notes"`
   CreatedAt   time.Time `json:"created_at" db:"created_at"`
   UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`
+
+  // Who approved or rejected the request, and when
+  ApproverID *uuid.UUID `json:"approver_id,omitempty" db:"approver_id"`
+  DecidedAt  *time.Time `json:"decided_at,omitempty" db:"decided_at"`
   
   // Populated fields (not from DB)
   User *User `json:"user,omitempty" db:"-"`
 }
 
+// Related records allocation and time off listings include with ?include=
+const (
+  ResourceIncludeUser    = "user"
+  ResourceIncludeProject = "project"
+)
+
+// AllocationIncludes lists the includes of resource allocation listings
+var AllocationIncludes = []string{ResourceIncludeProject, ResourceIncludeUser}
+
+// TimeOffIncludes lists the includes of time off request listings
+var TimeOffIncludes = []string{ResourceIncludeUser}
+
+// UpdateTimeOffStatusRequest is the decision on a time off request
+type UpdateTimeOffStatusRequest struct {
+  Status string `json:"status" validate:"required,oneof=pending approved rejected"`
+}
+
 // ResourceAllocationResponse is the API response for resource allocations
 type ResourceAllocationResponse struct {
   ID                   int       `json:"id"`
@@ -70,6 +91,8 @@ type TimeOffRequestResponse struct {
   Status      string    `json:"status"`
   RequestType string    `json:"request_type"`
   Notes       string    `json:"notes,omitempty"`
+  ApproverID  *uuid.UUID `json:"approver_id,omitempty"`
+  DecidedAt   *time.Time `json:"decided_at,omitempty"`
   CreatedAt   time.Time `json:"created_at"`
   UpdatedAt   time.Time `json:"updated_at"`
 }
@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// SecuritySettings are instance-wide authentication rules managed by admins
+type SecuritySettings struct {
+	RequireAdminTwoFactor bool      `json:"require_admin_two_factor"`
+	UpdatedAt             time.Time `json:"updated_at"`
+}
+
+// UpdateSecuritySettingsRequest represents the request to change security settings
+type UpdateSecuritySettingsRequest struct {
+	RequireAdminTwoFactor *bool `json:"require_admin_two_factor"`
+}
@@ -65,20 +65,25 @@ type UpdateTaskStatusRequest struct {
 	StatusID int `json:"status_id" validate:"required"`
 }
 
-// TaskResponse represents the task data with additional information
+// Related records a task response can include with ?include=
+const (
+	TaskIncludeComments = "comments"
+	TaskIncludeProject  = "project"
+)
+
+// TaskIncludes lists the includes of task responses
+var TaskIncludes = []string{TaskIncludeComments, TaskIncludeProject}
+
+// TaskResponse represents the task data with additional information. The
+// related users and status are always embedded; the project and comments
+// only when included.
 type TaskResponse struct {
-	ID          uuid.UUID      `json:"id"`
-	Title       string         `json:"title"`
-	Description string         `json:"description"`
-	Project     ProjectResponse `json:"project"`
-	Status      TaskStatus     `json:"status"`
-	Assignee    *UserResponse  `json:"assignee,omitempty"`
-	Reporter    UserResponse   `json:"reporter"`
-	DueDate     *time.Time     `json:"due_date"`
-	Priority    string         `json:"priority"`
-	Comments    []CommentResponse `json:"comments,omitempty"`
-	CreatedAt   time.Time      `json:"created_at"`
-	UpdatedAt   time.Time      `json:"updated_at"`
+	Task
+	Status   *TaskStatus       `json:"status"`   // Nil if the status was removed
+	Assignee *UserResponse     `json:"assignee"` // Nil if unassigned
+	Reporter *UserResponse     `json:"reporter"` // Nil if the reporter was removed
+	Project  *ProjectResponse  `json:"project,omitempty"`
+	Comments []CommentResponse `json:"comments,omitempty"`
 }
 
 // CreateCommentRequest represents the request to create a new comment
@@ -88,9 +93,6 @@ type CreateCommentRequest struct {
 
 // CommentResponse represents a comment with user information
 type CommentResponse struct {
-	ID        uuid.UUID    `json:"id"`
-	Content   string       `json:"content"`
-	User      UserResponse `json:"user"`
-	CreatedAt time.Time    `json:"created_at"`
-	UpdatedAt time.Time    `json:"updated_at"`
+	TaskComment
+	User *UserResponse `json:"user"` // Nil if the author was removed
 }
@@ -0,0 +1,109 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Team roles
+const (
+	TeamRoleLead   = "lead"
+	TeamRoleMember = "member"
+)
+
+// DefaultCapacityHours is the weekly capacity of team members who didn't set one
+const DefaultCapacityHours = 40
+
+// Team groups users of an organization. Adding a team to a project makes
+// all of its members project members.
+type Team struct {
+	ID             uuid.UUID `json:"id"`
+	OrganizationID uuid.UUID `json:"organization_id"`
+	Name           string    `json:"name"`
+	Description    string    `json:"description"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+// TeamMember represents a user's membership in a team
+type TeamMember struct {
+	TeamID        uuid.UUID `json:"team_id"`
+	UserID        uuid.UUID `json:"user_id"`
+	Role          string    `json:"role"`           // lead or member
+	CapacityHours int       `json:"capacity_hours"` // Weekly hours available for project work
+	JoinedAt      time.Time `json:"joined_at"`
+}
+
+// ProjectTeam links a team to a project. Its members join the project with Role.
+type ProjectTeam struct {
+	ProjectID uuid.UUID `json:"project_id"`
+	TeamID    uuid.UUID `json:"team_id"`
+	Role      string    `json:"role"`
+	AddedAt   time.Time `json:"added_at"`
+}
+
+// MemberWorkload is the open work of one team member
+type MemberWorkload struct {
+	User          UserResponse `json:"user"`
+	Role          string       `json:"role"`
+	CapacityHours int          `json:"capacity_hours"`
+	OpenTasks     int          `json:"open_tasks"`
+	OverdueTasks  int          `json:"overdue_tasks"`
+	HighPriority  int          `json:"high_priority_tasks"`
+	// Open tasks per 40 hours of weekly capacity, null without capacity
+	Load *float64 `json:"load"`
+}
+
+// AssignmentSuggestion is a team member who could take on a task of a project
+type AssignmentSuggestion struct {
+	User          UserResponse `json:"user"`
+	TeamID        uuid.UUID    `json:"team_id"`
+	CapacityHours int          `json:"capacity_hours"`
+	OpenTasks     int          `json:"open_tasks"`
+	OverdueTasks  int          `json:"overdue_tasks"`
+	Load          float64      `json:"load"`
+}
+
+// TeamWorkload sums up the open work and capacity of a team
+type TeamWorkload struct {
+	Team          *Team            `json:"team"`
+	Members       []MemberWorkload `json:"members,omitempty"`
+	MemberCount   int              `json:"member_count"`
+	CapacityHours int              `json:"capacity_hours"`
+	OpenTasks     int              `json:"open_tasks"`
+	OverdueTasks  int              `json:"overdue_tasks"`
+	HighPriority  int              `json:"high_priority_tasks"`
+	Load          *float64         `json:"load"`
+}
+
+// CreateTeamRequest represents the request to create a team
+type CreateTeamRequest struct {
+	Name        string `json:"name" validate:"required,min=2,max=100"`
+	Description string `json:"description"`
+}
+
+// UpdateTeamRequest represents the request to update a team
+type UpdateTeamRequest struct {
+	Name        string `json:"name" validate:"required,min=2,max=100"`
+	Description string `json:"description"`
+}
+
+// AddTeamMemberRequest represents the request to add a member to a team
+type AddTeamMemberRequest struct {
+	UserID        uuid.UUID `json:"user_id" validate:"required"`
+	Role          string    `json:"role" validate:"omitempty,oneof=lead member"`
+	CapacityHours *int      `json:"capacity_hours" validate:"omitempty,min=0,max=168"`
+}
+
+// UpdateTeamMemberRequest represents the request to change a team membership
+type UpdateTeamMemberRequest struct {
+	Role          string `json:"role" validate:"omitempty,oneof=lead member"`
+	CapacityHours *int   `json:"capacity_hours" validate:"omitempty,min=0,max=168"`
+}
+
+// AddProjectTeamRequest represents the request to add a team to a project
+type AddProjectTeamRequest struct {
+	TeamID uuid.UUID `json:"team_id" validate:"required"`
+	Role   string    `json:"role"` // Project role for the team's members, member by default
+}
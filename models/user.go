@@ -8,14 +8,38 @@ import (
 
 // User represents a user in the system
 type User struct {
-	ID           uuid.UUID  `json:"id"`
-	Username     string     `json:"username"`
-	Email        string     `json:"email"`
-	PasswordHash string     `json:"-"` // Never expose password hash in JSON
-	FullName     string     `json:"full_name"`
-	Role         string     `json:"role"` // admin or member
-	CreatedAt    time.Time  `json:"created_at"`
-	UpdatedAt    time.Time  `json:"updated_at"`
+	ID           uuid.UUID `json:"id"`
+	Username     string    `json:"username"`
+	Email        string    `json:"email"`
+	PasswordHash string    `json:"-"` // Never expose password hash in JSON
+	FullName     string    `json:"full_name"`
+	Role         string    `json:"role"` // admin or member
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+
+	EmailVerified     bool       `json:"email_verified"`
+	PasswordChangedAt *time.Time `json:"-"`
+
+	// New address waiting for confirmation, swapped in once its link is used
+	PendingEmail string `json:"-"`
+
+	// Profile picture and display preferences
+	AvatarURL string `json:"avatar_url,omitempty"`
+	Timezone  string `json:"timezone,omitempty"` // IANA name, UTC when empty
+	Locale    string `json:"locale,omitempty"`   // BCP 47 tag, e.g. en-US
+
+	// Set while an admin has deactivated the account
+	DeactivatedAt *time.Time `json:"deactivated_at,omitempty"`
+
+	// Organization the user currently works in, carried in the org claim
+	CurrentOrganizationID uuid.UUID `json:"-"`
+
+	// Two-factor authentication
+	TwoFactorEnabled   bool     `json:"two_factor_enabled"`
+	TOTPSecret         string   `json:"-"`
+	PendingTOTPSecret  string   `json:"-"` // Set during enrollment until a code confirms it
+	TOTPLastStep       int64    `json:"-"` // Last accepted time step, codes can't be replayed
+	RecoveryCodeHashes []string `json:"-"` // SHA-256 of unused recovery codes
 }
 
 // UserResponse is the user data that can be safely returned to clients
@@ -26,15 +50,36 @@ type UserResponse struct {
 	FullName  string    `json:"full_name"`
 	Role      string    `json:"role"`
 	CreatedAt time.Time `json:"created_at"`
+
+	EmailVerified    bool `json:"email_verified"`
+	TwoFactorEnabled bool `json:"two_factor_enabled"`
+
+	AvatarURL string `json:"avatar_url,omitempty"`
+	Timezone  string `json:"timezone,omitempty"`
+	Locale    string `json:"locale,omitempty"`
+
+	DeactivatedAt *time.Time `json:"deactivated_at,omitempty"`
+
+	CurrentOrganizationID uuid.UUID `json:"current_organization_id"`
 }
 
-// CreateUserRequest represents the request to create a new user
+// CreateUserRequest represents the request to create a new user. Everyone
+// signs up as a member; admins are appointed through the admin API.
 type CreateUserRequest struct {
 	Username string `json:"username" validate:"required,min=3,max=50"`
 	Email    string `json:"email" validate:"required,email"`
 	Password string `json:"password" validate:"required,min=8"`
 	FullName string `json:"full_name" validate:"required"`
-	Role     string `json:"role" validate:"required,oneof=admin member"`
+}
+
+// UpdateProfileRequest changes the current user's profile. Fields left out
+// are not changed; an empty timezone or locale restores the default.
+type UpdateProfileRequest struct {
+	Username *string `json:"username" validate:"omitempty,min=3,max=50"`
+	Email    *string `json:"email" validate:"omitempty,email"`
+	FullName *string `json:"full_name" validate:"omitempty,max=100"`
+	Timezone *string `json:"timezone"`
+	Locale   *string `json:"locale"`
 }
 
 // LoginRequest represents the request to login
@@ -52,5 +97,62 @@ func (u *User) ToResponse() UserResponse {
 		FullName:  u.FullName,
 		Role:      u.Role,
 		CreatedAt: u.CreatedAt,
+
+		EmailVerified:    u.EmailVerified,
+		TwoFactorEnabled: u.TwoFactorEnabled,
+
+		AvatarURL: u.AvatarURL,
+		Timezone:  u.Timezone,
+		Locale:    u.Locale,
+
+		DeactivatedAt: u.DeactivatedAt,
+
+		CurrentOrganizationID: u.CurrentOrganizationID,
 	}
 }
+
+// Active reports whether the user may sign in
+func (u *User) Active() bool {
+	return u.DeactivatedAt == nil
+}
+
+// TwoFactorLoginRequest completes a login that requires a second factor
+type TwoFactorLoginRequest struct {
+	ChallengeToken string `json:"challenge_token" validate:"required"`
+	Code           string `json:"code"`          // Code from the authenticator app
+	RecoveryCode   string `json:"recovery_code"` // Or one of the recovery codes
+}
+
+// TwoFactorCodeRequest carries a code from the authenticator app
+type TwoFactorCodeRequest struct {
+	Code string `json:"code" validate:"required,len=6"`
+}
+
+// DisableTwoFactorRequest requires the password and a current code or recovery code
+type DisableTwoFactorRequest struct {
+	Password     string `json:"password" validate:"required"`
+	Code         string `json:"code"`
+	RecoveryCode string `json:"recovery_code"`
+}
+
+// VerifyEmailRequest confirms an email address with the emailed token
+type VerifyEmailRequest struct {
+	Token string `json:"token" validate:"required"`
+}
+
+// ForgotPasswordRequest starts a password reset
+type ForgotPasswordRequest struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+// ResetPasswordRequest sets a new password with the emailed token
+type ResetPasswordRequest struct {
+	Token    string `json:"token" validate:"required"`
+	Password string `json:"password" validate:"required"`
+}
+
+// ChangePasswordRequest changes the password of the logged in user
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password"`
+	NewPassword     string `json:"new_password" validate:"required"`
+}
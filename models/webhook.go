@@ -0,0 +1,90 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Webhook event types
+const (
+	WebhookTaskCreated       = "task.created"
+	WebhookTaskUpdated       = "task.updated"
+	WebhookTaskStatusChanged = "task.status_changed"
+	WebhookTaskDeleted       = "task.deleted"
+	WebhookCommentCreated    = "comment.created"
+	WebhookProjectUpdated    = "project.updated"
+	WebhookProjectDeleted    = "project.deleted"
+	WebhookMemberAdded       = "member.added"
+	WebhookMemberUpdated     = "member.updated"
+	WebhookMemberRemoved     = "member.removed"
+)
+
+// WebhookEvents lists every event a webhook can subscribe to
+var WebhookEvents = []string{
+	WebhookTaskCreated,
+	WebhookTaskUpdated,
+	WebhookTaskStatusChanged,
+	WebhookTaskDeleted,
+	WebhookCommentCreated,
+	WebhookProjectUpdated,
+	WebhookProjectDeleted,
+	WebhookMemberAdded,
+	WebhookMemberUpdated,
+	WebhookMemberRemoved,
+}
+
+// Webhook represents an outgoing webhook subscription on a project
+type Webhook struct {
+	ID                  uuid.UUID  `json:"id"`
+	ProjectID           uuid.UUID  `json:"project_id"`
+	URL                 string     `json:"url"`
+	Secret              string     `json:"-"` // Used to sign payloads, only returned on creation
+	Events              []string   `json:"events"`
+	Active              bool       `json:"active"`
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	DisabledAt          *time.Time `json:"disabled_at,omitempty"` // Set when auto-disabled after repeated failures
+	CreatedBy           uuid.UUID  `json:"created_by"`
+	CreatedAt           time.Time  `json:"created_at"`
+	UpdatedAt           time.Time  `json:"updated_at"`
+}
+
+// Subscribes reports whether the webhook receives the given event
+func (w *Webhook) Subscribes(event string) bool {
+	for _, e := range w.Events {
+		if e == event || e == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookDelivery represents a single attempt to deliver an event to a webhook
+type WebhookDelivery struct {
+	ID          uuid.UUID `json:"id"`
+	WebhookID   uuid.UUID `json:"webhook_id"`
+	EventID     uuid.UUID `json:"event_id"` // Stable across retries and redeliveries
+	Event       string    `json:"event"`
+	Payload     string    `json:"payload"`
+	Attempt     int       `json:"attempt"`
+	StatusCode  int       `json:"status_code,omitempty"`
+	Error       string    `json:"error,omitempty"`
+	Success     bool      `json:"success"`
+	DurationMs  int64     `json:"duration_ms"`
+	Redelivery  bool      `json:"redelivery"`
+	DeliveredAt time.Time `json:"delivered_at"`
+}
+
+// CreateWebhookRequest represents the request to create a webhook
+type CreateWebhookRequest struct {
+	URL    string   `json:"url" validate:"required,url"`
+	Secret string   `json:"secret"` // Generated when empty
+	Events []string `json:"events" validate:"required,min=1"`
+}
+
+// UpdateWebhookRequest represents the request to update a webhook
+type UpdateWebhookRequest struct {
+	URL    string   `json:"url" validate:"required,url"`
+	Events []string `json:"events" validate:"required,min=1"`
+	Active bool     `json:"active"`
+}
@@ -0,0 +1,89 @@
+package notifier
+
+import (
+	"log"
+	"time"
+
+	"github.com/projectflow/mailer"
+	"github.com/projectflow/models"
+)
+
+// DigestHour is the UTC hour at which digests are sent
+const DigestHour = 8
+
+// DigestBatch is a recipient and the unread notifications to include in their digest
+type DigestBatch struct {
+	Recipient     *models.User
+	Notifications []*models.Notification
+}
+
+// DigestSource returns the batches for users subscribed to the given
+// frequency, limited to unread notifications created after since
+type DigestSource func(frequency string, since time.Time) []DigestBatch
+
+// DigestScheduler sends daily and weekly digests of unread notifications
+type DigestScheduler struct {
+	sender mailer.Sender
+	source DigestSource
+	appURL string
+}
+
+// NewDigestScheduler creates a digest scheduler and starts its hourly loop
+func NewDigestScheduler(sender mailer.Sender, source DigestSource, appURL string) *DigestScheduler {
+	s := &DigestScheduler{
+		sender: sender,
+		source: source,
+		appURL: appURL,
+	}
+
+	go s.run()
+
+	return s
+}
+
+func (s *DigestScheduler) run() {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+
+	for {
+		now := (<-ticker.C).UTC()
+		if now.Hour() != DigestHour {
+			continue
+		}
+		s.Send(models.DigestDaily, now.Add(-24*time.Hour))
+		if now.Weekday() == time.Monday {
+			s.Send(models.DigestWeekly, now.AddDate(0, 0, -7))
+		}
+	}
+}
+
+// Send emails a digest to every subscriber of the frequency with unread notifications
+func (s *DigestScheduler) Send(frequency string, since time.Time) {
+	for _, batch := range s.source(frequency, since) {
+		if batch.Recipient == nil || len(batch.Notifications) == 0 {
+			continue
+		}
+
+		items := make([]mailer.DigestItem, 0, len(batch.Notifications))
+		for _, notification := range batch.Notifications {
+			items = append(items, mailer.DigestItem{
+				Content:   notification.Content,
+				CreatedAt: notification.LastOccurredAt,
+			})
+		}
+
+		msg, err := mailer.Render("digest", batch.Recipient.Email, mailer.DigestData{
+			RecipientName: batch.Recipient.FullName,
+			Frequency:     frequency,
+			Items:         items,
+			AppURL:        s.appURL,
+		})
+		if err != nil {
+			log.Printf("notifier: rendering %s digest for %s failed: %v", frequency, batch.Recipient.ID, err)
+			continue
+		}
+		if err := s.sender.Send(msg); err != nil {
+			log.Printf("notifier: sending %s digest to %s failed: %v", frequency, batch.Recipient.ID, err)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package notifier
+
+import (
+	"github.com/projectflow/mailer"
+	"github.com/projectflow/models"
+)
+
+// EmailChannel renders notifications with the mailer templates and sends them over SMTP
+type EmailChannel struct {
+	sender mailer.Sender
+	appURL string
+}
+
+// NewEmailChannel creates an email channel that sends through sender
+func NewEmailChannel(sender mailer.Sender, appURL string) *EmailChannel {
+	return &EmailChannel{sender: sender, appURL: appURL}
+}
+
+// Name returns the channel identifier
+func (e *EmailChannel) Name() string {
+	return models.ChannelEmail
+}
+
+// Deliver emails the notification to the recipient.
+// Users who opted into a digest receive it batched instead.
+func (e *EmailChannel) Deliver(notification *models.Notification, recipient *models.User, prefs *models.NotificationPreferences) error {
+	if prefs.DigestFrequency != "" && prefs.DigestFrequency != models.DigestNone {
+		return nil
+	}
+
+	actionURL := e.appURL
+	if notification.RelatedID != nil {
+		switch notification.Type {
+		case models.NotificationTaskAssigned, models.NotificationCommentAdded,
+			models.NotificationMention, models.NotificationTaskDueSoon:
+			actionURL = e.appURL + "/tasks/" + notification.RelatedID.String()
+		}
+	}
+	if notification.Type == models.NotificationTimeOffDecision {
+		actionURL = e.appURL + "/time-off"
+	}
+
+	msg, err := mailer.Render(notification.Type, recipient.Email, mailer.EventData{
+		RecipientName: recipient.FullName,
+		Content:       notification.Content,
+		ActionURL:     actionURL,
+		AppURL:        e.appURL,
+	})
+	if err != nil {
+		return err
+	}
+
+	return e.sender.Send(msg)
+}
@@ -0,0 +1,146 @@
+package notifier
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"github.com/projectflow/models"
+)
+
+// Channel delivers a notification to a recipient over a single medium
+type Channel interface {
+	// Name returns the channel identifier (models.ChannelEmail, models.ChannelWebhook, ...)
+	Name() string
+	// Deliver sends the notification to the recipient
+	Deliver(notification *models.Notification, recipient *models.User, prefs *models.NotificationPreferences) error
+}
+
+// StoreFunc persists an in-app notification
+type StoreFunc func(notification *models.Notification)
+
+// deferredDelivery is a delivery held back until the recipient's quiet hours end
+type deferredDelivery struct {
+	channel      Channel
+	notification *models.Notification
+	recipient    *models.User
+	prefs        *models.NotificationPreferences
+	releaseAt    time.Time
+}
+
+// Dispatcher routes notifications to the channels enabled in each user's preferences
+type Dispatcher struct {
+	store    StoreFunc
+	channels map[string]Channel
+	deferred []deferredDelivery
+	mu       sync.Mutex
+}
+
+// New creates a dispatcher that stores in-app notifications with store
+// and delivers everything else through the given channels
+func New(store StoreFunc, channels ...Channel) *Dispatcher {
+	d := &Dispatcher{
+		store:    store,
+		channels: make(map[string]Channel),
+	}
+	for _, channel := range channels {
+		d.channels[channel.Name()] = channel
+	}
+
+	// Start the janitor to release deliveries held by quiet hours
+	go d.janitor()
+
+	return d
+}
+
+// Register adds or replaces a delivery channel
+func (d *Dispatcher) Register(channel Channel) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.channels[channel.Name()] = channel
+}
+
+// Dispatch routes a notification according to the recipient's preferences.
+// In-app notifications are always stored immediately; other channels are
+// deferred while the recipient is in quiet hours.
+func (d *Dispatcher) Dispatch(notification *models.Notification, recipient *models.User, prefs *models.NotificationPreferences) {
+	enabled := prefs.ChannelsFor(notification.Type)
+
+	if enabled.InApp && d.store != nil {
+		d.store(notification)
+	}
+
+	var names []string
+	if enabled.Email {
+		names = append(names, models.ChannelEmail)
+	}
+	if enabled.Webhook {
+		names = append(names, models.ChannelWebhook)
+	}
+	if len(names) == 0 || recipient == nil {
+		return
+	}
+
+	releaseAt, quiet := prefs.QuietHoursEndAfter(time.Now())
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for _, name := range names {
+		channel, ok := d.channels[name]
+		if !ok {
+			continue
+		}
+		if quiet {
+			d.deferred = append(d.deferred, deferredDelivery{
+				channel:      channel,
+				notification: notification,
+				recipient:    recipient,
+				prefs:        prefs,
+				releaseAt:    releaseAt,
+			})
+			continue
+		}
+		go deliver(channel, notification, recipient, prefs)
+	}
+}
+
+// deliver sends a notification through a channel and logs failures
+func deliver(channel Channel, notification *models.Notification, recipient *models.User, prefs *models.NotificationPreferences) {
+	if err := channel.Deliver(notification, recipient, prefs); err != nil {
+		log.Printf("notifier: %s delivery of notification %s failed: %v", channel.Name(), notification.ID, err)
+	}
+}
+
+func (d *Dispatcher) janitor() {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	for {
+		<-ticker.C
+		d.releaseDeferred()
+	}
+}
+
+// releaseDeferred delivers every notification whose quiet hours have ended
+func (d *Dispatcher) releaseDeferred() {
+	now := time.Now()
+
+	d.mu.Lock()
+	var due []deferredDelivery
+	remaining := d.deferred[:0]
+	for _, item := range d.deferred {
+		if now.Before(item.releaseAt) {
+			remaining = append(remaining, item)
+		} else {
+			due = append(due, item)
+		}
+	}
+	d.deferred = remaining
+	d.mu.Unlock()
+
+	for _, item := range due {
+		deliver(item.channel, item.notification, item.recipient, item.prefs)
+	}
+}
@@ -0,0 +1,64 @@
+package notifier
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/projectflow/models"
+	"github.com/projectflow/utils/safehttp"
+)
+
+// WebhookChannel posts notifications as JSON to the user's configured webhook URL
+type WebhookChannel struct {
+	Client *http.Client
+}
+
+// NewWebhookChannel creates a webhook channel with a bounded request timeout
+// that refuses to post to local or private network addresses
+func NewWebhookChannel() *WebhookChannel {
+	return &WebhookChannel{
+		Client: safehttp.NewClient(10 * time.Second),
+	}
+}
+
+// Name returns the channel identifier
+func (w *WebhookChannel) Name() string {
+	return models.ChannelWebhook
+}
+
+// Deliver posts the notification to the recipient's webhook URL
+func (w *WebhookChannel) Deliver(notification *models.Notification, recipient *models.User, prefs *models.NotificationPreferences) error {
+	if prefs.WebhookURL == "" {
+		return nil
+	}
+
+	body, err := json.Marshal(webhookPayload{
+		Event:        "notification." + notification.Type,
+		UserID:       recipient.ID.String(),
+		Notification: notification,
+	})
+	if err != nil {
+		return err
+	}
+
+	resp, err := w.Client.Post(prefs.WebhookURL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook responded with status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// webhookPayload is the JSON body sent to user webhooks
+type webhookPayload struct {
+	Event        string               `json:"event"`
+	UserID       string               `json:"user_id"`
+	Notification *models.Notification `json:"notification"`
+}
@@ -5,6 +5,10 @@ import Layout from './components/Layout';
 import AuthLayout from './pages/AuthLayout';
 import Login from './pages/Login';
 import Register from './pages/Register';
+import ResetPassword from './pages/ResetPassword';
+import VerifyEmail from './pages/VerifyEmail';
+import AcceptInvite from './pages/AcceptInvite';
+import AcceptProjectInvite from './pages/AcceptProjectInvite';
 import Dashboard from './pages/Dashboard';
 import Projects from './pages/Projects';
 import ProjectDetail from './pages/ProjectDetail';
@@ -43,6 +47,9 @@ function App() {
         <Route element={<AuthLayout />}>
           <Route path="/login" element={<Login />} />
           <Route path="/register" element={<Register />} />
+          <Route path="/reset-password" element={<ResetPassword />} />
+          <Route path="/verify-email" element={<VerifyEmail />} />
+          <Route path="/invites/project" element={<AcceptProjectInvite />} />
         </Route>
         
         <Route path="/" element={<Layout />}>
@@ -106,6 +113,12 @@ function App() {
             </ProtectedRoute>
           } />
           
+          <Route path="invites/accept" element={
+            <ProtectedRoute>
+              <AcceptInvite />
+            </ProtectedRoute>
+          } />
+          
           <Route path="profile" element={
             <ProtectedRoute>
               <Profile />
@@ -13,6 +13,7 @@ import {
   DropdownMenuTrigger 
 } from './ui/dropdown-menu';
 import ThemeToggle from './ThemeToggle';
+import OrganizationSwitcher from './OrganizationSwitcher';
 
 function Layout() {
   const { user, isAuthenticated, logout } = useAuth();
@@ -111,6 +112,9 @@ function Layout() {
               </nav>
             </div>
             <div className="hidden sm:ml-6 sm:flex sm:items-center">
+              {/* Organization switcher */}
+              <OrganizationSwitcher />
+
               {/* Theme toggle */}
               <ThemeToggle />
               
@@ -154,7 +158,7 @@ function Layout() {
                             <p className="text-sm">{notification.content}</p>
                             <p className="text-xs text-gray-500 mt-1">
                               {new Date(
-                                notification.created_at
+                                notification.last_occurred_at
                               ).toLocaleString()}
                             </p>
                           </div>
@@ -0,0 +1,65 @@
+import { useEffect, useState } from 'react';
+import { Building2, Check } from 'lucide-react';
+import { getOrganizations, switchOrganization } from '../lib/api';
+import { Organization } from '../lib/types';
+import { Button } from './ui/button';
+import {
+  DropdownMenu,
+  DropdownMenuContent,
+  DropdownMenuItem,
+  DropdownMenuTrigger
+} from './ui/dropdown-menu';
+
+interface Membership {
+  organization: Organization;
+  role: string;
+  current: boolean;
+}
+
+function OrganizationSwitcher() {
+  const [memberships, setMemberships] = useState<Membership[]>([]);
+
+  useEffect(() => {
+    getOrganizations()
+      .then((res) => setMemberships(res.data.organizations))
+      .catch((error) => console.error('Failed to fetch organizations', error));
+  }, []);
+
+  const handleSwitch = async (organizationId: string) => {
+    try {
+      const res = await switchOrganization(organizationId);
+      localStorage.setItem('token', res.data.token);
+      localStorage.setItem('refresh_token', res.data.refresh_token);
+      // Everything on screen belongs to the previous organization
+      window.location.assign('/');
+    } catch (error) {
+      console.error('Failed to switch organization', error);
+    }
+  };
+
+  const current = memberships.find((m) => m.current);
+
+  return (
+    <DropdownMenu>
+      <DropdownMenuTrigger asChild>
+        <Button variant="ghost" size="sm" className="ml-2 max-w-[12rem]">
+          <Building2 className="mr-2 h-4 w-4 flex-shrink-0" />
+          <span className="truncate">{current ? current.organization.name : 'Organization'}</span>
+        </Button>
+      </DropdownMenuTrigger>
+      <DropdownMenuContent align="end" className="w-56">
+        {memberships.map((m) => (
+          <DropdownMenuItem
+            key={m.organization.id}
+            onClick={() => !m.current && handleSwitch(m.organization.id)}
+          >
+            <Check className={`mr-2 h-4 w-4 ${m.current ? '' : 'invisible'}`} />
+            <span className="truncate">{m.organization.name}</span>
+          </DropdownMenuItem>
+        ))}
+      </DropdownMenuContent>
+    </DropdownMenu>
+  );
+}
+
+export default OrganizationSwitcher;
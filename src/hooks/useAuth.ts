@@ -1,5 +1,5 @@
 import { useState, useEffect } from 'react';
-import { login, register, getCurrentUser } from '../lib/api';
+import { login, register, getCurrentUser, completeTwoFactorLogin, logout as logoutSession } from '../lib/api';
 
 interface User {
   id: string;
@@ -8,6 +8,9 @@ interface User {
   full_name: string;
   role: string;
   created_at: string;
+  avatar_url?: string;
+  timezone?: string;
+  locale?: string;
 }
 
 interface AuthState {
@@ -25,7 +28,23 @@ export const useAuth = () => {
     error: null,
   });
 
+  // Set after the password or single sign-on step when the account uses
+  // two-factor authentication
+  const [challengeToken, setChallengeToken] = useState<string | null>(null);
+
   useEffect(() => {
+    // Single sign-on redirects back with tokens in the URL fragment
+    const params = new URLSearchParams(window.location.hash.slice(1));
+    if (params.get('token') && params.get('refresh_token')) {
+      localStorage.setItem('token', params.get('token') as string);
+      localStorage.setItem('refresh_token', params.get('refresh_token') as string);
+      window.history.replaceState(null, '', window.location.pathname + window.location.search);
+    } else if (params.get('challenge_token')) {
+      // Accounts with two-factor authentication still have to enter a code
+      setChallengeToken(params.get('challenge_token'));
+      window.history.replaceState(null, '', window.location.pathname + window.location.search);
+    }
+
     const token = localStorage.getItem('token');
     if (token) {
       loadUser();
@@ -51,6 +70,7 @@ export const useAuth = () => {
       });
     } catch (err) {
       localStorage.removeItem('token');
+      localStorage.removeItem('refresh_token');
       setAuthState({
         user: null,
         isAuthenticated: false,
@@ -60,12 +80,50 @@ export const useAuth = () => {
     }
   };
 
+  const startSession = (data: { token: string; refresh_token: string; user: User }) => {
+    localStorage.setItem('token', data.token);
+    localStorage.setItem('refresh_token', data.refresh_token);
+    setChallengeToken(null);
+    setAuthState({
+      user: data.user,
+      isAuthenticated: true,
+      isLoading: false,
+      error: null,
+    });
+  };
+
+  const completeTwoFactor = async (code: string) => {
+    if (!challengeToken) return false;
+    setAuthState({ ...authState, isLoading: true, error: null });
+    try {
+      const res = await completeTwoFactorLogin(challengeToken, code.trim());
+      startSession(res.data);
+      return true;
+    } catch (err: any) {
+      if (err.response?.status === 401 && err.response?.data?.code !== 'invalid_totp_code') {
+        setChallengeToken(null);
+      }
+      setAuthState({
+        ...authState,
+        isLoading: false,
+        error: err.response?.data?.error || 'Verification failed',
+      });
+      return false;
+    }
+  };
+
   const loginUser = async (email: string, password: string) => {
     setAuthState({ ...authState, isLoading: true, error: null });
     try {
       const res = await login(email, password);
-      const data = res.data as { token: string; user: User };
+      if (res.data.two_factor_required) {
+        setChallengeToken(res.data.challenge_token);
+        setAuthState({ ...authState, isLoading: false, error: null });
+        return false;
+      }
+      const data = res.data as { token: string; refresh_token: string; user: User };
       localStorage.setItem('token', data.token);
+      localStorage.setItem('refresh_token', data.refresh_token);
       setAuthState({
         user: data.user,
         isAuthenticated: true,
@@ -86,15 +144,10 @@ export const useAuth = () => {
   const registerUser = async (userData: any) => {
     setAuthState({ ...authState, isLoading: true, error: null });
     try {
-      const res = await register(userData);
-      const data = res.data as { token: string; user: User };
-      localStorage.setItem('token', data.token);
-      setAuthState({
-        user: data.user,
-        isAuthenticated: true,
-        isLoading: false,
-        error: null,
-      });
+      // Registration never signs in, so it doesn't reveal whether the
+      // email already had an account
+      await register(userData);
+      setAuthState({ ...authState, isLoading: false, error: null });
       return true;
     } catch (err: any) {
       setAuthState({
@@ -107,7 +160,10 @@ export const useAuth = () => {
   };
 
   const logout = () => {
+    // Revoke the session server-side; local state is cleared either way
+    logoutSession(localStorage.getItem('refresh_token')).catch(() => {});
     localStorage.removeItem('token');
+    localStorage.removeItem('refresh_token');
     setAuthState({
       user: null,
       isAuthenticated: false,
@@ -119,6 +175,8 @@ export const useAuth = () => {
   return {
     ...authState,
     loginUser,
+    completeTwoFactor,
+    twoFactorRequired: challengeToken !== null,
     registerUser,
     logout,
     loadUser,
@@ -3,10 +3,11 @@ import {
   Task, Project, TaskStatus, Notification, CommentResponse,
   ResourceAllocation, ResourceAllocationResponse, UserAvailability,
   TimeOffRequest, CreateResourceAllocationRequest, CreateUserAvailabilityRequest,
-  CreateTimeOffRequestRequest
+  CreateTimeOffRequestRequest, Organization, Team, TeamWorkload, AssignmentSuggestion, Impersonation,
+  UserResponse
 } from './types';
 
-const API_URL = import.meta.env.VITE_API_URL || 'http://localhost:8080/api';
+const API_URL = import.meta.env.VITE_API_URL || 'http://localhost:8080/api/v1';
 
 // Simple request cache
 const cache: Record<string, { data: any; timestamp: number }> = {};
@@ -31,6 +32,43 @@ api.interceptors.request.use(
   (error) => Promise.reject(error)
 );
 
+// Refresh the access token once when it expires, sharing one refresh between concurrent requests
+let refreshing: Promise<string> | null = null;
+
+const refreshAccessToken = async (): Promise<string> => {
+  const refreshToken = localStorage.getItem('refresh_token');
+  if (!refreshToken) {
+    throw new Error('No refresh token');
+  }
+  const res = await axios.post(`${API_URL}/auth/refresh`, { refresh_token: refreshToken });
+  localStorage.setItem('token', res.data.token);
+  localStorage.setItem('refresh_token', res.data.refresh_token);
+  return res.data.token;
+};
+
+api.interceptors.response.use(
+  (response) => response,
+  async (error) => {
+    const original = error.config;
+    if (error.response?.status !== 401 || !original || original._retry || /^\/auth\/(login|register|refresh|logout)/.test(original.url || '')) {
+      return Promise.reject(error);
+    }
+    original._retry = true;
+    try {
+      refreshing = refreshing || refreshAccessToken();
+      const token = await refreshing;
+      original.headers.Authorization = `Bearer ${token}`;
+      return api(original);
+    } catch {
+      localStorage.removeItem('token');
+      localStorage.removeItem('refresh_token');
+      return Promise.reject(error);
+    } finally {
+      refreshing = null;
+    }
+  }
+);
+
 // Create cached versions of GET requests
 export const cachedGet = async <T>(url: string): Promise<{ data: T }> => {
   const cacheKey = url;
@@ -57,12 +95,100 @@ export const cachedGet = async <T>(url: string): Promise<{ data: T }> => {
 export const login = (email: string, password: string) => 
   api.post('/auth/login', { email, password });
 
+export const completeTwoFactorLogin = (challengeToken: string, code: string) => 
+  api.post('/auth/login/2fa', code.includes('-') 
+    ? { challenge_token: challengeToken, recovery_code: code }
+    : { challenge_token: challengeToken, code });
+
 export const register = (userData: any) => 
   api.post('/auth/register', userData);
 
 export const getCurrentUser = () => 
   api.get('/auth/me');
 
+export const logout = (refreshToken: string | null) => 
+  api.post('/auth/logout', { refresh_token: refreshToken });
+
+export const logoutAll = () => 
+  api.post('/auth/logout-all');
+
+export const getSSOProviders = (): Promise<{ data: { providers: string[] } }> => 
+  api.get('/auth/oidc');
+
+export const verifyEmail = (token: string) => 
+  api.post('/auth/verify-email', { token });
+
+export const forgotPassword = (email: string) => 
+  api.post('/auth/forgot-password', { email });
+
+export const resetPassword = (token: string, password: string) => 
+  api.post('/auth/reset-password', { token, password });
+
+export const changePassword = (currentPassword: string, newPassword: string) => 
+  api.put('/me/password', { current_password: currentPassword, new_password: newPassword });
+
+// Profile endpoints
+export const updateProfile = (profile: { full_name?: string; username?: string; email?: string; timezone?: string; locale?: string }): Promise<{ data: { user: UserResponse; pending_email: string } }> =>
+  api.patch('/me', profile);
+
+export const uploadAvatar = (file: File): Promise<{ data: { user: UserResponse } }> => {
+  const form = new FormData();
+  form.append('avatar', file);
+  return api.put('/me/avatar', form, { headers: { 'Content-Type': 'multipart/form-data' } });
+};
+
+export const deleteAvatar = (): Promise<{ data: { user: UserResponse } }> =>
+  api.delete('/me/avatar');
+
+// Organization endpoints
+export const getOrganizations = (): Promise<{ data: { organizations: { organization: Organization; role: string; current: boolean }[] } }> =>
+  api.get('/organizations');
+
+export const createOrganization = (name: string) =>
+  api.post('/organizations', { name });
+
+// Returns a new session whose token carries the organization
+export const switchOrganization = (organizationId: string) =>
+  api.post('/organizations/switch', { organization_id: organizationId });
+
+export const getCurrentOrganization = () =>
+  api.get('/organizations/current');
+
+export const inviteToOrganization = (email: string, role: string) =>
+  api.post('/organizations/current/invites', { email, role });
+
+export const acceptOrganizationInvite = (token: string): Promise<{ data: { organization: Organization } }> =>
+  api.post('/organizations/invites/accept', { token });
+
+// Admin user management
+export const updateUserProfile = (userId: string, data: { username: string; email: string; full_name: string }) =>
+  api.put(`/admin/users/${userId}`, data);
+
+export const updateUserRole = (userId: string, role: 'admin' | 'member') =>
+  api.put(`/admin/users/${userId}/role`, { role });
+
+export const deactivateUser = (userId: string) =>
+  api.post(`/admin/users/${userId}/deactivate`);
+
+export const reactivateUser = (userId: string) =>
+  api.post(`/admin/users/${userId}/reactivate`);
+
+export const reassignUserWork = (userId: string, toUserId: string, addToProjects = false) =>
+  api.post(`/admin/users/${userId}/reassign`, { to_user_id: toUserId, add_to_projects: addToProjects });
+
+export const startImpersonation = (userId: string, reason: string, minutes?: number): Promise<{ data: { impersonation: Impersonation; token: string; expires_in: number } }> =>
+  api.post(`/admin/users/${userId}/impersonate`, { reason, minutes });
+
+export const getImpersonations = (): Promise<{ data: { impersonations: Impersonation[] } }> =>
+  api.get('/admin/impersonations');
+
+export const endImpersonation = (impersonationId: string) =>
+  api.delete(`/admin/impersonations/${impersonationId}`);
+
+// SSO starts with a full page redirect, not an XHR
+export const ssoLoginURL = (provider: string) => 
+  `${API_URL}/auth/oidc/${encodeURIComponent(provider)}`;
+
 // Projects API
 export const getProjects = (): Promise<{ data: { projects: Project[] } }> => 
   cachedGet('/projects');
@@ -85,6 +211,85 @@ export const addProjectMember = (projectId: string, userData: any) =>
 export const removeProjectMember = (projectId: string, userId: string) => 
   api.delete(`/projects/${projectId}/members/${userId}`);
 
+export const updateProjectMemberRole = (projectId: string, userId: string, role: string) =>
+  api.put(`/projects/${projectId}/members/${userId}`, { role });
+
+// Project invitation endpoints
+export const getProjectInvites = (projectId: string, status = 'pending') =>
+  api.get(`/projects/${projectId}/invites`, { params: { status } });
+
+export const inviteToProject = (projectId: string, email: string, role?: string) =>
+  api.post(`/projects/${projectId}/invites`, { email, role });
+
+export const resendProjectInvite = (projectId: string, inviteId: string) =>
+  api.post(`/projects/${projectId}/invites/${inviteId}/resend`);
+
+export const revokeProjectInvite = (projectId: string, inviteId: string) =>
+  api.delete(`/projects/${projectId}/invites/${inviteId}`);
+
+// Accepting needs no session; the token is enough
+export const getProjectInviteByToken = (token: string) =>
+  api.get('/project-invites', { params: { token } });
+
+export const acceptProjectInvite = (token: string, account?: { username: string; full_name: string; password: string }) =>
+  api.post('/project-invites/accept', { token, ...account });
+
+// Project role endpoints
+export const getProjectRoles = (projectId: string) =>
+  api.get(`/projects/${projectId}/roles`);
+
+export const createProjectRole = (projectId: string, roleData: any) =>
+  api.post(`/projects/${projectId}/roles`, roleData);
+
+export const updateProjectRole = (projectId: string, name: string, roleData: any) =>
+  api.put(`/projects/${projectId}/roles/${name}`, roleData);
+
+export const deleteProjectRole = (projectId: string, name: string) =>
+  api.delete(`/projects/${projectId}/roles/${name}`);
+
+export const getProjectTeams = (projectId: string) =>
+  api.get(`/projects/${projectId}/teams`);
+
+export const addProjectTeam = (projectId: string, teamId: string, role?: string) =>
+  api.post(`/projects/${projectId}/teams`, { team_id: teamId, role });
+
+export const removeProjectTeam = (projectId: string, teamId: string) =>
+  api.delete(`/projects/${projectId}/teams/${teamId}`);
+
+export const getAssignmentSuggestions = (projectId: string, teamId?: string): Promise<{ data: { suggestions: AssignmentSuggestion[] } }> =>
+  api.get(`/projects/${projectId}/assignment-suggestions`, { params: { team_id: teamId } });
+
+// Team endpoints
+export const getTeams = (): Promise<{ data: { teams: Team[] } }> =>
+  api.get('/teams');
+
+export const getTeam = (id: string) =>
+  api.get(`/teams/${id}`);
+
+export const createTeam = (teamData: { name: string; description?: string }) =>
+  api.post('/teams', teamData);
+
+export const updateTeam = (id: string, teamData: { name: string; description?: string }) =>
+  api.put(`/teams/${id}`, teamData);
+
+export const deleteTeam = (id: string) =>
+  api.delete(`/teams/${id}`);
+
+export const addTeamMember = (teamId: string, userId: string, role?: string, capacityHours?: number) =>
+  api.post(`/teams/${teamId}/members`, { user_id: userId, role, capacity_hours: capacityHours });
+
+export const updateTeamMember = (teamId: string, userId: string, data: { role?: string; capacity_hours?: number }) =>
+  api.put(`/teams/${teamId}/members/${userId}`, data);
+
+export const removeTeamMember = (teamId: string, userId: string) =>
+  api.delete(`/teams/${teamId}/members/${userId}`);
+
+export const getTeamWorkload = (teamId: string): Promise<{ data: { workload: TeamWorkload } }> =>
+  api.get(`/teams/${teamId}/workload`);
+
+export const getTeamsWorkload = (): Promise<{ data: { teams: TeamWorkload[] } }> =>
+  api.get('/teams/workload');
+
 // Tasks API
 export const getTasks = (projectId: string): Promise<{ data: { tasks: Task[] } }> => 
   cachedGet(`/tasks/project/${projectId}`);
@@ -122,12 +327,16 @@ export const markAllNotificationsRead = () =>
   api.patch('/notifications', { read: true });
 
 // Resource Allocation API
-export const getResourceAllocations = (filters?: { user_id?: string; project_id?: string }): Promise<{ data: { allocations: ResourceAllocation[] } }> => {
+// Listings are paged newest first; pass next_cursor back as cursor for the next page
+export const getResourceAllocations = (filters?: { user_id?: string; project_id?: string; include?: string; limit?: number; cursor?: string }): Promise<{ data: { allocations: ResourceAllocation[]; next_cursor: string } }> => {
   let url = '/resources/allocations';
   if (filters) {
     const params = new URLSearchParams();
     if (filters.user_id) params.append('user_id', filters.user_id);
     if (filters.project_id) params.append('project_id', filters.project_id);
+    if (filters.include !== undefined) params.append('include', filters.include);
+    if (filters.limit) params.append('limit', String(filters.limit));
+    if (filters.cursor) params.append('cursor', filters.cursor);
     if (params.toString()) url += `?${params.toString()}`;
   }
   return cachedGet(url);
@@ -162,12 +371,15 @@ export const deleteUserAvailability = (id: number) =>
   api.delete(`/resources/availability/${id}`);
 
 // Time Off Request API
-export const getTimeOffRequests = (filters?: { user_id?: string; status?: string }): Promise<{ data: { requests: TimeOffRequest[] } }> => {
+export const getTimeOffRequests = (filters?: { user_id?: string; status?: string; include?: string; limit?: number; cursor?: string }): Promise<{ data: { requests: TimeOffRequest[]; next_cursor: string } }> => {
   let url = '/resources/timeoff';
   if (filters) {
     const params = new URLSearchParams();
     if (filters.user_id) params.append('user_id', filters.user_id);
     if (filters.status) params.append('status', filters.status);
+    if (filters.include !== undefined) params.append('include', filters.include);
+    if (filters.limit) params.append('limit', String(filters.limit));
+    if (filters.cursor) params.append('cursor', filters.cursor);
     if (params.toString()) url += `?${params.toString()}`;
   }
   return cachedGet(url);
@@ -15,6 +15,10 @@ export interface UserResponse {
   full_name: string;
   role: string;
   created_at: string;
+  avatar_url?: string;
+  timezone?: string;
+  locale?: string;
+  deactivated_at?: string;
 }
 
 export interface LoginRequest {
@@ -155,7 +159,9 @@ export interface Notification {
   type: string;
   read: boolean;
   related_id?: string;
+  count: number;
   created_at: string;
+  last_occurred_at: string;
 }
 
 export interface NotificationResponse {
@@ -226,6 +232,8 @@ export interface TimeOffRequest {
   status: string;
   request_type: string;
   notes?: string;
+  approver_id?: string;
+  decided_at?: string;
   created_at: string;
   updated_at: string;
   user?: User;
@@ -265,3 +273,79 @@ export interface CreateTimeOffRequestRequest {
   request_type: string;
   notes?: string;
 }
+
+export interface Organization {
+  id: string;
+  name: string;
+  owner_id: string;
+  created_at: string;
+  updated_at: string;
+}
+
+export interface Team {
+  id: string;
+  organization_id: string;
+  name: string;
+  description: string;
+  created_at: string;
+  updated_at: string;
+}
+
+export interface MemberWorkload {
+  user: UserResponse;
+  role: 'lead' | 'member';
+  capacity_hours: number;
+  open_tasks: number;
+  overdue_tasks: number;
+  high_priority_tasks: number;
+  load: number | null;
+}
+
+export interface TeamWorkload {
+  team: Team;
+  members?: MemberWorkload[];
+  member_count: number;
+  capacity_hours: number;
+  open_tasks: number;
+  overdue_tasks: number;
+  high_priority_tasks: number;
+  load: number | null;
+}
+
+export interface AssignmentSuggestion {
+  user: UserResponse;
+  team_id: string;
+  capacity_hours: number;
+  open_tasks: number;
+  overdue_tasks: number;
+  load: number;
+}
+
+export interface ProjectInvite {
+  id: string;
+  project_id: string;
+  email: string;
+  role: string;
+  invited_by: string;
+  created_at: string;
+  expires_at: string;
+  sent_at: string;
+  send_count: number;
+  accepted_at?: string;
+  accepted_by?: string;
+  revoked_at?: string;
+  revoked_by?: string;
+  status: 'pending' | 'accepted' | 'revoked' | 'expired';
+}
+
+export interface Impersonation {
+  id: string;
+  admin_id: string;
+  user_id: string;
+  organization_id: string;
+  reason: string;
+  started_at: string;
+  expires_at: string;
+  ended_at?: string;
+  requests: { method: string; path: string; status: number; at: string }[];
+}
@@ -0,0 +1,55 @@
+import { useEffect, useState } from 'react';
+import { Link, useSearchParams } from 'react-router-dom';
+import { acceptOrganizationInvite } from '../lib/api';
+import { Card, CardContent, CardDescription, CardFooter, CardHeader, CardTitle } from '../components/ui/card';
+import { Alert, AlertDescription } from '../components/ui/alert';
+
+export default function AcceptInvite() {
+  const [searchParams] = useSearchParams();
+  const [status, setStatus] = useState<'pending' | 'accepted' | 'failed'>('pending');
+  const [organizationName, setOrganizationName] = useState('');
+  const [error, setError] = useState<string | null>(null);
+
+  useEffect(() => {
+    acceptOrganizationInvite(searchParams.get('token') || '')
+      .then((res) => {
+        setOrganizationName(res.data.organization.name);
+        setStatus('accepted');
+      })
+      .catch((err) => {
+        setError(err.response?.data?.error || 'The invitation could not be accepted');
+        setStatus('failed');
+      });
+  }, [searchParams]);
+
+  return (
+    <div className="flex items-center justify-center py-12 px-4 sm:px-6 lg:px-8">
+      <Card className="w-full max-w-md">
+        <CardHeader className="space-y-1">
+          <CardTitle className="text-2xl font-bold text-center">ProjectFlow</CardTitle>
+          <CardDescription className="text-center">Organization invitation</CardDescription>
+        </CardHeader>
+        <CardContent>
+          {status === 'pending' && <p className="text-center text-sm">Accepting invitation...</p>}
+          {status === 'accepted' && (
+            <Alert>
+              <AlertDescription>
+                You joined {organizationName}. Switch to it from the organization menu.
+              </AlertDescription>
+            </Alert>
+          )}
+          {status === 'failed' && (
+            <Alert variant="destructive">
+              <AlertDescription>{error}</AlertDescription>
+            </Alert>
+          )}
+        </CardContent>
+        <CardFooter className="flex justify-center">
+          <Link to="/" className="font-medium text-indigo-600 hover:text-indigo-500">
+            Go to dashboard
+          </Link>
+        </CardFooter>
+      </Card>
+    </div>
+  );
+}
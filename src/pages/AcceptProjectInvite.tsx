@@ -0,0 +1,111 @@
+import { useEffect, useState } from 'react';
+import { Link, useSearchParams } from 'react-router-dom';
+import { acceptProjectInvite, getProjectInviteByToken } from '../lib/api';
+import { Button } from '../components/ui/button';
+import { Input } from '../components/ui/input';
+import { Label } from '../components/ui/label';
+import { Card, CardContent, CardDescription, CardFooter, CardHeader, CardTitle } from '../components/ui/card';
+import { Alert, AlertDescription } from '../components/ui/alert';
+
+interface InviteDetails {
+  project_name: string;
+  email: string;
+  role: string;
+  invited_by: string;
+  account_exists: boolean;
+}
+
+// People without an account choose a username and password here;
+// everyone else joins with one click and signs in as usual
+export default function AcceptProjectInvite() {
+  const [searchParams] = useSearchParams();
+  const token = searchParams.get('token') || '';
+  const [invite, setInvite] = useState<InviteDetails | null>(null);
+  const [account, setAccount] = useState({ username: '', full_name: '', password: '' });
+  const [joined, setJoined] = useState(false);
+  const [error, setError] = useState<string | null>(null);
+  const [isLoading, setIsLoading] = useState(false);
+
+  useEffect(() => {
+    getProjectInviteByToken(token)
+      .then((res) => setInvite(res.data))
+      .catch((err) => setError(err.response?.data?.error || 'The invitation could not be loaded'));
+  }, [token]);
+
+  const handleSubmit = async (e: React.FormEvent) => {
+    e.preventDefault();
+    setIsLoading(true);
+    setError(null);
+    try {
+      const res = await acceptProjectInvite(token, invite?.account_exists ? undefined : account);
+      if (res.data.token) {
+        // New accounts are signed in right away
+        localStorage.setItem('token', res.data.token);
+        localStorage.setItem('refresh_token', res.data.refresh_token);
+        window.location.assign(`/projects/${res.data.project.id}`);
+        return;
+      }
+      setJoined(true);
+    } catch (err: any) {
+      setError(err.response?.data?.error || 'The invitation could not be accepted');
+    } finally {
+      setIsLoading(false);
+    }
+  };
+
+  return (
+    <div className="min-h-screen flex items-center justify-center bg-gray-50 py-12 px-4 sm:px-6 lg:px-8">
+      <Card className="w-full max-w-md">
+        <CardHeader className="space-y-1">
+          <CardTitle className="text-2xl font-bold text-center">ProjectFlow</CardTitle>
+          <CardDescription className="text-center">
+            {invite ? `${invite.invited_by || 'Someone'} invited you to ${invite.project_name} as ${invite.role}` : 'Project invitation'}
+          </CardDescription>
+        </CardHeader>
+        <CardContent>
+          {error && (
+            <Alert variant="destructive" className="mb-4">
+              <AlertDescription>{error}</AlertDescription>
+            </Alert>
+          )}
+          {joined ? (
+            <Alert>
+              <AlertDescription>You joined {invite?.project_name}. Sign in to get started.</AlertDescription>
+            </Alert>
+          ) : invite && (
+            <form onSubmit={handleSubmit} className="space-y-4">
+              {!invite.account_exists && (
+                <>
+                  <p className="text-sm text-gray-600">Create an account for {invite.email} to join.</p>
+                  <div className="space-y-2">
+                    <Label htmlFor="username">Username</Label>
+                    <Input id="username" value={account.username} required
+                      onChange={(e) => setAccount({ ...account, username: e.target.value })} />
+                  </div>
+                  <div className="space-y-2">
+                    <Label htmlFor="full_name">Full name</Label>
+                    <Input id="full_name" value={account.full_name}
+                      onChange={(e) => setAccount({ ...account, full_name: e.target.value })} />
+                  </div>
+                  <div className="space-y-2">
+                    <Label htmlFor="password">Password</Label>
+                    <Input id="password" type="password" value={account.password} required
+                      onChange={(e) => setAccount({ ...account, password: e.target.value })} />
+                  </div>
+                </>
+              )}
+              <Button type="submit" className="w-full" disabled={isLoading}>
+                {isLoading ? 'Joining...' : 'Join project'}
+              </Button>
+            </form>
+          )}
+        </CardContent>
+        <CardFooter className="flex justify-center">
+          <Link to="/login" className="font-medium text-indigo-600 hover:text-indigo-500">
+            Sign in
+          </Link>
+        </CardFooter>
+      </Card>
+    </div>
+  );
+}
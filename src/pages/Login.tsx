@@ -1,5 +1,5 @@
-import { useState } from 'react';
-import { Link, useNavigate } from 'react-router-dom';
+import { useEffect, useState } from 'react';
+import { Link, useNavigate, useSearchParams } from 'react-router-dom';
 import { useAuth } from '../hooks/useAuth';
 import { Button } from '../components/ui/button';
 import { Input } from '../components/ui/input';
@@ -7,16 +7,29 @@ import { Label } from '../components/ui/label';
 import { Card, CardContent, CardDescription, CardFooter, CardHeader, CardTitle } from '../components/ui/card';
 import { Alert, AlertDescription } from '../components/ui/alert';
 import TestCredentialsDisplay from '../components/TestCredentialsDisplay';
+import { getSSOProviders, ssoLoginURL } from '../lib/api';
 
 export default function Login() {
   const [email, setEmail] = useState('');
   const [password, setPassword] = useState('');
-  const { loginUser, isLoading, error } = useAuth();
+  const [code, setCode] = useState('');
+  const { loginUser, completeTwoFactor, twoFactorRequired, isLoading, error } = useAuth();
   const navigate = useNavigate();
+  const [searchParams] = useSearchParams();
+  const [ssoProviders, setSSOProviders] = useState<string[]>([]);
+  const ssoError = searchParams.get('sso_error');
+
+  useEffect(() => {
+    getSSOProviders()
+      .then((res) => setSSOProviders(res.data.providers))
+      .catch(() => setSSOProviders([]));
+  }, []);
 
   const handleSubmit = async (e: React.FormEvent) => {
     e.preventDefault();
-    const success = await loginUser(email, password);
+    const success = twoFactorRequired
+      ? await completeTwoFactor(code)
+      : await loginUser(email, password);
     if (success) {
       navigate('/');
     }
@@ -32,11 +45,35 @@ export default function Login() {
           </CardDescription>
         </CardHeader>
         <CardContent>
+          {ssoError && (
+            <Alert variant="destructive" className="mb-4">
+              <AlertDescription>Single sign-on failed ({ssoError})</AlertDescription>
+            </Alert>
+          )}
           {error && (
             <Alert variant="destructive" className="mb-4">
               <AlertDescription>{error}</AlertDescription>
             </Alert>
           )}
+          {twoFactorRequired ? (
+          <form onSubmit={handleSubmit} className="space-y-4">
+            <div className="space-y-2">
+              <Label htmlFor="code">Authentication code</Label>
+              <Input
+                id="code"
+                inputMode="numeric"
+                autoComplete="one-time-code"
+                placeholder="6-digit code or recovery code"
+                value={code}
+                onChange={(e) => setCode(e.target.value)}
+                required
+              />
+            </div>
+            <Button type="submit" className="w-full" disabled={isLoading}>
+              {isLoading ? 'Verifying...' : 'Verify'}
+            </Button>
+          </form>
+          ) : (
           <form onSubmit={handleSubmit} className="space-y-4">
             <div className="space-y-2">
               <Label htmlFor="email">Email</Label>
@@ -66,8 +103,29 @@ export default function Login() {
               {isLoading ? 'Signing in...' : 'Sign in'}
             </Button>
           </form>
+          )}
+          {ssoProviders.length > 0 && (
+            <div className="mt-4 space-y-2">
+              {ssoProviders.map((provider) => (
+                <Button
+                  key={provider}
+                  type="button"
+                  variant="outline"
+                  className="w-full"
+                  onClick={() => { window.location.href = ssoLoginURL(provider); }}
+                >
+                  Continue with {provider.charAt(0).toUpperCase() + provider.slice(1)}
+                </Button>
+              ))}
+            </div>
+          )}
         </CardContent>
         <CardFooter className="flex flex-col items-center">
+          <p className="text-sm text-gray-600 mb-2">
+            <Link to="/reset-password" className="font-medium text-indigo-600 hover:text-indigo-500">
+              Forgot your password?
+            </Link>
+          </p>
           <p className="text-sm text-gray-600 mb-2">
             Don't have an account?{' '}
             <Link to="/register" className="font-medium text-indigo-600 hover:text-indigo-500">
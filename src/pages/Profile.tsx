@@ -1,5 +1,6 @@
 import { useState, useEffect } from 'react';
 import { useAuth } from '../hooks/useAuth';
+import { changePassword, deleteAvatar, getCurrentUser, updateProfile, uploadAvatar } from '../lib/api';
 import { Card, CardContent, CardDescription, CardFooter, CardHeader, CardTitle } from '../components/ui/card';
 import { Button } from '../components/ui/button';
 import { Input } from '../components/ui/input';
@@ -13,6 +14,8 @@ export default function Profile() {
     full_name: '',
     email: '',
     username: '',
+    timezone: '',
+    locale: '',
     current_password: '',
     new_password: '',
     confirm_password: '',
@@ -20,6 +23,8 @@ export default function Profile() {
   const [updateError, setUpdateError] = useState<string | null>(null);
   const [updateSuccess, setUpdateSuccess] = useState<string | null>(null);
   const [isSaving, setIsSaving] = useState(false);
+  const [avatarURL, setAvatarURL] = useState<string | undefined>(undefined);
+  const [pendingEmail, setPendingEmail] = useState('');
 
   useEffect(() => {
     if (user) {
@@ -28,7 +33,13 @@ export default function Profile() {
         full_name: user.full_name,
         email: user.email,
         username: user.username,
+        timezone: user.timezone || '',
+        locale: user.locale || '',
       });
+      setAvatarURL(user.avatar_url);
+      getCurrentUser()
+        .then((res) => setPendingEmail(res.data.pending_email || ''))
+        .catch(() => undefined);
     }
   }, [user]);
 
@@ -42,13 +53,56 @@ export default function Profile() {
     setUpdateError(null);
     setUpdateSuccess(null);
 
-    // In a real app, we would call an API to update the profile
-    // For now, we'll just simulate a successful update
     setIsSaving(true);
-    setTimeout(() => {
-      setUpdateSuccess('Profile updated successfully');
+    try {
+      const res = await updateProfile({
+        full_name: formData.full_name,
+        username: formData.username,
+        email: formData.email,
+        timezone: formData.timezone,
+        locale: formData.locale,
+      });
+      setPendingEmail(res.data.pending_email);
+      setFormData({ ...formData, email: res.data.user.email });
+      setUpdateSuccess(
+        res.data.pending_email
+          ? `Profile updated. Check ${res.data.pending_email} for a link to confirm your new email address.`
+          : 'Profile updated successfully'
+      );
+    } catch (err: any) {
+      setUpdateError(err.response?.data?.error || 'Failed to update profile');
+    } finally {
       setIsSaving(false);
-    }, 1000);
+    }
+  };
+
+  const handleAvatarChange = async (e: React.ChangeEvent<HTMLInputElement>) => {
+    const file = e.target.files?.[0];
+    e.target.value = '';
+    if (!file) return;
+    setUpdateError(null);
+    setUpdateSuccess(null);
+
+    try {
+      const res = await uploadAvatar(file);
+      setAvatarURL(res.data.user.avatar_url);
+      setUpdateSuccess('Profile picture updated');
+    } catch (err: any) {
+      setUpdateError(err.response?.data?.error || 'Failed to upload profile picture');
+    }
+  };
+
+  const handleAvatarRemove = async () => {
+    setUpdateError(null);
+    setUpdateSuccess(null);
+
+    try {
+      await deleteAvatar();
+      setAvatarURL(undefined);
+      setUpdateSuccess('Profile picture removed');
+    } catch (err: any) {
+      setUpdateError(err.response?.data?.error || 'Failed to remove profile picture');
+    }
   };
 
   const handlePasswordUpdate = async (e: React.FormEvent) => {
@@ -62,10 +116,12 @@ export default function Profile() {
       return;
     }
 
-    // In a real app, we would call an API to update the password
-    // For now, we'll just simulate a successful update
     setIsSaving(true);
-    setTimeout(() => {
+    try {
+      // Other sessions are signed out, this one continues with new tokens
+      const res = await changePassword(formData.current_password, formData.new_password);
+      localStorage.setItem('token', res.data.token);
+      localStorage.setItem('refresh_token', res.data.refresh_token);
       setUpdateSuccess('Password updated successfully');
       setFormData({
         ...formData,
@@ -73,8 +129,11 @@ export default function Profile() {
         new_password: '',
         confirm_password: '',
       });
+    } catch (err: any) {
+      setUpdateError(err.response?.data?.error || 'Failed to update password');
+    } finally {
       setIsSaving(false);
-    }, 1000);
+    }
   };
 
   if (isLoading) {
@@ -131,6 +190,32 @@ export default function Profile() {
           </CardHeader>
           <form onSubmit={handleProfileUpdate}>
             <CardContent className="space-y-4">
+              <div className="flex items-center space-x-4">
+                {avatarURL ? (
+                  <img src={avatarURL} alt="Profile picture" className="h-16 w-16 rounded-full object-cover" />
+                ) : (
+                  <div className="h-16 w-16 rounded-full bg-indigo-100 flex items-center justify-center text-xl font-medium text-indigo-700">
+                    {formData.full_name.charAt(0).toUpperCase()}
+                  </div>
+                )}
+                <div className="space-x-2">
+                  <Label htmlFor="avatar" className="cursor-pointer text-sm text-indigo-600 hover:underline">
+                    Upload picture
+                  </Label>
+                  <input
+                    id="avatar"
+                    type="file"
+                    accept="image/png,image/jpeg,image/gif,image/webp"
+                    className="hidden"
+                    onChange={handleAvatarChange}
+                  />
+                  {avatarURL && (
+                    <Button type="button" variant="ghost" size="sm" onClick={handleAvatarRemove}>
+                      Remove
+                    </Button>
+                  )}
+                </div>
+              </div>
               <div className="space-y-2">
                 <Label htmlFor="full_name">Full Name</Label>
                 <Input
@@ -151,6 +236,11 @@ export default function Profile() {
                   onChange={handleChange}
                   required
                 />
+                {pendingEmail && (
+                  <p className="text-sm text-gray-500">
+                    Waiting for confirmation of {pendingEmail}. Until then you sign in with your current address.
+                  </p>
+                )}
               </div>
               <div className="space-y-2">
                 <Label htmlFor="username">Username</Label>
@@ -162,6 +252,26 @@ export default function Profile() {
                   required
                 />
               </div>
+              <div className="space-y-2">
+                <Label htmlFor="timezone">Timezone</Label>
+                <Input
+                  id="timezone"
+                  name="timezone"
+                  placeholder={Intl.DateTimeFormat().resolvedOptions().timeZone}
+                  value={formData.timezone}
+                  onChange={handleChange}
+                />
+              </div>
+              <div className="space-y-2">
+                <Label htmlFor="locale">Language</Label>
+                <Input
+                  id="locale"
+                  name="locale"
+                  placeholder={navigator.language}
+                  value={formData.locale}
+                  onChange={handleChange}
+                />
+              </div>
             </CardContent>
             <CardFooter>
               <Button type="submit" disabled={isSaving}>
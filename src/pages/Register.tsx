@@ -1,12 +1,11 @@
 import { useState } from 'react';
-import { Link, useNavigate } from 'react-router-dom';
+import { Link } from 'react-router-dom';
 import { useAuth } from '../hooks/useAuth';
 import { Button } from '../components/ui/button';
 import { Input } from '../components/ui/input';
 import { Label } from '../components/ui/label';
 import { Card, CardContent, CardDescription, CardFooter, CardHeader, CardTitle } from '../components/ui/card';
 import { Alert, AlertDescription } from '../components/ui/alert';
-import { Select, SelectContent, SelectItem, SelectTrigger, SelectValue } from '../components/ui/select';
 
 export default function Register() {
   const [formData, setFormData] = useState({
@@ -14,25 +13,20 @@ export default function Register() {
     email: '',
     password: '',
     full_name: '',
-    role: 'member',
   });
+  const [submitted, setSubmitted] = useState(false);
   const { registerUser, isLoading, error } = useAuth();
-  const navigate = useNavigate();
 
   const handleChange = (e: React.ChangeEvent<HTMLInputElement>) => {
     const { name, value } = e.target;
     setFormData({ ...formData, [name]: value });
   };
 
-  const handleRoleChange = (value: string) => {
-    setFormData({ ...formData, role: value });
-  };
-
   const handleSubmit = async (e: React.FormEvent) => {
     e.preventDefault();
     const success = await registerUser(formData);
     if (success) {
-      navigate('/');
+      setSubmitted(true);
     }
   };
 
@@ -51,6 +45,13 @@ export default function Register() {
               <AlertDescription>{error}</AlertDescription>
             </Alert>
           )}
+          {submitted && (
+            <Alert className="mb-4">
+              <AlertDescription>
+                Check your email to finish signing up, then <Link to="/login" className="font-medium text-indigo-600 hover:text-indigo-500">sign in</Link>.
+              </AlertDescription>
+            </Alert>
+          )}
           <form onSubmit={handleSubmit} className="space-y-4">
             <div className="space-y-2">
               <Label htmlFor="username">Username</Label>
@@ -100,18 +101,6 @@ export default function Register() {
                 required
               />
             </div>
-            <div className="space-y-2">
-              <Label htmlFor="role">Role</Label>
-              <Select value={formData.role} onValueChange={handleRoleChange}>
-                <SelectTrigger>
-                  <SelectValue placeholder="Select a role" />
-                </SelectTrigger>
-                <SelectContent>
-                  <SelectItem value="admin">Admin</SelectItem>
-                  <SelectItem value="member">Member</SelectItem>
-                </SelectContent>
-              </Select>
-            </div>
             <Button type="submit" className="w-full" disabled={isLoading}>
               {isLoading ? 'Creating account...' : 'Create account'}
             </Button>
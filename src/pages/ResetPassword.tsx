@@ -0,0 +1,80 @@
+import { useState } from 'react';
+import { Link, useSearchParams } from 'react-router-dom';
+import { forgotPassword, resetPassword } from '../lib/api';
+import { Button } from '../components/ui/button';
+import { Input } from '../components/ui/input';
+import { Label } from '../components/ui/label';
+import { Card, CardContent, CardDescription, CardFooter, CardHeader, CardTitle } from '../components/ui/card';
+import { Alert, AlertDescription } from '../components/ui/alert';
+
+// Without a token this asks for an email to send the reset link to,
+// with one it sets the new password
+export default function ResetPassword() {
+  const [searchParams] = useSearchParams();
+  const token = searchParams.get('token');
+  const [value, setValue] = useState('');
+  const [message, setMessage] = useState<string | null>(null);
+  const [error, setError] = useState<string | null>(null);
+  const [isLoading, setIsLoading] = useState(false);
+
+  const handleSubmit = async (e: React.FormEvent) => {
+    e.preventDefault();
+    setIsLoading(true);
+    setError(null);
+    try {
+      const response = token ? await resetPassword(token, value) : await forgotPassword(value);
+      setMessage(response.data.message);
+    } catch (err: any) {
+      setError(err.response?.data?.error || 'Something went wrong');
+    } finally {
+      setIsLoading(false);
+    }
+  };
+
+  return (
+    <div className="min-h-screen flex items-center justify-center bg-gray-50 py-12 px-4 sm:px-6 lg:px-8">
+      <Card className="w-full max-w-md">
+        <CardHeader className="space-y-1">
+          <CardTitle className="text-2xl font-bold text-center">ProjectFlow</CardTitle>
+          <CardDescription className="text-center">
+            {token ? 'Choose a new password' : 'Reset your password'}
+          </CardDescription>
+        </CardHeader>
+        <CardContent>
+          {error && (
+            <Alert variant="destructive" className="mb-4">
+              <AlertDescription>{error}</AlertDescription>
+            </Alert>
+          )}
+          {message ? (
+            <Alert className="mb-4">
+              <AlertDescription>{message}</AlertDescription>
+            </Alert>
+          ) : (
+            <form onSubmit={handleSubmit} className="space-y-4">
+              <div className="space-y-2">
+                <Label htmlFor="value">{token ? 'New password' : 'Email'}</Label>
+                <Input
+                  id="value"
+                  type={token ? 'password' : 'email'}
+                  placeholder={token ? 'New password' : 'Email'}
+                  value={value}
+                  onChange={(e) => setValue(e.target.value)}
+                  required
+                />
+              </div>
+              <Button type="submit" className="w-full" disabled={isLoading}>
+                {token ? 'Set password' : 'Send reset link'}
+              </Button>
+            </form>
+          )}
+        </CardContent>
+        <CardFooter className="flex justify-center">
+          <Link to="/login" className="font-medium text-indigo-600 hover:text-indigo-500">
+            Back to login
+          </Link>
+        </CardFooter>
+      </Card>
+    </div>
+  );
+}
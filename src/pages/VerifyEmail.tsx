@@ -0,0 +1,49 @@
+import { useEffect, useState } from 'react';
+import { Link, useSearchParams } from 'react-router-dom';
+import { verifyEmail } from '../lib/api';
+import { Card, CardContent, CardDescription, CardFooter, CardHeader, CardTitle } from '../components/ui/card';
+import { Alert, AlertDescription } from '../components/ui/alert';
+
+export default function VerifyEmail() {
+  const [searchParams] = useSearchParams();
+  const [status, setStatus] = useState<'pending' | 'verified' | 'failed'>('pending');
+  const [error, setError] = useState<string | null>(null);
+
+  useEffect(() => {
+    verifyEmail(searchParams.get('token') || '')
+      .then(() => setStatus('verified'))
+      .catch((err) => {
+        setError(err.response?.data?.error || 'Verification failed');
+        setStatus('failed');
+      });
+  }, [searchParams]);
+
+  return (
+    <div className="min-h-screen flex items-center justify-center bg-gray-50 py-12 px-4 sm:px-6 lg:px-8">
+      <Card className="w-full max-w-md">
+        <CardHeader className="space-y-1">
+          <CardTitle className="text-2xl font-bold text-center">ProjectFlow</CardTitle>
+          <CardDescription className="text-center">Email verification</CardDescription>
+        </CardHeader>
+        <CardContent>
+          {status === 'pending' && <p className="text-center text-sm">Verifying...</p>}
+          {status === 'verified' && (
+            <Alert>
+              <AlertDescription>Your email address is verified.</AlertDescription>
+            </Alert>
+          )}
+          {status === 'failed' && (
+            <Alert variant="destructive">
+              <AlertDescription>{error}</AlertDescription>
+            </Alert>
+          )}
+        </CardContent>
+        <CardFooter className="flex justify-center">
+          <Link to="/login" className="font-medium text-indigo-600 hover:text-indigo-500">
+            Go to login
+          </Link>
+        </CardFooter>
+      </Card>
+    </div>
+  );
+}
@@ -0,0 +1,92 @@
+package unit
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/amorin24/projecflow/api/middleware"
+	"github.com/amorin24/projecflow/api/routes"
+	"github.com/amorin24/projecflow/utils"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerifyEmailAndResetPassword(t *testing.T) {
+	openOutbox(t)
+	require.NoError(t, utils.UseEphemeralSigningKey())
+	app := fiber.New(fiber.Config{ErrorHandler: middleware.ErrorHandler})
+	routes.SetupRoutes(app, nil)
+
+	var token string
+	call := func(method, path string, body interface{}) (int, map[string]interface{}) {
+		data, err := json.Marshal(body)
+		require.NoError(t, err)
+		req := httptest.NewRequest(method, path, bytes.NewReader(data))
+		req.Header.Set("Content-Type", "application/json")
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := app.Test(req, -1)
+		require.NoError(t, err)
+		var out map[string]interface{}
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&out))
+		return resp.StatusCode, out
+	}
+
+	name := "acct" + uuid.NewString()[:8]
+	email := name + "@example.com"
+	status, body := call(http.MethodPost, "/api/v1/auth/register", map[string]string{
+		"username":  name,
+		"email":     email,
+		"password":  "correct horse battery",
+		"full_name": "Vera Fied",
+	})
+	require.Equal(t, fiber.StatusAccepted, status, body)
+	status, session := call(http.MethodPost, "/api/v1/auth/login", map[string]string{"email": email, "password": "correct horse battery"})
+	require.Equal(t, fiber.StatusOK, status, session)
+	token = session["token"].(string)
+
+	// Signing up sends a verification link
+	status, body = call(http.MethodPost, "/api/v1/auth/verify-email", map[string]string{"token": mailedToken(t, email, "/verify-email")})
+	require.Equal(t, fiber.StatusOK, status, body)
+	status, body = call(http.MethodGet, "/api/v1/auth/me", nil)
+	require.Equal(t, fiber.StatusOK, status, body)
+	assert.Equal(t, true, body["user"].(map[string]interface{})["email_verified"])
+
+	// A reset link sets a new password once
+	token = ""
+	status, body = call(http.MethodPost, "/api/v1/auth/forgot-password", map[string]string{"email": email})
+	require.Equal(t, fiber.StatusOK, status, body)
+	reset := mailedToken(t, email, "/reset-password")
+	status, body = call(http.MethodPost, "/api/v1/auth/reset-password", map[string]string{"token": reset, "password": "another horse battery"})
+	require.Equal(t, fiber.StatusOK, status, body)
+	status, body = call(http.MethodPost, "/api/v1/auth/reset-password", map[string]string{"token": reset, "password": "a third horse battery"})
+	assert.Equal(t, fiber.StatusBadRequest, status)
+	assert.Equal(t, "invalid_reset_token", body["code"])
+
+	status, body = call(http.MethodPost, "/api/v1/auth/login", map[string]string{"email": email, "password": "another horse battery"})
+	assert.Equal(t, fiber.StatusOK, status, body)
+}
+
+func TestChangePasswordEndsOtherSessions(t *testing.T) {
+	client := signUp(t, newAPIApp(t, nil), "change")
+	old := &apiClient{app: client.app, token: client.token, userID: client.userID}
+
+	status, body := client.call(t, http.MethodPut, "/api/v1/me/password", map[string]string{
+		"current_password": "correct horse battery",
+		"new_password":     "battery staple horse correct",
+	})
+	require.Equal(t, fiber.StatusOK, status, body)
+
+	// The session that changed the password carries on, the old token doesn't
+	client.token = body["token"].(string)
+	status, body = client.call(t, http.MethodGet, "/api/v1/auth/me", nil)
+	assert.Equal(t, fiber.StatusOK, status, body)
+	status, _ = old.call(t, http.MethodGet, "/api/v1/auth/me", nil)
+	assert.Equal(t, fiber.StatusUnauthorized, status)
+}
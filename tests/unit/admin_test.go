@@ -0,0 +1,144 @@
+package unit
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/amorin24/projecflow/utils"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// asAdmin returns a client for the same user with an admin token. Sign-up
+// never makes anyone an admin.
+func (a *apiClient) asAdmin(tb testing.TB) *apiClient {
+	orgID, err := uuid.Parse(a.user(tb)["current_organization_id"].(string))
+	require.NoError(tb, err)
+	token, err := utils.GenerateToken(a.userID, orgID, "admin")
+	require.NoError(tb, err)
+	return &apiClient{app: a.app, token: token, userID: a.userID}
+}
+
+func TestAdminDeactivatesUsers(t *testing.T) {
+	admin := signUp(t, newAPIApp(t, nil), "admin").asAdmin(t)
+	member := signUp(t, admin.app, "admin")
+	admin.join(t, member)
+	email := member.user(t)["email"].(string)
+	userPath := "/api/v1/admin/users/" + member.userID.String()
+
+	status, body := admin.call(t, http.MethodPost, "/api/v1/admin/users/"+admin.userID.String()+"/deactivate", nil)
+	assert.Equal(t, fiber.StatusForbidden, status)
+	assert.Equal(t, "self_action_not_allowed", body["code"])
+
+	status, body = admin.call(t, http.MethodPost, userPath+"/deactivate", nil)
+	require.Equal(t, fiber.StatusOK, status, body)
+	status, body = admin.call(t, http.MethodPost, userPath+"/deactivate", nil)
+	assert.Equal(t, fiber.StatusConflict, status)
+	assert.Equal(t, "user_deactivated", body["code"])
+
+	// Deactivated users can't sign in or receive work
+	status, body = signIn(t, admin.app, email)
+	assert.Equal(t, fiber.StatusForbidden, status)
+	assert.Equal(t, "account_deactivated", body["code"])
+	status, body = admin.call(t, http.MethodPost, "/api/v1/admin/users/"+admin.userID.String()+"/reassign", map[string]interface{}{"to_user_id": member.userID})
+	assert.Equal(t, fiber.StatusBadRequest, status)
+	assert.Equal(t, "invalid_reassignment_target", body["code"])
+
+	status, body = admin.call(t, http.MethodPost, userPath+"/reactivate", nil)
+	require.Equal(t, fiber.StatusOK, status, body)
+	status, body = signIn(t, admin.app, email)
+	assert.Equal(t, fiber.StatusOK, status, body)
+}
+
+func TestAdminChangesUserRoles(t *testing.T) {
+	admin := signUp(t, newAPIApp(t, nil), "admin").asAdmin(t)
+	member := signUp(t, admin.app, "admin")
+	admin.join(t, member)
+	email := member.user(t)["email"].(string)
+	stranger := signUp(t, admin.app, "admin")
+
+	status, body := admin.call(t, http.MethodPut, "/api/v1/admin/users/"+admin.userID.String()+"/role", map[string]string{"role": "member"})
+	assert.Equal(t, fiber.StatusForbidden, status)
+	assert.Equal(t, "self_action_not_allowed", body["code"])
+	status, body = admin.call(t, http.MethodPut, "/api/v1/admin/users/"+stranger.userID.String()+"/role", map[string]string{"role": "admin"})
+	assert.Equal(t, fiber.StatusNotFound, status)
+	assert.Equal(t, "user_not_found", body["code"])
+
+	status, body = admin.call(t, http.MethodPut, "/api/v1/admin/users/"+member.userID.String()+"/role", map[string]string{"role": "admin"})
+	require.Equal(t, fiber.StatusOK, status, body)
+	assert.Equal(t, "admin", body["user"].(map[string]interface{})["role"])
+
+	// The old token is revoked, the new role comes with the next sign-in
+	status, _ = member.call(t, http.MethodGet, "/api/v1/auth/me", nil)
+	assert.Equal(t, fiber.StatusUnauthorized, status)
+	status, body = signIn(t, admin.app, email)
+	require.Equal(t, fiber.StatusOK, status, body)
+	promoted := &apiClient{app: admin.app, token: body["token"].(string), userID: member.userID}
+	status, body = promoted.call(t, http.MethodGet, "/api/v1/users", nil)
+	assert.Equal(t, fiber.StatusOK, status, body)
+}
+
+func TestAdminReassignsUserWork(t *testing.T) {
+	f := newResourceFixture(t, openResourceDB(t))
+	admin := f.asAdmin(t)
+	leaver, heir := signUp(t, f.app, "res"), signUp(t, f.app, "res")
+	f.mirrorUser(t, f.apiClient)
+	for _, client := range []*apiClient{leaver, heir} {
+		f.join(t, client)
+		f.mirrorUser(t, client)
+	}
+
+	// The heir works on the first project, only the leaver on the second
+	var projects []string
+	for i, name := range []string{"Shared", "Solo"} {
+		status, body := f.call(t, http.MethodPost, "/api/v1/projects", map[string]string{"name": name})
+		require.Equal(t, fiber.StatusCreated, status, body)
+		project := body["project"].(map[string]interface{})
+		if i == 0 {
+			_, err := f.db.Exec("INSERT INTO organizations (id, name, owner_id) VALUES ($1, 'Workspace', $2)", project["organization_id"], f.userID)
+			require.NoError(t, err)
+		}
+		_, err := f.db.Exec("INSERT INTO projects (id, name, description, owner_id, organization_id) VALUES ($1, $2, '', $3, $4)",
+			project["id"], name, f.userID, project["organization_id"])
+		require.NoError(t, err)
+		projects = append(projects, project["id"].(string))
+	}
+	addMember := func(project string, client *apiClient) {
+		status, body := f.call(t, http.MethodPost, "/api/v1/projects/"+project+"/members", map[string]interface{}{"user_id": client.userID, "role": "member"})
+		require.Equal(t, fiber.StatusOK, status, body)
+	}
+	addMember(projects[0], leaver)
+	addMember(projects[0], heir)
+	addMember(projects[1], leaver)
+
+	assign := func(project string) string {
+		status, body := f.call(t, http.MethodPost, "/api/v1/tasks", map[string]interface{}{
+			"title": "Handover", "project_id": project, "status_id": 1, "assignee_id": leaver.userID,
+		})
+		require.Equal(t, fiber.StatusCreated, status, body)
+		return body["task"].(map[string]interface{})["id"].(string)
+	}
+	sharedTask, soloTask := assign(projects[0]), assign(projects[1])
+
+	allocate := func(client *apiClient, project string, percentage int, start string, end interface{}) float64 {
+		var id float64
+		require.NoError(t, f.db.QueryRow(`INSERT INTO resource_allocations (user_id, project_id, allocation_percentage, start_date, end_date)
+			VALUES ($1, $2, $3, $4, $5) RETURNING id`, client.userID, project, percentage, start, end).Scan(&id))
+		return id
+	}
+	allocate(heir, projects[0], 50, "2026-01-12", "2026-01-16")
+	overbooked := allocate(leaver, projects[0], 60, "2026-01-05", "2026-01-30")
+	open := allocate(leaver, projects[0], 50, "2026-03-02", nil)
+	solo := allocate(leaver, projects[1], 100, "2026-01-05", nil)
+
+	status, body := admin.call(t, http.MethodPost, "/api/v1/admin/users/"+leaver.userID.String()+"/reassign", map[string]interface{}{"to_user_id": heir.userID})
+	require.Equal(t, fiber.StatusOK, status, body)
+	assert.Equal(t, []interface{}{sharedTask}, body["reassigned_tasks"])
+	assert.Equal(t, []interface{}{soloTask}, body["skipped_tasks"])
+	assert.Equal(t, []interface{}{open}, body["reassigned_allocations"])
+
+	// Moving the first allocation would book the heir at 110%
+	assert.ElementsMatch(t, []interface{}{overbooked, solo}, body["skipped_allocations"])
+}
@@ -0,0 +1,114 @@
+package unit
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/amorin24/projecflow/api/handlers"
+	"github.com/amorin24/projecflow/api/middleware"
+	"github.com/amorin24/projecflow/api/routes"
+	"github.com/amorin24/projecflow/config"
+	"github.com/amorin24/projecflow/utils"
+	"github.com/amorin24/projecflow/utils/ratelimit"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+// apiClient sends requests to the API as one signed-in user
+type apiClient struct {
+	app    *fiber.App
+	token  string
+	userID uuid.UUID
+}
+
+// newAPIApp serves the API on db, which may be nil for requests that never
+// reach the database
+func newAPIApp(tb testing.TB, db *sql.DB) *fiber.App {
+	require.NoError(tb, utils.UseEphemeralSigningKey())
+	// Every test sends its requests from the same address, far more than
+	// the route quotas and sign-up limits allow
+	middleware.ConfigureRateLimits(&config.Config{RateLimitEnabled: false})
+	tb.Cleanup(func() { middleware.ConfigureRateLimits(&config.Config{RateLimitEnabled: true}) })
+	handlers.ConfigureLoginProtection(config.LoadConfig(), ratelimit.NewMemoryLimiter())
+	app := fiber.New(fiber.Config{ErrorHandler: middleware.ErrorHandler})
+	routes.SetupRoutes(app, db)
+	return app
+}
+
+// signUp registers a new user with a unique name and signs them in
+func signUp(tb testing.TB, app *fiber.App, prefix string) *apiClient {
+	client := &apiClient{app: app}
+	name := prefix + uuid.NewString()[:8]
+	status, body := client.call(tb, http.MethodPost, "/api/v1/auth/register", map[string]string{
+		"username":  name,
+		"email":     name + "@example.com",
+		"password":  "correct horse battery",
+		"full_name": "Test " + prefix,
+	})
+	require.Equal(tb, fiber.StatusAccepted, status, body)
+
+	status, session := signIn(tb, app, name+"@example.com")
+	require.Equal(tb, fiber.StatusOK, status, session)
+	client.token = session["token"].(string)
+	client.userID = uuid.MustParse(session["user"].(map[string]interface{})["id"].(string))
+	return client
+}
+
+// signIn signs in with the password signUp gives everyone
+func signIn(tb testing.TB, app *fiber.App, email string) (int, map[string]interface{}) {
+	return (&apiClient{app: app}).call(tb, http.MethodPost, "/api/v1/auth/login", map[string]string{
+		"email":    email,
+		"password": "correct horse battery",
+	})
+}
+
+// call sends a JSON request and decodes the JSON response
+func (a *apiClient) call(tb testing.TB, method, path string, body interface{}) (int, map[string]interface{}) {
+	reader := bytes.NewReader(nil)
+	if body != nil {
+		data, err := json.Marshal(body)
+		require.NoError(tb, err)
+		reader = bytes.NewReader(data)
+	}
+	req := httptest.NewRequest(method, path, reader)
+	req.Header.Set("Content-Type", "application/json")
+	if a.token != "" {
+		req.Header.Set("Authorization", "Bearer "+a.token)
+	}
+	resp, err := a.app.Test(req, -1)
+	require.NoError(tb, err)
+	var out map[string]interface{}
+	if resp.StatusCode != fiber.StatusNoContent {
+		require.NoError(tb, json.NewDecoder(resp.Body).Decode(&out))
+	}
+	return resp.StatusCode, out
+}
+
+// join invites member into the organization a works in, accepts the
+// invitation by email and switches member to the organization
+func (a *apiClient) join(tb testing.TB, member *apiClient) {
+	openOutbox(tb)
+	email := member.user(tb)["email"].(string)
+	status, body := a.call(tb, http.MethodPost, "/api/v1/organizations/current/invites", map[string]string{"email": email})
+	require.Equal(tb, fiber.StatusCreated, status, body)
+
+	token := mailedToken(tb, email, "/invites/accept")
+	status, body = member.call(tb, http.MethodPost, "/api/v1/organizations/invites/accept", map[string]string{"token": token})
+	require.Equal(tb, fiber.StatusOK, status, body)
+	orgID := body["organization"].(map[string]interface{})["id"]
+	status, session := member.call(tb, http.MethodPost, "/api/v1/organizations/switch", map[string]interface{}{"organization_id": orgID})
+	require.Equal(tb, fiber.StatusOK, status, session)
+	member.token = session["token"].(string)
+}
+
+// user returns the signed-in user
+func (a *apiClient) user(tb testing.TB) map[string]interface{} {
+	status, body := a.call(tb, http.MethodGet, "/api/v1/auth/me", nil)
+	require.Equal(tb, fiber.StatusOK, status, body)
+	return body["user"].(map[string]interface{})
+}
@@ -0,0 +1,46 @@
+package unit
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/amorin24/projecflow/models"
+	"github.com/amorin24/projecflow/utils/apikey"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAPIKeyAuthenticate(t *testing.T) {
+	store := apikey.NewMemoryStore()
+	userID := uuid.New()
+
+	plaintext, prefix, hash, err := apikey.Generate()
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(plaintext, apikey.KeyPrefix+prefix+"_"))
+	assert.True(t, apikey.IsAPIKey(plaintext))
+
+	key := &models.APIKey{
+		ID:        uuid.New(),
+		UserID:    userID,
+		Name:      "CI",
+		Prefix:    prefix,
+		KeyHash:   hash,
+		Scopes:    []string{models.ScopeReadTasks},
+		CreatedAt: time.Now(),
+	}
+	store.Save(key)
+
+	authenticated, err := store.Authenticate(plaintext)
+	assert.NoError(t, err)
+	assert.Equal(t, key.ID, authenticated.ID)
+	assert.True(t, authenticated.HasScope(models.ScopeReadTasks))
+	assert.False(t, authenticated.HasScope(models.ScopeWriteTasks))
+
+	_, err = store.Authenticate(plaintext + "x")
+	assert.ErrorIs(t, err, apikey.ErrInvalidKey)
+
+	assert.True(t, store.Revoke(userID, key.ID))
+	_, err = store.Authenticate(plaintext)
+	assert.ErrorIs(t, err, apikey.ErrInvalidKey)
+}
@@ -0,0 +1,75 @@
+package unit
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/amorin24/projecflow/api/middleware"
+	"github.com/amorin24/projecflow/apperr"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestErrorHandlerProblemDocuments(t *testing.T) {
+	app := fiber.New(fiber.Config{ErrorHandler: middleware.ErrorHandler})
+	app.Use(middleware.RequestID())
+	app.Get("/conflict", func(c *fiber.Ctx) error {
+		return apperr.Conflict("username_taken", "Username already taken").With("field", "username")
+	})
+	app.Get("/broken", func(c *fiber.Ctx) error {
+		return errors.New("connection refused by 10.0.0.5")
+	})
+
+	problem := func(path string, status int) map[string]interface{} {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("X-Request-ID", "req-123")
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+		assert.Equal(t, status, resp.StatusCode)
+		assert.Equal(t, middleware.ProblemContentType, resp.Header.Get("Content-Type"))
+
+		var body map[string]interface{}
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+		assert.Equal(t, "req-123", body["request_id"])
+		assert.Equal(t, float64(status), body["status"])
+		assert.Equal(t, path, body["instance"])
+		return body
+	}
+
+	body := problem("/conflict", fiber.StatusConflict)
+	assert.Equal(t, "username_taken", body["code"])
+	assert.Equal(t, "urn:projectflow:problem:username_taken", body["type"])
+	assert.Equal(t, "Conflict", body["title"])
+	assert.Equal(t, "Username already taken", body["detail"])
+	assert.Equal(t, "Username already taken", body["error"])
+	assert.Equal(t, "username", body["field"])
+
+	// Unexpected errors don't leak their message
+	body = problem("/broken", fiber.StatusInternalServerError)
+	assert.Equal(t, "internal_error", body["code"])
+	assert.NotContains(t, body["detail"], "10.0.0.5")
+
+	// Fiber's own errors keep their status
+	body = problem("/missing", fiber.StatusNotFound)
+	assert.Equal(t, "not_found", body["code"])
+}
+
+func TestAppErrorMatching(t *testing.T) {
+	err := apperr.ErrUserNotFound.With("id", "42")
+	assert.True(t, errors.Is(err, apperr.ErrUserNotFound))
+	assert.False(t, errors.Is(err, apperr.ErrUnauthorized))
+
+	cause := errors.New("disk full")
+	wrapped := apperr.Internal("internal_error", "Failed to save").Wrap(cause)
+	assert.True(t, errors.Is(wrapped, cause))
+	assert.Contains(t, wrapped.Error(), "disk full")
+
+	appErr, ok := apperr.As(wrapped)
+	require.True(t, ok)
+	assert.Equal(t, apperr.KindInternal, appErr.Kind)
+	assert.Nil(t, apperr.ErrUserNotFound.Extra, "With must not change the original")
+}
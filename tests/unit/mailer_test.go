@@ -0,0 +1,271 @@
+package unit
+
+import (
+	"mime"
+	"net"
+	"net/textproto"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/amorin24/projecflow/api/handlers"
+	"github.com/amorin24/projecflow/config"
+	"github.com/amorin24/projecflow/mailer"
+	"github.com/amorin24/projecflow/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRenderNotificationTemplates(t *testing.T) {
+	for _, notificationType := range models.NotificationTypes {
+		t.Run(notificationType, func(t *testing.T) {
+			msg, err := mailer.Render(notificationType, "dev@example.com", mailer.EventData{
+				RecipientName: "Dev User",
+				Content:       "Something <important> happened",
+				ActionURL:     "http://localhost:5173/tasks/123",
+				AppURL:        "http://localhost:5173",
+			})
+			assert.NoError(t, err)
+			assert.Equal(t, "dev@example.com", msg.To)
+			assert.NotEmpty(t, msg.Subject)
+			assert.NotContains(t, msg.Subject, "Subject:")
+			assert.Contains(t, msg.Text, "Something <important> happened")
+			assert.Contains(t, msg.HTML, "Something &lt;important&gt; happened", "HTML body should be escaped")
+		})
+	}
+}
+
+func TestRenderDigestTemplate(t *testing.T) {
+	msg, err := mailer.Render("digest", "dev@example.com", mailer.DigestData{
+		RecipientName: "Dev User",
+		Frequency:     models.DigestDaily,
+		Items: []mailer.DigestItem{
+			{Content: "You have been assigned a task: Fix login", CreatedAt: time.Now()},
+			{Content: "New comment on task: Fix login", CreatedAt: time.Now()},
+		},
+		AppURL: "http://localhost:5173",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "Your daily ProjectFlow digest (2 unread)", msg.Subject)
+	assert.Contains(t, msg.Text, "Fix login")
+	assert.Contains(t, msg.HTML, "New comment on task: Fix login")
+}
+
+func TestRenderAccountTemplates(t *testing.T) {
+	for _, name := range []string{"verify_email", "password_reset", "password_changed", "organization_invite", "project_invite", "email_change", "email_changed", "signup_attempt"} {
+		t.Run(name, func(t *testing.T) {
+			msg, err := mailer.Render(name, "dev@example.com", mailer.EventData{
+				RecipientName: "Dev User",
+				Content:       "Account message",
+				ActionURL:     "http://localhost:5173/reset-password?token=abc",
+				AppURL:        "http://localhost:5173",
+			})
+			assert.NoError(t, err)
+			assert.NotEmpty(t, msg.Subject)
+			assert.Contains(t, msg.Text, "token=abc")
+			assert.NotContains(t, msg.Text, "notification preferences", "account emails are not notifications")
+		})
+	}
+}
+
+// captureSMTP accepts a single message over SMTP and returns its data
+func captureSMTP(t *testing.T) (string, <-chan string) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+
+	data := make(chan string, 1)
+	go acceptSMTP(listener, func(message string) {
+		select {
+		case data <- message:
+		default:
+		}
+	})
+	return listener.Addr().String(), data
+}
+
+// outbox holds every email the API sent since a test called openOutbox
+var outbox struct {
+	once     sync.Once
+	mu       sync.Mutex
+	messages []string
+}
+
+// openOutbox delivers the API's emails to a local SMTP server for the rest
+// of the test run
+func openOutbox(tb testing.TB) {
+	outbox.once.Do(func() {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(tb, err)
+		go acceptSMTP(listener, func(message string) {
+			outbox.mu.Lock()
+			outbox.messages = append(outbox.messages, message)
+			outbox.mu.Unlock()
+		})
+
+		host, port, err := net.SplitHostPort(listener.Addr().String())
+		require.NoError(tb, err)
+		portNumber, err := strconv.Atoi(port)
+		require.NoError(tb, err)
+		handlers.ConfigureNotifications(&config.Config{
+			SMTPHost: host,
+			SMTPPort: portNumber,
+			SMTPFrom: "no-reply@example.com",
+			AppURL:   "http://localhost:5173",
+		})
+	})
+}
+
+// mailedToken waits for an email to address with a link to path and
+// returns the token in the latest such link
+func mailedToken(tb testing.TB, address, path string) string {
+	link := regexp.MustCompile(regexp.QuoteMeta(path) + `\?token=([A-Za-z0-9%._~-]+)`)
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		outbox.mu.Lock()
+		var match []string
+		for _, message := range outbox.messages {
+			if !strings.Contains(message, "To: "+address) {
+				continue
+			}
+			if found := link.FindStringSubmatch(message); found != nil {
+				match = found
+			}
+		}
+		outbox.mu.Unlock()
+		if match != nil {
+			token, err := url.QueryUnescape(match[1])
+			require.NoError(tb, err)
+			return token
+		}
+	}
+	tb.Fatalf("no email with a %s link was sent to %s", path, address)
+	return ""
+}
+
+// mailedTo waits for an email to address that contains text
+func mailedTo(tb testing.TB, address, text string) bool {
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		outbox.mu.Lock()
+		found := false
+		for _, message := range outbox.messages {
+			if strings.Contains(message, "To: "+address) && strings.Contains(message, text) {
+				found = true
+				break
+			}
+		}
+		outbox.mu.Unlock()
+		if found {
+			return true
+		}
+	}
+	return false
+}
+
+// acceptSMTP serves SMTP on listener until it is closed, passing the data
+// of each message to deliver
+func acceptSMTP(listener net.Listener, deliver func(string)) {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		go serveSMTP(conn, deliver)
+	}
+}
+
+// serveSMTP plays the server side of one SMTP session
+func serveSMTP(conn net.Conn, deliver func(string)) {
+	defer conn.Close()
+	text := textproto.NewConn(conn)
+	text.PrintfLine("220 localhost ESMTP")
+	for {
+		line, err := text.ReadLine()
+		if err != nil {
+			return
+		}
+		switch command := strings.ToUpper(strings.SplitN(line, " ", 2)[0]); command {
+		case "EHLO", "HELO":
+			text.PrintfLine("250 localhost")
+		case "DATA":
+			text.PrintfLine("354 go ahead")
+			body, _ := text.ReadDotLines()
+			deliver(strings.Join(body, "\n"))
+			text.PrintfLine("250 queued")
+		case "QUIT":
+			text.PrintfLine("221 bye")
+			return
+		default:
+			text.PrintfLine("250 ok")
+		}
+	}
+}
+
+func TestMailerEncodesSubject(t *testing.T) {
+	addr, data := captureSMTP(t)
+	host, port, err := net.SplitHostPort(addr)
+	require.NoError(t, err)
+	portNumber, err := strconv.Atoi(port)
+	require.NoError(t, err)
+
+	err = mailer.New(mailer.Config{Host: host, Port: portNumber, From: "no-reply@example.com"}).Send(mailer.Message{
+		To:      "dev@example.com",
+		Subject: "New task: Größe\r\nBcc: victim@example.com",
+		Text:    "Hello",
+	})
+	require.NoError(t, err)
+
+	message := <-data
+	headers, _, _ := strings.Cut(message, "\n\n")
+	assert.NotContains(t, headers, "\nBcc:", "the subject must not start a new header")
+	assert.Contains(t, headers, "Subject: =?utf-8?q?")
+
+	var subject string
+	for _, line := range strings.Split(headers, "\n") {
+		if strings.HasPrefix(line, "Subject: ") {
+			subject = strings.TrimPrefix(line, "Subject: ")
+		}
+	}
+	decoded, err := new(mime.WordDecoder).DecodeHeader(subject)
+	require.NoError(t, err)
+	assert.Equal(t, "New task: Größe Bcc: victim@example.com", decoded)
+}
+
+// blockingSender delivers messages only once release is closed
+type blockingSender struct {
+	release chan struct{}
+	sent    chan mailer.Message
+}
+
+func (s *blockingSender) Send(msg mailer.Message) error {
+	<-s.release
+	s.sent <- msg
+	return nil
+}
+
+func TestMailQueueSendsInTheBackground(t *testing.T) {
+	sender := &blockingSender{release: make(chan struct{}), sent: make(chan mailer.Message, 1)}
+	queue := mailer.NewQueue(sender, 3, time.Hour)
+
+	// Send returns while the SMTP server is still busy
+	returned := make(chan error, 1)
+	go func() { returned <- queue.Send(mailer.Message{To: "dev@example.com", Subject: "Reset"}) }()
+	select {
+	case err := <-returned:
+		require.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("Send waited for the message to be delivered")
+	}
+
+	close(sender.release)
+	select {
+	case msg := <-sender.sent:
+		assert.Equal(t, "dev@example.com", msg.To)
+	case <-time.After(time.Second):
+		t.Fatal("the queued message was never sent")
+	}
+	assert.Zero(t, queue.Pending())
+}
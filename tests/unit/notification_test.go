@@ -0,0 +1,187 @@
+package unit
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/amorin24/projecflow/api/handlers"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// notificationFixture is a project owner who assigns tasks to, and comments
+// on the tasks of, a colleague
+type notificationFixture struct {
+	owner     *apiClient
+	colleague *apiClient
+	tasks     []string
+}
+
+func newNotificationFixture(t *testing.T, app *fiber.App) *notificationFixture {
+	owner := signUp(t, app, "notify")
+	colleague := signUp(t, app, "notify")
+	owner.join(t, colleague)
+
+	status, body := owner.call(t, http.MethodPost, "/api/v1/projects", map[string]string{"name": "Notified"})
+	require.Equal(t, fiber.StatusCreated, status, body)
+	project := body["project"].(map[string]interface{})["id"].(string)
+	status, body = owner.call(t, http.MethodPost, "/api/v1/projects/"+project+"/members", map[string]interface{}{"user_id": colleague.userID, "role": "member"})
+	require.Equal(t, fiber.StatusOK, status, body)
+
+	f := &notificationFixture{owner: owner, colleague: colleague}
+	for _, title := range []string{"First task", "Second task", "Third task"} {
+		status, body := owner.call(t, http.MethodPost, "/api/v1/tasks", map[string]interface{}{
+			"title": title, "project_id": project, "status_id": 1, "assignee_id": colleague.userID,
+		})
+		require.Equal(t, fiber.StatusCreated, status, body)
+		f.tasks = append(f.tasks, body["task"].(map[string]interface{})["id"].(string))
+	}
+	return f
+}
+
+// comment comments on a task as the owner
+func (f *notificationFixture) comment(t *testing.T, task string) {
+	status, body := f.owner.call(t, http.MethodPost, "/api/v1/tasks/"+task+"/comments", map[string]string{"content": "Any news?"})
+	require.Equal(t, fiber.StatusCreated, status, body)
+}
+
+// list returns the colleague's notifications at path
+func (f *notificationFixture) list(t *testing.T, path string) ([]map[string]interface{}, string) {
+	status, body := f.colleague.call(t, http.MethodGet, path, nil)
+	require.Equal(t, fiber.StatusOK, status, body)
+	var list []map[string]interface{}
+	for _, notification := range body["notifications"].([]interface{}) {
+		list = append(list, notification.(map[string]interface{}))
+	}
+	return list, body["next_cursor"].(string)
+}
+
+// unread returns the colleague's unread notification count
+func (f *notificationFixture) unread(t *testing.T) float64 {
+	status, body := f.colleague.call(t, http.MethodGet, "/api/v1/notifications/unread-count", nil)
+	require.Equal(t, fiber.StatusOK, status, body)
+	return body["unread_count"].(float64)
+}
+
+func TestNotificationsGroupRepeatedEvents(t *testing.T) {
+	f := newNotificationFixture(t, newAPIApp(t, nil))
+	f.comment(t, f.tasks[0])
+	time.Sleep(time.Millisecond)
+	f.comment(t, f.tasks[0])
+
+	// Both comments are one notification, listed by its latest comment
+	list, _ := f.list(t, "/api/v1/notifications")
+	require.Len(t, list, 4)
+	grouped := list[0]
+	assert.Equal(t, "comment_added", grouped["type"])
+	assert.Equal(t, float64(2), grouped["count"])
+	created, err := time.Parse(time.RFC3339Nano, grouped["created_at"].(string))
+	require.NoError(t, err)
+	lastOccurred, err := time.Parse(time.RFC3339Nano, grouped["last_occurred_at"].(string))
+	require.NoError(t, err)
+	assert.True(t, lastOccurred.After(created), "the first comment's time is kept")
+
+	// Read notifications aren't added to
+	status, body := f.colleague.call(t, http.MethodPatch, "/api/v1/notifications/"+grouped["id"].(string), map[string]bool{"read": true})
+	require.Equal(t, fiber.StatusOK, status, body)
+	f.comment(t, f.tasks[0])
+	list, _ = f.list(t, "/api/v1/notifications?type=comment_added")
+	require.Len(t, list, 2)
+	assert.Equal(t, float64(1), list[0]["count"])
+	assert.Equal(t, false, list[0]["read"])
+}
+
+func TestNotificationListing(t *testing.T) {
+	f := newNotificationFixture(t, newAPIApp(t, nil))
+	f.comment(t, f.tasks[1])
+
+	// Pages follow each other without gaps or repeats
+	all, _ := f.list(t, "/api/v1/notifications")
+	require.Len(t, all, 4)
+	var paged []map[string]interface{}
+	path := "/api/v1/notifications?limit=3"
+	for {
+		page, cursor := f.list(t, path)
+		paged = append(paged, page...)
+		if cursor == "" {
+			break
+		}
+		path = "/api/v1/notifications?limit=3&cursor=" + cursor
+	}
+	assert.Equal(t, all, paged)
+
+	status, body := f.colleague.call(t, http.MethodGet, "/api/v1/notifications?cursor=bogus", nil)
+	assert.Equal(t, fiber.StatusBadRequest, status)
+	assert.Equal(t, "invalid_cursor", body["code"])
+
+	// Filters by type and read state
+	assigned, _ := f.list(t, "/api/v1/notifications?type=task_assigned")
+	assert.Len(t, assigned, 3)
+	assert.Equal(t, float64(4), f.unread(t))
+	status, body = f.colleague.call(t, http.MethodPatch, "/api/v1/notifications/"+assigned[0]["id"].(string), map[string]bool{"read": true})
+	require.Equal(t, fiber.StatusOK, status, body)
+	assert.Equal(t, float64(3), f.unread(t))
+	read, _ := f.list(t, "/api/v1/notifications?read=true")
+	require.Len(t, read, 1)
+	assert.Equal(t, assigned[0]["id"], read[0]["id"])
+	unread, _ := f.list(t, "/api/v1/notifications?read=false&type=task_assigned")
+	assert.Len(t, unread, 2)
+
+	// Nobody else can see or change them
+	status, _ = f.owner.call(t, http.MethodPatch, "/api/v1/notifications/"+assigned[1]["id"].(string), map[string]bool{"read": true})
+	assert.Equal(t, fiber.StatusForbidden, status)
+	status, body = f.owner.call(t, http.MethodGet, "/api/v1/notifications", nil)
+	require.Equal(t, fiber.StatusOK, status, body)
+	assert.Empty(t, body["notifications"])
+}
+
+func TestNotificationBulkChanges(t *testing.T) {
+	f := newNotificationFixture(t, newAPIApp(t, nil))
+	all, _ := f.list(t, "/api/v1/notifications")
+	require.Len(t, all, 3)
+
+	// Deleting by ID never touches other users' notifications
+	others := newNotificationFixture(t, f.owner.app)
+	theirs, _ := others.list(t, "/api/v1/notifications")
+	status, body := f.colleague.call(t, http.MethodDelete, "/api/v1/notifications", map[string]interface{}{
+		"ids": []interface{}{all[0]["id"], theirs[0]["id"]},
+	})
+	require.Equal(t, fiber.StatusOK, status, body)
+	assert.Equal(t, float64(1), body["deleted"])
+	stillTheirs, _ := others.list(t, "/api/v1/notifications")
+	assert.Len(t, stillTheirs, 3)
+
+	// Marking all read, then deleting all read
+	status, body = f.colleague.call(t, http.MethodPatch, "/api/v1/notifications", nil)
+	require.Equal(t, fiber.StatusOK, status, body)
+	assert.Zero(t, f.unread(t))
+	status, body = f.colleague.call(t, http.MethodDelete, "/api/v1/notifications", map[string]bool{"all_read": true})
+	require.Equal(t, fiber.StatusOK, status, body)
+	assert.Equal(t, float64(2), body["deleted"])
+
+	status, body = f.colleague.call(t, http.MethodDelete, "/api/v1/notifications", map[string]interface{}{})
+	assert.Equal(t, fiber.StatusBadRequest, status)
+	assert.Equal(t, "notification_ids_required", body["code"])
+}
+
+func TestPurgeReadNotifications(t *testing.T) {
+	f := newNotificationFixture(t, newAPIApp(t, nil))
+	all, _ := f.list(t, "/api/v1/notifications")
+	require.Len(t, all, 3)
+	status, body := f.colleague.call(t, http.MethodPatch, "/api/v1/notifications/"+all[0]["id"].(string), map[string]bool{"read": true})
+	require.Equal(t, fiber.StatusOK, status, body)
+
+	// Only read notifications past the retention period go
+	handlers.PurgeReadNotifications(time.Now().Add(-time.Hour))
+	kept, _ := f.list(t, "/api/v1/notifications")
+	assert.Len(t, kept, 3)
+
+	handlers.PurgeReadNotifications(time.Now().Add(time.Second))
+	kept, _ = f.list(t, "/api/v1/notifications")
+	require.Len(t, kept, 2)
+	for _, notification := range kept {
+		assert.Equal(t, false, notification["read"])
+	}
+}
@@ -0,0 +1,185 @@
+package unit
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/amorin24/projecflow/models"
+	"github.com/amorin24/projecflow/notifier"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingChannel reports every delivery on delivered
+type recordingChannel struct {
+	name      string
+	delivered chan string
+}
+
+func (c *recordingChannel) Name() string { return c.name }
+
+func (c *recordingChannel) Deliver(notification *models.Notification, recipient *models.User, prefs *models.NotificationPreferences) error {
+	c.delivered <- c.name
+	return nil
+}
+
+// dispatched sends one notification through a new dispatcher and returns
+// the channels it reached, in-app included
+func dispatched(t *testing.T, notificationType string, recipient *models.User, prefs *models.NotificationPreferences) map[string]bool {
+	delivered := make(chan string, 3)
+	var mu sync.Mutex
+	reached := map[string]bool{}
+	store := func(notification *models.Notification) {
+		mu.Lock()
+		reached[models.ChannelInApp] = true
+		mu.Unlock()
+	}
+	dispatcher := notifier.New(store,
+		&recordingChannel{name: models.ChannelEmail, delivered: delivered},
+		&recordingChannel{name: models.ChannelWebhook, delivered: delivered})
+
+	dispatcher.Dispatch(&models.Notification{ID: uuid.New(), Type: notificationType, Content: "Hello"}, recipient, prefs)
+
+	// Deliveries run in the background
+	timeout := time.After(100 * time.Millisecond)
+	for {
+		select {
+		case name := <-delivered:
+			mu.Lock()
+			reached[name] = true
+			mu.Unlock()
+		case <-timeout:
+			mu.Lock()
+			defer mu.Unlock()
+			return reached
+		}
+	}
+}
+
+func TestDispatcherFollowsChannelPreferences(t *testing.T) {
+	recipient := &models.User{ID: uuid.New(), Email: "dev@example.com"}
+	for _, inApp := range []bool{false, true} {
+		for _, email := range []bool{false, true} {
+			for _, webhook := range []bool{false, true} {
+				pref := models.ChannelPreference{InApp: inApp, Email: email, Webhook: webhook}
+				t.Run(fmt.Sprintf("in_app=%v email=%v webhook=%v", inApp, email, webhook), func(t *testing.T) {
+					prefs := models.DefaultNotificationPreferences(recipient.ID)
+					prefs.Types[models.NotificationMention] = pref
+
+					reached := dispatched(t, models.NotificationMention, recipient, prefs)
+					assert.Equal(t, inApp, reached[models.ChannelInApp], "in-app")
+					assert.Equal(t, email, reached[models.ChannelEmail], "email")
+					assert.Equal(t, webhook, reached[models.ChannelWebhook], "webhook")
+				})
+			}
+		}
+	}
+}
+
+func TestDispatcherSpecialCases(t *testing.T) {
+	recipient := &models.User{ID: uuid.New(), Email: "dev@example.com"}
+	everywhere := models.ChannelPreference{InApp: true, Email: true, Webhook: true}
+	now := time.Now().UTC()
+
+	for _, tc := range []struct {
+		name      string
+		typ       string
+		recipient *models.User
+		prefs     func(*models.NotificationPreferences)
+		reached   []string
+	}{
+		{
+			name:      "defaults",
+			typ:       models.NotificationTaskAssigned,
+			recipient: recipient,
+			reached:   []string{models.ChannelInApp, models.ChannelEmail},
+		},
+		{
+			name:      "types without a preference stay in-app",
+			typ:       "something_new",
+			recipient: recipient,
+			reached:   []string{models.ChannelInApp},
+		},
+		{
+			name:      "unknown recipients only get in-app",
+			typ:       models.NotificationMention,
+			recipient: nil,
+			prefs:     func(p *models.NotificationPreferences) { p.Types[models.NotificationMention] = everywhere },
+			reached:   []string{models.ChannelInApp},
+		},
+		{
+			name:      "quiet hours hold back other channels",
+			typ:       models.NotificationMention,
+			recipient: recipient,
+			prefs: func(p *models.NotificationPreferences) {
+				p.Types[models.NotificationMention] = everywhere
+				p.QuietHoursStart = now.Add(-time.Hour).Format("15:04")
+				p.QuietHoursEnd = now.Add(time.Hour).Format("15:04")
+			},
+			reached: []string{models.ChannelInApp},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			prefs := models.DefaultNotificationPreferences(recipient.ID)
+			if tc.prefs != nil {
+				tc.prefs(prefs)
+			}
+
+			reached := dispatched(t, tc.typ, tc.recipient, prefs)
+			want := map[string]bool{}
+			for _, name := range tc.reached {
+				want[name] = true
+			}
+			assert.Equal(t, want, reached)
+		})
+	}
+}
+
+func TestQuietHoursEndAfter(t *testing.T) {
+	day := func(d, hour, minute int, loc *time.Location) time.Time {
+		return time.Date(2026, time.January, d, hour, minute, 0, 0, loc)
+	}
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	require.NoError(t, err)
+
+	for _, tc := range []struct {
+		name       string
+		start, end string
+		timezone   string
+		at         time.Time
+		endsAt     time.Time // zero when t is outside quiet hours
+	}{
+		{name: "not configured", at: day(15, 23, 0, time.UTC)},
+		{name: "only a start", start: "22:00", at: day(15, 23, 0, time.UTC)},
+		{name: "invalid time", start: "25:00", end: "07:00", at: day(15, 23, 0, time.UTC)},
+		{name: "empty window", start: "09:00", end: "09:00", at: day(15, 9, 0, time.UTC)},
+
+		{name: "before a daytime window", start: "09:00", end: "17:00", at: day(15, 8, 59, time.UTC)},
+		{name: "daytime window starts", start: "09:00", end: "17:00", at: day(15, 9, 0, time.UTC), endsAt: day(15, 17, 0, time.UTC)},
+		{name: "daytime window's last minute", start: "09:00", end: "17:00", at: day(15, 16, 59, time.UTC), endsAt: day(15, 17, 0, time.UTC)},
+		{name: "daytime window ends", start: "09:00", end: "17:00", at: day(15, 17, 0, time.UTC)},
+
+		{name: "before a night window", start: "22:00", end: "07:00", at: day(15, 21, 59, time.UTC)},
+		{name: "night window starts", start: "22:00", end: "07:00", at: day(15, 22, 0, time.UTC), endsAt: day(16, 7, 0, time.UTC)},
+		{name: "night window before midnight", start: "22:00", end: "07:00", at: day(15, 23, 59, time.UTC), endsAt: day(16, 7, 0, time.UTC)},
+		{name: "night window at midnight", start: "22:00", end: "07:00", at: day(16, 0, 0, time.UTC), endsAt: day(16, 7, 0, time.UTC)},
+		{name: "night window's last minute", start: "22:00", end: "07:00", at: day(16, 6, 59, time.UTC), endsAt: day(16, 7, 0, time.UTC)},
+		{name: "night window ends", start: "22:00", end: "07:00", at: day(16, 7, 0, time.UTC)},
+
+		{name: "in the user's timezone", start: "22:00", end: "07:00", timezone: "Europe/Berlin", at: day(15, 21, 30, time.UTC), endsAt: day(16, 7, 0, berlin)},
+		{name: "outside in the user's timezone", start: "22:00", end: "07:00", timezone: "Europe/Berlin", at: day(16, 6, 30, time.UTC)},
+		{name: "unknown timezones use UTC", start: "22:00", end: "07:00", timezone: "Mars/Olympus", at: day(15, 22, 30, time.UTC), endsAt: day(16, 7, 0, time.UTC)},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			prefs := &models.NotificationPreferences{QuietHoursStart: tc.start, QuietHoursEnd: tc.end, Timezone: tc.timezone}
+			endsAt, quiet := prefs.QuietHoursEndAfter(tc.at)
+			assert.Equal(t, !tc.endsAt.IsZero(), quiet)
+			if quiet {
+				assert.True(t, tc.endsAt.Equal(endsAt), "ends at %v, want %v", endsAt, tc.endsAt)
+			}
+		})
+	}
+}
@@ -0,0 +1,209 @@
+package unit
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/amorin24/projecflow/api/handlers"
+	"github.com/amorin24/projecflow/api/middleware"
+	"github.com/amorin24/projecflow/api/routes"
+	"github.com/amorin24/projecflow/config"
+	"github.com/amorin24/projecflow/oidc"
+	"github.com/amorin24/projecflow/utils"
+	"github.com/amorin24/projecflow/utils/totp"
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// mockOIDCProvider is a minimal OpenID Connect provider that issues an ID
+// token for a single authorization code
+type mockOIDCProvider struct {
+	*httptest.Server
+	key       *rsa.PrivateKey
+	challenge string
+	nonce     string
+	email     string
+}
+
+func newMockOIDCProvider(t *testing.T) *mockOIDCProvider {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	m := &mockOIDCProvider{key: key, email: "ada@example.com"}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 m.URL,
+			"authorization_endpoint": m.URL + "/authorize",
+			"token_endpoint":         m.URL + "/token",
+			"jwks_uri":               m.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": "test",
+			"use": "sig",
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		if r.Form.Get("code") != "good-code" || oidc.CodeChallenge(r.Form.Get("code_verifier")) != m.challenge {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+			"iss":            m.URL,
+			"sub":            "user-1",
+			"aud":            "projectflow",
+			"exp":            time.Now().Add(time.Hour).Unix(),
+			"nonce":          m.nonce,
+			"email":          m.email,
+			"email_verified": true,
+			"groups":         []string{"engineering", "pf-admins"},
+		})
+		token.Header["kid"] = "test"
+		signed, _ := token.SignedString(key)
+		json.NewEncoder(w).Encode(map[string]string{"id_token": signed, "token_type": "Bearer"})
+	})
+	m.Server = httptest.NewServer(mux)
+	t.Cleanup(m.Close)
+	return m
+}
+
+func TestOIDCAuthorizationCodeFlow(t *testing.T) {
+	mock := newMockOIDCProvider(t)
+	provider := oidc.NewProvider(oidc.Config{
+		Name:        "mock",
+		Issuer:      mock.URL,
+		ClientID:    "projectflow",
+		RedirectURL: "http://localhost:8080/api/auth/oidc/mock/callback",
+		RoleMapping: map[string]string{"pf-admins": "admin"},
+	})
+	ctx := context.Background()
+
+	verifier, err := oidc.RandomString()
+	require.NoError(t, err)
+	mock.challenge, mock.nonce = oidc.CodeChallenge(verifier), "nonce-1"
+
+	authURL, err := provider.AuthCodeURL(ctx, "state-1", mock.nonce, mock.challenge)
+	require.NoError(t, err)
+	parsed, err := url.Parse(authURL)
+	require.NoError(t, err)
+	assert.Equal(t, "S256", parsed.Query().Get("code_challenge_method"))
+	assert.Equal(t, mock.challenge, parsed.Query().Get("code_challenge"))
+
+	// A wrong verifier is rejected by the provider
+	_, err = provider.Exchange(ctx, "good-code", "wrong-verifier")
+	assert.Error(t, err)
+
+	tokens, err := provider.Exchange(ctx, "good-code", verifier)
+	require.NoError(t, err)
+
+	claims, err := provider.VerifyIDToken(ctx, tokens.IDToken, mock.nonce)
+	require.NoError(t, err)
+	assert.Equal(t, "ada@example.com", claims.Email)
+	assert.True(t, claims.EmailVerified)
+	assert.Equal(t, "admin", provider.MapRole(claims.Groups))
+
+	// Replaying the token with another login's nonce fails
+	_, err = provider.VerifyIDToken(ctx, tokens.IDToken, "nonce-2")
+	assert.Error(t, err)
+}
+
+func TestOIDCLoginRequiresSecondFactor(t *testing.T) {
+	require.NoError(t, utils.UseEphemeralSigningKey())
+	app := fiber.New(fiber.Config{ErrorHandler: middleware.ErrorHandler})
+	routes.SetupRoutes(app, nil)
+
+	var token string
+	call := func(method, path string, body interface{}) *http.Response {
+		data, err := json.Marshal(body)
+		require.NoError(t, err)
+		req := httptest.NewRequest(method, path, bytes.NewReader(data))
+		req.Header.Set("Content-Type", "application/json")
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := app.Test(req, -1)
+		require.NoError(t, err)
+		return resp
+	}
+	decode := func(resp *http.Response) map[string]interface{} {
+		var out map[string]interface{}
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&out))
+		return out
+	}
+
+	// A password account with two-factor authentication
+	name := "sso" + uuid.NewString()[:8]
+	resp := call(http.MethodPost, "/api/v1/auth/register", map[string]string{
+		"username":  name,
+		"email":     name + "@example.com",
+		"password":  "correct horse battery",
+		"full_name": "Tess Otp",
+	})
+	require.Equal(t, fiber.StatusAccepted, resp.StatusCode)
+	session := decode(call(http.MethodPost, "/api/v1/auth/login", map[string]string{
+		"email":    name + "@example.com",
+		"password": "correct horse battery",
+	}))
+	token = session["token"].(string)
+	secret := decode(call(http.MethodPost, "/api/v1/me/2fa/setup", nil))["secret"].(string)
+	code, err := totp.GenerateCode(secret, time.Now(), 6)
+	require.NoError(t, err)
+	resp = call(http.MethodPost, "/api/v1/me/2fa/confirm", map[string]string{"code": code})
+	require.Equal(t, fiber.StatusOK, resp.StatusCode)
+	recoveryCodes := decode(resp)["recovery_codes"].([]interface{})
+	token = ""
+
+	// The provider vouches for the same verified email
+	mock := newMockOIDCProvider(t)
+	mock.email = name + "@example.com"
+	handlers.ConfigureSSO(&config.Config{
+		APIURL:        "http://localhost:8080",
+		OIDCProviders: []config.OIDCProvider{{Name: "mock2fa", Issuer: mock.URL, ClientID: "projectflow"}},
+	})
+
+	resp = call(http.MethodGet, "/api/v1/auth/oidc/mock2fa", nil)
+	require.Equal(t, fiber.StatusFound, resp.StatusCode)
+	authURL, err := url.Parse(resp.Header.Get("Location"))
+	require.NoError(t, err)
+	mock.challenge, mock.nonce = authURL.Query().Get("code_challenge"), authURL.Query().Get("nonce")
+
+	// The callback hands out a challenge, not a session
+	resp = call(http.MethodGet, "/api/v1/auth/oidc/mock2fa/callback?code=good-code&state="+url.QueryEscape(authURL.Query().Get("state")), nil)
+	require.Equal(t, fiber.StatusFound, resp.StatusCode)
+	redirect, err := url.Parse(resp.Header.Get("Location"))
+	require.NoError(t, err)
+	assert.Equal(t, "/login", redirect.Path)
+	fragment, err := url.ParseQuery(redirect.Fragment)
+	require.NoError(t, err)
+	assert.Empty(t, fragment.Get("token"))
+	assert.Empty(t, fragment.Get("refresh_token"))
+	assert.Equal(t, "true", fragment.Get("two_factor_required"))
+	require.NotEmpty(t, fragment.Get("challenge_token"))
+
+	// Only the second factor completes the login
+	resp = call(http.MethodPost, "/api/v1/auth/login/2fa", map[string]string{
+		"challenge_token": fragment.Get("challenge_token"),
+		"recovery_code":   recoveryCodes[0].(string),
+	})
+	require.Equal(t, fiber.StatusOK, resp.StatusCode)
+	assert.NotEmpty(t, decode(resp)["token"])
+}
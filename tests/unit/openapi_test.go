@@ -0,0 +1,74 @@
+package unit
+
+import (
+	"os"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/amorin24/projecflow/api/openapi"
+	"github.com/amorin24/projecflow/api/routes"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Checked-in copy of the document, used to generate the frontend client
+const openAPIFile = "../../docs/openapi.json"
+
+func TestOpenAPIDescribesEveryRoute(t *testing.T) {
+	app := fiber.New()
+	routes.SetupRoutes(app, nil)
+
+	param := regexp.MustCompile(`:(\w+)`)
+	registered := make(map[string]bool)
+	aliases := make(map[string]bool)
+	for _, route := range app.GetRoutes(true) {
+		if route.Method == fiber.MethodHead {
+			continue
+		}
+		path := param.ReplaceAllString(strings.TrimSuffix(route.Path, "/"), "{$1}")
+		if rest := strings.TrimPrefix(path, "/api"); rest != path && !strings.HasPrefix(rest, "/v1") {
+			aliases[route.Method+" /api/v1"+rest] = true
+			continue
+		}
+		registered[route.Method+" "+path] = true
+	}
+
+	doc := routes.OpenAPI()
+	described := make(map[string]bool)
+	ids := make(map[string]bool)
+	for path, item := range doc.Paths {
+		for method := range item {
+			described[strings.ToUpper(method)+" "+path] = true
+		}
+	}
+	for key := range registered {
+		assert.True(t, described[key], "%s is not in the OpenAPI document, add it to api/routes/openapi.go", key)
+	}
+	for key := range described {
+		assert.True(t, registered[key], "%s is in the OpenAPI document but not routed", key)
+	}
+	for key := range aliases {
+		assert.True(t, registered[key], "deprecated alias of %s has no v1 route", key)
+	}
+
+	data, err := openapi.JSON(doc)
+	require.NoError(t, err)
+	for _, id := range regexp.MustCompile(`"operationId": "(\w+)"`).FindAllStringSubmatch(string(data), -1) {
+		assert.False(t, ids[id[1]], "operationId %s is used twice", id[1])
+		ids[id[1]] = true
+	}
+}
+
+func TestOpenAPIFileUpToDate(t *testing.T) {
+	data, err := openapi.JSON(routes.OpenAPI())
+	require.NoError(t, err)
+
+	if os.Getenv("UPDATE_OPENAPI") != "" {
+		require.NoError(t, os.WriteFile(openAPIFile, data, 0o644))
+	}
+	checkedIn, err := os.ReadFile(openAPIFile)
+	require.NoError(t, err)
+	assert.Equal(t, string(data), string(checkedIn), "docs/openapi.json is stale, run make openapi")
+}
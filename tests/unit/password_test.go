@@ -0,0 +1,33 @@
+package unit
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/amorin24/projecflow/utils/password"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPasswordPolicy(t *testing.T) {
+	policy := password.NewPolicy(10)
+	policy.RequireDigit = true
+
+	assert.Empty(t, policy.Validate("correct horse 9 battery"))
+	assert.Contains(t, policy.Validate("short1"), "must be at least 10 characters")
+	assert.Contains(t, policy.Validate("no digits in here"), "must contain a digit")
+	assert.Contains(t, policy.Validate("ada.lovelace99x", "ada.lovelace@example.com"), "must not contain your username or email")
+	assert.Contains(t, policy.Validate("Password123"), "appears in a list of breached passwords")
+}
+
+func TestPasswordPolicyLoadsHashedBreachList(t *testing.T) {
+	policy := password.NewPolicy(8)
+
+	// SHA-1 of "tr0ub4dor&3" in the Have I Been Pwned format
+	path := filepath.Join(t.TempDir(), "breached.txt")
+	assert.NoError(t, os.WriteFile(path, []byte("281397B1F7880ADE0F53530A55D9AF0210B9AD7B:12\n"), 0600))
+	assert.NoError(t, policy.LoadBreachedFile(path))
+
+	assert.True(t, policy.IsBreached("tr0ub4dor&3"))
+	assert.False(t, policy.IsBreached("something else entirely"))
+}
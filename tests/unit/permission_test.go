@@ -0,0 +1,74 @@
+package unit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/amorin24/projecflow/api/middleware"
+	"github.com/amorin24/projecflow/models"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuiltInRoles(t *testing.T) {
+	owner := models.BuiltInRole(models.RoleOwner)
+	require.NotNil(t, owner)
+	for _, permission := range models.Permissions {
+		assert.True(t, owner.Has(permission), permission)
+	}
+
+	admin := models.BuiltInRole(models.RoleAdmin)
+	assert.True(t, admin.Has(models.PermMemberManage))
+	assert.False(t, admin.Has(models.PermProjectDelete))
+
+	viewer := models.BuiltInRole(models.RoleViewer)
+	assert.True(t, viewer.Has(models.PermTaskComment))
+	assert.False(t, viewer.Has(models.PermTaskEdit))
+
+	assert.Nil(t, models.BuiltInRole("triager"))
+}
+
+func TestAuthorizeMiddleware(t *testing.T) {
+	projectID := uuid.New()
+	roles := map[string]*models.ProjectRole{
+		"viewer": models.BuiltInRole(models.RoleViewer),
+		"member": models.BuiltInRole(models.RoleMember),
+	}
+	resolve := func(c *fiber.Ctx, userID uuid.UUID) (uuid.UUID, *models.ProjectRole, error) {
+		if c.Params("id") != projectID.String() {
+			return uuid.Nil, nil, fiber.NewError(fiber.StatusNotFound, "Project not found")
+		}
+		return projectID, roles[c.Get("X-Test-Role")], nil
+	}
+
+	app := fiber.New(fiber.Config{ErrorHandler: middleware.ErrorHandler})
+	app.Use(func(c *fiber.Ctx) error {
+		c.Locals("userID", uuid.New())
+		c.Locals("role", c.Get("X-Test-Global-Role", "member"))
+		return c.Next()
+	})
+	app.Post("/projects/:id/tasks", middleware.Authorize(models.PermTaskCreate, resolve), func(c *fiber.Ctx) error {
+		assert.Equal(t, projectID, c.Locals("projectID"))
+		return c.SendStatus(fiber.StatusCreated)
+	})
+
+	request := func(id, role, globalRole string) int {
+		req := httptest.NewRequest(http.MethodPost, "/projects/"+id+"/tasks", nil)
+		req.Header.Set("X-Test-Role", role)
+		if globalRole != "" {
+			req.Header.Set("X-Test-Global-Role", globalRole)
+		}
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+		return resp.StatusCode
+	}
+
+	assert.Equal(t, fiber.StatusCreated, request(projectID.String(), "member", ""))
+	assert.Equal(t, fiber.StatusForbidden, request(projectID.String(), "viewer", ""))
+	assert.Equal(t, fiber.StatusForbidden, request(projectID.String(), "", ""))
+	assert.Equal(t, fiber.StatusCreated, request(projectID.String(), "", "admin"))
+	assert.Equal(t, fiber.StatusNotFound, request(uuid.NewString(), "member", ""))
+}
@@ -0,0 +1,179 @@
+package unit
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/amorin24/projecflow/api/handlers"
+	"github.com/amorin24/projecflow/config"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// configureAvatars stores profile pictures of at most maxKB in dir for the
+// rest of the test
+func configureAvatars(t *testing.T, dir string, maxKB int) {
+	require.NoError(t, handlers.ConfigureAccounts(&config.Config{
+		PasswordMinLength: 10,
+		AvatarDir:         dir,
+		AvatarMaxKB:       maxKB,
+		APIURL:            "http://localhost:8080",
+	}))
+	t.Cleanup(func() {
+		handlers.ConfigureAccounts(&config.Config{
+			PasswordMinLength: 10,
+			AvatarDir:         "uploads/avatars",
+			AvatarMaxKB:       1024,
+			APIURL:            "http://localhost:8080",
+		})
+	})
+}
+
+// uploadAvatar sends data as the avatar form field
+func (a *apiClient) uploadAvatar(t *testing.T, data []byte) (int, map[string]interface{}) {
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	part, err := form.CreateFormFile("avatar", "picture.png")
+	require.NoError(t, err)
+	_, err = part.Write(data)
+	require.NoError(t, err)
+	require.NoError(t, form.Close())
+
+	req := httptest.NewRequest(http.MethodPut, "/api/v1/me/avatar", &body)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	req.Header.Set("Authorization", "Bearer "+a.token)
+	resp, err := a.app.Test(req, -1)
+	require.NoError(t, err)
+	var out map[string]interface{}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&out))
+	return resp.StatusCode, out
+}
+
+// pngHeader is enough of a PNG file for content sniffing
+var pngHeader = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR\x00\x00\x00\x01\x00\x00\x00\x01")
+
+func TestUpdateProfile(t *testing.T) {
+	app := newAPIApp(t, nil)
+	client := signUp(t, app, "prof")
+	other := signUp(t, app, "prof")
+
+	status, body := client.call(t, http.MethodPatch, "/api/v1/me", map[string]string{
+		"username":  "renamed" + client.userID.String()[:8],
+		"full_name": "  Renamed User ",
+		"timezone":  "Europe/Berlin",
+		"locale":    "de-DE",
+	})
+	require.Equal(t, fiber.StatusOK, status, body)
+	user := client.user(t)
+	assert.Equal(t, "renamed"+client.userID.String()[:8], user["username"])
+	assert.Equal(t, "Renamed User", user["full_name"])
+	assert.Equal(t, "Europe/Berlin", user["timezone"])
+	assert.Equal(t, "de-DE", user["locale"])
+
+	for field, invalid := range map[string]struct {
+		value, code string
+		status      int
+	}{
+		"username":  {"has space", "invalid_username", fiber.StatusBadRequest},
+		"email":     {"nobody", "validation_failed", fiber.StatusUnprocessableEntity},
+		"full_name": {"   ", "invalid_full_name", fiber.StatusBadRequest},
+		"timezone":  {"Mars/Olympus_Mons", "invalid_timezone", fiber.StatusBadRequest},
+		"locale":    {"not a locale", "invalid_locale", fiber.StatusBadRequest},
+	} {
+		status, body := client.call(t, http.MethodPatch, "/api/v1/me", map[string]string{field: invalid.value})
+		assert.Equal(t, invalid.status, status, field)
+		assert.Equal(t, invalid.code, body["code"], field)
+	}
+
+	// Usernames and addresses of other users are taken regardless of case
+	otherUser := other.user(t)
+	status, body = client.call(t, http.MethodPatch, "/api/v1/me", map[string]string{
+		"username": strings.ToUpper(otherUser["username"].(string)),
+	})
+	assert.Equal(t, fiber.StatusConflict, status)
+	assert.Equal(t, "username_taken", body["code"])
+	status, body = client.call(t, http.MethodPatch, "/api/v1/me", map[string]string{
+		"email": strings.ToUpper(otherUser["email"].(string)),
+	})
+	assert.Equal(t, fiber.StatusConflict, status)
+	assert.Equal(t, "email_taken", body["code"])
+}
+
+func TestChangeEmailRequiresConfirmation(t *testing.T) {
+	openOutbox(t)
+	client := signUp(t, newAPIApp(t, nil), "mail")
+	oldEmail := client.user(t)["email"]
+	newEmail := "moved" + client.userID.String()[:8] + "@example.com"
+
+	status, body := client.call(t, http.MethodPatch, "/api/v1/me", map[string]string{"email": newEmail})
+	require.Equal(t, fiber.StatusOK, status, body)
+	assert.Equal(t, newEmail, body["pending_email"])
+	assert.Equal(t, oldEmail, client.user(t)["email"], "the address only changes once confirmed")
+
+	// The link is sent to the new address
+	token := mailedToken(t, newEmail, "/verify-email")
+	status, body = client.call(t, http.MethodPost, "/api/v1/auth/verify-email", map[string]string{"token": token})
+	require.Equal(t, fiber.StatusOK, status, body)
+	user := client.user(t)
+	assert.Equal(t, newEmail, user["email"])
+
+	// Links work once
+	status, body = client.call(t, http.MethodPost, "/api/v1/auth/verify-email", map[string]string{"token": token})
+	assert.Equal(t, fiber.StatusBadRequest, status)
+	assert.Equal(t, "invalid_verification_token", body["code"])
+}
+
+func TestUploadAvatar(t *testing.T) {
+	dir := t.TempDir()
+	configureAvatars(t, dir, 1)
+	client := signUp(t, newAPIApp(t, nil), "avatar")
+
+	status, body := client.uploadAvatar(t, pngHeader)
+	require.Equal(t, fiber.StatusOK, status, body)
+	avatarURL := body["user"].(map[string]interface{})["avatar_url"].(string)
+	assert.True(t, strings.HasPrefix(avatarURL, "http://localhost:8080/avatars/"+client.userID.String()), avatarURL)
+	assert.True(t, strings.HasSuffix(avatarURL, ".png"), avatarURL)
+	stored := filepath.Join(dir, filepath.Base(avatarURL))
+	assert.FileExists(t, stored)
+
+	// The content decides the type, not the file name
+	status, body = client.uploadAvatar(t, []byte("plain text, not a picture"))
+	assert.Equal(t, fiber.StatusUnsupportedMediaType, status)
+	assert.Equal(t, "unsupported_avatar_type", body["code"])
+
+	status, body = client.uploadAvatar(t, append(append([]byte{}, pngHeader...), make([]byte, 1024)...))
+	assert.Equal(t, fiber.StatusRequestEntityTooLarge, status)
+	assert.Equal(t, "avatar_too_large", body["code"])
+
+	status, body = client.call(t, http.MethodPut, "/api/v1/me/avatar", nil)
+	assert.Equal(t, fiber.StatusBadRequest, status)
+	assert.Equal(t, "avatar_required", body["code"])
+	assert.Equal(t, avatarURL, client.user(t)["avatar_url"], "rejected uploads keep the picture")
+
+	// Deleting removes the file
+	status, body = client.call(t, http.MethodDelete, "/api/v1/me/avatar", nil)
+	require.Equal(t, fiber.StatusOK, status, body)
+	assert.NotContains(t, client.user(t), "avatar_url")
+	assert.NoFileExists(t, stored)
+}
+
+func TestUploadAvatarStorageFailure(t *testing.T) {
+	// The directory cannot be created below a regular file
+	blocker := filepath.Join(t.TempDir(), "file")
+	require.NoError(t, os.WriteFile(blocker, nil, 0o644))
+	configureAvatars(t, filepath.Join(blocker, "avatars"), 1)
+	client := signUp(t, newAPIApp(t, nil), "avatar")
+
+	status, body := client.uploadAvatar(t, pngHeader)
+	assert.Equal(t, fiber.StatusInternalServerError, status)
+	assert.Equal(t, "internal_error", body["code"])
+	assert.NotContains(t, client.user(t), "avatar_url")
+}
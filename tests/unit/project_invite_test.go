@@ -0,0 +1,159 @@
+package unit
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/amorin24/projecflow/models"
+	"github.com/amorin24/projecflow/utils"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// inviteFixture is a project owner who invites people by email
+type inviteFixture struct {
+	owner   *apiClient
+	project string
+}
+
+func newInviteFixture(t *testing.T) *inviteFixture {
+	openOutbox(t)
+	owner := signUp(t, newAPIApp(t, nil), "invite")
+	status, body := owner.call(t, http.MethodPost, "/api/v1/projects", map[string]string{"name": "Invited"})
+	require.Equal(t, fiber.StatusCreated, status, body)
+	return &inviteFixture{owner: owner, project: body["project"].(map[string]interface{})["id"].(string)}
+}
+
+// invite invites email with role and returns the invitation ID and the
+// token in the email
+func (f *inviteFixture) invite(t *testing.T, email, role string) (string, string) {
+	status, body := f.owner.call(t, http.MethodPost, "/api/v1/projects/"+f.project+"/invites", map[string]string{"email": email, "role": role})
+	require.Equal(t, fiber.StatusCreated, status, body)
+	return body["invite"].(map[string]interface{})["id"].(string), mailedToken(t, email, "/invites/project")
+}
+
+// accept accepts an invitation, signed in as client if it isn't nil
+func (f *inviteFixture) accept(t *testing.T, client *apiClient, body map[string]string) (int, map[string]interface{}) {
+	if client == nil {
+		client = &apiClient{app: f.owner.app}
+	}
+	return client.call(t, http.MethodPost, "/api/v1/project-invites/accept", body)
+}
+
+// roleOf returns the role a user has in the project, or "" if they aren't
+// a member
+func (f *inviteFixture) roleOf(t *testing.T, client *apiClient) string {
+	status, body := client.call(t, http.MethodGet, "/api/v1/projects/"+f.project, nil)
+	if status != fiber.StatusOK {
+		return ""
+	}
+	return body["role"].(map[string]interface{})["name"].(string)
+}
+
+func TestProjectInviteForExistingAccount(t *testing.T) {
+	f := newInviteFixture(t)
+	guest, stranger := signUp(t, f.owner.app, "invite"), signUp(t, f.owner.app, "invite")
+	_, token := f.invite(t, guest.user(t)["email"].(string), "viewer")
+
+	// Signed in as someone else the invitation can't be accepted
+	status, body := f.accept(t, stranger, map[string]string{"token": token})
+	assert.Equal(t, fiber.StatusForbidden, status)
+	assert.Equal(t, "invitation_email_mismatch", body["code"])
+	assert.Empty(t, f.roleOf(t, stranger))
+
+	// The invited user joins with the invited role
+	status, body = f.accept(t, guest, map[string]string{"token": token})
+	require.Equal(t, fiber.StatusOK, status, body)
+	assert.Equal(t, "viewer", body["member"].(map[string]interface{})["role"])
+	status, session := guest.call(t, http.MethodPost, "/api/v1/organizations/switch", map[string]interface{}{
+		"organization_id": body["project"].(map[string]interface{})["organization_id"],
+	})
+	require.Equal(t, fiber.StatusOK, status, session)
+	guest.token = session["token"].(string)
+	assert.Equal(t, "viewer", f.roleOf(t, guest))
+
+	// Each invitation is accepted once
+	status, body = f.accept(t, nil, map[string]string{"token": token})
+	assert.Equal(t, fiber.StatusBadRequest, status)
+	assert.Equal(t, "invalid_invitation", body["code"])
+}
+
+func TestProjectInviteCreatesAccount(t *testing.T) {
+	f := newInviteFixture(t)
+	email := "newbie" + uuid.NewString()[:8] + "@example.com"
+	inviteID, first := f.invite(t, email, "member")
+
+	// Resending replaces the link
+	status, body := f.owner.call(t, http.MethodPost, "/api/v1/projects/"+f.project+"/invites/"+inviteID+"/resend", nil)
+	require.Equal(t, fiber.StatusOK, status, body)
+	var second string
+	require.Eventually(t, func() bool {
+		second = mailedToken(t, email, "/invites/project")
+		return second != first
+	}, 5*time.Second, 10*time.Millisecond)
+	status, body = f.accept(t, nil, map[string]string{"token": first})
+	assert.Equal(t, fiber.StatusBadRequest, status)
+	assert.Equal(t, "invalid_invitation", body["code"])
+
+	status, body = (&apiClient{app: f.owner.app}).call(t, http.MethodGet, "/api/v1/project-invites?token="+url.QueryEscape(second), nil)
+	require.Equal(t, fiber.StatusOK, status, body)
+	assert.Equal(t, false, body["account_exists"])
+
+	// Without an account one has to be created
+	status, body = f.accept(t, nil, map[string]string{"token": second})
+	assert.Equal(t, fiber.StatusBadRequest, status)
+	assert.Equal(t, "account_required", body["code"])
+
+	status, session := f.accept(t, nil, map[string]string{
+		"token":     second,
+		"username":  "newbie" + uuid.NewString()[:8],
+		"full_name": "New Bee",
+		"password":  "correct horse battery",
+	})
+	require.Equal(t, fiber.StatusCreated, status, session)
+	assert.Equal(t, true, session["user"].(map[string]interface{})["email_verified"])
+	newbie := &apiClient{app: f.owner.app, token: session["token"].(string)}
+	assert.Equal(t, "member", f.roleOf(t, newbie))
+}
+
+func TestRevokedProjectInvite(t *testing.T) {
+	f := newInviteFixture(t)
+	inviteID, token := f.invite(t, "revoked"+uuid.NewString()[:8]+"@example.com", "member")
+	invitePath := "/api/v1/projects/" + f.project + "/invites/" + inviteID
+
+	status, body := f.owner.call(t, http.MethodDelete, invitePath, nil)
+	require.Equal(t, fiber.StatusOK, status, body)
+	assert.Equal(t, models.InviteStatusRevoked, body["invite"].(map[string]interface{})["status"])
+
+	status, body = f.accept(t, nil, map[string]string{"token": token})
+	assert.Equal(t, fiber.StatusBadRequest, status)
+	assert.Equal(t, "invalid_invitation", body["code"])
+
+	// Revoked invitations can't be sent again
+	status, body = f.owner.call(t, http.MethodPost, invitePath+"/resend", nil)
+	assert.Equal(t, fiber.StatusConflict, status)
+	assert.Equal(t, "invitation_closed", body["code"])
+}
+
+func TestExpiredProjectInvite(t *testing.T) {
+	require.NoError(t, utils.UseEphemeralSigningKey())
+	past := time.Now().Add(-time.Minute)
+
+	// Expired links are rejected before the invitation is looked up
+	token, err := utils.GenerateInviteToken(uuid.New(), uuid.NewString(), past)
+	require.NoError(t, err)
+	_, err = utils.ValidateInviteToken(token)
+	assert.Error(t, err)
+
+	// And an expired invitation stays expired until it's resent
+	invite := &models.ProjectInvite{ExpiresAt: past}
+	assert.Equal(t, models.InviteStatusExpired, invite.Status(time.Now()))
+	assert.Equal(t, models.InviteStatusPending, invite.Status(past.Add(-time.Second)))
+	revokedAt := past.Add(-time.Hour)
+	invite.RevokedAt = &revokedAt
+	assert.Equal(t, models.InviteStatusRevoked, invite.Status(time.Now()), "revocation outranks expiry")
+}
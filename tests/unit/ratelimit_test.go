@@ -0,0 +1,122 @@
+package unit
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/amorin24/projecflow/api/middleware"
+	"github.com/amorin24/projecflow/config"
+	"github.com/amorin24/projecflow/utils/ratelimit"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryLimiterSlidingWindow(t *testing.T) {
+	limiter := ratelimit.NewMemoryLimiter()
+	ctx := context.Background()
+	rule := ratelimit.Rule{Limit: 3, Window: 200 * time.Millisecond}
+
+	for i := 0; i < 3; i++ {
+		res, err := limiter.Allow(ctx, "login:ip:10.0.0.1", rule)
+		require.NoError(t, err)
+		assert.True(t, res.Allowed)
+		assert.Equal(t, 2-i, res.Remaining)
+	}
+
+	res, err := limiter.Allow(ctx, "login:ip:10.0.0.1", rule)
+	require.NoError(t, err)
+	assert.False(t, res.Allowed)
+	assert.Greater(t, res.RetryAfter, time.Duration(0))
+
+	// Other keys are counted separately
+	res, _ = limiter.Allow(ctx, "login:ip:10.0.0.2", rule)
+	assert.True(t, res.Allowed)
+
+	// Rejected attempts aren't counted, so the window frees up on time
+	time.Sleep(250 * time.Millisecond)
+	res, _ = limiter.Allow(ctx, "login:ip:10.0.0.1", rule)
+	assert.True(t, res.Allowed)
+}
+
+func TestLockoutBackoff(t *testing.T) {
+	lockout := &ratelimit.Lockout{
+		Limiter:   ratelimit.NewMemoryLimiter(),
+		Threshold: 3,
+		BaseDelay: time.Minute,
+		MaxDelay:  10 * time.Minute,
+		Window:    time.Hour,
+	}
+	ctx := context.Background()
+
+	assert.Equal(t, time.Duration(0), lockout.Delay(2))
+	assert.Equal(t, time.Minute, lockout.Delay(3))
+	assert.Equal(t, 4*time.Minute, lockout.Delay(5))
+	assert.Equal(t, 10*time.Minute, lockout.Delay(100))
+
+	for i := 0; i < 2; i++ {
+		locked, err := lockout.Fail(ctx, "Ada@Example.com")
+		require.NoError(t, err)
+		assert.Zero(t, locked)
+	}
+	locked, _ := lockout.Fail(ctx, "ada@example.com")
+	assert.InDelta(t, time.Minute, locked, float64(time.Second))
+
+	locked, _ = lockout.LockedFor(ctx, "ADA@example.com ")
+	assert.Greater(t, locked, time.Duration(0))
+
+	require.NoError(t, lockout.Reset(ctx, "ada@example.com"))
+	locked, _ = lockout.LockedFor(ctx, "ada@example.com")
+	assert.Zero(t, locked)
+}
+
+func TestTokenBucketRefills(t *testing.T) {
+	buckets := ratelimit.NewBuckets()
+	quota := ratelimit.Quota{Requests: 2, Period: 200 * time.Millisecond}
+
+	assert.True(t, buckets.Take("default:user:1", quota).Allowed)
+	res := buckets.Take("default:user:1", quota)
+	assert.True(t, res.Allowed)
+	assert.Equal(t, 0, res.Remaining)
+
+	res = buckets.Take("default:user:1", quota)
+	assert.False(t, res.Allowed)
+	assert.Greater(t, res.RetryAfter, time.Duration(0))
+	assert.LessOrEqual(t, res.RetryAfter, 100*time.Millisecond)
+
+	// One token comes back every 100ms
+	time.Sleep(110 * time.Millisecond)
+	assert.True(t, buckets.Take("default:user:1", quota).Allowed)
+
+	usage := buckets.Usage()
+	require.Len(t, usage, 1)
+	assert.Equal(t, int64(3), usage[0].Allowed)
+	assert.Equal(t, int64(1), usage[0].Rejected)
+}
+
+func TestRateLimitMiddlewareHeaders(t *testing.T) {
+	middleware.ConfigureRateLimits(&config.Config{
+		RateLimitEnabled: true,
+		RateLimits:       map[string]config.RateLimit{"test": {Requests: 1, Period: time.Minute}},
+	})
+
+	app := fiber.New(fiber.Config{ErrorHandler: middleware.ErrorHandler})
+	app.Get("/limited", middleware.RateLimit("test"), func(c *fiber.Ctx) error {
+		return c.SendStatus(fiber.StatusOK)
+	})
+
+	resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/limited", nil))
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	assert.Equal(t, "1", resp.Header.Get("RateLimit-Limit"))
+	assert.Equal(t, "0", resp.Header.Get("RateLimit-Remaining"))
+	assert.Equal(t, "1;w=60", resp.Header.Get("RateLimit-Policy"))
+
+	resp, err = app.Test(httptest.NewRequest(http.MethodGet, "/limited", nil))
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusTooManyRequests, resp.StatusCode)
+	assert.Equal(t, "60", resp.Header.Get("Retry-After"))
+}
@@ -0,0 +1,102 @@
+package unit
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/amorin24/projecflow/api/middleware"
+	"github.com/amorin24/projecflow/api/routes"
+	"github.com/amorin24/projecflow/utils"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTaskAndProjectResponses(t *testing.T) {
+	require.NoError(t, utils.UseEphemeralSigningKey())
+	app := fiber.New(fiber.Config{ErrorHandler: middleware.ErrorHandler})
+	routes.SetupRoutes(app, nil)
+
+	var token string
+	call := func(method, path string, body interface{}, status int) map[string]interface{} {
+		reader := bytes.NewReader(nil)
+		if body != nil {
+			data, err := json.Marshal(body)
+			require.NoError(t, err)
+			reader = bytes.NewReader(data)
+		}
+		req := httptest.NewRequest(method, path, reader)
+		req.Header.Set("Content-Type", "application/json")
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+		var out map[string]interface{}
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&out))
+		require.Equal(t, status, resp.StatusCode, "%s %s: %v", method, path, out)
+		return out
+	}
+
+	name := "dto" + uuid.NewString()[:8]
+	call(http.MethodPost, "/api/v1/auth/register", map[string]string{
+		"username":  name,
+		"email":     name + "@example.com",
+		"password":  "correct horse battery",
+		"full_name": "Dee Tee-Oh",
+	}, fiber.StatusAccepted)
+	token = call(http.MethodPost, "/api/v1/auth/login", map[string]string{
+		"email":    name + "@example.com",
+		"password": "correct horse battery",
+	}, fiber.StatusOK)["token"].(string)
+
+	project := call(http.MethodPost, "/api/v1/projects", map[string]string{"name": "Responses"}, fiber.StatusCreated)["project"].(map[string]interface{})
+	projectID := project["id"].(string)
+	assert.Equal(t, name, project["owner"].(map[string]interface{})["username"])
+	assert.NotContains(t, project, "members", "members are only embedded when included")
+
+	task := call(http.MethodPost, "/api/v1/tasks", map[string]interface{}{
+		"title":      "Write the DTOs",
+		"project_id": projectID,
+		"status_id":  1,
+	}, fiber.StatusCreated)["task"].(map[string]interface{})
+	taskID := task["id"].(string)
+	call(http.MethodPost, "/api/v1/tasks/"+taskID+"/comments", map[string]string{"content": "Looks good"}, fiber.StatusCreated)
+
+	// Related users and the status are embedded, the raw IDs stay
+	assert.Equal(t, projectID, task["project_id"])
+	assert.Equal(t, "To Do", task["status"].(map[string]interface{})["name"])
+	assert.Equal(t, name, task["reporter"].(map[string]interface{})["username"])
+	assert.Nil(t, task["assignee"])
+	assert.NotContains(t, task, "comments")
+
+	// Includes embed the project and the comments with their authors
+	body := call(http.MethodGet, "/api/v1/tasks/"+taskID+"?include=comments,project", nil, fiber.StatusOK)
+	task = body["task"].(map[string]interface{})
+	assert.Equal(t, "Responses", task["project"].(map[string]interface{})["name"])
+	comments := task["comments"].([]interface{})
+	require.Len(t, comments, 1)
+	assert.Equal(t, name, comments[0].(map[string]interface{})["user"].(map[string]interface{})["username"])
+	assert.Len(t, body["comments"], 1)
+
+	// Sparse fieldsets keep the requested fields and the ID
+	tasks := call(http.MethodGet, "/api/v1/tasks/project/"+projectID+"?fields=title,status", nil, fiber.StatusOK)["tasks"].([]interface{})
+	require.Len(t, tasks, 1)
+	assert.Len(t, tasks[0], 3)
+	assert.Equal(t, "Write the DTOs", tasks[0].(map[string]interface{})["title"])
+
+	projects := call(http.MethodGet, "/api/v1/projects?include=members&fields=name,members", nil, fiber.StatusOK)["projects"].([]interface{})
+	require.Len(t, projects, 1)
+	assert.Len(t, projects[0].(map[string]interface{})["members"], 1)
+	assert.NotContains(t, projects[0], "owner")
+
+	// Unknown names are refused
+	problem := call(http.MethodGet, "/api/v1/tasks/"+taskID+"?include=watchers", nil, fiber.StatusBadRequest)
+	assert.Equal(t, "unknown_include", problem["code"])
+	problem = call(http.MethodGet, "/api/v1/projects?fields=budget", nil, fiber.StatusBadRequest)
+	assert.Equal(t, "unknown_field", problem["code"])
+}
@@ -0,0 +1,91 @@
+package unit
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/amorin24/projecflow/utils/safehttp"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSafeHTTPForbiddenAddresses(t *testing.T) {
+	for address, forbidden := range map[string]bool{
+		"127.0.0.1":        true,
+		"::1":              true,
+		"169.254.169.254":  true,
+		"10.1.2.3":         true,
+		"172.16.0.1":       true,
+		"192.168.1.1":      true,
+		"100.64.0.1":       true,
+		"0.0.0.0":          true,
+		"fd00::1":          true,
+		"fe80::1":          true,
+		"::ffff:127.0.0.1": true,
+		"93.184.216.34":    false,
+		"2606:4700::1111":  false,
+	} {
+		assert.Equal(t, forbidden, safehttp.Forbidden(net.ParseIP(address)), address)
+	}
+}
+
+func TestSafeHTTPCheckURL(t *testing.T) {
+	ctx := context.Background()
+	for _, rawURL := range []string{
+		"http://127.0.0.1:8080/hook",
+		"http://169.254.169.254/latest/meta-data/",
+		"http://localhost/hook",
+		"http://api.localhost./hook",
+		"https://[::1]/hook",
+		"http://10.0.0.5/hook",
+	} {
+		assert.ErrorIs(t, safehttp.CheckURL(ctx, rawURL), safehttp.ErrForbiddenAddress, rawURL)
+	}
+	for _, rawURL := range []string{"ftp://example.com/hook", "http:///hook", "not a url"} {
+		assert.ErrorIs(t, safehttp.CheckURL(ctx, rawURL), safehttp.ErrInvalidURL, rawURL)
+	}
+	assert.NoError(t, safehttp.CheckURL(ctx, "https://93.184.216.34/hook"))
+}
+
+func TestSafeHTTPClientRefusesLocalServers(t *testing.T) {
+	var reached bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reached = true
+	}))
+	defer server.Close()
+
+	_, err := safehttp.NewClient(time.Second).Post(server.URL, "application/json", nil)
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, safehttp.ErrForbiddenAddress), err.Error())
+	assert.False(t, reached)
+}
+
+func TestNotificationWebhookURLMustBePublic(t *testing.T) {
+	client := signUp(t, newAPIApp(t, nil), "hook")
+
+	for rawURL, code := range map[string]string{
+		"http://127.0.0.1:9000/hook":               "forbidden_webhook_url",
+		"http://169.254.169.254/latest/meta-data/": "forbidden_webhook_url",
+		"http://localhost/hook":                    "forbidden_webhook_url",
+		"ftp://93.184.216.34/hook":                 "invalid_webhook_url",
+	} {
+		status, body := client.call(t, http.MethodPut, "/api/v1/me/notification-preferences", map[string]interface{}{
+			"webhook_url":      rawURL,
+			"digest_frequency": "none",
+		})
+		assert.Equal(t, fiber.StatusBadRequest, status, rawURL)
+		assert.Equal(t, code, body["code"], rawURL)
+	}
+
+	status, body := client.call(t, http.MethodPut, "/api/v1/me/notification-preferences", map[string]interface{}{
+		"webhook_url":      "https://93.184.216.34/hook",
+		"digest_frequency": "none",
+	})
+	assert.Equal(t, fiber.StatusOK, status, body)
+}
@@ -0,0 +1,109 @@
+package unit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/amorin24/projecflow/utils"
+	"github.com/amorin24/projecflow/utils/refreshtoken"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRefreshTokenRotationAndReuse(t *testing.T) {
+	store := refreshtoken.NewMemoryStore()
+	userID := uuid.New()
+
+	first, issued, err := store.Issue(userID)
+	assert.NoError(t, err)
+
+	second, rotated, err := store.Rotate(first)
+	assert.NoError(t, err)
+	assert.Equal(t, issued.FamilyID, rotated.FamilyID)
+
+	// Replaying the rotated token revokes the whole family
+	_, _, err = store.Rotate(first)
+	assert.ErrorIs(t, err, refreshtoken.ErrTokenReused)
+	_, _, err = store.Rotate(second)
+	assert.ErrorIs(t, err, refreshtoken.ErrInvalidToken)
+}
+
+func TestRevokedAccessTokenIsRejected(t *testing.T) {
+	token, err := utils.GenerateToken(uuid.New(), uuid.New(), "member")
+	assert.NoError(t, err)
+
+	claims, err := utils.ValidateToken(token)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, claims.ID)
+
+	utils.RevokeToken(claims.ID, claims.ExpiresAt.Time)
+	_, err = utils.ValidateToken(token)
+	assert.ErrorIs(t, err, utils.ErrTokenRevoked)
+}
+
+func TestRevokeAllTokens(t *testing.T) {
+	userID := uuid.New()
+	before, err := utils.GenerateToken(userID, uuid.New(), "member")
+	assert.NoError(t, err)
+
+	// Tokens issued in the same second as the revocation are revoked too
+	utils.RevokeAllTokens(userID)
+	_, err = utils.ValidateToken(before)
+	assert.ErrorIs(t, err, utils.ErrTokenRevoked)
+
+	// But not the ones issued right after it
+	after, err := utils.GenerateToken(userID, uuid.New(), "member")
+	assert.NoError(t, err)
+	_, err = utils.ValidateToken(after)
+	assert.NoError(t, err)
+}
+
+func TestAccessTokenCarriesOrganization(t *testing.T) {
+	userID, orgID := uuid.New(), uuid.New()
+	token, err := utils.GenerateToken(userID, orgID, "member")
+	assert.NoError(t, err)
+
+	claims, err := utils.ValidateToken(token)
+	assert.NoError(t, err)
+	assert.Equal(t, userID, claims.UserID)
+	assert.Equal(t, orgID, claims.OrganizationID)
+}
+
+func TestInviteTokenIsNotAnAccessToken(t *testing.T) {
+	inviteID := uuid.New()
+	token, err := utils.GenerateInviteToken(inviteID, "first", time.Now().Add(time.Hour))
+	assert.NoError(t, err)
+
+	claims, err := utils.ValidateInviteToken(token)
+	assert.NoError(t, err)
+	assert.Equal(t, inviteID, claims.InviteID)
+	assert.Equal(t, "first", claims.ID)
+
+	// Neither kind of token is accepted as the other
+	_, err = utils.ValidateToken(token)
+	assert.Error(t, err)
+	access, err := utils.GenerateToken(uuid.New(), uuid.New(), "member")
+	assert.NoError(t, err)
+	_, err = utils.ValidateInviteToken(access)
+	assert.Error(t, err)
+}
+
+func TestImpersonationTokenNamesTheAdmin(t *testing.T) {
+	userID, adminID := uuid.New(), uuid.New()
+	token, tokenID, err := utils.GenerateImpersonationToken(userID, uuid.New(), "member", adminID, 30*time.Minute)
+	assert.NoError(t, err)
+
+	claims, err := utils.ValidateToken(token)
+	assert.NoError(t, err)
+	assert.Equal(t, userID, claims.UserID)
+	assert.Equal(t, tokenID, claims.ID)
+	if assert.NotNil(t, claims.ImpersonatorID) {
+		assert.Equal(t, adminID, *claims.ImpersonatorID)
+	}
+	assert.WithinDuration(t, time.Now().Add(30*time.Minute), claims.ExpiresAt.Time, time.Minute)
+
+	// Ending the impersonation revokes the token
+	utils.RevokeToken(tokenID, claims.ExpiresAt.Time)
+	_, err = utils.ValidateToken(token)
+	assert.ErrorIs(t, err, utils.ErrTokenRevoked)
+}
@@ -0,0 +1,54 @@
+package unit
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/amorin24/projecflow/utils"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writePrivateKey(t *testing.T, key interface{}) string {
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "key.pem")
+	require.NoError(t, os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0600))
+	return path
+}
+
+func TestSigningKeyRotation(t *testing.T) {
+	defer utils.UseEphemeralSigningKey()
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	oldKey, newKey := writePrivateKey(t, rsaKey), writePrivateKey(t, edKey)
+
+	require.NoError(t, utils.ConfigureSigningKeys(oldKey, nil))
+	oldToken, err := utils.GenerateToken(uuid.New(), uuid.New(), "member")
+	require.NoError(t, err)
+
+	// Rotate: the new key signs, the old one is still accepted
+	require.NoError(t, utils.ConfigureSigningKeys(newKey, []string{oldKey}))
+	_, err = utils.ValidateToken(oldToken)
+	assert.NoError(t, err)
+	newToken, err := utils.GenerateToken(uuid.New(), uuid.New(), "member")
+	require.NoError(t, err)
+	_, err = utils.ValidateToken(newToken)
+	assert.NoError(t, err)
+	assert.Len(t, utils.JWKS()["keys"], 2)
+
+	// Retire the old key
+	require.NoError(t, utils.ConfigureSigningKeys(newKey, nil))
+	_, err = utils.ValidateToken(oldToken)
+	assert.Error(t, err)
+}
@@ -0,0 +1,62 @@
+package unit
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSignUpAlwaysCreatesMembers(t *testing.T) {
+	client := &apiClient{app: newAPIApp(t, nil)}
+	name := "wouldbeadmin" + uuid.NewString()[:8]
+
+	// A requested role is ignored
+	status, body := client.call(t, http.MethodPost, "/api/v1/auth/register", map[string]string{
+		"username":  name,
+		"email":     name + "@example.com",
+		"password":  "correct horse battery",
+		"full_name": "Would Be Admin",
+		"role":      "admin",
+	})
+	require.Equal(t, fiber.StatusAccepted, status, body)
+	status, session := signIn(t, client.app, name+"@example.com")
+	require.Equal(t, fiber.StatusOK, status, session)
+	user := session["user"].(map[string]interface{})
+	assert.Equal(t, "member", user["role"])
+
+	// Nor can members promote themselves
+	client.token = session["token"].(string)
+	status, _ = client.call(t, http.MethodPut, "/api/v1/admin/users/"+user["id"].(string)+"/role", map[string]string{"role": "admin"})
+	assert.Equal(t, fiber.StatusForbidden, status)
+}
+
+func TestSignUpDoesNotRevealTakenEmails(t *testing.T) {
+	openOutbox(t)
+	holder := signUp(t, newAPIApp(t, nil), "taken")
+	email := holder.user(t)["email"].(string)
+	register := func(username, email string) (int, map[string]interface{}) {
+		return (&apiClient{app: holder.app}).call(t, http.MethodPost, "/api/v1/auth/register", map[string]string{
+			"username":  username,
+			"email":     email,
+			"password":  "another horse battery",
+			"full_name": "Copy Cat",
+		})
+	}
+
+	// Taken and free emails get the same answer
+	fresh := "fresh" + uuid.NewString()[:8]
+	freeStatus, freeBody := register(fresh, fresh+"@example.com")
+	takenStatus, takenBody := register("copy"+uuid.NewString()[:8], email)
+	assert.Equal(t, fiber.StatusAccepted, takenStatus)
+	assert.Equal(t, freeStatus, takenStatus)
+	assert.Equal(t, freeBody, takenBody)
+
+	// The account holder is told instead, and keeps their password
+	assert.True(t, mailedTo(t, email, "Someone tried to create a new account"), "no email to the account holder")
+	status, body := signIn(t, holder.app, email)
+	assert.Equal(t, fiber.StatusOK, status, body)
+}
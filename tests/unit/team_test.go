@@ -0,0 +1,157 @@
+package unit
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// teamFixture is an organization owner with a project and a team, and
+// further members of the organization
+type teamFixture struct {
+	owner   *apiClient
+	project string
+	team    string
+}
+
+func newTeamFixture(t *testing.T) *teamFixture {
+	owner := signUp(t, newAPIApp(t, nil), "team")
+	f := &teamFixture{owner: owner}
+	f.project = f.create(t, "/api/v1/projects", map[string]string{"name": "Teamwork"}, "project")
+	f.team = f.create(t, "/api/v1/teams", map[string]string{"name": "Platform"}, "team")
+	return f
+}
+
+// create posts body as the owner and returns the ID of the created record
+func (f *teamFixture) create(t *testing.T, path string, body interface{}, key string) string {
+	status, resp := f.owner.call(t, http.MethodPost, path, body)
+	require.Equal(t, fiber.StatusCreated, status, resp)
+	return resp[key].(map[string]interface{})["id"].(string)
+}
+
+// colleague signs up a new member of the organization
+func (f *teamFixture) colleague(t *testing.T) *apiClient {
+	client := signUp(t, f.owner.app, "team")
+	f.owner.join(t, client)
+	return client
+}
+
+// projectRoleOf returns the role a user has in the project, or "" if they
+// aren't a member
+func (f *teamFixture) projectRoleOf(t *testing.T, client *apiClient) string {
+	status, body := client.call(t, http.MethodGet, "/api/v1/projects/"+f.project, nil)
+	if status != fiber.StatusOK {
+		return ""
+	}
+	return body["role"].(map[string]interface{})["name"].(string)
+}
+
+func TestTeamMembersFollowLinkedProjects(t *testing.T) {
+	f := newTeamFixture(t)
+	early, late := f.colleague(t), f.colleague(t)
+	teamMembers := "/api/v1/teams/" + f.team + "/members"
+
+	status, body := f.owner.call(t, http.MethodPost, teamMembers, map[string]interface{}{"user_id": early.userID})
+	require.Equal(t, fiber.StatusCreated, status, body)
+	assert.Empty(t, f.projectRoleOf(t, early), "unlinked teams don't grant access")
+
+	// Linking adds the current members with the link's role
+	status, body = f.owner.call(t, http.MethodPost, "/api/v1/projects/"+f.project+"/teams", map[string]string{"team_id": f.team, "role": "viewer"})
+	require.Equal(t, fiber.StatusCreated, status, body)
+	assert.Equal(t, "viewer", f.projectRoleOf(t, early))
+
+	// Members joining or leaving the team join or leave the project
+	status, body = f.owner.call(t, http.MethodPost, teamMembers, map[string]interface{}{"user_id": late.userID})
+	require.Equal(t, fiber.StatusCreated, status, body)
+	assert.Equal(t, "viewer", f.projectRoleOf(t, late))
+
+	status, body = f.owner.call(t, http.MethodDelete, teamMembers+"/"+late.userID.String(), nil)
+	require.Equal(t, fiber.StatusOK, status, body)
+	assert.Empty(t, f.projectRoleOf(t, late))
+
+	// Unlinking removes everyone who only belonged through the team
+	status, body = f.owner.call(t, http.MethodDelete, "/api/v1/projects/"+f.project+"/teams/"+f.team, nil)
+	require.Equal(t, fiber.StatusOK, status, body)
+	assert.Empty(t, f.projectRoleOf(t, early))
+
+	// So does deleting the team, but direct members stay
+	status, body = f.owner.call(t, http.MethodPost, "/api/v1/projects/"+f.project+"/teams", map[string]string{"team_id": f.team, "role": "member"})
+	require.Equal(t, fiber.StatusCreated, status, body)
+	status, body = f.owner.call(t, http.MethodPost, teamMembers, map[string]interface{}{"user_id": late.userID})
+	require.Equal(t, fiber.StatusCreated, status, body)
+	status, body = f.owner.call(t, http.MethodPut, "/api/v1/projects/"+f.project+"/members/"+late.userID.String(), map[string]string{"role": "viewer"})
+	require.Equal(t, fiber.StatusOK, status, body)
+	assert.Equal(t, "member", f.projectRoleOf(t, early))
+
+	status, body = f.owner.call(t, http.MethodDelete, "/api/v1/teams/"+f.team, nil)
+	require.Equal(t, fiber.StatusOK, status, body)
+	assert.Empty(t, f.projectRoleOf(t, early))
+	assert.Equal(t, "viewer", f.projectRoleOf(t, late))
+}
+
+func TestTeamLeadsNeedMemberManageInLinkedProjects(t *testing.T) {
+	f := newTeamFixture(t)
+	lead, recruit := f.colleague(t), f.colleague(t)
+	teamMembers := "/api/v1/teams/" + f.team + "/members"
+
+	status, body := f.owner.call(t, http.MethodPost, teamMembers, map[string]interface{}{"user_id": lead.userID, "role": "lead"})
+	require.Equal(t, fiber.StatusCreated, status, body)
+	status, body = f.owner.call(t, http.MethodPost, "/api/v1/projects/"+f.project+"/teams", map[string]string{"team_id": f.team, "role": "admin"})
+	require.Equal(t, fiber.StatusCreated, status, body)
+
+	// The lead is only a member of the project and can't make anyone an admin
+	status, body = f.owner.call(t, http.MethodPut, "/api/v1/projects/"+f.project+"/members/"+lead.userID.String(), map[string]string{"role": "member"})
+	require.Equal(t, fiber.StatusOK, status, body)
+	status, body = lead.call(t, http.MethodPost, teamMembers, map[string]interface{}{"user_id": recruit.userID})
+	assert.Equal(t, fiber.StatusForbidden, status)
+	assert.Equal(t, "project_member_manage_required", body["code"])
+	assert.Empty(t, f.projectRoleOf(t, recruit))
+
+	// Managing members isn't enough when the team's role has more permissions
+	status, body = f.owner.call(t, http.MethodPost, "/api/v1/projects/"+f.project+"/roles", map[string]interface{}{
+		"name":        "staffing",
+		"permissions": []string{"project:view", "member:view", "member:manage"},
+	})
+	require.Equal(t, fiber.StatusCreated, status, body)
+	status, body = f.owner.call(t, http.MethodPut, "/api/v1/projects/"+f.project+"/members/"+lead.userID.String(), map[string]string{"role": "staffing"})
+	require.Equal(t, fiber.StatusOK, status, body)
+	status, body = lead.call(t, http.MethodPost, teamMembers, map[string]interface{}{"user_id": recruit.userID})
+	assert.Equal(t, fiber.StatusForbidden, status)
+	assert.Equal(t, "permission_not_grantable", body["code"])
+
+	// As a project admin the lead can add and remove members
+	status, body = f.owner.call(t, http.MethodPut, "/api/v1/projects/"+f.project+"/members/"+lead.userID.String(), map[string]string{"role": "admin"})
+	require.Equal(t, fiber.StatusOK, status, body)
+	status, body = lead.call(t, http.MethodPost, teamMembers, map[string]interface{}{"user_id": recruit.userID})
+	require.Equal(t, fiber.StatusCreated, status, body)
+	assert.Equal(t, "admin", f.projectRoleOf(t, recruit))
+	status, body = lead.call(t, http.MethodDelete, teamMembers+"/"+recruit.userID.String(), nil)
+	require.Equal(t, fiber.StatusOK, status, body)
+	assert.Empty(t, f.projectRoleOf(t, recruit))
+}
+
+func TestDeleteProjectRoleUsedByTeam(t *testing.T) {
+	f := newTeamFixture(t)
+	roles := "/api/v1/projects/" + f.project + "/roles"
+
+	status, body := f.owner.call(t, http.MethodPost, roles, map[string]interface{}{
+		"name":        "reviewer",
+		"permissions": []string{"project:view", "task:comment"},
+	})
+	require.Equal(t, fiber.StatusCreated, status, body)
+
+	// The team has no members, so only its link uses the role
+	status, body = f.owner.call(t, http.MethodPost, "/api/v1/projects/"+f.project+"/teams", map[string]string{"team_id": f.team, "role": "reviewer"})
+	require.Equal(t, fiber.StatusCreated, status, body)
+	status, body = f.owner.call(t, http.MethodDelete, roles+"/reviewer", nil)
+	assert.Equal(t, fiber.StatusConflict, status)
+	assert.Equal(t, "role_in_use", body["code"])
+
+	status, body = f.owner.call(t, http.MethodDelete, "/api/v1/projects/"+f.project+"/teams/"+f.team, nil)
+	require.Equal(t, fiber.StatusOK, status, body)
+	status, body = f.owner.call(t, http.MethodDelete, roles+"/reviewer", nil)
+	assert.Equal(t, fiber.StatusOK, status, body)
+}
@@ -0,0 +1,81 @@
+package unit
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// tenantRecords are the records an organization owner created
+type tenantRecords struct {
+	owner   *apiClient
+	project string
+	task    string
+	team    string
+}
+
+func newTenantRecords(t *testing.T, owner *apiClient) *tenantRecords {
+	create := func(path string, body interface{}, key string) string {
+		status, resp := owner.call(t, http.MethodPost, path, body)
+		require.Equal(t, fiber.StatusCreated, status, resp)
+		return resp[key].(map[string]interface{})["id"].(string)
+	}
+	r := &tenantRecords{owner: owner}
+	r.project = create("/api/v1/projects", map[string]string{"name": "Private plans"}, "project")
+	r.task = create("/api/v1/tasks", map[string]interface{}{"title": "Secret task", "project_id": r.project, "status_id": 1}, "task")
+	r.team = create("/api/v1/teams", map[string]string{"name": "Skunkworks"}, "team")
+	return r
+}
+
+// listedIDs returns the IDs of the records listed under key
+func listedIDs(t *testing.T, client *apiClient, path, key string) []string {
+	status, body := client.call(t, http.MethodGet, path, nil)
+	require.Equal(t, fiber.StatusOK, status, body)
+	ids := []string{}
+	list, _ := body[key].([]interface{})
+	for _, record := range list {
+		ids = append(ids, record.(map[string]interface{})["id"].(string))
+	}
+	return ids
+}
+
+func TestOrganizationsAreIsolated(t *testing.T) {
+	app := newAPIApp(t, nil)
+	ours := newTenantRecords(t, signUp(t, app, "ours"))
+	theirs := newTenantRecords(t, signUp(t, app, "theirs"))
+	client := ours.owner
+
+	// Records of the other organization are not found
+	for _, path := range []string{
+		"/api/v1/users/" + theirs.owner.userID.String(),
+		"/api/v1/projects/" + theirs.project,
+		"/api/v1/projects/" + theirs.project + "/teams",
+		"/api/v1/tasks/" + theirs.task,
+		"/api/v1/tasks/project/" + theirs.project,
+		"/api/v1/statuses/project/" + theirs.project,
+		"/api/v1/teams/" + theirs.team,
+		"/api/v1/teams/" + theirs.team + "/workload",
+		"/api/v1/resources/availability?user_id=" + theirs.owner.userID.String(),
+	} {
+		status, body := client.call(t, http.MethodGet, path, nil)
+		assert.Equal(t, fiber.StatusNotFound, status, "%s: %v", path, body)
+	}
+
+	// Nor are they listed
+	assert.Equal(t, []string{ours.project}, listedIDs(t, client, "/api/v1/projects", "projects"))
+	assert.Equal(t, []string{ours.task}, listedIDs(t, client, "/api/v1/tasks/project/"+ours.project, "tasks"))
+	assert.Equal(t, []string{ours.team}, listedIDs(t, client, "/api/v1/teams", "teams"))
+
+	// Nor can their users be assigned work
+	status, body := client.call(t, http.MethodPost, "/api/v1/tasks", map[string]interface{}{
+		"title":       "Borrowed hands",
+		"project_id":  ours.project,
+		"status_id":   1,
+		"assignee_id": theirs.owner.userID,
+	})
+	assert.Equal(t, fiber.StatusNotFound, status, body)
+	assert.Equal(t, "assignee_not_found", body["code"])
+}
@@ -0,0 +1,43 @@
+package unit
+
+import (
+	"encoding/base32"
+	"testing"
+	"time"
+
+	"github.com/amorin24/projecflow/utils/totp"
+	"github.com/stretchr/testify/assert"
+)
+
+// Test vectors from RFC 6238 Appendix B (SHA-1)
+func TestTOTPRFC6238Vectors(t *testing.T) {
+	secret := base32.StdEncoding.EncodeToString([]byte("12345678901234567890"))
+
+	vectors := map[int64]string{
+		59:          "94287082",
+		1111111109:  "07081804",
+		1111111111:  "14050471",
+		1234567890:  "89005924",
+		2000000000:  "69279037",
+		20000000000: "65353130",
+	}
+	for unix, expected := range vectors {
+		code, err := totp.GenerateCode(secret, time.Unix(unix, 0), 8)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, code, "time %d", unix)
+	}
+}
+
+func TestTOTPValidateAllowsOneStepOfDrift(t *testing.T) {
+	secret, err := totp.GenerateSecret()
+	assert.NoError(t, err)
+	now := time.Now()
+
+	code, _ := totp.GenerateCode(secret, now.Add(-totp.Period*time.Second), totp.Digits)
+	_, ok := totp.Validate(secret, code, now)
+	assert.True(t, ok)
+
+	code, _ = totp.GenerateCode(secret, now.Add(-3*totp.Period*time.Second), totp.Digits)
+	_, ok = totp.Validate(secret, code, now)
+	assert.False(t, ok)
+}
@@ -0,0 +1,77 @@
+package unit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/amorin24/projecflow/models"
+	"github.com/amorin24/projecflow/utils/validate"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateReportsFieldsByJSONName(t *testing.T) {
+	errs := validate.Struct(&models.CreateTaskRequest{Title: "ab", Priority: "urgent"})
+
+	fields := map[string]string{}
+	for _, fieldErr := range errs {
+		fields[fieldErr.Field] = fieldErr.Rule
+	}
+	assert.Equal(t, map[string]string{
+		"title":      "min",
+		"project_id": "required",
+		"status_id":  "required",
+		"priority":   "oneof",
+	}, fields)
+
+	valid := models.CreateTaskRequest{Title: "Write docs", ProjectID: uuid.New(), StatusID: 1}
+	assert.Nil(t, validate.Struct(&valid))
+}
+
+func TestValidateCustomRules(t *testing.T) {
+	start := time.Date(2025, 3, 10, 0, 0, 0, 0, time.UTC)
+
+	request := models.TimeOffRequest{StartDate: start, EndDate: start.AddDate(0, 0, -1), RequestType: "vacation"}
+	errs := validate.Struct(&request)
+	assert.Len(t, errs, 1)
+	assert.Equal(t, "end_date must not be before start_date", errs[0].Message)
+
+	request.EndDate = start
+	assert.Nil(t, validate.Struct(&request))
+
+	var lookup struct {
+		ID   string `json:"id" validate:"uuid"`
+		Day  string `json:"day" validate:"omitempty,date"`
+		Hook string `json:"hook" validate:"required,url"`
+	}
+	lookup.ID, lookup.Day, lookup.Hook = "nope", "2025-02-30", "ftp://example.com"
+	assert.Len(t, validate.Struct(&lookup), 3)
+
+	lookup.ID, lookup.Day, lookup.Hook = uuid.NewString(), "", "https://example.com/hook"
+	assert.Nil(t, validate.Struct(&lookup))
+}
+
+func TestEveryRequestTagIsKnown(t *testing.T) {
+	// Unknown rules panic, so validating empty requests checks every tag
+	requests := []interface{}{
+		models.CreateUserRequest{}, models.LoginRequest{}, models.UpdateProfileRequest{},
+		models.TwoFactorLoginRequest{}, models.TwoFactorCodeRequest{}, models.DisableTwoFactorRequest{},
+		models.VerifyEmailRequest{}, models.ForgotPasswordRequest{}, models.ResetPasswordRequest{},
+		models.ChangePasswordRequest{}, models.RefreshTokenRequest{},
+		models.CreateProjectRequest{}, models.UpdateProjectRequest{}, models.AddMemberRequest{},
+		models.CreateRoleRequest{}, models.UpdateRoleRequest{}, models.UpdateMemberRoleRequest{},
+		models.CreateProjectInviteRequest{}, models.AcceptProjectInviteRequest{},
+		models.CreateTaskRequest{}, models.UpdateTaskRequest{}, models.UpdateTaskStatusRequest{},
+		models.CreateCommentRequest{}, models.CreateAPIKeyRequest{},
+		models.CreateOrganizationRequest{}, models.UpdateOrganizationRequest{}, models.SwitchOrganizationRequest{},
+		models.InviteMemberRequest{}, models.AcceptInviteRequest{},
+		models.CreateTeamRequest{}, models.UpdateTeamRequest{}, models.AddTeamMemberRequest{},
+		models.UpdateTeamMemberRequest{}, models.AddProjectTeamRequest{},
+		models.AdminUpdateUserRequest{}, models.UpdateUserRoleRequest{}, models.ReassignWorkRequest{},
+		models.StartImpersonationRequest{}, models.CreateWebhookRequest{}, models.UpdateWebhookRequest{},
+		models.ResourceAllocation{}, models.UserAvailability{}, models.TimeOffRequest{},
+	}
+	for _, request := range requests {
+		assert.NotPanics(t, func() { validate.Struct(request) })
+	}
+}
@@ -0,0 +1,65 @@
+package unit
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/amorin24/projecflow/api/middleware"
+	"github.com/amorin24/projecflow/api/routes"
+	"github.com/amorin24/projecflow/config"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUnversionedAPIPathsAreDeprecatedAliases(t *testing.T) {
+	sunset := time.Date(2099, time.January, 1, 0, 0, 0, 0, time.UTC)
+	middleware.ConfigureDeprecation(&config.Config{LegacyAPISunset: sunset})
+	app := fiber.New(fiber.Config{ErrorHandler: middleware.ErrorHandler})
+	routes.SetupRoutes(app, nil)
+
+	resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/api/v1/auth/password-policy", nil))
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	assert.Empty(t, resp.Header.Get("Deprecation"))
+	assert.Empty(t, resp.Header.Get("Sunset"))
+
+	resp, err = app.Test(httptest.NewRequest(http.MethodGet, "/api/auth/password-policy", nil))
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	assert.True(t, strings.HasPrefix(resp.Header.Get("Deprecation"), "@"))
+	assert.Equal(t, "Thu, 01 Jan 2099 00:00:00 GMT", resp.Header.Get("Sunset"))
+	assert.Equal(t, `</api/v1/auth/password-policy>; rel="successor-version"`, resp.Header.Get("Link"))
+
+	// Errors of aliases are deprecated too
+	resp, err = app.Test(httptest.NewRequest(http.MethodGet, "/api/tasks/not-a-task", nil))
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusUnauthorized, resp.StatusCode)
+	assert.NotEmpty(t, resp.Header.Get("Deprecation"))
+
+	// After the sunset date the aliases are gone
+	middleware.ConfigureDeprecation(&config.Config{LegacyAPISunset: time.Now().Add(-time.Hour)})
+	defer middleware.ConfigureDeprecation(&config.Config{LegacyAPISunset: sunset})
+
+	resp, err = app.Test(httptest.NewRequest(http.MethodGet, "/api/auth/password-policy", nil))
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusGone, resp.StatusCode)
+	var body map[string]interface{}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+	assert.Equal(t, "api_version_sunset", body["code"])
+	assert.Equal(t, "/api/v1/auth/password-policy", body["successor"])
+
+	resp, err = app.Test(httptest.NewRequest(http.MethodGet, "/api/v1/auth/password-policy", nil))
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+}
+
+func TestAPIPathIgnoresVersion(t *testing.T) {
+	assert.Equal(t, "/me/2fa/setup", middleware.APIPath("/api/me/2fa/setup"))
+	assert.Equal(t, "/me/2fa/setup", middleware.APIPath("/api/v1/me/2fa/setup"))
+	assert.Equal(t, "/auth/me", middleware.APIPath("/api/v12/auth/me"))
+}
@@ -0,0 +1,158 @@
+package unit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/amorin24/projecflow/models"
+	"github.com/amorin24/projecflow/webhooks"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebhookSignature(t *testing.T) {
+	body := []byte(`{"type":"task.created"}`)
+	signature := webhooks.Sign("whsec_test", 1700000000, body)
+
+	assert.Regexp(t, "^sha256=[0-9a-f]{64}$", signature)
+	assert.True(t, webhooks.Verify("whsec_test", 1700000000, body, signature))
+	assert.False(t, webhooks.Verify("whsec_other", 1700000000, body, signature), "Different secret must not verify")
+	assert.False(t, webhooks.Verify("whsec_test", 1700000001, body, signature), "Different timestamp must not verify")
+	assert.False(t, webhooks.Verify("whsec_test", 1700000000, []byte(`{}`), signature), "Tampered body must not verify")
+}
+
+func TestWebhookSubscribes(t *testing.T) {
+	webhook := models.Webhook{Events: []string{models.WebhookTaskCreated}}
+	assert.True(t, webhook.Subscribes(models.WebhookTaskCreated))
+	assert.False(t, webhook.Subscribes(models.WebhookTaskDeleted))
+
+	wildcard := models.Webhook{Events: []string{"*"}}
+	assert.True(t, wildcard.Subscribes(models.WebhookProjectUpdated))
+}
+
+func TestWebhookDeliveredAfterProjectDeletion(t *testing.T) {
+	received := make(chan string, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received <- r.Header.Get(webhooks.HeaderEvent)
+	}))
+	defer server.Close()
+
+	store := webhooks.NewMemoryStore()
+	projectID := uuid.New()
+	store.Save(&models.Webhook{
+		ID:        uuid.New(),
+		ProjectID: projectID,
+		URL:       server.URL,
+		Secret:    "whsec_test",
+		Events:    []string{models.WebhookProjectDeleted},
+		Active:    true,
+	})
+
+	// The project's webhooks go right after the event is published. The
+	// test server listens on loopback, which the default client refuses.
+	dispatcher := webhooks.NewDispatcher(store)
+	dispatcher.Client = server.Client()
+	dispatcher.Publish(projectID, models.WebhookProjectDeleted, map[string]string{"id": projectID.String()})
+	store.DeleteProject(projectID)
+
+	select {
+	case event := <-received:
+		assert.Equal(t, models.WebhookProjectDeleted, event)
+	case <-time.After(5 * time.Second):
+		t.Fatal("project.deleted was not delivered")
+	}
+}
+
+func TestWebhookDispatcherRefusesLocalEndpoints(t *testing.T) {
+	reached := make(chan bool, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reached <- true
+	}))
+	defer server.Close()
+
+	// The URL may have passed validation and resolve to loopback later
+	store := webhooks.NewMemoryStore()
+	projectID := uuid.New()
+	webhook := &models.Webhook{
+		ID:        uuid.New(),
+		ProjectID: projectID,
+		URL:       server.URL,
+		Secret:    "whsec_test",
+		Events:    []string{"*"},
+		Active:    true,
+	}
+	store.Save(webhook)
+	webhooks.NewDispatcher(store).Publish(projectID, models.WebhookProjectUpdated, map[string]string{"id": projectID.String()})
+
+	require.Eventually(t, func() bool { return len(store.Deliveries(webhook.ID)) == 1 }, 5*time.Second, 10*time.Millisecond)
+	delivery := store.Deliveries(webhook.ID)[0]
+	assert.False(t, delivery.Success)
+	assert.Contains(t, delivery.Error, "address is not public")
+	assert.Empty(t, reached)
+}
+
+func TestProjectWebhookURLMustBePublic(t *testing.T) {
+	client := signUp(t, newAPIApp(t, nil), "hook")
+	status, body := client.call(t, http.MethodPost, "/api/v1/projects", map[string]string{"name": "Hooked"})
+	require.Equal(t, fiber.StatusCreated, status, body)
+	path := "/api/v1/projects/" + body["project"].(map[string]interface{})["id"].(string) + "/webhooks"
+
+	for rawURL, code := range map[string]string{
+		"http://127.0.0.1:9000/hook":               "forbidden_webhook_url",
+		"http://169.254.169.254/latest/meta-data/": "forbidden_webhook_url",
+		"http://localhost:8080/api/v1/admin/users": "forbidden_webhook_url",
+		"http://192.168.0.10/hook":                 "forbidden_webhook_url",
+	} {
+		status, body := client.call(t, http.MethodPost, path, map[string]interface{}{
+			"url":    rawURL,
+			"events": []string{"*"},
+		})
+		assert.Equal(t, fiber.StatusBadRequest, status, rawURL)
+		assert.Equal(t, code, body["code"], rawURL)
+	}
+
+	status, body = client.call(t, http.MethodPost, path, map[string]interface{}{
+		"url":    "https://93.184.216.34/hook",
+		"events": []string{"*"},
+	})
+	require.Equal(t, fiber.StatusCreated, status, body)
+	webhookPath := path + "/" + body["webhook"].(map[string]interface{})["id"].(string)
+
+	status, body = client.call(t, http.MethodPut, webhookPath, map[string]interface{}{
+		"url":    "http://[::1]/hook",
+		"events": []string{"*"},
+		"active": true,
+	})
+	assert.Equal(t, fiber.StatusBadRequest, status)
+	assert.Equal(t, "forbidden_webhook_url", body["code"])
+}
+
+func TestWebhookStoreUpdateKeepsDeliveryResults(t *testing.T) {
+	store := webhooks.NewMemoryStore()
+	webhook := &models.Webhook{ID: uuid.New(), URL: "https://93.184.216.34/old", Events: []string{"*"}, Active: true}
+	store.Save(webhook)
+
+	// Deliveries fail between loading the webhook and saving the change
+	loaded, ok := store.Get(webhook.ID)
+	require.True(t, ok)
+	store.RecordResult(webhook.ID, false, 15)
+	store.RecordResult(webhook.ID, false, 15)
+
+	updated, ok := store.Update(loaded.ID, func(webhook *models.Webhook) {
+		webhook.URL = "https://93.184.216.34/new"
+	})
+	require.True(t, ok)
+	assert.Equal(t, "https://93.184.216.34/new", updated.URL)
+	assert.Equal(t, 2, updated.ConsecutiveFailures)
+
+	stored, _ := store.Get(webhook.ID)
+	assert.Equal(t, 2, stored.ConsecutiveFailures)
+
+	store.Delete(webhook.ID)
+	_, ok = store.Update(webhook.ID, func(*models.Webhook) { t.Fatal("removed webhooks must not change") })
+	assert.False(t, ok)
+}
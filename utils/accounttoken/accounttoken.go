@@ -0,0 +1,134 @@
+package accounttoken
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Purposes a token can be issued for. A token only works for its own purpose.
+const (
+	PurposeVerifyEmail   = "verify_email"
+	PurposeResetPassword = "reset_password"
+	PurposeChangeEmail   = "change_email"
+)
+
+// ErrInvalidToken is returned for unknown, used or expired tokens
+var ErrInvalidToken = errors.New("invalid or expired token")
+
+// token is a stored single-use token; only its hash is kept
+type token struct {
+	UserID    uuid.UUID
+	Purpose   string
+	ExpiresAt time.Time
+}
+
+// MemoryStore keeps single-use email tokens in memory, indexed by hash
+type MemoryStore struct {
+	tokens map[string]token
+	mu     sync.Mutex
+}
+
+// NewMemoryStore creates an empty token store and starts its janitor
+func NewMemoryStore() *MemoryStore {
+	store := &MemoryStore{tokens: make(map[string]token)}
+
+	// Start the janitor to drop expired tokens
+	go store.janitor()
+
+	return store
+}
+
+// Issue creates a token for a user and purpose, replacing any earlier token
+// for the same purpose, and returns the plaintext to email to the user
+func (s *MemoryStore) Issue(userID uuid.UUID, purpose string, ttl time.Duration) (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	plaintext := base64.RawURLEncoding.EncodeToString(buf)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.revoke(userID, purpose)
+	s.tokens[hash(plaintext)] = token{
+		UserID:    userID,
+		Purpose:   purpose,
+		ExpiresAt: time.Now().Add(ttl),
+	}
+	return plaintext, nil
+}
+
+// Lookup returns the user a token was issued to without using it up
+func (s *MemoryStore) Lookup(plaintext, purpose string) (uuid.UUID, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.tokens[hash(plaintext)]
+	if !ok || stored.Purpose != purpose || time.Now().After(stored.ExpiresAt) {
+		return uuid.Nil, ErrInvalidToken
+	}
+	return stored.UserID, nil
+}
+
+// Consume redeems a token once and returns the user it was issued to
+func (s *MemoryStore) Consume(plaintext, purpose string) (uuid.UUID, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := hash(plaintext)
+	stored, ok := s.tokens[key]
+	if !ok || stored.Purpose != purpose {
+		return uuid.Nil, ErrInvalidToken
+	}
+	delete(s.tokens, key)
+
+	if time.Now().After(stored.ExpiresAt) {
+		return uuid.Nil, ErrInvalidToken
+	}
+	return stored.UserID, nil
+}
+
+// Revoke drops a user's outstanding tokens for a purpose
+func (s *MemoryStore) Revoke(userID uuid.UUID, purpose string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.revoke(userID, purpose)
+}
+
+func (s *MemoryStore) revoke(userID uuid.UUID, purpose string) {
+	for key, stored := range s.tokens {
+		if stored.UserID == userID && stored.Purpose == purpose {
+			delete(s.tokens, key)
+		}
+	}
+}
+
+func (s *MemoryStore) janitor() {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		now := time.Now()
+		s.mu.Lock()
+		for key, stored := range s.tokens {
+			if now.After(stored.ExpiresAt) {
+				delete(s.tokens, key)
+			}
+		}
+		s.mu.Unlock()
+	}
+}
+
+func hash(plaintext string) string {
+	sum := sha256.Sum256([]byte(plaintext))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,146 @@
+package apikey
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/projectflow/models"
+)
+
+// KeyPrefix marks a bearer credential as an API key rather than a JWT
+const KeyPrefix = "pf_"
+
+// ErrInvalidKey is returned for unknown, malformed, revoked or expired keys
+var ErrInvalidKey = errors.New("invalid API key")
+
+// DefaultStore holds the API keys used by the auth middleware
+var DefaultStore = NewMemoryStore()
+
+// Generate creates a new key of the form pf_<prefix>_<secret>.
+// It returns the plaintext key (shown once), its public prefix and its hash.
+func Generate() (key, prefix, hash string, err error) {
+	buf := make([]byte, 28)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", "", err
+	}
+	encoded := hex.EncodeToString(buf)
+	prefix = encoded[:8]
+	key = KeyPrefix + prefix + "_" + encoded[8:]
+	return key, prefix, Hash(key), nil
+}
+
+// Hash returns the SHA-256 digest stored for a key
+func Hash(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// IsAPIKey reports whether a credential looks like an API key
+func IsAPIKey(credential string) bool {
+	return strings.HasPrefix(credential, KeyPrefix)
+}
+
+// parsePrefix extracts the public prefix from a key
+func parsePrefix(key string) (string, bool) {
+	rest := strings.TrimPrefix(key, KeyPrefix)
+	prefix, secret, found := strings.Cut(rest, "_")
+	if !found || len(prefix) != 8 || secret == "" {
+		return "", false
+	}
+	return prefix, true
+}
+
+// MemoryStore keeps API keys in memory, indexed by prefix
+type MemoryStore struct {
+	keys map[uuid.UUID]*models.APIKey
+	mu   sync.RWMutex
+}
+
+// NewMemoryStore creates an empty key store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{keys: make(map[uuid.UUID]*models.APIKey)}
+}
+
+// Save creates or replaces a key
+func (s *MemoryStore) Save(key *models.APIKey) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.keys[key.ID] = key
+}
+
+// ListByUser returns copies of every key owned by a user
+func (s *MemoryStore) ListByUser(userID uuid.UUID) []models.APIKey {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	list := []models.APIKey{}
+	for _, key := range s.keys {
+		if key.UserID == userID {
+			list = append(list, *key)
+		}
+	}
+	return list
+}
+
+// Revoke marks a user's key as revoked. It returns false if the user has no such key.
+func (s *MemoryStore) Revoke(userID, keyID uuid.UUID) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key, ok := s.keys[keyID]
+	if !ok || key.UserID != userID {
+		return false
+	}
+	if key.RevokedAt == nil {
+		now := time.Now()
+		key.RevokedAt = &now
+	}
+	return true
+}
+
+// RevokeUser marks every key of a user as revoked
+func (s *MemoryStore) RevokeUser(userID uuid.UUID) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for _, key := range s.keys {
+		if key.UserID == userID && key.RevokedAt == nil {
+			key.RevokedAt = &now
+		}
+	}
+}
+
+// Authenticate resolves a plaintext key, records its use and returns a copy
+func (s *MemoryStore) Authenticate(plaintext string) (*models.APIKey, error) {
+	prefix, ok := parsePrefix(plaintext)
+	if !ok {
+		return nil, ErrInvalidKey
+	}
+	hash := Hash(plaintext)
+	now := time.Now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, key := range s.keys {
+		if key.Prefix != prefix {
+			continue
+		}
+		if subtle.ConstantTimeCompare([]byte(key.KeyHash), []byte(hash)) != 1 || !key.Usable(now) {
+			return nil, ErrInvalidKey
+		}
+		key.LastUsedAt = &now
+		copied := *key
+		return &copied, nil
+	}
+	return nil, ErrInvalidKey
+}
@@ -2,43 +2,91 @@ package utils
 
 import (
 	"errors"
-	"os"
 	"time"
 
 	"github.com/golang-jwt/jwt/v4"
 	"github.com/google/uuid"
 )
 
+// AccessTokenTTL is how long an access token is valid. Sessions are kept
+// alive with refresh tokens instead of long-lived access tokens.
+const AccessTokenTTL = 15 * time.Minute
+
+func init() {
+	// Keep token times to the millisecond so revoking every token of a user
+	// doesn't also revoke the ones issued in the rest of that second
+	jwt.TimePrecision = time.Millisecond
+}
+
 // JWTClaims represents the claims in a JWT token
 type JWTClaims struct {
 	UserID uuid.UUID `json:"user_id"`
 	Role   string    `json:"role"`
+	// Organization the token acts in
+	OrganizationID uuid.UUID `json:"org"`
+	// Set when the user must enroll in two-factor authentication before
+	// using the rest of the API
+	EnrollmentRequired bool `json:"enrollment_required,omitempty"`
+	// Set when an admin acts as the user
+	ImpersonatorID *uuid.UUID `json:"impersonator_id,omitempty"`
 	jwt.RegisteredClaims
 }
 
-// GenerateToken generates a new JWT token for a user
-func GenerateToken(userID uuid.UUID, role string) (string, error) {
-	// Get JWT secret from environment
-	jwtSecret := os.Getenv("JWT_SECRET")
-	if jwtSecret == "" {
-		jwtSecret = "your_jwt_secret_key" // Default for development
-	}
+// GenerateToken generates a new JWT token for a user working in an organization
+func GenerateToken(userID, orgID uuid.UUID, role string) (string, error) {
+	return generateToken(userID, orgID, role, false)
+}
+
+// GenerateEnrollmentToken generates a token that only allows enrolling in
+// two-factor authentication
+func GenerateEnrollmentToken(userID, orgID uuid.UUID, role string) (string, error) {
+	return generateToken(userID, orgID, role, true)
+}
 
-	// Create claims
-	claims := JWTClaims{
-		UserID: userID,
-		Role:   role,
+// GenerateImpersonationToken generates a token that lets an admin act as a
+// user until it expires. It returns the token and its ID.
+func GenerateImpersonationToken(userID, orgID uuid.UUID, role string, impersonatorID uuid.UUID, ttl time.Duration) (string, string, error) {
+	claims := newClaims(userID, orgID, role, ttl)
+	claims.ImpersonatorID = &impersonatorID
+
+	token, err := signClaims(claims)
+	return token, claims.ID, err
+}
+
+func generateToken(userID, orgID uuid.UUID, role string, enrollmentRequired bool) (string, error) {
+	claims := newClaims(userID, orgID, role, AccessTokenTTL)
+	claims.EnrollmentRequired = enrollmentRequired
+	return signClaims(claims)
+}
+
+// newClaims creates the claims of an access token valid for ttl
+func newClaims(userID, orgID uuid.UUID, role string, ttl time.Duration) JWTClaims {
+	return JWTClaims{
+		UserID:         userID,
+		Role:           role,
+		OrganizationID: orgID,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(24 * time.Hour)), // Token expires in 24 hours
+			ID:        uuid.NewString(), // jti, used to revoke the token
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(ttl)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 		},
 	}
+}
 
-	// Create token
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+// signClaims signs access token claims with the active signing key
+func signClaims(claims JWTClaims) (string, error) {
+	// Get the active signing key
+	key, err := currentSigningKey()
+	if err != nil {
+		return "", err
+	}
 
-	// Sign token with secret
-	tokenString, err := token.SignedString([]byte(jwtSecret))
+	// Create token, naming the key so verifiers can pick it during rotation
+	token := jwt.NewWithClaims(key.Method, claims)
+	token.Header["kid"] = key.ID
+
+	// Sign token with the private key
+	tokenString, err := token.SignedString(key.Private)
 	if err != nil {
 		return "", err
 	}
@@ -48,29 +96,37 @@ func GenerateToken(userID uuid.UUID, role string) (string, error) {
 
 // ValidateToken validates a JWT token and returns the claims
 func ValidateToken(tokenString string) (*JWTClaims, error) {
-	// Get JWT secret from environment
-	jwtSecret := os.Getenv("JWT_SECRET")
-	if jwtSecret == "" {
-		jwtSecret = "your_jwt_secret_key" // Default for development
-	}
-
 	// Parse token
-	token, err := jwt.ParseWithClaims(tokenString, &JWTClaims{}, func(token *jwt.Token) (interface{}, error) {
-		// Validate signing method
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, errors.New("unexpected signing method")
-		}
-		return []byte(jwtSecret), nil
-	})
-
+	token, err := jwt.ParseWithClaims(tokenString, &JWTClaims{}, keyFunc)
 	if err != nil {
 		return nil, err
 	}
 
-	// Validate token and extract claims
-	if claims, ok := token.Claims.(*JWTClaims); ok && token.Valid {
+	// Validate token and extract claims. Tokens signed for other purposes
+	// carry an audience and are never access tokens.
+	if claims, ok := token.Claims.(*JWTClaims); ok && token.Valid && len(claims.Audience) == 0 {
+		// Reject tokens that were revoked server-side
+		if IsTokenRevoked(claims) {
+			return nil, ErrTokenRevoked
+		}
 		return claims, nil
 	}
 
 	return nil, errors.New("invalid token")
 }
+
+// keyFunc finds the key a token was signed with
+func keyFunc(token *jwt.Token) (interface{}, error) {
+	// Find the verification key named by the kid header
+	kid, _ := token.Header["kid"].(string)
+	key, ok := verificationKey(kid)
+	if !ok {
+		return nil, errors.New("unknown signing key")
+	}
+
+	// Validate signing method against the key, never trusting alg alone
+	if token.Method.Alg() != key.Method.Alg() {
+		return nil, errors.New("unexpected signing method")
+	}
+	return key.Public, nil
+}
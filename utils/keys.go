@@ -0,0 +1,204 @@
+package utils
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"sync"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// SigningKey is an RSA or Ed25519 key used to sign or verify access tokens
+type SigningKey struct {
+	ID      string // kid header, derived from the public key
+	Method  jwt.SigningMethod
+	Private crypto.Signer // nil for verification-only keys
+	Public  crypto.PublicKey
+}
+
+var (
+	// Key used to sign new tokens
+	signingKey *SigningKey
+	// Every key tokens are accepted from, by kid. Includes the signing key.
+	verificationKeys = make(map[string]*SigningKey)
+	keysMu           sync.RWMutex
+)
+
+// ConfigureSigningKeys loads the active signing key and any older keys that
+// are still accepted for verification. Older keys may be given as public or
+// private PEM files, so a retired signing key can be kept as is until every
+// token it signed has expired.
+func ConfigureSigningKeys(privateKeyFile string, verificationKeyFiles []string) error {
+	signer, err := loadKeyFile(privateKeyFile)
+	if err != nil {
+		return err
+	}
+	if signer.Private == nil {
+		return fmt.Errorf("%s: signing key must be a private key", privateKeyFile)
+	}
+
+	keys := map[string]*SigningKey{signer.ID: signer}
+	for _, file := range verificationKeyFiles {
+		key, err := loadKeyFile(file)
+		if err != nil {
+			return err
+		}
+		key.Private = nil
+		keys[key.ID] = key
+	}
+
+	keysMu.Lock()
+	defer keysMu.Unlock()
+
+	signingKey = signer
+	verificationKeys = keys
+	return nil
+}
+
+// UseEphemeralSigningKey signs tokens with a freshly generated Ed25519 key.
+// Tokens do not survive a restart, so this is only meant for development.
+func UseEphemeralSigningKey() error {
+	_, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return err
+	}
+	key, err := newSigningKey(private)
+	if err != nil {
+		return err
+	}
+
+	keysMu.Lock()
+	defer keysMu.Unlock()
+
+	signingKey = key
+	verificationKeys = map[string]*SigningKey{key.ID: key}
+	return nil
+}
+
+// currentSigningKey returns the active signing key, generating an ephemeral
+// one if none was configured
+func currentSigningKey() (*SigningKey, error) {
+	keysMu.RLock()
+	key := signingKey
+	keysMu.RUnlock()
+
+	if key != nil {
+		return key, nil
+	}
+	if err := UseEphemeralSigningKey(); err != nil {
+		return nil, err
+	}
+	return currentSigningKey()
+}
+
+// verificationKey finds the key a token was signed with
+func verificationKey(kid string) (*SigningKey, bool) {
+	keysMu.RLock()
+	defer keysMu.RUnlock()
+
+	key, ok := verificationKeys[kid]
+	return key, ok
+}
+
+// JWKS returns the public verification keys as a JSON Web Key Set
+func JWKS() map[string]interface{} {
+	keysMu.RLock()
+	defer keysMu.RUnlock()
+
+	keys := []map[string]string{}
+	for _, key := range verificationKeys {
+		jwk := map[string]string{
+			"kid": key.ID,
+			"use": "sig",
+			"alg": key.Method.Alg(),
+		}
+		switch public := key.Public.(type) {
+		case *rsa.PublicKey:
+			jwk["kty"] = "RSA"
+			jwk["n"] = base64.RawURLEncoding.EncodeToString(public.N.Bytes())
+			jwk["e"] = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk["kty"] = "OKP"
+			jwk["crv"] = "Ed25519"
+			jwk["x"] = base64.RawURLEncoding.EncodeToString(public)
+		}
+		keys = append(keys, jwk)
+	}
+
+	return map[string]interface{}{"keys": keys}
+}
+
+// loadKeyFile reads a PEM encoded RSA or Ed25519 key, private or public
+func loadKeyFile(path string) (*SigningKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%s: no PEM data found", path)
+	}
+
+	var parsed interface{}
+	switch block.Type {
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		parsed, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("%s: unsupported PEM block %q", path, block.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	key, err := newSigningKey(parsed)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return key, nil
+}
+
+// newSigningKey wraps a parsed key and derives its kid
+func newSigningKey(parsed interface{}) (*SigningKey, error) {
+	key := &SigningKey{}
+	switch k := parsed.(type) {
+	case *rsa.PrivateKey:
+		key.Method, key.Private, key.Public = jwt.SigningMethodRS256, k, &k.PublicKey
+	case *rsa.PublicKey:
+		key.Method, key.Public = jwt.SigningMethodRS256, k
+	case ed25519.PrivateKey:
+		key.Method, key.Private, key.Public = jwt.SigningMethodEdDSA, k, k.Public()
+	case ed25519.PublicKey:
+		key.Method, key.Public = jwt.SigningMethodEdDSA, k
+	default:
+		return nil, errors.New("only RSA and Ed25519 keys are supported")
+	}
+
+	if rsaKey, ok := key.Public.(*rsa.PublicKey); ok && rsaKey.N.BitLen() < 2048 {
+		return nil, errors.New("RSA keys must be at least 2048 bits")
+	}
+
+	der, err := x509.MarshalPKIXPublicKey(key.Public)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(der)
+	key.ID = base64.RawURLEncoding.EncodeToString(sum[:12])
+
+	return key, nil
+}
@@ -0,0 +1,144 @@
+package password
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"embed"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+//go:embed breached.txt
+var breachedFS embed.FS
+
+// bcrypt ignores everything after 72 bytes
+const maxBytes = 72
+
+var sha1Line = regexp.MustCompile(`^[0-9A-Fa-f]{40}(:\d+)?$`)
+
+// Policy describes the rules new passwords must follow
+type Policy struct {
+	MinLength      int  `json:"min_length"`
+	RequireUpper   bool `json:"require_upper"`
+	RequireLower   bool `json:"require_lower"`
+	RequireDigit   bool `json:"require_digit"`
+	RequireSymbol  bool `json:"require_symbol"`
+	RejectBreached bool `json:"reject_breached"`
+
+	breached map[string]bool // Uppercase SHA-1 hex of breached passwords
+	mu       sync.RWMutex
+}
+
+// NewPolicy creates a policy that knows the built-in breached password list
+func NewPolicy(minLength int) *Policy {
+	p := &Policy{MinLength: minLength, RejectBreached: true, breached: make(map[string]bool)}
+	if f, err := breachedFS.Open("breached.txt"); err == nil {
+		defer f.Close()
+		p.loadBreached(f)
+	}
+	return p
+}
+
+// LoadBreachedFile adds passwords from a local file. Lines are either plain
+// passwords or SHA-1 hashes, optionally followed by ":count" as in the
+// Have I Been Pwned downloads.
+func (p *Policy) LoadBreachedFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return p.loadBreached(f)
+}
+
+func (p *Policy) loadBreached(r io.Reader) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if sha1Line.MatchString(line) {
+			p.breached[strings.ToUpper(line[:40])] = true
+			continue
+		}
+		p.breached[hash(strings.ToLower(line))] = true
+	}
+	return scanner.Err()
+}
+
+// IsBreached reports whether a password appears on the breached list
+func (p *Policy) IsBreached(password string) bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	return p.breached[hash(password)] || p.breached[hash(strings.ToLower(password))]
+}
+
+// Validate returns every rule the password breaks, or nil if it is acceptable.
+// Personal values such as the username or email must not appear in it.
+func (p *Policy) Validate(password string, personal ...string) []string {
+	var problems []string
+
+	if len([]rune(password)) < p.MinLength {
+		problems = append(problems, fmt.Sprintf("must be at least %d characters", p.MinLength))
+	}
+	if len(password) > maxBytes {
+		problems = append(problems, fmt.Sprintf("must be at most %d bytes", maxBytes))
+	}
+
+	var upper, lower, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsDigit(r):
+			digit = true
+		default:
+			symbol = true
+		}
+	}
+	if p.RequireUpper && !upper {
+		problems = append(problems, "must contain an uppercase letter")
+	}
+	if p.RequireLower && !lower {
+		problems = append(problems, "must contain a lowercase letter")
+	}
+	if p.RequireDigit && !digit {
+		problems = append(problems, "must contain a digit")
+	}
+	if p.RequireSymbol && !symbol {
+		problems = append(problems, "must contain a symbol")
+	}
+
+	lowered := strings.ToLower(password)
+	for _, value := range personal {
+		value, _, _ = strings.Cut(strings.ToLower(value), "@")
+		if len(value) >= 3 && strings.Contains(lowered, value) {
+			problems = append(problems, "must not contain your username or email")
+			break
+		}
+	}
+
+	if p.RejectBreached && p.IsBreached(password) {
+		problems = append(problems, "appears in a list of breached passwords")
+	}
+
+	return problems
+}
+
+func hash(value string) string {
+	sum := sha1.Sum([]byte(value))
+	return strings.ToUpper(hex.EncodeToString(sum[:]))
+}
@@ -0,0 +1,159 @@
+package ratelimit
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Quota allows Requests per Period on average, in bursts of up to Requests
+type Quota struct {
+	Requests int
+	Period   time.Duration
+}
+
+// BucketResult is the outcome of taking a token
+type BucketResult struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	Reset      time.Duration // Until the bucket is full again
+	RetryAfter time.Duration // Until the next token, when not allowed
+}
+
+// BucketUsage describes one bucket for monitoring
+type BucketUsage struct {
+	Key           string    `json:"key"`
+	Limit         int       `json:"limit"`
+	PeriodSeconds int       `json:"period_seconds"`
+	Remaining     int       `json:"remaining"`
+	Allowed       int64     `json:"allowed"`  // Requests let through since the bucket was created
+	Rejected      int64     `json:"rejected"` // Requests refused since the bucket was created
+	LastSeen      time.Time `json:"last_seen"`
+}
+
+type bucket struct {
+	quota    Quota
+	tokens   float64
+	updated  time.Time
+	lastSeen time.Time
+	allowed  int64
+	rejected int64
+}
+
+// refill adds the tokens earned since the last update
+func (b *bucket) refill(now time.Time) {
+	rate := float64(b.quota.Requests) / b.quota.Period.Seconds()
+	b.tokens += now.Sub(b.updated).Seconds() * rate
+	if b.tokens > float64(b.quota.Requests) {
+		b.tokens = float64(b.quota.Requests)
+	}
+	b.updated = now
+}
+
+// wait returns how long until the bucket holds the given number of tokens
+func (b *bucket) wait(tokens float64) time.Duration {
+	if b.tokens >= tokens {
+		return 0
+	}
+	rate := float64(b.quota.Requests) / b.quota.Period.Seconds()
+	return time.Duration((tokens - b.tokens) / rate * float64(time.Second))
+}
+
+// Buckets keeps a token bucket per key in memory
+type Buckets struct {
+	buckets map[string]*bucket
+	mu      sync.Mutex
+}
+
+// DefaultBuckets holds the buckets used by the API rate limit middleware
+var DefaultBuckets = NewBuckets()
+
+// NewBuckets creates an empty bucket set and starts its janitor
+func NewBuckets() *Buckets {
+	b := &Buckets{buckets: make(map[string]*bucket)}
+
+	// Start the janitor to drop buckets that have refilled
+	go b.janitor()
+
+	return b
+}
+
+// Take removes a token from the bucket for key if one is available
+func (b *Buckets) Take(key string, quota Quota) BucketResult {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	bkt, ok := b.buckets[key]
+	if !ok || bkt.quota != quota {
+		bkt = &bucket{quota: quota, tokens: float64(quota.Requests), updated: now}
+		b.buckets[key] = bkt
+	}
+	bkt.refill(now)
+	bkt.lastSeen = now
+
+	res := BucketResult{Limit: quota.Requests}
+	if bkt.tokens >= 1 {
+		bkt.tokens--
+		bkt.allowed++
+		res.Allowed = true
+	} else {
+		bkt.rejected++
+		res.RetryAfter = bkt.wait(1)
+	}
+	res.Remaining = int(bkt.tokens)
+	res.Reset = bkt.wait(float64(quota.Requests))
+
+	return res
+}
+
+// Usage lists every active bucket, busiest first
+func (b *Buckets) Usage() []BucketUsage {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	usage := make([]BucketUsage, 0, len(b.buckets))
+	for key, bkt := range b.buckets {
+		bkt.refill(now)
+		usage = append(usage, BucketUsage{
+			Key:           key,
+			Limit:         bkt.quota.Requests,
+			PeriodSeconds: int(bkt.quota.Period.Seconds()),
+			Remaining:     int(bkt.tokens),
+			Allowed:       bkt.allowed,
+			Rejected:      bkt.rejected,
+			LastSeen:      bkt.lastSeen,
+		})
+	}
+
+	sort.Slice(usage, func(i, j int) bool {
+		return usage[i].Allowed+usage[i].Rejected > usage[j].Allowed+usage[j].Rejected
+	})
+	return usage
+}
+
+func (b *Buckets) janitor() {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	for {
+		<-ticker.C
+		b.deleteFull()
+	}
+}
+
+// deleteFull drops buckets that have refilled, they are the same as new ones
+func (b *Buckets) deleteFull() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	for key, bkt := range b.buckets {
+		bkt.refill(now)
+		if bkt.tokens >= float64(bkt.quota.Requests) {
+			delete(b.buckets, key)
+		}
+	}
+}
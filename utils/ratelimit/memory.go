@@ -0,0 +1,107 @@
+package ratelimit
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// MemoryLimiter keeps event timestamps in memory. Counts are per process, so
+// use the Postgres backend when running several replicas.
+type MemoryLimiter struct {
+	events    map[string][]time.Time // Sorted oldest first
+	maxWindow time.Duration          // Longest window asked for, older events are dropped
+	mu        sync.Mutex
+}
+
+// NewMemoryLimiter creates an empty limiter and starts its janitor
+func NewMemoryLimiter() *MemoryLimiter {
+	limiter := &MemoryLimiter{events: make(map[string][]time.Time)}
+
+	// Start the janitor to drop events outside every window
+	go limiter.janitor()
+
+	return limiter
+}
+
+// usage summarizes the events of key after the start of the window. The
+// caller must hold the lock.
+func (m *MemoryLimiter) usage(key string, window time.Duration, now time.Time) Usage {
+	if window > m.maxWindow {
+		m.maxWindow = window
+	}
+
+	events := m.events[key]
+	start := now.Add(-window)
+	i := sort.Search(len(events), func(i int) bool { return events[i].After(start) })
+	if i == len(events) {
+		return Usage{}
+	}
+	return Usage{Count: len(events) - i, Oldest: events[i], Latest: events[len(events)-1]}
+}
+
+// Allow records an event for key if the rule permits it
+func (m *MemoryLimiter) Allow(ctx context.Context, key string, rule Rule) (Result, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	res := result(rule, m.usage(key, rule.Window, now), now)
+	if res.Allowed {
+		m.events[key] = append(m.events[key], now)
+	}
+	return res, nil
+}
+
+// Record records an event for key and returns the usage including it
+func (m *MemoryLimiter) Record(ctx context.Context, key string, window time.Duration) (Usage, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	m.events[key] = append(m.events[key], now)
+	return m.usage(key, window, now), nil
+}
+
+// Usage returns the events recorded for key within the window
+func (m *MemoryLimiter) Usage(ctx context.Context, key string, window time.Duration) (Usage, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.usage(key, window, time.Now()), nil
+}
+
+// Reset forgets every event recorded for key
+func (m *MemoryLimiter) Reset(ctx context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.events, key)
+	return nil
+}
+
+func (m *MemoryLimiter) janitor() {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	for {
+		<-ticker.C
+		m.deleteExpired()
+	}
+}
+
+func (m *MemoryLimiter) deleteExpired() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	start := time.Now().Add(-m.maxWindow)
+	for key, events := range m.events {
+		i := sort.Search(len(events), func(i int) bool { return events[i].After(start) })
+		if i == len(events) {
+			delete(m.events, key)
+		} else if i > 0 {
+			m.events[key] = append([]time.Time(nil), events[i:]...)
+		}
+	}
+}
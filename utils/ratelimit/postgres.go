@@ -0,0 +1,145 @@
+package ratelimit
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"sync"
+	"time"
+)
+
+// queryer is satisfied by both *sql.DB and *sql.Tx
+type queryer interface {
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// PostgresLimiter keeps events in the rate_limit_events table so every
+// replica sees the same counts. Checks for a key are serialized with a
+// transaction-scoped advisory lock.
+type PostgresLimiter struct {
+	db        *sql.DB
+	maxWindow time.Duration // Longest window asked for, older events are deleted
+	mu        sync.Mutex
+}
+
+// NewPostgresLimiter creates a limiter on the given database and starts its janitor
+func NewPostgresLimiter(db *sql.DB) *PostgresLimiter {
+	limiter := &PostgresLimiter{db: db}
+
+	// Start the janitor to delete events outside every window
+	go limiter.janitor()
+
+	return limiter
+}
+
+func (p *PostgresLimiter) seen(window time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if window > p.maxWindow {
+		p.maxWindow = window
+	}
+}
+
+// usage summarizes the events of key after the start of the window
+func (p *PostgresLimiter) usage(ctx context.Context, q queryer, key string, window time.Duration, now time.Time) (Usage, error) {
+	p.seen(window)
+
+	var usage Usage
+	var oldest, latest sql.NullTime
+	err := q.QueryRowContext(ctx, `
+		SELECT COUNT(*), MIN(occurred_at), MAX(occurred_at)
+		FROM rate_limit_events
+		WHERE key = $1 AND occurred_at > $2`,
+		key, now.Add(-window),
+	).Scan(&usage.Count, &oldest, &latest)
+	if err != nil {
+		return Usage{}, err
+	}
+	usage.Oldest, usage.Latest = oldest.Time, latest.Time
+	return usage, nil
+}
+
+// locked runs fn in a transaction holding the advisory lock for key
+func (p *PostgresLimiter) locked(ctx context.Context, key string, fn func(tx *sql.Tx) error) error {
+	tx, err := p.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock(hashtext($1))`, key); err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func insertEvent(ctx context.Context, tx *sql.Tx, key string, now time.Time) error {
+	_, err := tx.ExecContext(ctx, `INSERT INTO rate_limit_events (key, occurred_at) VALUES ($1, $2)`, key, now)
+	return err
+}
+
+// Allow records an event for key if the rule permits it
+func (p *PostgresLimiter) Allow(ctx context.Context, key string, rule Rule) (Result, error) {
+	var res Result
+	err := p.locked(ctx, key, func(tx *sql.Tx) error {
+		now := time.Now()
+		usage, err := p.usage(ctx, tx, key, rule.Window, now)
+		if err != nil {
+			return err
+		}
+		if res = result(rule, usage, now); !res.Allowed {
+			return nil
+		}
+		return insertEvent(ctx, tx, key, now)
+	})
+	return res, err
+}
+
+// Record records an event for key and returns the usage including it
+func (p *PostgresLimiter) Record(ctx context.Context, key string, window time.Duration) (Usage, error) {
+	var usage Usage
+	err := p.locked(ctx, key, func(tx *sql.Tx) error {
+		now := time.Now()
+		if err := insertEvent(ctx, tx, key, now); err != nil {
+			return err
+		}
+		var err error
+		usage, err = p.usage(ctx, tx, key, window, now)
+		return err
+	})
+	return usage, err
+}
+
+// Usage returns the events recorded for key within the window
+func (p *PostgresLimiter) Usage(ctx context.Context, key string, window time.Duration) (Usage, error) {
+	return p.usage(ctx, p.db, key, window, time.Now())
+}
+
+// Reset forgets every event recorded for key
+func (p *PostgresLimiter) Reset(ctx context.Context, key string) error {
+	_, err := p.db.ExecContext(ctx, `DELETE FROM rate_limit_events WHERE key = $1`, key)
+	return err
+}
+
+func (p *PostgresLimiter) janitor() {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	for {
+		<-ticker.C
+		p.mu.Lock()
+		maxWindow := p.maxWindow
+		p.mu.Unlock()
+
+		if maxWindow == 0 {
+			continue
+		}
+		if _, err := p.db.Exec(`DELETE FROM rate_limit_events WHERE occurred_at < $1`, time.Now().Add(-maxWindow)); err != nil {
+			log.Printf("Failed to delete old rate limit events: %v", err)
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package ratelimit
+
+import (
+	"context"
+	"strings"
+	"time"
+)
+
+// Rule allows at most Limit events per key in any sliding Window
+type Rule struct {
+	Limit  int
+	Window time.Duration
+}
+
+// Result is the outcome of checking a rule
+type Result struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	RetryAfter time.Duration // Until the next event is allowed, when not allowed
+}
+
+// Usage describes the events recorded for a key within a window
+type Usage struct {
+	Count  int
+	Oldest time.Time
+	Latest time.Time
+}
+
+// Limiter counts events per key in sliding windows. Implementations must be
+// safe for concurrent use; the Postgres backend also shares counts between
+// replicas.
+type Limiter interface {
+	// Allow records an event for key if the rule permits it
+	Allow(ctx context.Context, key string, rule Rule) (Result, error)
+	// Record records an event for key unconditionally and returns the usage including it
+	Record(ctx context.Context, key string, window time.Duration) (Usage, error)
+	// Usage returns the events recorded for key within the window
+	Usage(ctx context.Context, key string, window time.Duration) (Usage, error)
+	// Reset forgets every event recorded for key
+	Reset(ctx context.Context, key string) error
+}
+
+// result builds the result of a rule from the usage before the current event
+func result(rule Rule, usage Usage, now time.Time) Result {
+	if usage.Count >= rule.Limit {
+		retryAfter := usage.Oldest.Add(rule.Window).Sub(now)
+		if retryAfter < time.Second {
+			retryAfter = time.Second
+		}
+		return Result{Limit: rule.Limit, RetryAfter: retryAfter}
+	}
+	return Result{Allowed: true, Limit: rule.Limit, Remaining: rule.Limit - usage.Count - 1}
+}
+
+// Lockout locks an account after repeated failures. Every failure past the
+// threshold doubles the lock, up to MaxDelay. Accounts are keyed by the
+// submitted identifier, so unknown accounts lock exactly like real ones.
+type Lockout struct {
+	Limiter   Limiter
+	Threshold int           // Failures before the first lock
+	BaseDelay time.Duration // Length of the first lock
+	MaxDelay  time.Duration // Longest lock
+	Window    time.Duration // Failures older than this are forgotten
+}
+
+func (l *Lockout) key(account string) string {
+	return "lockout:" + strings.ToLower(strings.TrimSpace(account))
+}
+
+// Delay returns how long an account is locked after the given number of failures
+func (l *Lockout) Delay(failures int) time.Duration {
+	if failures < l.Threshold {
+		return 0
+	}
+	steps := failures - l.Threshold
+	if steps > 30 {
+		return l.MaxDelay
+	}
+	delay := l.BaseDelay << steps
+	if delay > l.MaxDelay {
+		return l.MaxDelay
+	}
+	return delay
+}
+
+// lockedFor returns how much of the lock following the latest failure is left
+func (l *Lockout) lockedFor(usage Usage) time.Duration {
+	remaining := time.Until(usage.Latest.Add(l.Delay(usage.Count)))
+	if remaining <= 0 {
+		return 0
+	}
+	return remaining
+}
+
+// LockedFor returns how long the account stays locked, or zero
+func (l *Lockout) LockedFor(ctx context.Context, account string) (time.Duration, error) {
+	usage, err := l.Limiter.Usage(ctx, l.key(account), l.Window)
+	if err != nil {
+		return 0, err
+	}
+	return l.lockedFor(usage), nil
+}
+
+// Fail records a failed attempt and returns how long the account is now locked
+func (l *Lockout) Fail(ctx context.Context, account string) (time.Duration, error) {
+	usage, err := l.Limiter.Record(ctx, l.key(account), l.Window)
+	if err != nil {
+		return 0, err
+	}
+	return l.lockedFor(usage), nil
+}
+
+// Reset clears the failures of an account, after a successful login or by an admin
+func (l *Lockout) Reset(ctx context.Context, account string) error {
+	return l.Limiter.Reset(ctx, l.key(account))
+}
@@ -0,0 +1,161 @@
+package refreshtoken
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/projectflow/models"
+)
+
+// TTL is how long a refresh token stays valid if it is not rotated
+const TTL = 30 * 24 * time.Hour
+
+var (
+	// ErrInvalidToken is returned for unknown, expired or revoked tokens
+	ErrInvalidToken = errors.New("invalid refresh token")
+	// ErrTokenReused is returned when an already rotated token is presented again.
+	// The whole token family is revoked when this happens.
+	ErrTokenReused = errors.New("refresh token reuse detected")
+)
+
+// DefaultStore holds the refresh tokens used by the auth handlers
+var DefaultStore = NewMemoryStore()
+
+// Hash returns the SHA-256 digest stored for a token
+func Hash(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func generate() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// MemoryStore keeps refresh tokens in memory, indexed by hash
+type MemoryStore struct {
+	tokens map[string]*models.RefreshToken
+	mu     sync.Mutex
+}
+
+// NewMemoryStore creates an empty token store and starts its janitor
+func NewMemoryStore() *MemoryStore {
+	store := &MemoryStore{tokens: make(map[string]*models.RefreshToken)}
+
+	// Start the janitor to drop expired tokens
+	go store.janitor()
+
+	return store
+}
+
+// Issue starts a new token family for a user and returns the plaintext token
+func (s *MemoryStore) Issue(userID uuid.UUID) (string, *models.RefreshToken, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.issue(userID, uuid.New())
+}
+
+func (s *MemoryStore) issue(userID, familyID uuid.UUID) (string, *models.RefreshToken, error) {
+	plaintext, err := generate()
+	if err != nil {
+		return "", nil, err
+	}
+
+	now := time.Now()
+	token := &models.RefreshToken{
+		ID:        uuid.New(),
+		UserID:    userID,
+		FamilyID:  familyID,
+		TokenHash: Hash(plaintext),
+		ExpiresAt: now.Add(TTL),
+		CreatedAt: now,
+	}
+	s.tokens[token.TokenHash] = token
+
+	copied := *token
+	return plaintext, &copied, nil
+}
+
+// Rotate exchanges a token for the next one in its family. Presenting a token
+// that was already rotated revokes the family and returns ErrTokenReused.
+func (s *MemoryStore) Rotate(plaintext string) (string, *models.RefreshToken, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	token, ok := s.tokens[Hash(plaintext)]
+	if !ok || token.RevokedAt != nil {
+		return "", nil, ErrInvalidToken
+	}
+	now := time.Now()
+	if token.RotatedAt != nil {
+		s.revokeFamily(token.FamilyID, now)
+		return "", nil, ErrTokenReused
+	}
+	if now.After(token.ExpiresAt) {
+		return "", nil, ErrInvalidToken
+	}
+
+	token.RotatedAt = &now
+	return s.issue(token.UserID, token.FamilyID)
+}
+
+// Revoke ends the family a user's token belongs to. It returns false if the
+// user has no such token.
+func (s *MemoryStore) Revoke(userID uuid.UUID, plaintext string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	token, ok := s.tokens[Hash(plaintext)]
+	if !ok || token.UserID != userID {
+		return false
+	}
+	s.revokeFamily(token.FamilyID, time.Now())
+	return true
+}
+
+// RevokeUser ends every token family belonging to a user
+func (s *MemoryStore) RevokeUser(userID uuid.UUID) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for _, token := range s.tokens {
+		if token.UserID == userID && token.RevokedAt == nil {
+			token.RevokedAt = &now
+		}
+	}
+}
+
+func (s *MemoryStore) revokeFamily(familyID uuid.UUID, now time.Time) {
+	for _, token := range s.tokens {
+		if token.FamilyID == familyID && token.RevokedAt == nil {
+			token.RevokedAt = &now
+		}
+	}
+}
+
+func (s *MemoryStore) janitor() {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		now := time.Now()
+		s.mu.Lock()
+		for hash, token := range s.tokens {
+			if now.After(token.ExpiresAt) {
+				delete(s.tokens, hash)
+			}
+		}
+		s.mu.Unlock()
+	}
+}
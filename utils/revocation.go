@@ -0,0 +1,65 @@
+package utils
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/google/uuid"
+	"github.com/projectflow/utils/cache"
+)
+
+// ErrTokenRevoked is returned by ValidateToken for revoked tokens
+var ErrTokenRevoked = errors.New("token has been revoked")
+
+var (
+	// Revoked token IDs (jti), kept until the token would have expired anyway
+	revokedTokens = cache.New()
+
+	// Tokens issued to a user before this time are revoked ("log out everywhere")
+	revokedBefore   = make(map[uuid.UUID]time.Time)
+	revokedBeforeMu sync.RWMutex
+)
+
+// RevokeToken adds a token ID to the revocation list until expiresAt
+func RevokeToken(tokenID string, expiresAt time.Time) {
+	if tokenID == "" {
+		return
+	}
+	if ttl := time.Until(expiresAt); ttl > 0 {
+		revokedTokens.Set(tokenID, true, ttl)
+	}
+}
+
+// RevokeAllTokens revokes every access token issued to a user so far. It
+// returns once tokens issued from then on, like the new session of the
+// request that revoked them, are valid again.
+func RevokeAllTokens(userID uuid.UUID) {
+	cutoff := time.Now()
+
+	revokedBeforeMu.Lock()
+	revokedBefore[userID] = cutoff
+	revokedBeforeMu.Unlock()
+
+	// Issue times are kept to the millisecond and may come back from the
+	// token a millisecond early, so wait until a new token's can't be
+	// mistaken for the cutoff
+	time.Sleep(time.Until(cutoff.Truncate(jwt.TimePrecision).Add(2 * jwt.TimePrecision)))
+}
+
+// IsTokenRevoked reports whether a token's jti or issue time has been revoked
+func IsTokenRevoked(claims *JWTClaims) bool {
+	if claims.ID == "" {
+		return true
+	}
+	if _, found := revokedTokens.Get(claims.ID); found {
+		return true
+	}
+
+	revokedBeforeMu.RLock()
+	cutoff, ok := revokedBefore[claims.UserID]
+	revokedBeforeMu.RUnlock()
+
+	return ok && (claims.IssuedAt == nil || !claims.IssuedAt.Time.After(cutoff))
+}
@@ -0,0 +1,82 @@
+// Package safehttp sends requests to URLs that users configured, such as
+// webhook endpoints, without letting them reach the server's own network:
+// loopback, link-local (including the cloud metadata service at
+// 169.254.169.254), private and other non-public addresses are refused.
+package safehttp
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// ErrForbiddenAddress is returned for hosts that resolve to a non-public address
+var ErrForbiddenAddress = errors.New("safehttp: address is not public")
+
+// ErrInvalidURL is returned for URLs that are not http or https with a host
+var ErrInvalidURL = errors.New("safehttp: URL must be http or https with a host")
+
+// Shared address space for carrier-grade NAT, not covered by net.IP.IsPrivate
+var sharedAddressSpace = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+// Forbidden reports whether ip is an address user-configured URLs may not reach
+func Forbidden(ip net.IP) bool {
+	return ip.IsLoopback() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || ip.IsPrivate() ||
+		ip.IsUnspecified() || sharedAddressSpace.Contains(ip)
+}
+
+// CheckURL checks that rawURL is an http or https URL whose host resolves
+// to public addresses only. It returns ErrInvalidURL, ErrForbiddenAddress or
+// the error of the lookup.
+func CheckURL(ctx context.Context, rawURL string) error {
+	parsed, err := url.Parse(rawURL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Hostname() == "" {
+		return ErrInvalidURL
+	}
+
+	host := strings.ToLower(strings.TrimSuffix(parsed.Hostname(), "."))
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return ErrForbiddenAddress
+	}
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return err
+	}
+	for _, addr := range addrs {
+		if Forbidden(addr.IP) {
+			return ErrForbiddenAddress
+		}
+	}
+	return nil
+}
+
+// NewClient returns an HTTP client that refuses to connect to forbidden
+// addresses. The check runs on the resolved address of every connection,
+// redirects included, so a host that passed CheckURL can't later resolve
+// to an internal address. Proxies from the environment are not used, since
+// they would connect on the client's behalf.
+func NewClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{Timeout: timeout, Control: refuseForbidden}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return &http.Client{Timeout: timeout, Transport: transport}
+}
+
+// refuseForbidden stops dialing a forbidden address
+func refuseForbidden(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	if ip := net.ParseIP(host); ip == nil || Forbidden(ip) {
+		return ErrForbiddenAddress
+	}
+	return nil
+}
@@ -0,0 +1,264 @@
+// Package validate checks request structs against their `validate` struct
+// tags. Rules are separated by commas and applied in order:
+//
+//	required          the field must not be empty (blank strings count as empty)
+//	omitempty         skip the remaining rules when the field is empty
+//	min=N, max=N      bounds for numbers, length bounds for strings and lists
+//	len=N             exact length of a string or list
+//	oneof=a b c       enumerations of strings or numbers
+//	email, url, uuid  string formats
+//	date              a YYYY-MM-DD date
+//	gtfield=Field     greater than another field, e.g. an end date after a start date
+//	gtefield=Field    greater than or equal to another field
+//
+// Errors name fields by their JSON name so clients can map them to inputs.
+package validate
+
+import (
+	"fmt"
+	"net/mail"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+)
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// FieldError describes one rule a field broke
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Param   string `json:"param,omitempty"`
+	Message string `json:"message"`
+}
+
+func (e FieldError) Error() string {
+	return e.Message
+}
+
+// Errors lists every broken rule of a struct
+type Errors []FieldError
+
+func (e Errors) Error() string {
+	messages := make([]string, len(e))
+	for i, fieldErr := range e {
+		messages[i] = fieldErr.Message
+	}
+	return strings.Join(messages, ", ")
+}
+
+// Struct validates a struct, or a pointer to one, and returns the broken
+// rules, or nil if there are none. Each field reports only its first broken
+// rule. Unknown rules panic, since tags are fixed at compile time.
+func Struct(s interface{}) Errors {
+	v := reflect.ValueOf(s)
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return nil
+	}
+
+	var errs Errors
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("validate")
+		if tag == "" || tag == "-" || !field.IsExported() {
+			continue
+		}
+		if fieldErr := checkField(v, field, tag); fieldErr != nil {
+			errs = append(errs, *fieldErr)
+		}
+	}
+	return errs
+}
+
+// checkField applies the rules of one field and returns the first broken one
+func checkField(parent reflect.Value, field reflect.StructField, tag string) *FieldError {
+	name := jsonName(field)
+	value := parent.FieldByIndex(field.Index)
+
+	// Pointers are optional values: nil is empty, anything else is checked
+	present := true
+	for value.Kind() == reflect.Ptr {
+		if value.IsNil() {
+			present = false
+			break
+		}
+		value = value.Elem()
+	}
+	empty := !present || isEmpty(value)
+
+	for _, rule := range strings.Split(tag, ",") {
+		rule, param, _ := strings.Cut(rule, "=")
+		switch rule {
+		case "required":
+			if empty {
+				return fail(name, rule, "", "%s is required", name)
+			}
+			continue
+		case "omitempty":
+			if empty {
+				return nil
+			}
+			continue
+		}
+		if !present {
+			return nil
+		}
+		if fieldErr := checkRule(parent, value, name, rule, param); fieldErr != nil {
+			return fieldErr
+		}
+	}
+	return nil
+}
+
+// checkRule applies one rule other than required and omitempty
+func checkRule(parent, value reflect.Value, name, rule, param string) *FieldError {
+	switch rule {
+	case "min", "max", "len":
+		limit, err := strconv.ParseFloat(param, 64)
+		if err != nil {
+			panic("validate: " + rule + " needs a number, got " + param)
+		}
+		size, unit := measure(value)
+		if limit == 1 {
+			unit = strings.TrimSuffix(unit, "s")
+		}
+		switch {
+		case rule == "min" && size < limit:
+			return fail(name, rule, param, "%s must be at least %s%s", name, param, unit)
+		case rule == "max" && size > limit:
+			return fail(name, rule, param, "%s must be at most %s%s", name, param, unit)
+		case rule == "len" && size != limit:
+			return fail(name, rule, param, "%s must be exactly %s%s", name, param, unit)
+		}
+	case "oneof":
+		options := strings.Fields(param)
+		actual := fmt.Sprint(value.Interface())
+		for _, option := range options {
+			if actual == option {
+				return nil
+			}
+		}
+		return fail(name, rule, param, "%s must be one of %s", name, strings.Join(options, ", "))
+	case "email":
+		address, err := mail.ParseAddress(value.String())
+		if err != nil || address.Address != value.String() {
+			return fail(name, rule, "", "%s must be a valid email address", name)
+		}
+	case "url":
+		u, err := url.Parse(value.String())
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fail(name, rule, "", "%s must be an http or https URL", name)
+		}
+	case "uuid":
+		if _, err := uuid.Parse(value.String()); err != nil {
+			return fail(name, rule, "", "%s must be a UUID", name)
+		}
+	case "date":
+		if _, err := time.Parse("2006-01-02", value.String()); err != nil {
+			return fail(name, rule, "", "%s must be a date in YYYY-MM-DD format", name)
+		}
+	case "gtfield", "gtefield":
+		other, ok := parent.Type().FieldByName(param)
+		if !ok {
+			panic("validate: " + rule + " names unknown field " + param)
+		}
+		otherValue := parent.FieldByIndex(other.Index)
+		for otherValue.Kind() == reflect.Ptr {
+			if otherValue.IsNil() {
+				return nil
+			}
+			otherValue = otherValue.Elem()
+		}
+		if isEmpty(otherValue) {
+			// Whether the other field may be empty is its own rule
+			return nil
+		}
+		cmp := compare(value, otherValue)
+		otherName := jsonName(other)
+		if rule == "gtfield" && cmp <= 0 {
+			return fail(name, rule, otherName, "%s must be after %s", name, otherName)
+		}
+		if rule == "gtefield" && cmp < 0 {
+			return fail(name, rule, otherName, "%s must not be before %s", name, otherName)
+		}
+	default:
+		panic("validate: unknown rule " + rule)
+	}
+	return nil
+}
+
+// measure returns the size rules compare: the value of a number, or the
+// length of a string or list
+func measure(value reflect.Value) (float64, string) {
+	switch value.Kind() {
+	case reflect.String:
+		return float64(utf8.RuneCountInString(value.String())), " characters"
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return float64(value.Len()), " items"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(value.Int()), ""
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(value.Uint()), ""
+	case reflect.Float32, reflect.Float64:
+		return value.Float(), ""
+	}
+	panic("validate: size rules don't apply to " + value.Type().String())
+}
+
+// compare orders two values of the same kind, times or numbers
+func compare(a, b reflect.Value) int {
+	if a.Type() == timeType && b.Type() == timeType {
+		return a.Interface().(time.Time).Compare(b.Interface().(time.Time))
+	}
+	x, _ := measure(a)
+	y, _ := measure(b)
+	switch {
+	case x < y:
+		return -1
+	case x > y:
+		return 1
+	}
+	return 0
+}
+
+// isEmpty reports whether a value is the zero value of its type. Blank
+// strings and empty lists count as empty.
+func isEmpty(value reflect.Value) bool {
+	switch value.Kind() {
+	case reflect.String:
+		return strings.TrimSpace(value.String()) == ""
+	case reflect.Slice, reflect.Map:
+		return value.Len() == 0
+	}
+	return value.IsZero()
+}
+
+// jsonName returns the name a field has in request bodies
+func jsonName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	if name == "" || name == "-" {
+		return field.Name
+	}
+	return name
+}
+
+func fail(field, rule, param, format string, args ...interface{}) *FieldError {
+	return &FieldError{
+		Field:   field,
+		Rule:    rule,
+		Param:   param,
+		Message: fmt.Sprintf(format, args...),
+	}
+}
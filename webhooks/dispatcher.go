@@ -0,0 +1,159 @@
+package webhooks
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/projectflow/models"
+	"github.com/projectflow/utils/safehttp"
+)
+
+// Event is the JSON body delivered to webhook endpoints
+type Event struct {
+	ID        uuid.UUID   `json:"id"`
+	Type      string      `json:"type"`
+	ProjectID uuid.UUID   `json:"project_id"`
+	CreatedAt time.Time   `json:"created_at"`
+	Data      interface{} `json:"data"`
+}
+
+// Dispatcher delivers events asynchronously and retries failures with exponential backoff
+type Dispatcher struct {
+	Store            *MemoryStore
+	Client           *http.Client
+	MaxAttempts      int           // Attempts per event before giving up
+	BaseDelay        time.Duration // Delay before the first retry, doubled after each failure
+	FailureThreshold int           // Consecutive failures before a webhook is disabled
+}
+
+// NewDispatcher creates a dispatcher with production defaults. Its client
+// refuses to connect to local or private network addresses, whatever the
+// webhook's host resolves to at the time of delivery.
+func NewDispatcher(store *MemoryStore) *Dispatcher {
+	return &Dispatcher{
+		Store:            store,
+		Client:           safehttp.NewClient(10 * time.Second),
+		MaxAttempts:      6,
+		BaseDelay:        30 * time.Second,
+		FailureThreshold: 15,
+	}
+}
+
+// Publish sends an event to every active webhook on the project subscribed to it
+func (d *Dispatcher) Publish(projectID uuid.UUID, eventType string, data interface{}) {
+	subscribers := d.Store.Subscribers(projectID, eventType)
+	if len(subscribers) == 0 {
+		return
+	}
+
+	event := Event{
+		ID:        uuid.New(),
+		Type:      eventType,
+		ProjectID: projectID,
+		CreatedAt: time.Now(),
+		Data:      data,
+	}
+	body, err := json.Marshal(event)
+	if err != nil {
+		log.Printf("webhooks: encoding %s event failed: %v", eventType, err)
+		return
+	}
+
+	for _, webhook := range subscribers {
+		go d.attempt(webhook.ID, webhook, event.ID, eventType, body, 1, false)
+	}
+}
+
+// Redeliver sends a previously logged delivery again, synchronously, and returns the new log entry
+func (d *Dispatcher) Redeliver(webhookID, deliveryID uuid.UUID) (*models.WebhookDelivery, error) {
+	previous, ok := d.Store.Delivery(webhookID, deliveryID)
+	if !ok {
+		return nil, errors.New("delivery not found")
+	}
+	return d.attempt(webhookID, nil, previous.EventID, previous.Event, []byte(previous.Payload), 1, true), nil
+}
+
+// attempt performs a single delivery, logs it and schedules a retry on
+// failure. The first attempt of a published event goes to the webhook as it
+// was when subscribed, so events such as project.deleted still reach webhooks
+// removed right after publishing. Retries look the webhook up again and stop
+// once it is gone or disabled.
+func (d *Dispatcher) attempt(webhookID uuid.UUID, subscribed *models.Webhook, eventID uuid.UUID, eventType string, body []byte, attempt int, redelivery bool) *models.WebhookDelivery {
+	webhook := subscribed
+	if webhook == nil {
+		var ok bool
+		webhook, ok = d.Store.Get(webhookID)
+		if !ok || (!webhook.Active && !redelivery) {
+			return nil
+		}
+	}
+
+	delivery := &models.WebhookDelivery{
+		ID:         uuid.New(),
+		WebhookID:  webhookID,
+		EventID:    eventID,
+		Event:      eventType,
+		Payload:    string(body),
+		Attempt:    attempt,
+		Redelivery: redelivery,
+	}
+
+	start := time.Now()
+	statusCode, err := d.send(webhook, eventID, eventType, body)
+	delivery.DurationMs = time.Since(start).Milliseconds()
+	delivery.DeliveredAt = time.Now()
+	delivery.StatusCode = statusCode
+	delivery.Success = err == nil
+	if err != nil {
+		delivery.Error = err.Error()
+	}
+
+	d.Store.AddDelivery(delivery)
+	disabled := d.Store.RecordResult(webhookID, delivery.Success, d.FailureThreshold)
+
+	// Manual redeliveries are not retried automatically
+	if delivery.Success || redelivery || disabled || attempt >= d.MaxAttempts {
+		return delivery
+	}
+
+	delay := d.BaseDelay << uint(attempt-1)
+	time.AfterFunc(delay, func() {
+		d.attempt(webhookID, nil, eventID, eventType, body, attempt+1, false)
+	})
+	return delivery
+}
+
+// send posts a signed payload and returns the response status code
+func (d *Dispatcher) send(webhook *models.Webhook, eventID uuid.UUID, eventType string, body []byte) (int, error) {
+	req, err := http.NewRequest(http.MethodPost, webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+
+	timestamp := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "ProjectFlow-Webhooks/1.0")
+	req.Header.Set(HeaderEvent, eventType)
+	req.Header.Set(HeaderDelivery, eventID.String())
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(HeaderSignature, Sign(webhook.Secret, timestamp, body))
+
+	resp, err := d.Client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, errors.New("endpoint responded with status " + strconv.Itoa(resp.StatusCode))
+	}
+	return resp.StatusCode, nil
+}
@@ -0,0 +1,31 @@
+package webhooks
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+)
+
+// Headers sent with every delivery
+const (
+	HeaderEvent     = "X-ProjectFlow-Event"
+	HeaderDelivery  = "X-ProjectFlow-Delivery"
+	HeaderTimestamp = "X-ProjectFlow-Timestamp"
+	HeaderSignature = "X-ProjectFlow-Signature"
+)
+
+// Sign returns the signature header value for a payload. The HMAC-SHA256 is
+// computed over "<timestamp>.<body>" so receivers can reject replayed requests.
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks a signature header value in constant time
+func Verify(secret string, timestamp int64, body []byte, signature string) bool {
+	return hmac.Equal([]byte(Sign(secret, timestamp, body)), []byte(signature))
+}
@@ -0,0 +1,184 @@
+package webhooks
+
+import (
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/projectflow/models"
+)
+
+// Number of deliveries kept per webhook in the delivery log
+const deliveryLogSize = 100
+
+// MemoryStore keeps webhooks and their delivery logs in memory
+type MemoryStore struct {
+	webhooks   map[uuid.UUID]*models.Webhook
+	deliveries map[uuid.UUID][]*models.WebhookDelivery
+	mu         sync.RWMutex
+}
+
+// NewMemoryStore creates an empty webhook store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		webhooks:   make(map[uuid.UUID]*models.Webhook),
+		deliveries: make(map[uuid.UUID][]*models.WebhookDelivery),
+	}
+}
+
+// Save creates or replaces a webhook
+func (s *MemoryStore) Save(webhook *models.Webhook) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.webhooks[webhook.ID] = webhook
+}
+
+// Get returns a copy of a webhook by ID
+func (s *MemoryStore) Get(id uuid.UUID) (*models.Webhook, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	webhook, ok := s.webhooks[id]
+	if !ok {
+		return nil, false
+	}
+	copied := *webhook
+	return &copied, true
+}
+
+// Update changes a webhook in place while holding the store's lock, so
+// concurrent changes such as delivery results are not overwritten. It
+// returns a copy of the updated webhook, or false if it no longer exists.
+func (s *MemoryStore) Update(id uuid.UUID, change func(*models.Webhook)) (*models.Webhook, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	webhook, ok := s.webhooks[id]
+	if !ok {
+		return nil, false
+	}
+	change(webhook)
+	copied := *webhook
+	return &copied, true
+}
+
+// Delete removes a webhook and its delivery log
+func (s *MemoryStore) Delete(id uuid.UUID) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.webhooks, id)
+	delete(s.deliveries, id)
+}
+
+// DeleteProject removes every webhook registered on a project
+func (s *MemoryStore) DeleteProject(projectID uuid.UUID) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for id, webhook := range s.webhooks {
+		if webhook.ProjectID == projectID {
+			delete(s.webhooks, id)
+			delete(s.deliveries, id)
+		}
+	}
+}
+
+// ListByProject returns copies of every webhook registered on a project
+func (s *MemoryStore) ListByProject(projectID uuid.UUID) []*models.Webhook {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	list := []*models.Webhook{}
+	for _, webhook := range s.webhooks {
+		if webhook.ProjectID == projectID {
+			copied := *webhook
+			list = append(list, &copied)
+		}
+	}
+	return list
+}
+
+// Subscribers returns copies of the active webhooks on a project subscribed to an event
+func (s *MemoryStore) Subscribers(projectID uuid.UUID, event string) []*models.Webhook {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var list []*models.Webhook
+	for _, webhook := range s.webhooks {
+		if webhook.ProjectID == projectID && webhook.Active && webhook.Subscribes(event) {
+			copied := *webhook
+			list = append(list, &copied)
+		}
+	}
+	return list
+}
+
+// AddDelivery appends a delivery to the webhook's log, dropping the oldest
+// entries. Deliveries of removed webhooks are not kept.
+func (s *MemoryStore) AddDelivery(delivery *models.WebhookDelivery) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.webhooks[delivery.WebhookID]; !ok {
+		return
+	}
+
+	log := append(s.deliveries[delivery.WebhookID], delivery)
+	if len(log) > deliveryLogSize {
+		log = log[len(log)-deliveryLogSize:]
+	}
+	s.deliveries[delivery.WebhookID] = log
+}
+
+// Deliveries returns a webhook's delivery log, newest first
+func (s *MemoryStore) Deliveries(webhookID uuid.UUID) []*models.WebhookDelivery {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	log := s.deliveries[webhookID]
+	list := make([]*models.WebhookDelivery, 0, len(log))
+	for i := len(log) - 1; i >= 0; i-- {
+		list = append(list, log[i])
+	}
+	return list
+}
+
+// Delivery returns a single delivery from a webhook's log
+func (s *MemoryStore) Delivery(webhookID, deliveryID uuid.UUID) (*models.WebhookDelivery, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, delivery := range s.deliveries[webhookID] {
+		if delivery.ID == deliveryID {
+			return delivery, true
+		}
+	}
+	return nil, false
+}
+
+// RecordResult updates the webhook's failure streak and disables it once the
+// streak reaches threshold. It returns true when the webhook is now disabled.
+func (s *MemoryStore) RecordResult(webhookID uuid.UUID, success bool, threshold int) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	webhook, ok := s.webhooks[webhookID]
+	if !ok {
+		return true
+	}
+	if success {
+		webhook.ConsecutiveFailures = 0
+		return false
+	}
+
+	webhook.ConsecutiveFailures++
+	if webhook.Active && webhook.ConsecutiveFailures >= threshold {
+		now := time.Now()
+		webhook.Active = false
+		webhook.DisabledAt = &now
+		webhook.UpdatedAt = now
+	}
+	return !webhook.Active
+}